@@ -0,0 +1,28 @@
+// Package commands implements CLI subcommands for imapsync-go.
+package commands
+
+import (
+	"github.com/greeddj/imapsync-go/internal/app"
+	"github.com/urfave/cli/v3"
+)
+
+// Check returns the "check" subcommand definition.
+func Check() *cli.Command {
+	return &cli.Command{
+		Name:   "check",
+		Usage:  "diagnose connectivity, TLS, login and capabilities of every configured account",
+		Action: app.ActionCheck,
+		Flags: append([]cli.Flag{
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "report format: text or json",
+				Value: "text",
+			},
+			&cli.BoolFlag{
+				Name:    "verbose",
+				Aliases: []string{"V"},
+				Sources: cli.EnvVars("IMAPSYNC_VERBOSE"),
+			},
+		}, traceFlags()...),
+	}
+}
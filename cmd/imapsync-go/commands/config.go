@@ -0,0 +1,63 @@
+// Package commands implements CLI subcommands for imapsync-go.
+package commands
+
+import (
+	"github.com/greeddj/imapsync-go/internal/app"
+	"github.com/urfave/cli/v3"
+)
+
+// Config returns the "config" command definition, whose subcommands
+// write, inspect and print the config file.
+func Config() *cli.Command {
+	return &cli.Command{
+		Name:  "config",
+		Usage: "write, validate, describe and print the config file",
+		Commands: []*cli.Command{
+			{
+				Name:   "init",
+				Usage:  "write a config file interactively, testing both logins",
+				Action: app.ActionConfigInit,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "output",
+						Aliases: []string{"o"},
+						Usage:   "path of the YAML file to write",
+						Value:   "config.yaml",
+					},
+					&cli.BoolFlag{
+						Name:  "force",
+						Usage: "overwrite the file without asking",
+					},
+				},
+			},
+			{
+				Name:   "validate",
+				Usage:  "check the config file for unknown keys, bad values and conflicting map entries",
+				Action: app.ActionConfigValidate,
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "strict",
+						Usage: "fail on warnings too",
+					},
+				},
+			},
+			{
+				Name:   "schema",
+				Usage:  "print the JSON Schema of the config file, for editors",
+				Action: app.ActionConfigSchema,
+			},
+			{
+				Name:   "print",
+				Usage:  "print the effective config, with variables expanded and defaults filled in",
+				Action: app.ActionConfigPrint,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "format",
+						Usage: "output format: yaml or json",
+						Value: "yaml",
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,67 @@
+// Package commands implements CLI subcommands for imapsync-go.
+package commands
+
+import (
+	"github.com/greeddj/imapsync-go/internal/app"
+	"github.com/urfave/cli/v3"
+)
+
+// Dedupe returns the "dedupe" subcommand definition.
+func Dedupe() *cli.Command {
+	return &cli.Command{
+		Name:   "dedupe",
+		Usage:  "find and delete duplicate messages on the destination server",
+		Action: app.ActionDedupe,
+		Flags: append([]cli.Flag{
+			&cli.StringSliceFlag{
+				Name:    "folder",
+				Aliases: []string{"f"},
+				Usage:   "folder to scan (repeatable; default: every folder)",
+			},
+			&cli.BoolFlag{
+				Name:    "recursive",
+				Aliases: []string{"r"},
+				Usage:   "also scan the subfolders of each --folder",
+			},
+			&cli.BoolFlag{
+				Name:  "across",
+				Usage: "treat copies in different folders as duplicates too",
+			},
+			&cli.StringFlag{
+				Name:  "match",
+				Usage: "what copies must share: message-id, size (Message-Id and size) or hash (Message-Id, size and body)",
+				Value: "message-id",
+			},
+			&cli.StringFlag{
+				Name:  "keep",
+				Usage: "which copy survives: oldest or flagged",
+				Value: "oldest",
+			},
+			&cli.BoolFlag{
+				Name:    "dry-run",
+				Aliases: []string{"n"},
+				Usage:   "show what would be deleted without deleting anything",
+			},
+			&cli.StringFlag{
+				Name:  "report",
+				Usage: "JSON Lines file listing every duplicate and whether it was kept or deleted",
+			},
+			&cli.BoolFlag{
+				Name:    "confirm",
+				Aliases: []string{"y", "yes"},
+				Usage:   "auto-confirm (skip confirmation prompt); required with --quiet unless --dry-run",
+				Sources: cli.EnvVars("IMAPSYNC_CONFIRM"),
+			},
+			&cli.BoolFlag{
+				Name:    "verbose",
+				Aliases: []string{"V"},
+				Sources: cli.EnvVars("IMAPSYNC_VERBOSE"),
+			},
+			&cli.BoolFlag{
+				Name:    "quiet",
+				Aliases: []string{"q"},
+				Sources: cli.EnvVars("IMAPSYNC_QUIET"),
+			},
+		}, traceFlags()...),
+	}
+}
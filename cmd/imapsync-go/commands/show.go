@@ -12,7 +12,20 @@ func Show() *cli.Command {
 		Name:   "show",
 		Usage:  "show IMAP dirs in source and destination servers",
 		Action: app.ActionShow,
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "output format: table, json or csv",
+				Value: "table",
+			},
+			&cli.BoolFlag{
+				Name:  "compare",
+				Usage: "line up source and destination folders through the folder mapping and show the differences",
+			},
+			&cli.BoolFlag{
+				Name:  "with-unique",
+				Usage: "with --compare, scan Message-Ids to count the messages missing from each destination folder",
+			},
 			&cli.BoolFlag{
 				Name:    "verbose",
 				Aliases: []string{"V"},
@@ -24,6 +37,6 @@ func Show() *cli.Command {
 				Usage:   "suppress progress bars so output is pipe-friendly",
 				Sources: cli.EnvVars("IMAPSYNC_QUIET"),
 			},
-		},
+		}, traceFlags()...),
 	}
 }
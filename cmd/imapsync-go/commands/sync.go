@@ -12,7 +12,7 @@ func Sync() *cli.Command {
 		Name:   "sync",
 		Usage:  "sync IMAP dir(s) between two servers",
 		Action: app.ActionSync,
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
 			&cli.StringFlag{
 				Name:    "src-folder",
 				Aliases: []string{"s"},
@@ -47,13 +47,13 @@ func Sync() *cli.Command {
 			},
 			&cli.IntFlag{
 				Name:    "bps-down",
-				Usage:   "max bytes/sec read from the source server (0 = unlimited; for Gmail try 300000)",
+				Usage:   "max bytes/sec read from the source server (0 = unlimited, or a known provider's recommendation with --provider-limits auto)",
 				Value:   0,
 				Sources: cli.EnvVars("IMAPSYNC_BPS_DOWN"),
 			},
 			&cli.IntFlag{
 				Name:    "bps-up",
-				Usage:   "max bytes/sec written to the destination server (0 = unlimited; for Gmail try 300000)",
+				Usage:   "max bytes/sec written to the destination server (0 = unlimited, or a known provider's recommendation with --provider-limits auto)",
 				Value:   0,
 				Sources: cli.EnvVars("IMAPSYNC_BPS_UP"),
 			},
@@ -63,6 +63,90 @@ func Sync() *cli.Command {
 				Value:   0,
 				Sources: cli.EnvVars("IMAPSYNC_MAX_CONNECTIONS"),
 			},
-		},
+			&cli.StringFlag{
+				Name:    "provider-limits",
+				Usage:   "for a known provider such as Gmail: auto applies its recommended limits where none are set, warn only prints them, off does neither",
+				Value:   "warn",
+				Sources: cli.EnvVars("IMAPSYNC_PROVIDER_LIMITS"),
+			},
+			&cli.IntFlag{
+				Name:    "max-inflight-bytes",
+				Usage:   "cap on fetched message bytes held in memory across all workers (0 = unlimited)",
+				Value:   128 << 20,
+				Sources: cli.EnvVars("IMAPSYNC_MAX_INFLIGHT_BYTES"),
+			},
+			&cli.StringFlag{
+				Name:    "since",
+				Usage:   "only copy messages stored on or after this date (YYYY-MM-DD)",
+				Sources: cli.EnvVars("IMAPSYNC_SINCE"),
+			},
+			&cli.StringFlag{
+				Name:    "before",
+				Usage:   "only copy messages stored before this date (YYYY-MM-DD)",
+				Sources: cli.EnvVars("IMAPSYNC_BEFORE"),
+			},
+			&cli.StringFlag{
+				Name:    "max-age",
+				Usage:   "only copy messages newer than this age (e.g. 90d, 12w, 6m, 2y)",
+				Sources: cli.EnvVars("IMAPSYNC_MAX_AGE"),
+			},
+			&cli.StringFlag{
+				Name:    "min-size",
+				Usage:   "skip messages smaller than this size (bytes, or with K/M/G suffix)",
+				Sources: cli.EnvVars("IMAPSYNC_MIN_SIZE"),
+			},
+			&cli.StringFlag{
+				Name:    "max-size",
+				Usage:   "skip messages larger than this size (bytes, or with K/M/G suffix, e.g. 25M)",
+				Sources: cli.EnvVars("IMAPSYNC_MAX_SIZE"),
+			},
+			&cli.StringFlag{
+				Name:    "failures-file",
+				Usage:   "JSON Lines file that receives every message that could not be copied (empty = don't write)",
+				Value:   "imapsync-failures.jsonl",
+				Sources: cli.EnvVars("IMAPSYNC_FAILURES_FILE"),
+			},
+			&cli.StringFlag{
+				Name:  "retry-failures",
+				Usage: "re-attempt only the messages listed in a failures file from an earlier run",
+			},
+			&cli.BoolFlag{
+				Name:    "ignore-quota",
+				Usage:   "sync even when the plan does not fit in the destination quota",
+				Sources: cli.EnvVars("IMAPSYNC_IGNORE_QUOTA"),
+			},
+			&cli.BoolFlag{
+				Name:    "two-way",
+				Usage:   "copy new messages in both directions (single source account only)",
+				Sources: cli.EnvVars("IMAPSYNC_TWO_WAY"),
+			},
+			&cli.StringFlag{
+				Name:    "state-file",
+				Usage:   "file where --two-way remembers what both sides held after the last run",
+				Value:   "imapsync-state.json",
+				Sources: cli.EnvVars("IMAPSYNC_STATE_FILE"),
+			},
+			&cli.BoolFlag{
+				Name:    "sync-flags",
+				Usage:   "with --two-way, carry flag changes (seen, answered, flagged, keywords) across",
+				Sources: cli.EnvVars("IMAPSYNC_SYNC_FLAGS"),
+			},
+			&cli.BoolFlag{
+				Name:    "sync-deletes",
+				Usage:   "with --two-way, delete messages on one side that were deleted on the other since the last run; with --quiet, also needs --confirm",
+				Sources: cli.EnvVars("IMAPSYNC_SYNC_DELETES"),
+			},
+			&cli.StringFlag{
+				Name:    "conflict",
+				Usage:   "with --sync-flags, which flags win when both sides changed: source, destination, union or newest",
+				Value:   "source",
+				Sources: cli.EnvVars("IMAPSYNC_CONFLICT"),
+			},
+			&cli.StringFlag{
+				Name:    "metrics-listen",
+				Usage:   "serve Prometheus metrics on this address (e.g. :9090) while the sync runs",
+				Sources: cli.EnvVars("IMAPSYNC_METRICS_LISTEN"),
+			},
+		}, traceFlags()...),
 	}
 }
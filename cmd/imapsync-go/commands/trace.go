@@ -0,0 +1,25 @@
+// Package commands implements CLI subcommands for imapsync-go.
+package commands
+
+import (
+	"github.com/greeddj/imapsync-go/internal/client"
+	"github.com/urfave/cli/v3"
+)
+
+// traceFlags are the IMAP wire trace flags shared by every command that
+// talks to a server.
+func traceFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    "trace-imap",
+			Usage:   "record every IMAP command and response to this file, with credentials redacted",
+			Sources: cli.EnvVars("IMAPSYNC_TRACE_IMAP"),
+		},
+		&cli.IntFlag{
+			Name:    "trace-literal-limit",
+			Usage:   "bytes of each literal (message body) kept in the IMAP trace",
+			Value:   client.DefaultTraceLiteralLimit,
+			Sources: cli.EnvVars("IMAPSYNC_TRACE_LITERAL_LIMIT"),
+		},
+	}
+}
@@ -0,0 +1,85 @@
+// Package commands implements CLI subcommands for imapsync-go.
+package commands
+
+import (
+	"github.com/greeddj/imapsync-go/internal/app"
+	"github.com/urfave/cli/v3"
+)
+
+// TUI returns the "tui" subcommand definition.
+func TUI() *cli.Command {
+	return &cli.Command{
+		Name:   "tui",
+		Usage:  "pick folders to sync and watch the copy in a full-screen terminal UI",
+		Action: app.ActionTUI,
+		Flags: append([]cli.Flag{
+			&cli.IntFlag{
+				Name:    "workers",
+				Aliases: []string{"w"},
+				Value:   4,
+				Sources: cli.EnvVars("IMAPSYNC_WORKERS"),
+			},
+			&cli.IntFlag{
+				Name:    "bps-down",
+				Usage:   "max bytes/sec read from the source server (0 = unlimited, or a known provider's recommendation with --provider-limits auto)",
+				Value:   0,
+				Sources: cli.EnvVars("IMAPSYNC_BPS_DOWN"),
+			},
+			&cli.IntFlag{
+				Name:    "bps-up",
+				Usage:   "max bytes/sec written to the destination server (0 = unlimited, or a known provider's recommendation with --provider-limits auto)",
+				Value:   0,
+				Sources: cli.EnvVars("IMAPSYNC_BPS_UP"),
+			},
+			&cli.IntFlag{
+				Name:    "max-connections",
+				Usage:   "hard cap on simultaneous IMAP connections per side (0 = workers)",
+				Value:   0,
+				Sources: cli.EnvVars("IMAPSYNC_MAX_CONNECTIONS"),
+			},
+			&cli.StringFlag{
+				Name:    "provider-limits",
+				Usage:   "for a known provider such as Gmail: auto applies its recommended limits where none are set, warn only logs them, off does neither",
+				Value:   "warn",
+				Sources: cli.EnvVars("IMAPSYNC_PROVIDER_LIMITS"),
+			},
+			&cli.IntFlag{
+				Name:    "max-inflight-bytes",
+				Usage:   "cap on fetched message bytes held in memory across all workers (0 = unlimited)",
+				Value:   128 << 20,
+				Sources: cli.EnvVars("IMAPSYNC_MAX_INFLIGHT_BYTES"),
+			},
+			&cli.StringFlag{
+				Name:    "since",
+				Usage:   "only copy messages stored on or after this date (YYYY-MM-DD)",
+				Sources: cli.EnvVars("IMAPSYNC_SINCE"),
+			},
+			&cli.StringFlag{
+				Name:    "before",
+				Usage:   "only copy messages stored before this date (YYYY-MM-DD)",
+				Sources: cli.EnvVars("IMAPSYNC_BEFORE"),
+			},
+			&cli.StringFlag{
+				Name:    "max-age",
+				Usage:   "only copy messages newer than this age (e.g. 90d, 12w, 6m, 2y)",
+				Sources: cli.EnvVars("IMAPSYNC_MAX_AGE"),
+			},
+			&cli.StringFlag{
+				Name:    "min-size",
+				Usage:   "skip messages smaller than this size (bytes, or with K/M/G suffix)",
+				Sources: cli.EnvVars("IMAPSYNC_MIN_SIZE"),
+			},
+			&cli.StringFlag{
+				Name:    "max-size",
+				Usage:   "skip messages larger than this size (bytes, or with K/M/G suffix, e.g. 25M)",
+				Sources: cli.EnvVars("IMAPSYNC_MAX_SIZE"),
+			},
+			&cli.StringFlag{
+				Name:    "failures-file",
+				Usage:   "JSON Lines file that receives every message that could not be copied (empty = don't write)",
+				Value:   "imapsync-failures.jsonl",
+				Sources: cli.EnvVars("IMAPSYNC_FAILURES_FILE"),
+			},
+		}, traceFlags()...),
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 	"os/signal"
 	"runtime"
@@ -13,6 +14,10 @@ import (
 	"github.com/greeddj/imapsync-go/cmd/imapsync-go/commands"
 	"github.com/greeddj/imapsync-go/cmd/imapsync-go/helpers"
 	appkg "github.com/greeddj/imapsync-go/internal/app"
+	"github.com/greeddj/imapsync-go/internal/config"
+	"github.com/greeddj/imapsync-go/internal/logging"
+	"github.com/greeddj/imapsync-go/internal/progress"
+	"github.com/greeddj/imapsync-go/internal/secret"
 
 	"github.com/urfave/cli/v3"
 )
@@ -37,6 +42,14 @@ func run() int {
 		_, _ = fmt.Fprintln(c.Writer, Version)
 	}
 
+	// closeLog is set once Before has set up the structured log.
+	var closeLog func() error
+	defer func() {
+		if closeLog != nil {
+			_ = closeLog()
+		}
+	}()
+
 	app := &cli.Command{
 		Name:                   "imapsync-go",
 		Usage:                  "IMAP to IMAP synchronization tool",
@@ -51,10 +64,83 @@ func run() int {
 				Usage:   "path to configuration file (JSON or YAML)",
 				Sources: cli.EnvVars("IMAPSYNC_CONFIG"),
 			},
+			&cli.StringFlag{
+				Name:    "log-file",
+				Usage:   "write a structured log to this file (- for stderr)",
+				Sources: cli.EnvVars("IMAPSYNC_LOG_FILE"),
+			},
+			&cli.StringFlag{
+				Name:    "log-level",
+				Value:   "info",
+				Usage:   "log level: debug, info, warn or error",
+				Sources: cli.EnvVars("IMAPSYNC_LOG_LEVEL"),
+			},
+			&cli.StringFlag{
+				Name:    "log-format",
+				Value:   logging.FormatText,
+				Usage:   "log format: text or json",
+				Sources: cli.EnvVars("IMAPSYNC_LOG_FORMAT"),
+			},
+			&cli.StringFlag{
+				Name:    "log-max-size",
+				Value:   "100M",
+				Usage:   "rotate the log file once it reaches this size (0 = never)",
+				Sources: cli.EnvVars("IMAPSYNC_LOG_MAX_SIZE"),
+			},
+			&cli.IntFlag{
+				Name:    "log-max-backups",
+				Value:   5,
+				Usage:   "rotated log files to keep",
+				Sources: cli.EnvVars("IMAPSYNC_LOG_MAX_BACKUPS"),
+			},
+			&cli.StringFlag{
+				Name:    "progress",
+				Value:   progress.ModeAuto,
+				Usage:   "progress output: auto, tty (bars), plain (status lines) or json (NDJSON events)",
+				Sources: cli.EnvVars("IMAPSYNC_PROGRESS"),
+			},
+			&cli.IntFlag{
+				Name:    "progress-fd",
+				Value:   1,
+				Usage:   "file descriptor for plain and json progress output",
+				Sources: cli.EnvVars("IMAPSYNC_PROGRESS_FD"),
+			},
+			&cli.DurationFlag{
+				Name:    "progress-interval",
+				Usage:   "time between plain and json progress updates (0 = 10s plain, 1s json)",
+				Sources: cli.EnvVars("IMAPSYNC_PROGRESS_INTERVAL"),
+			},
+		},
+		Before: func(ctx context.Context, c *cli.Command) (context.Context, error) {
+			maxSize, err := config.ParseSize(c.String("log-max-size"))
+			if err != nil {
+				return ctx, fmt.Errorf("--log-max-size: %w", err)
+			}
+			logger, closeFn, err := logging.New(logging.Options{
+				File:       c.String("log-file"),
+				Level:      c.String("log-level"),
+				Format:     c.String("log-format"),
+				MaxSize:    maxSize,
+				MaxBackups: c.Int("log-max-backups"),
+			})
+			if err != nil {
+				return ctx, err
+			}
+			closeLog = closeFn
+			slog.SetDefault(logger)
+			return ctx, progress.Configure(progress.Options{
+				Mode:     c.String("progress"),
+				FD:       c.Int("progress-fd"),
+				Interval: c.Duration("progress-interval"),
+			})
 		},
 		Commands: []*cli.Command{
 			commands.Sync(),
 			commands.Show(),
+			commands.TUI(),
+			commands.Dedupe(),
+			commands.Check(),
+			commands.Config(),
 		},
 	}
 
@@ -74,6 +160,9 @@ func run() int {
 	defer stop()
 
 	if err := app.Run(ctx, os.Args); err != nil {
+		if closeLog != nil {
+			slog.Error("run failed", "error", err)
+		}
 		switch {
 		case errors.Is(err, context.Canceled):
 			fmt.Fprintln(os.Stderr, "Cancelled.")
@@ -82,7 +171,7 @@ func run() int {
 			// ActionSync has already printed a human-friendly summary.
 			return 1
 		default:
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			fmt.Fprintf(os.Stderr, "Error: %s\n", secret.Redact(err.Error()))
 			return 1
 		}
 	}
@@ -0,0 +1,233 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/greeddj/imapsync-go/internal/client"
+	"github.com/greeddj/imapsync-go/internal/config"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	"github.com/urfave/cli/v3"
+)
+
+// Output formats of the check command.
+const (
+	formatText = "text"
+	formatJSON = "json"
+)
+
+// checkedAccount is one account in the check report.
+type checkedAccount struct {
+	*client.Diagnosis
+	Role  string `json:"role"`
+	Label string `json:"label"`
+}
+
+// ActionCheck diagnoses the connection to every source and the destination:
+// DNS, TCP, TLS, capabilities, login, namespaces and the delimiter. The
+// report is printed as text or, for support tickets, as JSON.
+func ActionCheck(ctx context.Context, c *cli.Command) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	format := c.String("format")
+	if format != formatText && format != formatJSON {
+		return fmt.Errorf("--format must be %s or %s, got %q", formatText, formatJSON, format)
+	}
+
+	cfg, err := config.New(c)
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+
+	sources := cfg.SourceList()
+	accounts := make([]checkedAccount, 0, len(sources)+1)
+	creds := make([]config.Credentials, 0, len(sources)+1)
+	for _, src := range sources {
+		accounts = append(accounts, checkedAccount{Role: "source", Label: src.Label})
+		creds = append(creds, src.Credentials)
+	}
+	accounts = append(accounts, checkedAccount{Role: "destination", Label: cfg.Dst.Label})
+	creds = append(creds, cfg.Dst)
+
+	trace, closeTrace, err := openTrace(c)
+	if err != nil {
+		return err
+	}
+	defer closeTrace()
+
+	if format == formatText {
+		fmt.Printf("🔎 Checking %d accounts...\n\n", len(accounts))
+	}
+
+	// Diagnose never fails as a whole; each account records where it
+	// stopped, so every account is checked even when one is down.
+	var wg sync.WaitGroup
+	for i := range accounts {
+		wg.Go(func() {
+			accounts[i].Diagnosis = client.Diagnose(ctx, creds[i].Server, creds[i].User, creds[i].Pass, client.Options{
+				UseTLS:    true,
+				Auth:      creds[i].Auth,
+				Label:     accounts[i].Label,
+				Providers: cfg.ProviderTable(),
+				Verbose:   c.Bool("verbose"),
+				Trace:     trace,
+				Logger:    accountLogger(accounts[i].Role, accounts[i].Label),
+			})
+		})
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, a := range accounts {
+		if !a.OK() {
+			failed++
+			slog.Error("check failed", "side", a.Role, "account", a.Label, "server", a.Server, "step", a.Error)
+		}
+	}
+
+	if format == formatJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(struct {
+			Accounts []checkedAccount `json:"accounts"`
+		}{accounts}); err != nil {
+			return fmt.Errorf("write report: %w", err)
+		}
+	} else {
+		for _, a := range accounts {
+			printDiagnosis(a)
+			fmt.Println()
+		}
+		if failed == 0 {
+			fmt.Printf("✅ Every account passed the check\n")
+		} else {
+			fmt.Printf("❌ %d of %d accounts failed the check\n", failed, len(accounts))
+		}
+	}
+
+	if failed > 0 {
+		return ErrSilentExit
+	}
+	return nil
+}
+
+// printDiagnosis renders one account's diagnosis as a borderless table in
+// the style of show. Steps that were never reached are left out.
+func printDiagnosis(a checkedAccount) {
+	d := a.Diagnosis
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.Style().Options.DrawBorder = false
+	t.Style().Options.SeparateColumns = false
+	title := "Source"
+	if a.Role == "destination" {
+		title = "Destination"
+	}
+	t.SetTitle(text.Colors{text.Bold, text.FgHiCyan}.Sprint(fmt.Sprintf("%s [%s]", title, a.Label)))
+
+	t.AppendRows([]table.Row{
+		{"Server", d.Server},
+		{"User", d.User},
+	})
+	if d.Provider != "" {
+		t.AppendRow(table.Row{"Provider", d.Provider})
+	}
+	if d.DNS != nil {
+		t.AppendRow(table.Row{"DNS", stepLine(d.DNS.Error, strings.Join(d.DNS.Addresses, ", "), d.DNS.DurationMS)})
+	}
+	if d.TCP != nil {
+		t.AppendRow(table.Row{"TCP", stepLine(d.TCP.Error, d.TCP.Address, d.TCP.DurationMS)})
+	}
+	if d.TLS != nil {
+		tlsErr, detail := d.TLS.Error, fmt.Sprintf("%s, %s, certificate valid", d.TLS.Version, d.TLS.CipherSuite)
+		if tlsErr == "" && !d.TLS.Verified {
+			tlsErr = fmt.Sprintf("%s, %s, certificate rejected: %s", d.TLS.Version, d.TLS.CipherSuite, d.TLS.VerifyError)
+		}
+		t.AppendRow(table.Row{"TLS", stepLine(tlsErr, detail, d.TLS.DurationMS)})
+		for _, cert := range d.TLS.Certificates {
+			t.AppendRow(table.Row{"Certificate", fmt.Sprintf("%s\nissued by %s, valid %s – %s",
+				cert.Subject, cert.Issuer, cert.NotBefore.Format("2006-01-02"), cert.NotAfter.Format("2006-01-02"))})
+		}
+	}
+	if len(d.Capabilities) > 0 {
+		t.AppendRow(table.Row{"Capabilities", strings.Join(d.Capabilities, " ")})
+		mechs := strings.Join(d.AuthMechanisms, " ")
+		if mechs == "" {
+			mechs = "none advertised (LOGIN only)"
+		}
+		t.AppendRow(table.Row{"AUTH", mechs})
+	}
+	if d.Login != nil {
+		t.AppendRow(table.Row{"Login", stepLine(d.Login.Error, d.Login.Method, d.Login.DurationMS)})
+	}
+	if d.Namespaces != nil {
+		t.AppendRow(table.Row{"Namespace", formatNamespaces(d.Namespaces)})
+	}
+	if d.Delimiter != "" {
+		t.AppendRow(table.Row{"Delimiter", fmt.Sprintf("%q", d.Delimiter)})
+	}
+	if d.Extensions != nil {
+		parts := make([]string, 0, len(client.Extensions))
+		for _, ext := range client.Extensions {
+			mark := "❌"
+			if d.Extensions[ext] {
+				mark = "✅"
+			}
+			parts = append(parts, mark+" "+ext)
+		}
+		t.AppendRow(table.Row{"Extensions", strings.Join(parts, "  ")})
+	}
+	if d.Error != "" {
+		t.AppendRow(table.Row{"Result", "❌ " + d.Error})
+	} else {
+		t.AppendRow(table.Row{"Result", "✅ ready to sync"})
+	}
+
+	t.SetColumnConfigs([]table.ColumnConfig{
+		{Number: 2, WidthMax: 100, WidthMaxEnforcer: text.WrapSoft},
+	})
+	t.Render()
+}
+
+// stepLine renders one step as a mark, its detail or error, and its
+// duration.
+func stepLine(errMsg, detail string, ms int64) string {
+	if errMsg != "" {
+		return fmt.Sprintf("❌ %s (%d ms)", errMsg, ms)
+	}
+	return fmt.Sprintf("✅ %s (%d ms)", detail, ms)
+}
+
+// formatNamespaces renders the NAMESPACE reply as one line per namespace
+// class that has entries.
+func formatNamespaces(ns *client.NamespaceCheck) string {
+	if ns.Error != "" {
+		return "⚠️  " + ns.Error
+	}
+	var lines []string
+	for _, class := range []struct {
+		name string
+		list []client.Namespace
+	}{{"personal", ns.Personal}, {"other users", ns.Other}, {"shared", ns.Shared}} {
+		if len(class.list) == 0 {
+			continue
+		}
+		parts := make([]string, 0, len(class.list))
+		for _, n := range class.list {
+			parts = append(parts, fmt.Sprintf("%q %q", n.Prefix, n.Delimiter))
+		}
+		lines = append(lines, class.name+": "+strings.Join(parts, ", "))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,65 @@
+package app
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/greeddj/imapsync-go/internal/client"
+)
+
+// Test_checkedAccount_jsonFlattensDiagnosis asserts that the JSON report
+// carries the diagnosis fields beside the role and label, as support
+// tickets expect, and leaves out steps that were never reached.
+func Test_checkedAccount_jsonFlattensDiagnosis(t *testing.T) {
+	t.Parallel()
+
+	a := checkedAccount{
+		Role:  "destination",
+		Label: "dst",
+		Diagnosis: &client.Diagnosis{
+			Server: "imap.example.com:993",
+			User:   "me",
+			DNS:    &client.DNSCheck{Host: "imap.example.com", Error: "no such host"},
+			Error:  "DNS lookup failed",
+		},
+	}
+	raw, err := json.Marshal(a)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(raw, &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	for _, key := range []string{"role", "label", "server", "user", "dns", "error"} {
+		if _, ok := got[key]; !ok {
+			t.Errorf("report lacks %q: %s", key, raw)
+		}
+	}
+	for _, key := range []string{"tcp", "tls", "login", "delimiter"} {
+		if _, ok := got[key]; ok {
+			t.Errorf("report has unreached step %q: %s", key, raw)
+		}
+	}
+}
+
+// Test_formatNamespaces_classes asserts that empty namespace classes are
+// left out and a failed NAMESPACE shows its error.
+func Test_formatNamespaces_classes(t *testing.T) {
+	t.Parallel()
+
+	got := formatNamespaces(&client.NamespaceCheck{
+		Personal: []client.Namespace{{Prefix: "INBOX.", Delimiter: "."}},
+		Shared:   []client.Namespace{{Prefix: "#shared."}},
+	})
+	want := "personal: \"INBOX.\" \".\"\nshared: \"#shared.\" \"\""
+	if got != want {
+		t.Errorf("formatNamespaces = %q, want %q", got, want)
+	}
+
+	got = formatNamespaces(&client.NamespaceCheck{Error: "NAMESPACE failed"})
+	if !strings.Contains(got, "NAMESPACE failed") {
+		t.Errorf("formatNamespaces = %q, want the error", got)
+	}
+}
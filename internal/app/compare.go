@@ -0,0 +1,292 @@
+package app
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/greeddj/imapsync-go/internal/client"
+	"github.com/greeddj/imapsync-go/internal/config"
+	"github.com/greeddj/imapsync-go/internal/progress"
+	"github.com/greeddj/imapsync-go/internal/utils"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+)
+
+// folderComparison is one row of show --compare: a source folder and the
+// destination folder sync maps it to. Rows without a source folder are
+// destination folders no mapping targets. Missing is only set by
+// --with-unique.
+type folderComparison struct {
+	Missing             *int   `json:"missing,omitempty"`
+	Source              string `json:"source,omitempty"`
+	SourceFolder        string `json:"source_folder,omitempty"`
+	DestinationFolder   string `json:"destination_folder"`
+	SourceSize          uint64 `json:"source_size"`
+	DestinationSize     uint64 `json:"destination_size"`
+	DeltaMessages       int64  `json:"delta_messages"`
+	DeltaSize           int64  `json:"delta_size"`
+	SourceMessages      uint32 `json:"source_messages"`
+	DestinationMessages uint32 `json:"destination_messages"`
+	SourceExists        bool   `json:"source_exists"`
+	DestinationExists   bool   `json:"destination_exists"`
+}
+
+// compareMappings returns the folder mappings sync would use for one source:
+// its map, prefixed and expanded with subfolders, or without a map every
+// source folder under its name in the destination's delimiter.
+func compareMappings(ctx context.Context, cli *client.Client, src config.Source, boxes []*client.MailboxInfo, dstDelimiter string) ([]config.DirectoryMapping, error) {
+	srcDelimiter := cli.GetDelimiter()
+	if len(src.Map) == 0 {
+		mappings := make([]config.DirectoryMapping, 0, len(boxes))
+		for _, b := range boxes {
+			mappings = append(mappings, config.DirectoryMapping{
+				Source:      b.Name,
+				Destination: autoDestination(b.Name, src.Prefix, srcDelimiter, dstDelimiter),
+			})
+		}
+		return mappings, nil
+	}
+	mappings := slices.Clone(src.Map)
+	for i := range mappings {
+		if src.Prefix != "" {
+			mappings[i].Destination = prefixFolder(src.Prefix, mappings[i].Destination, dstDelimiter)
+		}
+	}
+	return expandMappingsWithSubfolders(ctx, cli, mappings, srcDelimiter, dstDelimiter, false, true)
+}
+
+// alignFolders returns a row for each mapping of the source labeled key,
+// with the counts of both folders as listed.
+func alignFolders(key string, mappings []config.DirectoryMapping, srcBoxes, dstBoxes []*client.MailboxInfo) []folderComparison {
+	srcByName := mailboxesByName(srcBoxes)
+	dstByName := mailboxesByName(dstBoxes)
+	rows := make([]folderComparison, 0, len(mappings))
+	for _, m := range mappings {
+		row := folderComparison{Source: key, SourceFolder: m.Source, DestinationFolder: m.Destination}
+		if b, ok := srcByName[m.Source]; ok {
+			row.SourceExists, row.SourceMessages, row.SourceSize = true, b.Messages, b.Size
+		}
+		if b, ok := dstByName[m.Destination]; ok {
+			row.DestinationExists, row.DestinationMessages, row.DestinationSize = true, b.Messages, b.Size
+		}
+		row.DeltaMessages = int64(row.SourceMessages) - int64(row.DestinationMessages)
+		row.DeltaSize = int64(row.SourceSize) - int64(row.DestinationSize)
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// appendUnmapped adds a row for every destination folder no row targets,
+// sorted by name, so that the comparison covers the whole destination.
+func appendUnmapped(rows []folderComparison, dstBoxes []*client.MailboxInfo) []folderComparison {
+	targeted := make(map[string]struct{}, len(rows))
+	for _, r := range rows {
+		targeted[r.DestinationFolder] = struct{}{}
+	}
+	var extra []folderComparison
+	for _, b := range dstBoxes {
+		if _, ok := targeted[b.Name]; ok {
+			continue
+		}
+		extra = append(extra, folderComparison{
+			DestinationFolder:   b.Name,
+			DestinationExists:   true,
+			DestinationMessages: b.Messages,
+			DestinationSize:     b.Size,
+			DeltaMessages:       -int64(b.Messages),
+			DeltaSize:           -int64(b.Size),
+		})
+	}
+	slices.SortFunc(extra, func(a, b folderComparison) int { return strings.Compare(a.DestinationFolder, b.DestinationFolder) })
+	return append(rows, extra...)
+}
+
+// mailboxesByName indexes boxes by folder name.
+func mailboxesByName(boxes []*client.MailboxInfo) map[string]*client.MailboxInfo {
+	m := make(map[string]*client.MailboxInfo, len(boxes))
+	for _, b := range boxes {
+		m[b.Name] = b
+	}
+	return m
+}
+
+// countMissing sets Missing on every row with a source folder: how many of
+// its Message-Ids the destination folder lacks. srcClients holds the client
+// of each row's source, by label. Each destination folder is scanned once.
+func countMissing(ctx context.Context, rows []folderComparison, srcClients map[string]*client.Client, dstClient *client.Client, tr *progress.Tracker) error {
+	dstIDs := make(map[string]map[string]struct{})
+	for i := range rows {
+		r := &rows[i]
+		if !r.SourceExists {
+			continue
+		}
+		tr.UpdateMessage(fmt.Sprintf("Scanning Message-Ids: %s", r.SourceFolder))
+		src, _, err := srcClients[r.Source].FetchMessageMap(ctx, r.SourceFolder)
+		if err != nil {
+			return fmt.Errorf("scan source folder %q: %w", r.SourceFolder, err)
+		}
+		have, ok := dstIDs[r.DestinationFolder]
+		if !ok && r.DestinationExists {
+			if have, err = dstClient.FetchMessageIDSet(ctx, r.DestinationFolder); err != nil {
+				return fmt.Errorf("scan destination folder %q: %w", r.DestinationFolder, err)
+			}
+			dstIDs[r.DestinationFolder] = have
+		}
+		missing := 0
+		for id := range src {
+			if _, ok := have[id]; !ok {
+				missing++
+			}
+		}
+		r.Missing = &missing
+		tr.Increment(1)
+	}
+	return nil
+}
+
+// printComparison writes the comparison as a borderless table in the style
+// of show, with totals in the footer.
+func printComparison(out io.Writer, rows []folderComparison, withUnique bool) {
+	t := table.NewWriter()
+	t.SetOutputMirror(out)
+	t.Style().Options.DrawBorder = false
+	t.Style().Options.SeparateColumns = false
+	t.SetTitle(text.Colors{text.Bold, text.FgHiCyan}.Sprint("Source vs destination"))
+	// Footer totals carry units; go-pretty would upper-case them.
+	t.Style().Format.Footer = text.FormatDefault
+	header := table.Row{"Source folder", "Messages", "Size", "Destination folder", "Exists", "Messages", "Size", "Δ Messages", "Δ Size"}
+	if withUnique {
+		header = append(header, "Missing")
+	}
+	t.AppendHeader(header)
+
+	var total folderComparison
+	var missing int
+	for _, r := range rows {
+		source, srcMessages, srcSize := "-", "-", "-"
+		if r.SourceFolder != "" {
+			source = r.SourceFolder
+			if r.Source != "" {
+				source = fmt.Sprintf("[%s] %s", r.Source, r.SourceFolder)
+			}
+		}
+		if r.SourceExists {
+			srcMessages, srcSize = strconv.FormatUint(uint64(r.SourceMessages), 10), utils.FormatSize(r.SourceSize)
+		} else if r.SourceFolder != "" {
+			source = text.FgHiBlack.Sprint(source + " (missing)")
+		}
+		exists, dstMessages, dstSize := text.FgYellow.Sprint("no"), "-", "-"
+		if r.DestinationExists {
+			exists = "yes"
+			dstMessages, dstSize = strconv.FormatUint(uint64(r.DestinationMessages), 10), utils.FormatSize(r.DestinationSize)
+		}
+		row := table.Row{source, srcMessages, srcSize, r.DestinationFolder, exists, dstMessages, dstSize,
+			formatDelta(r.DeltaMessages), formatSizeDelta(r.DeltaSize)}
+		if withUnique {
+			n := "-"
+			if r.Missing != nil {
+				n = strconv.Itoa(*r.Missing)
+				missing += *r.Missing
+			}
+			row = append(row, n)
+		}
+		t.AppendRow(row)
+
+		total.SourceMessages += r.SourceMessages
+		total.SourceSize += r.SourceSize
+		total.DestinationMessages += r.DestinationMessages
+		total.DestinationSize += r.DestinationSize
+		total.DeltaMessages += r.DeltaMessages
+		total.DeltaSize += r.DeltaSize
+	}
+
+	footer := table.Row{
+		text.Bold.Sprintf("total folders %d", len(rows)),
+		text.Bold.Sprintf("%d", total.SourceMessages),
+		text.Bold.Sprint(utils.FormatSize(total.SourceSize)),
+		"", "",
+		text.Bold.Sprintf("%d", total.DestinationMessages),
+		text.Bold.Sprint(utils.FormatSize(total.DestinationSize)),
+		text.Bold.Sprint(formatDelta(total.DeltaMessages)),
+		text.Bold.Sprint(formatSizeDelta(total.DeltaSize)),
+	}
+	if withUnique {
+		footer = append(footer, text.Bold.Sprintf("%d", missing))
+	}
+	t.AppendFooter(footer)
+
+	configs := make([]table.ColumnConfig, 0, len(header))
+	for n := 1; n <= len(header); n++ {
+		align := text.AlignRight
+		if n == 1 || n == 4 || n == 5 {
+			align = text.AlignLeft
+		}
+		configs = append(configs, table.ColumnConfig{Number: n, Align: align, AlignHeader: text.AlignCenter, AlignFooter: align})
+	}
+	t.SetColumnConfigs(configs)
+	t.Render()
+}
+
+// writeComparisonJSON writes the comparison as one JSON document.
+func writeComparisonJSON(out io.Writer, rows []folderComparison) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		Folders []folderComparison `json:"folders"`
+	}{rows})
+}
+
+// writeComparisonCSV writes the comparison as CSV with a header row; sizes
+// are in bytes and missing is empty where it was not counted.
+func writeComparisonCSV(out io.Writer, rows []folderComparison, withUnique bool) error {
+	w := csv.NewWriter(out)
+	header := []string{"source", "source_folder", "source_exists", "source_messages", "source_size",
+		"destination_folder", "destination_exists", "destination_messages", "destination_size",
+		"delta_messages", "delta_size"}
+	if withUnique {
+		header = append(header, "missing")
+	}
+	_ = w.Write(header)
+	for _, r := range rows {
+		record := []string{r.Source, r.SourceFolder, strconv.FormatBool(r.SourceExists),
+			strconv.FormatUint(uint64(r.SourceMessages), 10), strconv.FormatUint(r.SourceSize, 10),
+			r.DestinationFolder, strconv.FormatBool(r.DestinationExists),
+			strconv.FormatUint(uint64(r.DestinationMessages), 10), strconv.FormatUint(r.DestinationSize, 10),
+			strconv.FormatInt(r.DeltaMessages, 10), strconv.FormatInt(r.DeltaSize, 10)}
+		if withUnique {
+			missing := ""
+			if r.Missing != nil {
+				missing = strconv.Itoa(*r.Missing)
+			}
+			record = append(record, missing)
+		}
+		_ = w.Write(record)
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// formatDelta renders n with its sign, or "0".
+func formatDelta(n int64) string {
+	if n > 0 {
+		return "+" + strconv.FormatInt(n, 10)
+	}
+	return strconv.FormatInt(n, 10)
+}
+
+// formatSizeDelta renders a size difference with its sign, or "0 B".
+func formatSizeDelta(n int64) string {
+	switch {
+	case n > 0:
+		return "+" + utils.FormatSize(uint64(n))
+	case n < 0:
+		return "-" + utils.FormatSize(uint64(-n))
+	}
+	return utils.FormatSize(0)
+}
@@ -0,0 +1,117 @@
+package app
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/greeddj/imapsync-go/internal/client"
+	"github.com/greeddj/imapsync-go/internal/config"
+	"github.com/jedib0t/go-pretty/v6/text"
+)
+
+// Test_alignFolders_deltasAndUnmapped asserts that each mapping gets a row
+// with both sides' counts and the delta, that a destination that does not
+// exist counts as empty, and that destination folders no mapping targets are
+// appended by name.
+func Test_alignFolders_deltasAndUnmapped(t *testing.T) {
+	t.Parallel()
+
+	src := []*client.MailboxInfo{{Name: "INBOX", Messages: 10, Size: 1000}, {Name: "Work.Reports", Messages: 2, Size: 50}}
+	dst := []*client.MailboxInfo{{Name: "Trash", Messages: 1, Size: 5}, {Name: "INBOX", Messages: 7, Size: 1200}, {Name: "Archive", Messages: 3, Size: 30}}
+	mappings := []config.DirectoryMapping{
+		{Source: "INBOX", Destination: "INBOX"},
+		{Source: "Work.Reports", Destination: autoDestination("Work.Reports", "", ".", "/")},
+		{Source: "Gone", Destination: "Gone"},
+	}
+	rows := appendUnmapped(alignFolders("", mappings, src, dst), dst)
+
+	if len(rows) != 5 {
+		t.Fatalf("got %d rows, want 5: %+v", len(rows), rows)
+	}
+	inbox := rows[0]
+	if !inbox.SourceExists || !inbox.DestinationExists || inbox.DeltaMessages != 3 || inbox.DeltaSize != -200 {
+		t.Errorf("INBOX = %+v, want both sides, +3 messages, -200 bytes", inbox)
+	}
+	work := rows[1]
+	if work.DestinationFolder != "Work/Reports" || work.DestinationExists || work.DeltaMessages != 2 || work.DeltaSize != 50 {
+		t.Errorf("Work.Reports = %+v, want Work/Reports missing on the destination", work)
+	}
+	if rows[2].SourceExists || rows[2].DeltaMessages != 0 {
+		t.Errorf("Gone = %+v, want no source folder", rows[2])
+	}
+	if rows[3].DestinationFolder != "Archive" || rows[4].DestinationFolder != "Trash" || rows[3].SourceFolder != "" || rows[3].DeltaMessages != -3 {
+		t.Errorf("unmapped rows = %+v %+v, want Archive then Trash without a source", rows[3], rows[4])
+	}
+}
+
+// Test_printComparison_table asserts that the table shows both sides, the
+// signed deltas, the missing counts and the totals.
+func Test_printComparison_table(t *testing.T) {
+	t.Parallel()
+
+	missing := 4
+	rows := []folderComparison{
+		{Source: "old", SourceFolder: "INBOX", DestinationFolder: "INBOX", SourceExists: true, DestinationExists: true,
+			SourceMessages: 10, SourceSize: 2048, DestinationMessages: 6, DestinationSize: 1024, DeltaMessages: 4, DeltaSize: 1024, Missing: &missing},
+		{DestinationFolder: "Archive", DestinationExists: true, DestinationMessages: 3, DestinationSize: 30, DeltaMessages: -3, DeltaSize: -30},
+	}
+	var buf bytes.Buffer
+	printComparison(&buf, rows, true)
+	out := text.StripEscape(buf.String())
+
+	for _, want := range []string{
+		"Source vs destination",
+		"[old] INBOX            10  2.00 KB  INBOX               yes            6  1.00 KB          +4  +1.00 KB        4",
+		"-                       -        -  Archive             yes            3     30 B          -3     -30 B        -",
+		"total folders 2        10  2.00 KB                                     9  1.03 KB          +1    +994 B        4",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("table lacks %q:\n%s", want, out)
+		}
+	}
+}
+
+// Test_writeComparisonCSV_bytesAndEmptyMissing asserts that the CSV has raw
+// byte counts and leaves missing empty where it was not counted.
+func Test_writeComparisonCSV_bytesAndEmptyMissing(t *testing.T) {
+	t.Parallel()
+
+	rows := []folderComparison{{SourceFolder: "INBOX", DestinationFolder: "INBOX", SourceExists: true,
+		SourceMessages: 2, SourceSize: 2048, DeltaMessages: 2, DeltaSize: 2048}}
+	var buf bytes.Buffer
+	if err := writeComparisonCSV(&buf, rows, true); err != nil {
+		t.Fatalf("writeComparisonCSV: %v", err)
+	}
+	want := "source,source_folder,source_exists,source_messages,source_size,destination_folder,destination_exists,destination_messages,destination_size,delta_messages,delta_size,missing\n" +
+		",INBOX,true,2,2048,INBOX,false,0,0,2,2048,\n"
+	if buf.String() != want {
+		t.Errorf("csv = %q, want %q", buf.String(), want)
+	}
+}
+
+// Test_writeAccountsJSON_foldersAndQuota asserts that the JSON lists every
+// account with its folders and flattened quota resources.
+func Test_writeAccountsJSON_foldersAndQuota(t *testing.T) {
+	t.Parallel()
+
+	a := newShownAccount("source", "old", config.Credentials{Server: "imap.example.com:993", User: "me"},
+		[]*client.MailboxInfo{{Name: "INBOX", Messages: 3, Size: 300}},
+		[]client.Quota{{Root: "", Resources: []client.QuotaResource{{Name: client.QuotaStorage, Usage: 10, Limit: 100}}}})
+	var buf bytes.Buffer
+	if err := writeAccountsJSON(&buf, []shownAccount{a}); err != nil {
+		t.Fatalf("writeAccountsJSON: %v", err)
+	}
+	for _, want := range []string{
+		`"role": "source"`,
+		`"server": "imap.example.com:993"`,
+		`"resource": "STORAGE"`,
+		`"name": "INBOX"`,
+		`"messages": 3`,
+		`"size": 300`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("json lacks %s:\n%s", want, buf.String())
+		}
+	}
+}
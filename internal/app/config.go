@@ -0,0 +1,175 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/greeddj/imapsync-go/internal/config"
+	"github.com/greeddj/imapsync-go/internal/secret"
+	"github.com/urfave/cli/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// formatYAML is the YAML output of config print.
+const formatYAML = "yaml"
+
+// ActionConfigValidate checks the config file without connecting anywhere:
+// unknown keys, auth and server syntax, password sources, duplicate and
+// overlapping map entries, and rate limits, including those beyond what a
+// known provider tolerates. Errors fail the command; warnings only do so
+// with --strict.
+func ActionConfigValidate(ctx context.Context, c *cli.Command) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	path := c.String("config")
+	cfg, problems, err := config.Lint(path)
+	if err != nil {
+		return err
+	}
+	problems = append(problems, providerProblems(cfg)...)
+
+	var errs, warns int
+	for _, p := range problems {
+		if p.Warning {
+			warns++
+			fmt.Printf("⚠️  %s\n", p)
+		} else {
+			errs++
+			fmt.Printf("❌ %s\n", p)
+		}
+	}
+
+	switch {
+	case errs > 0:
+		fmt.Printf("❌ %s has %d errors and %d warnings\n", path, errs, warns)
+		return ErrSilentExit
+	case warns > 0 && c.Bool("strict"):
+		fmt.Printf("❌ %s has %d warnings (--strict)\n", path, warns)
+		return ErrSilentExit
+	case warns > 0:
+		fmt.Printf("✅ %s is valid, with %d warnings\n", path, warns)
+	default:
+		fmt.Printf("✅ %s is valid\n", path)
+	}
+	return nil
+}
+
+// providerProblems warns about rate limits above what a detected provider
+// tolerates. Unset limits are left to the banner sync prints.
+func providerProblems(cfg *config.Config) []config.Problem {
+	type side struct {
+		path, server string
+		bps          int
+		bpsKey       string
+		upload       bool
+	}
+	rl := cfg.RateLimit
+	var sides []side
+	for i, s := range cfg.SourceList() {
+		path := "src"
+		if len(cfg.Sources) > 1 {
+			path = fmt.Sprintf("src[%d]", i+1)
+		}
+		sides = append(sides, side{path: path, server: s.Server, bps: rl.DownBPS, bpsKey: "down_bps"})
+	}
+	sides = append(sides, side{path: "dst", server: cfg.Dst.Server, bps: rl.UpBPS, bpsKey: "up_bps", upload: true})
+
+	providers := cfg.ProviderTable()
+	var problems []config.Problem
+	for _, s := range sides {
+		p, ok := providers.Detect(s.server)
+		if !ok {
+			continue
+		}
+		limit := p.DownBPS
+		if s.upload {
+			limit = p.UpBPS
+		}
+		if limit > 0 && s.bps > limit {
+			problems = append(problems, config.Problem{
+				Path:    "rate_limit." + s.bpsKey,
+				Message: fmt.Sprintf("%d bytes/sec is above the %d %s recommends for %s", s.bps, limit, p.Name, s.path),
+				Warning: true,
+			})
+		}
+		if p.MaxConnections > 0 && rl.MaxConnections > p.MaxConnections {
+			problems = append(problems, config.Problem{
+				Path:    "rate_limit.max_connections",
+				Message: fmt.Sprintf("%d is above the %d connections %s allows for %s", rl.MaxConnections, p.MaxConnections, p.Name, s.path),
+				Warning: true,
+			})
+		}
+	}
+	return problems
+}
+
+// ActionConfigSchema prints the JSON Schema of the config file.
+func ActionConfigSchema(_ context.Context, _ *cli.Command) error {
+	_, err := os.Stdout.Write(config.Schema)
+	return err
+}
+
+// ActionConfigPrint prints the config as sync sees it: ${VAR} expanded,
+// default labels filled in, every source with its effective map, and every
+// map entry with the top-level limits merged in. Plain-text passwords are
+// redacted; the other password fields only name where the password lives
+// and are shown as written.
+func ActionConfigPrint(ctx context.Context, c *cli.Command) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	format := c.String("format")
+	if format != formatYAML && format != formatJSON {
+		return fmt.Errorf("--format must be %s or %s, got %q", formatYAML, formatJSON, format)
+	}
+
+	cfg, err := config.Load(c.String("config"))
+	if err != nil {
+		return err
+	}
+	effective := effectiveConfig(cfg)
+
+	if format == formatJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(effective)
+	}
+	enc := yaml.NewEncoder(os.Stdout)
+	enc.SetIndent(2)
+	if err := enc.Encode(effective); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// effectiveConfig returns the config print view of cfg.
+func effectiveConfig(cfg *config.Config) *config.Config {
+	out := *cfg
+	out.Sources = cfg.SourceList()
+	out.Map = nil
+	for i := range out.Sources {
+		s := &out.Sources[i]
+		s.Pass = redactPass(s.Pass)
+		mapped := make([]config.DirectoryMapping, len(s.Map))
+		for j, m := range s.Map {
+			m.Limits = cfg.Limits.Merge(m.Limits)
+			mapped[j] = m
+		}
+		s.Map = mapped
+	}
+	out.Dst.Pass = redactPass(out.Dst.Pass)
+	return &out
+}
+
+// redactPass hides a plain-text password, keeping whether one is set.
+func redactPass(pass string) string {
+	if pass == "" {
+		return ""
+	}
+	return secret.Redacted
+}
@@ -0,0 +1,72 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/greeddj/imapsync-go/internal/config"
+	"github.com/greeddj/imapsync-go/internal/secret"
+)
+
+// Test_providerProblems_aboveGmailLimits asserts that rate limits beyond a
+// detected provider's recommendation are warned about, and unset ones not.
+func Test_providerProblems_aboveGmailLimits(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{
+		Src:       config.Credentials{Server: "imap.gmail.com:993"},
+		Dst:       config.Credentials{Server: "imap.example.com:993"},
+		RateLimit: config.RateLimit{DownBPS: 1_000_000, MaxConnections: 20},
+	}
+	problems := providerProblems(cfg)
+	if len(problems) != 2 {
+		t.Fatalf("providerProblems() = %v; want down_bps and max_connections warnings", problems)
+	}
+	for _, p := range problems {
+		if !p.Warning || !strings.Contains(p.Message, "Gmail") {
+			t.Errorf("problem %v; want a Gmail warning", p)
+		}
+	}
+
+	cfg.RateLimit = config.RateLimit{}
+	if problems := providerProblems(cfg); len(problems) != 0 {
+		t.Errorf("providerProblems() with no limits = %v; want none", problems)
+	}
+}
+
+// Test_effectiveConfig_mergesAndRedacts asserts that config print shows each
+// source with its effective map and merged limits, and no password.
+func Test_effectiveConfig_mergesAndRedacts(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{
+		Sources: config.Sources{
+			{Credentials: config.Credentials{Label: "a", Pass: "s3cret"}},
+			{Credentials: config.Credentials{Label: "b", PassEnv: "B_PASS"}, Map: []config.DirectoryMapping{{Source: "Own", Destination: "Own"}}},
+		},
+		Dst:    config.Credentials{Pass: "d3st"},
+		Map:    []config.DirectoryMapping{{Source: "INBOX", Destination: "INBOX", Limits: config.Limits{MaxSize: "5M"}}},
+		Limits: config.Limits{Since: "2024-01-01", MaxSize: "25M"},
+	}
+	got := effectiveConfig(cfg)
+
+	if got.Map != nil {
+		t.Errorf("top-level map kept: %v", got.Map)
+	}
+	if got.Sources[0].Pass != secret.Redacted || got.Dst.Pass != secret.Redacted {
+		t.Errorf("passwords not redacted: %q, %q", got.Sources[0].Pass, got.Dst.Pass)
+	}
+	if got.Sources[1].PassEnv != "B_PASS" {
+		t.Errorf("pass_env = %q; want it shown as written", got.Sources[1].PassEnv)
+	}
+	m := got.Sources[0].Map
+	if len(m) != 1 || m[0].Since != "2024-01-01" || m[0].MaxSize != "5M" {
+		t.Errorf("source a map = %+v; want INBOX with the merged limits", m)
+	}
+	if m := got.Sources[1].Map; len(m) != 1 || m[0].Source != "Own" {
+		t.Errorf("source b map = %+v; want its own map", m)
+	}
+	if cfg.Sources[0].Pass != "s3cret" || cfg.Map[0].Since != "" {
+		t.Error("effectiveConfig modified its input")
+	}
+}
@@ -0,0 +1,449 @@
+package app
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/emersion/go-imap"
+	"github.com/greeddj/imapsync-go/internal/client"
+	"github.com/greeddj/imapsync-go/internal/config"
+	"github.com/greeddj/imapsync-go/internal/progress"
+	"github.com/greeddj/imapsync-go/internal/secret"
+	"github.com/greeddj/imapsync-go/internal/utils"
+	"github.com/urfave/cli/v3"
+)
+
+// What two messages must share to count as copies of each other (--match).
+// Each level includes the ones before it.
+const (
+	matchMessageID = "message-id"
+	matchSize      = "size"
+	matchHash      = "hash"
+)
+
+// Which copy of a duplicate group survives (--keep).
+const (
+	keepOldest  = "oldest"
+	keepFlagged = "flagged"
+)
+
+// dupeMessage is one scanned destination message. order is the position of
+// its folder in the scan, the last tie-breaker when picking the copy to keep.
+type dupeMessage struct {
+	client.FolderMessage
+	Folder string
+	Hash   string
+	order  int
+}
+
+// dupeGroup is a set of copies of one message: Keep survives, Extra goes.
+type dupeGroup struct {
+	Keep  dupeMessage
+	Extra []dupeMessage
+}
+
+// dedupeRecord is one line of the --report file.
+type dedupeRecord struct {
+	Folder    string   `json:"folder"`
+	MessageID string   `json:"message_id"`
+	Action    string   `json:"action"`
+	Date      string   `json:"date"`
+	Error     string   `json:"error,omitempty"`
+	Flags     []string `json:"flags,omitempty"`
+	Group     int      `json:"group"`
+	UID       uint32   `json:"uid"`
+	Size      uint32   `json:"size"`
+}
+
+// checkQuietConfirm refuses a quiet run that would delete messages without an
+// explicit --confirm: --quiet hides the confirmation prompt, and must not turn
+// into a silent yes.
+func checkQuietConfirm(quiet, confirm bool, action string) error {
+	if quiet && !confirm {
+		return fmt.Errorf("--quiet cannot ask before it would %s; add --confirm to proceed without asking", action)
+	}
+	return nil
+}
+
+// ActionDedupe finds messages stored more than once on the destination and
+// deletes the extra copies.
+func ActionDedupe(ctx context.Context, c *cli.Command) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	quiet := c.Bool("quiet")
+	verbose := c.Bool("verbose")
+	autoConfirm := c.Bool("confirm")
+	dryRun := c.Bool("dry-run")
+	across := c.Bool("across")
+	reportPath := c.String("report")
+	match := strings.ToLower(c.String("match"))
+	keep := strings.ToLower(c.String("keep"))
+	switch match {
+	case matchMessageID, matchSize, matchHash:
+	default:
+		return fmt.Errorf("--match must be %s, %s or %s, got %q", matchMessageID, matchSize, matchHash, match)
+	}
+	switch keep {
+	case keepOldest, keepFlagged:
+	default:
+		return fmt.Errorf("--keep must be %s or %s, got %q", keepOldest, keepFlagged, keep)
+	}
+	if !dryRun {
+		if err := checkQuietConfirm(quiet, autoConfirm, "delete duplicates"); err != nil {
+			return err
+		}
+	}
+
+	cfg, err := config.New(c)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	// Where folders are labels, as on Gmail, a message in two folders is one
+	// message, and deleting a "copy" from a folder only removes the label.
+	if p, ok := cfg.ProviderTable().Detect(cfg.Dst.Server); ok && p.LabelFolders && across {
+		return fmt.Errorf("--across cannot be used on %s: its folders are labels of one message, not copies", p.Name)
+	}
+
+	trace, closeTrace, err := openTrace(c)
+	if err != nil {
+		return err
+	}
+	defer closeTrace()
+	dstClient, err := client.New(ctx, cfg.Dst.Server, cfg.Dst.User, cfg.Dst.Pass, client.Options{
+		UseTLS:  true,
+		Auth:    cfg.Dst.Auth,
+		Label:   cfg.Dst.Label,
+		Verbose: verbose,
+		Trace:   trace,
+		Logger:  accountLogger("destination", cfg.Dst.Label),
+	})
+	if err != nil {
+		return fmt.Errorf("destination connection failed: %w", err)
+	}
+	defer func() { _ = dstClient.Logout() }()
+
+	folders, err := dedupeFolders(ctx, dstClient, c.StringSlice("folder"), c.Bool("recursive"))
+	if err != nil {
+		return err
+	}
+
+	pw := progress.NewWriter(1, quiet)
+	pw.Start()
+	tracker := progress.NewTracker(fmt.Sprintf("[%s] Scanning folders", cfg.Dst.Label), int64(len(folders)))
+	traceTracker("dedupe", tracker.Message)
+	pw.AppendTracker(tracker)
+	dstClient.SetProgressWriter(pw)
+
+	var msgs []dupeMessage
+	for i, folder := range folders {
+		tracker.UpdateMessage(fmt.Sprintf("[%s] Scanning %s (%d/%d)", cfg.Dst.Label, folder, i+1, len(folders)))
+		list, err := dstClient.ListMessages(ctx, folder)
+		if err != nil {
+			if ctx.Err() != nil {
+				pw.Stop()
+				return ctx.Err()
+			}
+			pw.Log("⚠️ Failed to scan %s, skipping: %v", folder, err)
+			tracker.Increment(1)
+			continue
+		}
+		for _, m := range list {
+			// Already on its way out; neither a copy to keep nor one to delete.
+			if hasFlag(m.Flags, imap.DeletedFlag) == 1 {
+				continue
+			}
+			msgs = append(msgs, dupeMessage{FolderMessage: m, Folder: folder, order: i})
+		}
+		tracker.Increment(1)
+	}
+
+	if match == matchHash {
+		// Only messages that already share a Message-Id and size can be
+		// copies, so only those bodies are downloaded.
+		candidates := findDuplicates(msgs, across, matchSize, keep)
+		byFolder := make(map[string][]uint32)
+		for _, g := range candidates {
+			for _, m := range append([]dupeMessage{g.Keep}, g.Extra...) {
+				byFolder[m.Folder] = append(byFolder[m.Folder], m.UID)
+			}
+		}
+		hashes := make(map[string]map[uint32]string, len(byFolder))
+		for _, folder := range folders {
+			uids := byFolder[folder]
+			if len(uids) == 0 {
+				continue
+			}
+			tracker.UpdateMessage(fmt.Sprintf("[%s] Hashing %d messages in %s", cfg.Dst.Label, len(uids), folder))
+			h, err := dstClient.HashBodies(ctx, folder, uids)
+			if err != nil {
+				pw.Stop()
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				return fmt.Errorf("hash bodies in %s: %w", folder, err)
+			}
+			hashes[folder] = h
+		}
+		for i := range msgs {
+			msgs[i].Hash = hashes[msgs[i].Folder][msgs[i].UID]
+		}
+	}
+	tracker.MarkAsDone()
+	pw.StopAndClear()
+	dstClient.SetProgressWriter(nil)
+
+	groups := findDuplicates(msgs, across, match, keep)
+	if len(groups) == 0 {
+		if !quiet {
+			fmt.Printf("✅ No duplicates found in %d folders\n", len(folders))
+		}
+		return nil
+	}
+
+	extras := make(map[string][]uint32)
+	var extraFolders []string
+	var extraCount int
+	var extraSize uint64
+	for _, g := range groups {
+		for _, m := range g.Extra {
+			if _, ok := extras[m.Folder]; !ok {
+				extraFolders = append(extraFolders, m.Folder)
+			}
+			extras[m.Folder] = append(extras[m.Folder], m.UID)
+			extraCount++
+			extraSize += uint64(m.Size)
+		}
+	}
+	slices.Sort(extraFolders)
+
+	if !quiet {
+		fmt.Printf("🔍 Duplicates to delete (matched by %s, keeping the %s copy):\n", match, keep)
+		for _, folder := range extraFolders {
+			fmt.Printf("• %s: %d messages\n", folder, len(extras[folder]))
+		}
+		fmt.Printf("\n📨 Total: %d extra copies of %d messages (%s)\n", extraCount, len(groups), utils.FormatSize(extraSize))
+	}
+
+	failed := make(map[string]error)
+	if dryRun {
+		if err := writeDedupeReport(reportPath, groups, failed); err != nil {
+			return err
+		}
+		if !quiet {
+			fmt.Println("ℹ️  Dry run: nothing was deleted")
+		}
+		return nil
+	}
+
+	if !autoConfirm {
+		confirmed, err := utils.AskConfirm(ctx, "✍️ Delete the extra copies?")
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			fmt.Println("❌ Dedupe canceled by user")
+			return nil
+		}
+	}
+
+	flaggedOnly := 0
+	for _, folder := range extraFolders {
+		expunged, err := dstClient.DeleteMessages(ctx, folder, extras[folder])
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			fmt.Printf("⚠️ Failed to delete duplicates in %s: %v\n", folder, err)
+			slog.Error("deleting duplicates failed", "folder", folder, "messages", len(extras[folder]), "error", err)
+			failed[folder] = err
+			continue
+		}
+		if !expunged {
+			flaggedOnly += len(extras[folder])
+		}
+		slog.Info("duplicates deleted", "folder", folder, "messages", len(extras[folder]), "expunged", expunged)
+		if verbose {
+			fmt.Printf("🗑️  %s: %d duplicates deleted\n", folder, len(extras[folder]))
+		}
+	}
+	if err := writeDedupeReport(reportPath, groups, failed); err != nil {
+		return err
+	}
+
+	if flaggedOnly > 0 {
+		fmt.Printf("ℹ️  %d duplicates flagged \\Deleted; the server has no UIDPLUS, so expunge them from a mail client\n", flaggedOnly)
+	}
+	if len(failed) > 0 {
+		fmt.Printf("❌ Dedupe completed with errors in %d folders\n", len(failed))
+		return ErrSilentExit
+	}
+	if !quiet {
+		fmt.Println("✨ Dedupe completed successfully. ✨")
+	}
+	return nil
+}
+
+// dedupeFolders resolves the folders to scan: the given ones (each checked
+// to exist), with their subfolders when recursive is set, or every folder
+// of the account when none is given.
+func dedupeFolders(ctx context.Context, c *client.Client, names []string, recursive bool) ([]string, error) {
+	if len(names) == 0 {
+		folders, err := c.ListFolders(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("list folders: %w", err)
+		}
+		return folders, nil
+	}
+	var out []string
+	for _, name := range names {
+		exists, err := c.MailboxExists(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("check folder %s: %w", name, err)
+		}
+		if !exists {
+			return nil, fmt.Errorf("folder %s does not exist", name)
+		}
+		out = append(out, name)
+		if recursive {
+			subs, err := c.ListSubfolders(ctx, name, "")
+			if err != nil {
+				return nil, fmt.Errorf("list subfolders of %s: %w", name, err)
+			}
+			slices.Sort(subs)
+			out = append(out, subs...)
+		}
+	}
+	seen := make(map[string]bool, len(out))
+	return slices.DeleteFunc(out, func(f string) bool {
+		dup := seen[f]
+		seen[f] = true
+		return dup
+	}), nil
+}
+
+// findDuplicates groups msgs into sets of copies and picks the copy to keep
+// in each. Copies share a Message-Id, plus the size (match size) or the size
+// and body hash (match hash); a message whose body could not be hashed is
+// never a copy. Unless across is set, only messages in the same folder are
+// compared. Groups come back in folder and UID order of their kept copy.
+func findDuplicates(msgs []dupeMessage, across bool, match, keep string) []dupeGroup {
+	type key struct {
+		folder string
+		id     string
+		hash   string
+		size   uint32
+	}
+	byKey := make(map[key][]dupeMessage)
+	var order []key
+	for _, m := range msgs {
+		k := key{id: m.ID}
+		if !across {
+			k.folder = m.Folder
+		}
+		if match == matchSize || match == matchHash {
+			k.size = m.Size
+		}
+		if match == matchHash {
+			if m.Hash == "" {
+				continue
+			}
+			k.hash = m.Hash
+		}
+		if _, ok := byKey[k]; !ok {
+			order = append(order, k)
+		}
+		byKey[k] = append(byKey[k], m)
+	}
+
+	var groups []dupeGroup
+	for _, k := range order {
+		copies := byKey[k]
+		if len(copies) < 2 {
+			continue
+		}
+		slices.SortFunc(copies, func(a, b dupeMessage) int { return compareKeep(a, b, keep) })
+		groups = append(groups, dupeGroup{Keep: copies[0], Extra: copies[1:]})
+	}
+	slices.SortFunc(groups, func(a, b dupeGroup) int {
+		return cmp.Or(cmp.Compare(a.Keep.order, b.Keep.order), cmp.Compare(a.Keep.UID, b.Keep.UID))
+	})
+	return groups
+}
+
+// compareKeep orders a before b when a is the better copy to keep. With
+// keepFlagged a copy marked \Flagged, then \Answered, then \Seen, then one
+// with more flags wins; otherwise, and on a tie, the oldest INTERNALDATE
+// wins, then the earlier folder and the lower UID.
+func compareKeep(a, b dupeMessage, keep string) int {
+	if keep == keepFlagged {
+		for _, f := range []string{imap.FlaggedFlag, imap.AnsweredFlag, imap.SeenFlag} {
+			if c := cmp.Compare(hasFlag(b.Flags, f), hasFlag(a.Flags, f)); c != 0 {
+				return c
+			}
+		}
+		if c := cmp.Compare(len(syncableFlags(b.Flags)), len(syncableFlags(a.Flags))); c != 0 {
+			return c
+		}
+	}
+	return cmp.Or(a.Date.Compare(b.Date), cmp.Compare(a.order, b.order), cmp.Compare(a.UID, b.UID))
+}
+
+// hasFlag returns 1 when flags contains flag, 0 otherwise.
+func hasFlag(flags []string, flag string) int {
+	if slices.ContainsFunc(flags, func(f string) bool { return strings.EqualFold(f, flag) }) {
+		return 1
+	}
+	return 0
+}
+
+// writeDedupeReport writes every message of every group to path as JSON
+// Lines. Messages in a folder whose deletion failed carry the error. An
+// empty path writes nothing.
+func writeDedupeReport(path string, groups []dupeGroup, failed map[string]error) error {
+	if path == "" {
+		return nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create report: %w", err)
+	}
+	enc := json.NewEncoder(secret.NewWriter(f))
+	for i, g := range groups {
+		for j, m := range append([]dupeMessage{g.Keep}, g.Extra...) {
+			rec := dedupeRecord{
+				Folder:    m.Folder,
+				MessageID: m.ID,
+				Action:    "delete",
+				Date:      m.Date.UTC().Format("2006-01-02T15:04:05Z"),
+				Flags:     m.Flags,
+				Group:     i + 1,
+				UID:       m.UID,
+				Size:      m.Size,
+			}
+			if j == 0 {
+				rec.Action = "keep"
+			} else if err := failed[m.Folder]; err != nil {
+				rec.Error = err.Error()
+			}
+			if err := enc.Encode(rec); err != nil {
+				_ = f.Close()
+				return fmt.Errorf("write report: %w", err)
+			}
+		}
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("write report: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package app
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/greeddj/imapsync-go/internal/client"
+)
+
+func dupe(folder string, order int, uid uint32, id string, size uint32, day int, flags ...string) dupeMessage {
+	return dupeMessage{
+		FolderMessage: client.FolderMessage{
+			Date:  time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC),
+			ID:    id,
+			Flags: flags,
+			UID:   uid,
+			Size:  size,
+		},
+		Folder: folder,
+		order:  order,
+	}
+}
+
+// Test_findDuplicates_perFolderAndAcross asserts that copies are only
+// grouped within a folder unless across is set, and that the oldest copy is
+// kept.
+func Test_findDuplicates_perFolderAndAcross(t *testing.T) {
+	t.Parallel()
+
+	msgs := []dupeMessage{
+		dupe("INBOX", 0, 1, "a@x", 100, 5),
+		dupe("INBOX", 0, 2, "a@x", 100, 3),
+		dupe("Work", 1, 7, "a@x", 100, 1),
+		dupe("Work", 1, 8, "b@x", 50, 1),
+	}
+
+	groups := findDuplicates(msgs, false, matchMessageID, keepOldest)
+	if len(groups) != 1 {
+		t.Fatalf("per folder: %d groups, want 1", len(groups))
+	}
+	if g := groups[0]; g.Keep.UID != 2 || len(g.Extra) != 1 || g.Extra[0].UID != 1 {
+		t.Errorf("per folder: keep %d extra %v, want keep 2 extra [1]", g.Keep.UID, g.Extra)
+	}
+
+	groups = findDuplicates(msgs, true, matchMessageID, keepOldest)
+	if len(groups) != 1 {
+		t.Fatalf("across: %d groups, want 1", len(groups))
+	}
+	if g := groups[0]; g.Keep.Folder != "Work" || g.Keep.UID != 7 || len(g.Extra) != 2 {
+		t.Errorf("across: keep %s/%d with %d extras, want Work/7 with 2", g.Keep.Folder, g.Keep.UID, len(g.Extra))
+	}
+}
+
+// Test_findDuplicates_matchLevels asserts that size and hash matching split
+// messages that only share a Message-Id, and that unhashed messages are
+// never treated as copies.
+func Test_findDuplicates_matchLevels(t *testing.T) {
+	t.Parallel()
+
+	msgs := []dupeMessage{
+		dupe("INBOX", 0, 1, "a@x", 100, 1),
+		dupe("INBOX", 0, 2, "a@x", 100, 2),
+		dupe("INBOX", 0, 3, "a@x", 200, 3),
+	}
+	if got := len(findDuplicates(msgs, false, matchMessageID, keepOldest)[0].Extra); got != 2 {
+		t.Errorf("message-id: %d extras, want 2", got)
+	}
+	if got := len(findDuplicates(msgs, false, matchSize, keepOldest)[0].Extra); got != 1 {
+		t.Errorf("size: %d extras, want 1", got)
+	}
+
+	msgs[0].Hash, msgs[1].Hash = "h1", "h2"
+	if got := findDuplicates(msgs, false, matchHash, keepOldest); len(got) != 0 {
+		t.Errorf("hash: different bodies grouped: %+v", got)
+	}
+	msgs[1].Hash = "h1"
+	if got := findDuplicates(msgs, false, matchHash, keepOldest); len(got) != 1 || len(got[0].Extra) != 1 {
+		t.Errorf("hash: got %+v, want one group with one extra", got)
+	}
+}
+
+// Test_findDuplicates_keepFlagged asserts that the best-flagged copy wins
+// over an older one.
+func Test_findDuplicates_keepFlagged(t *testing.T) {
+	t.Parallel()
+
+	msgs := []dupeMessage{
+		dupe("INBOX", 0, 1, "a@x", 100, 1, "\\Seen"),
+		dupe("INBOX", 0, 2, "a@x", 100, 2, "\\Seen", "\\Flagged"),
+		dupe("INBOX", 0, 3, "a@x", 100, 3, "\\Seen", "$Work"),
+	}
+	if g := findDuplicates(msgs, false, matchMessageID, keepFlagged)[0]; g.Keep.UID != 2 {
+		t.Errorf("keep flagged kept UID %d, want 2", g.Keep.UID)
+	}
+	if g := findDuplicates(msgs, false, matchMessageID, keepOldest)[0]; g.Keep.UID != 1 {
+		t.Errorf("keep oldest kept UID %d, want 1", g.Keep.UID)
+	}
+}
+
+// Test_writeDedupeReport asserts one line per message, with the kept copy
+// marked and the failure attached to the extras of a failed folder.
+func Test_writeDedupeReport(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "report.jsonl")
+	groups := []dupeGroup{{
+		Keep:  dupe("INBOX", 0, 1, "a@x", 100, 1),
+		Extra: []dupeMessage{dupe("INBOX", 0, 2, "a@x", 100, 2)},
+	}}
+	if err := writeDedupeReport(path, groups, map[string]error{"INBOX": errors.New("NO read-only")}); err != nil {
+		t.Fatalf("writeDedupeReport: %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var recs []dedupeRecord
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var r dedupeRecord
+		if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+			t.Fatalf("line %q: %v", sc.Text(), err)
+		}
+		recs = append(recs, r)
+	}
+	if len(recs) != 2 {
+		t.Fatalf("%d records, want 2", len(recs))
+	}
+	if recs[0].Action != "keep" || recs[0].Error != "" || recs[0].UID != 1 {
+		t.Errorf("kept record = %+v", recs[0])
+	}
+	if recs[1].Action != "delete" || recs[1].Error != "NO read-only" || recs[1].Group != 1 {
+		t.Errorf("extra record = %+v", recs[1])
+	}
+}
+
+// Test_checkQuietConfirm_requiresConfirm asserts that --quiet alone is refused
+// and that --confirm, with or without --quiet, is accepted.
+func Test_checkQuietConfirm_requiresConfirm(t *testing.T) {
+	t.Parallel()
+
+	err := checkQuietConfirm(true, false, "delete duplicates")
+	if err == nil || !strings.Contains(err.Error(), "--confirm") {
+		t.Errorf("quiet without --confirm: err = %v, want one naming --confirm", err)
+	}
+	for _, tc := range []struct{ quiet, confirm bool }{{true, true}, {false, false}, {false, true}} {
+		if err := checkQuietConfirm(tc.quiet, tc.confirm, "delete duplicates"); err != nil {
+			t.Errorf("quiet=%v confirm=%v: err = %v, want nil", tc.quiet, tc.confirm, err)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/greeddj/imapsync-go/internal/client"
+	"github.com/greeddj/imapsync-go/internal/secret"
+)
+
+// failureRecord is one line of the failures file. A record without a UID
+// stands for a folder-level error (a broken source stream) that cannot be
+// pinned on one message; --retry-failures skips those. Source is the label
+// of the source account and is only set when the config has several.
+// Reverse marks a copy from the destination back to the source (two-way).
+type failureRecord struct {
+	Time              time.Time `json:"time"`
+	Source            string    `json:"source,omitempty"`
+	Reverse           bool      `json:"reverse,omitempty"`
+	SourceFolder      string    `json:"source_folder"`
+	DestinationFolder string    `json:"destination_folder"`
+	MessageID         string    `json:"message_id,omitempty"`
+	Class             string    `json:"class"`
+	Response          string    `json:"response"`
+	UID               uint32    `json:"uid,omitempty"`
+	Size              int       `json:"size,omitempty"`
+}
+
+// failureLog appends failureRecords to a JSON Lines file shared by all
+// workers. The file is created on the first record, so a clean run leaves
+// no file behind. A nil *failureLog is valid and records nothing.
+type failureLog struct {
+	f    *os.File
+	enc  *json.Encoder
+	err  error
+	path string
+	n    int
+	mu   sync.Mutex
+}
+
+// newFailureLog returns a log writing to path, or nil when path is empty.
+func newFailureLog(path string) *failureLog {
+	if path == "" {
+		return nil
+	}
+	return &failureLog{path: path}
+}
+
+// record logs one failure and writes it to the file; a nil log only logs
+// it. Write errors are kept and reported by close rather than interrupting
+// the sync.
+func (l *failureLog) record(p FolderSyncPlan, uid uint32, msgID string, size int, cause error) {
+	slog.Error("message not copied", "source", p.Source, "source_folder", p.SourceFolder,
+		"destination_folder", p.DestinationFolder, "uid", uid, "message_id", msgID, "size", size,
+		"class", client.Classify(cause).String(), "error", cause)
+	if l == nil {
+		return
+	}
+	rec := failureRecord{
+		Time:              time.Now().UTC(),
+		Source:            p.Source,
+		Reverse:           p.Reverse,
+		SourceFolder:      p.SourceFolder,
+		DestinationFolder: p.DestinationFolder,
+		MessageID:         msgID,
+		Class:             client.Classify(cause).String(),
+		Response:          cause.Error(),
+		UID:               uid,
+		Size:              size,
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err != nil {
+		return
+	}
+	if l.f == nil {
+		f, err := os.Create(l.path)
+		if err != nil {
+			l.err = err
+			return
+		}
+		l.f = f
+		l.enc = json.NewEncoder(secret.NewWriter(f))
+	}
+	if err := l.enc.Encode(rec); err != nil {
+		l.err = err
+		return
+	}
+	l.n++
+}
+
+// close flushes the file and reports how many failures were written.
+func (l *failureLog) close() (int, error) {
+	if l == nil {
+		return 0, nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f != nil {
+		if err := l.f.Close(); err != nil && l.err == nil {
+			l.err = err
+		}
+		l.f = nil
+	}
+	if l.err != nil {
+		return l.n, fmt.Errorf("write failures file %s: %w", l.path, l.err)
+	}
+	return l.n, nil
+}
+
+// checkRetryPaths refuses a retry whose new failures would go to the file
+// it retries: the first new failure would truncate it, losing the records
+// this run does not retry, such as folder-level ones.
+func checkRetryPaths(retryFile, failuresFile string) error {
+	if failuresFile == "" || !sameFile(retryFile, failuresFile) {
+		return nil
+	}
+	return fmt.Errorf("--failures-file %s is the file being retried; set it to another path, e.g. --failures-file %s", failuresFile, retryFailuresPath(retryFile))
+}
+
+// sameFile reports whether a and b name the same file, comparing absolute
+// paths when either does not exist yet.
+func sameFile(a, b string) bool {
+	ai, aerr := os.Stat(a)
+	bi, berr := os.Stat(b)
+	if aerr == nil && berr == nil {
+		return os.SameFile(ai, bi)
+	}
+	aa, aerr := filepath.Abs(a)
+	ba, berr := filepath.Abs(b)
+	return aerr == nil && berr == nil && aa == ba
+}
+
+// retryFailuresPath returns where a retry of path records its own failures:
+// beside it, with ".retry" before the extension.
+func retryFailuresPath(path string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + ".retry" + ext
+}
+
+// retryHint tells how to retry the failures written to path.
+func retryHint(path string) string {
+	return fmt.Sprintf("sync --retry-failures %s --failures-file %s", path, retryFailuresPath(path))
+}
+
+// readFailures loads a failures file written by a previous sync.
+func readFailures(path string) ([]failureRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open failures file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var recs []failureRecord
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64<<10), 1<<20)
+	for line := 1; sc.Scan(); line++ {
+		if strings.TrimSpace(sc.Text()) == "" {
+			continue
+		}
+		var rec failureRecord
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("failures file %s line %d: %w", path, line, err)
+		}
+		recs = append(recs, rec)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read failures file: %w", err)
+	}
+	return recs, nil
+}
+
+// planFromFailures rebuilds a SyncSummary from failure records, one plan per
+// source account and folder pair in file order. Messages that reached the destination since the
+// failure (matched by Message-Id) are dropped, so retrying the same file
+// twice does not duplicate mail. skipped counts records without a UID.
+// Reverse records are checked against src, where their copies go.
+func planFromFailures(ctx context.Context, src, dst *client.Client, recs []failureRecord) (summary *SyncSummary, skipped int, err error) {
+	type pair struct {
+		source, src, dst string
+		reverse          bool
+	}
+	var order []pair
+	byPair := make(map[pair][]failureRecord)
+	for _, rec := range recs {
+		if rec.UID == 0 || rec.SourceFolder == "" || rec.DestinationFolder == "" {
+			skipped++
+			continue
+		}
+		k := pair{rec.Source, rec.SourceFolder, rec.DestinationFolder, rec.Reverse}
+		if _, ok := byPair[k]; !ok {
+			order = append(order, k)
+		}
+		byPair[k] = append(byPair[k], rec)
+	}
+
+	summary = &SyncSummary{Plans: make([]FolderSyncPlan, 0, len(order))}
+	for _, k := range order {
+		if err := ctx.Err(); err != nil {
+			return nil, 0, err
+		}
+		target := dst
+		if k.reverse {
+			target = src
+		}
+		exists, err := target.MailboxExists(ctx, k.dst)
+		if err != nil {
+			return nil, 0, fmt.Errorf("check destination folder %q: %w", k.dst, err)
+		}
+		var present map[string]struct{}
+		if exists {
+			if present, err = target.FetchMessageIDSet(ctx, k.dst); err != nil {
+				return nil, 0, fmt.Errorf("scan destination folder %q: %w", k.dst, err)
+			}
+		}
+
+		plan := FolderSyncPlan{Source: k.source, Reverse: k.reverse, SourceFolder: k.src, DestinationFolder: k.dst, DestinationFolderExists: exists}
+		seen := make(map[uint32]struct{})
+		for _, rec := range byPair[k] {
+			if _, dup := seen[rec.UID]; dup {
+				continue
+			}
+			seen[rec.UID] = struct{}{}
+			if id := strings.TrimSuffix(strings.TrimPrefix(rec.MessageID, "<"), ">"); id != "" {
+				if _, ok := present[id]; ok {
+					continue
+				}
+			}
+			if plan.Sizes == nil {
+				plan.Sizes = make(map[uint32]uint32)
+			}
+			size := uint32(max(rec.Size, 0))
+			plan.SrcUIDs = append(plan.SrcUIDs, rec.UID)
+			plan.Sizes[rec.UID] = size
+			plan.NewSize += uint64(size)
+		}
+		if len(plan.SrcUIDs) == 0 {
+			continue
+		}
+		slices.Sort(plan.SrcUIDs)
+		plan.NewMessages = len(plan.SrcUIDs)
+		summary.Plans = append(summary.Plans, plan)
+		summary.TotalNew += plan.NewMessages
+		summary.TotalNewSize += plan.NewSize
+	}
+	return summary, skipped, nil
+}
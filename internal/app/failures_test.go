@@ -0,0 +1,135 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// Test_failureLog_roundTrip asserts that records written by a failureLog read
+// back unchanged, and that the file only appears once something failed.
+func Test_failureLog_roundTrip(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "failures.jsonl")
+	l := newFailureLog(path)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("file created before first failure: %v", err)
+	}
+
+	plan := FolderSyncPlan{SourceFolder: "INBOX", DestinationFolder: "Archive"}
+	l.record(plan, 42, "<big@x>", 1234, errors.New("NO message too large"))
+	l.record(plan, 0, "", 0, errors.New("stream broke"))
+	n, err := l.close()
+	if err != nil || n != 2 {
+		t.Fatalf("close = (%d, %v), want (2, nil)", n, err)
+	}
+
+	recs, err := readFailures(path)
+	if err != nil {
+		t.Fatalf("readFailures: %v", err)
+	}
+	if len(recs) != 2 {
+		t.Fatalf("got %d records, want 2", len(recs))
+	}
+	r := recs[0]
+	if r.SourceFolder != "INBOX" || r.DestinationFolder != "Archive" || r.UID != 42 ||
+		r.MessageID != "<big@x>" || r.Size != 1234 || r.Class != "unknown" || r.Response != "NO message too large" {
+		t.Errorf("record = %+v", r)
+	}
+}
+
+// Test_failureLog_nilRecordsNothing asserts that a nil log is a no-op.
+func Test_failureLog_nilRecordsNothing(t *testing.T) {
+	t.Parallel()
+
+	l := newFailureLog("")
+	l.record(FolderSyncPlan{}, 1, "", 0, errors.New("x"))
+	if n, err := l.close(); n != 0 || err != nil {
+		t.Errorf("close = (%d, %v), want (0, nil)", n, err)
+	}
+}
+
+// Test_planFromFailures_skipsDeliveredAndFolderLevel asserts that the retry
+// plan drops messages already present on the destination, duplicates and
+// records without a UID.
+func Test_planFromFailures_skipsDeliveredAndFolderLevel(t *testing.T) {
+	t.Parallel()
+
+	dstSrv := newFakeServer(t)
+	dstMsgs := map[string][]struct {
+		msgID string
+		uid   uint32
+	}{
+		"INBOX": {{uid: 1, msgID: "delivered@x"}},
+	}
+	dstSrv.addConnHandler(msgIDFetchHandler(dstSrv, []string{"INBOX"}, dstMsgs))
+	dst := newAppClient(t, dstSrv, "dst")
+
+	recs := []failureRecord{
+		{SourceFolder: "INBOX", DestinationFolder: "INBOX", UID: 9, MessageID: "<missing@x>", Size: 10},
+		{SourceFolder: "INBOX", DestinationFolder: "INBOX", UID: 3, MessageID: "<delivered@x>", Size: 20},
+		{SourceFolder: "INBOX", DestinationFolder: "INBOX", UID: 7, Size: 30},
+		{SourceFolder: "INBOX", DestinationFolder: "INBOX", UID: 7, Size: 30},
+		{SourceFolder: "INBOX", DestinationFolder: "INBOX"},
+	}
+	summary, skipped, err := planFromFailures(context.Background(), nil, dst, recs)
+	if err != nil {
+		t.Fatalf("planFromFailures: %v", err)
+	}
+	if skipped != 1 {
+		t.Errorf("skipped=%d, want 1", skipped)
+	}
+	if len(summary.Plans) != 1 {
+		t.Fatalf("plans=%d, want 1", len(summary.Plans))
+	}
+	p := summary.Plans[0]
+	if !slices.Equal(p.SrcUIDs, []uint32{7, 9}) {
+		t.Errorf("SrcUIDs=%v, want [7 9]", p.SrcUIDs)
+	}
+	if !p.DestinationFolderExists || summary.TotalNew != 2 || summary.TotalNewSize != 40 {
+		t.Errorf("plan=%+v summary total=%d size=%d", p, summary.TotalNew, summary.TotalNewSize)
+	}
+}
+
+// Test_checkRetryPaths_refusesSameFile asserts that a retry whose new
+// failures would go to the file it retries is refused before anything is
+// written, however the path is spelled, and that the suggested file is
+// accepted and leaves the retried records intact after failing again.
+func Test_checkRetryPaths_refusesSameFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "imapsync-failures.jsonl")
+	plan := FolderSyncPlan{SourceFolder: "INBOX", DestinationFolder: "INBOX"}
+	first := newFailureLog(path)
+	first.record(plan, 7, "<a@x>", 10, errors.New("NO try later"))
+	first.record(plan, 0, "", 0, errors.New("stream broke"))
+	if _, err := first.close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	t.Chdir(dir)
+	for _, failuresFile := range []string{path, "imapsync-failures.jsonl", "./imapsync-failures.jsonl"} {
+		if err := checkRetryPaths(path, failuresFile); err == nil {
+			t.Errorf("checkRetryPaths(%q) accepted the file being retried", failuresFile)
+		}
+	}
+
+	next := retryFailuresPath(path)
+	if next != filepath.Join(dir, "imapsync-failures.retry.jsonl") {
+		t.Fatalf("retryFailuresPath = %q", next)
+	}
+	if err := checkRetryPaths(path, next); err != nil {
+		t.Fatalf("checkRetryPaths(%q): %v", next, err)
+	}
+	retry := newFailureLog(next)
+	retry.record(plan, 7, "<a@x>", 10, errors.New("NO try later"))
+	if _, err := retry.close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if recs, err := readFailures(path); err != nil || len(recs) != 2 {
+		t.Errorf("retried file has %d records, %v; want both kept", len(recs), err)
+	}
+}
@@ -514,3 +514,55 @@ func uidFetchBodyHandler(srv *fakeServer, mailboxes []string, uidBodies map[stri
 		}
 	}
 }
+
+// flakyAppendHandler returns a destination-side handler that answers the
+// first failFirst APPENDs with "NO Temporary failure" and accepts the rest.
+// The NO is unclassified, so the client does not reconnect on it.
+func flakyAppendHandler(srv *fakeServer, failFirst int) func(net.Conn) {
+	return func(conn net.Conn) {
+		defer func() { _ = conn.Close() }()
+		_, _ = fmt.Fprintf(conn, "* OK [CAPABILITY IMAP4rev1] fake ready\r\n")
+		reader := bufio.NewReader(conn)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			parts := strings.SplitN(strings.TrimRight(line, "\r\n"), " ", 3)
+			if len(parts) < 2 {
+				continue
+			}
+			tag, verb := parts[0], strings.ToUpper(parts[1])
+			arg := ""
+			if len(parts) == 3 {
+				arg = parts[2]
+			}
+			srv.mu.Lock()
+			srv.counts[verb]++
+			n := srv.counts[verb]
+			srv.mu.Unlock()
+
+			switch verb {
+			case "LIST":
+				_, _ = fmt.Fprintf(conn, "* LIST (\\HasNoChildren) \"/\" INBOX\r\n")
+				_, _ = fmt.Fprintf(conn, "%s OK LIST completed\r\n", tag)
+			case "APPEND":
+				if n <= failFirst {
+					_, _ = fmt.Fprintf(conn, "%s NO Temporary failure\r\n", tag)
+					continue
+				}
+				_, _ = fmt.Fprintf(conn, "+ Ready for literal data\r\n")
+				if size := parseLiteralSize(arg); size > 0 {
+					_, _ = io.ReadFull(reader, make([]byte, size))
+				}
+				_, _ = fmt.Fprintf(conn, "%s OK APPEND completed\r\n", tag)
+			case "LOGOUT":
+				_, _ = fmt.Fprintf(conn, "* BYE Logging out\r\n")
+				_, _ = fmt.Fprintf(conn, "%s OK LOGOUT completed\r\n", tag)
+				return
+			default:
+				_, _ = fmt.Fprintf(conn, "%s OK %s completed\r\n", tag, verb)
+			}
+		}
+	}
+}
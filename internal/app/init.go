@@ -0,0 +1,428 @@
+package app
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/greeddj/imapsync-go/internal/client"
+	"github.com/greeddj/imapsync-go/internal/config"
+	"github.com/greeddj/imapsync-go/internal/provider"
+	"github.com/greeddj/imapsync-go/internal/utils"
+	"github.com/urfave/cli/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultIMAPSPort is added to a server entered without a port.
+const defaultIMAPSPort = "993"
+
+// Ways config init stores a password.
+const (
+	passStoreEnv     = "env"
+	passStoreCommand = "command"
+	passStorePlain   = "plain"
+)
+
+// wizardAccount is one account as entered in config init.
+type wizardAccount struct {
+	provider *provider.Provider
+	creds    config.Credentials
+	role     string // "source" or "destination"
+}
+
+// configWizard asks the questions of config init. connect opens a live
+// session to an account, so tests can point it at a fake server.
+type configWizard struct {
+	p       *utils.Prompter
+	out     io.Writer
+	connect func(ctx context.Context, a *wizardAccount) (*client.Client, error)
+}
+
+// ActionConfigInit writes a first config file interactively: both accounts
+// with a live login test, the provider's recommended rate limits, and the
+// folder map picked from the source's folders.
+func ActionConfigInit(ctx context.Context, c *cli.Command) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	path := c.String("output")
+	w := &configWizard{
+		p:   utils.Stdio(),
+		out: os.Stdout,
+		connect: func(ctx context.Context, a *wizardAccount) (*client.Client, error) {
+			return client.New(ctx, a.creds.Server, a.creds.User, a.creds.Pass, client.Options{
+				UseTLS: true,
+				Auth:   a.creds.Auth,
+				Label:  a.creds.Label,
+				Logger: accountLogger(a.role, a.creds.Label),
+			})
+		},
+	}
+
+	if _, err := os.Stat(path); err == nil && !c.Bool("force") {
+		ok, err := w.p.Confirm(ctx, fmt.Sprintf("⚠️  %s exists. Overwrite it?", path), false)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println("Nothing written.")
+			return nil
+		}
+	}
+
+	data, err := w.run(ctx)
+	if err != nil {
+		return err
+	}
+	// The file may hold a password; keep it private to the user.
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("write config: %w", err)
+	}
+	fmt.Printf("\n✅ Wrote %s\n", path)
+
+	if _, problems, err := config.Lint(path); err == nil {
+		for _, p := range problems {
+			fmt.Printf("⚠️  %s\n", p)
+		}
+	}
+	fmt.Printf("Next: imapsync-go -c %s show, then imapsync-go -c %s sync\n", path, path)
+	return nil
+}
+
+// run asks every question and returns the config file to write.
+func (w *configWizard) run(ctx context.Context) ([]byte, error) {
+	_, _ = fmt.Fprintln(w.out, "🧙 This writes a config for imapsync-go. Press Enter to accept [defaults].")
+
+	src, srcCli, err := w.askAccount(ctx, "source", "src")
+	if err != nil {
+		return nil, err
+	}
+	// List the folders now rather than keep the session idle while the
+	// destination is entered.
+	folders, err := srcCli.ListFolders(ctx)
+	_ = srcCli.Logout()
+	if err != nil {
+		return nil, fmt.Errorf("list source folders: %w", err)
+	}
+
+	dst, dstCli, err := w.askAccount(ctx, "destination", "dst")
+	if err != nil {
+		return nil, err
+	}
+	_ = dstCli.Logout()
+
+	rl, err := w.askRateLimit(ctx, src, dst)
+	if err != nil {
+		return nil, err
+	}
+
+	mappings, err := w.askMappings(ctx, folders, src, dst)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, a := range []*wizardAccount{src, dst} {
+		if err := w.askPassStorage(ctx, a); err != nil {
+			return nil, err
+		}
+	}
+	return renderWizardConfig(src, dst, rl, mappings), nil
+}
+
+// askAccount asks for one account's details and logs in with them, asking
+// again after a failed login until it works or the user gives up.
+func (w *configWizard) askAccount(ctx context.Context, role, label string) (*wizardAccount, *client.Client, error) {
+	_, _ = fmt.Fprintf(w.out, "\n📬 %s account\n", strings.ToUpper(role[:1])+role[1:])
+	a := &wizardAccount{role: role, creds: config.Credentials{Label: label}}
+	for {
+		var err error
+		if a.creds.Label, err = w.p.Ask(ctx, "Label", a.creds.Label); err != nil {
+			return nil, nil, err
+		}
+		if err := w.askServer(ctx, a); err != nil {
+			return nil, nil, err
+		}
+		if a.creds.User, err = w.p.Ask(ctx, "User", a.creds.User); err != nil {
+			return nil, nil, err
+		}
+		if a.creds.Pass, err = w.p.AskPassword(ctx, "Password"); err != nil {
+			return nil, nil, err
+		}
+		if err := w.askAuth(ctx, a); err != nil {
+			return nil, nil, err
+		}
+
+		_, _ = fmt.Fprintf(w.out, "🔌 Logging in to %s...\n", a.creds.Server)
+		cli, err := w.connect(ctx, a)
+		if err == nil {
+			_, _ = fmt.Fprintln(w.out, "✅ Login works")
+			return a, cli, nil
+		}
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		_, _ = fmt.Fprintf(w.out, "❌ Login failed: %v\n", err)
+		again, aerr := w.p.Confirm(ctx, "Try again?", true)
+		if aerr != nil {
+			return nil, nil, aerr
+		}
+		if !again {
+			return nil, nil, fmt.Errorf("%s login: %w", role, err)
+		}
+	}
+}
+
+// askServer asks for the server until it is a valid host:port, adding the
+// IMAPS port when none is given, and reports a known provider.
+func (w *configWizard) askServer(ctx context.Context, a *wizardAccount) error {
+	for {
+		server, err := w.p.Ask(ctx, "Server (host or host:port)", a.creds.Server)
+		if err != nil {
+			return err
+		}
+		if _, _, err := net.SplitHostPort(server); err != nil && server != "" {
+			server = net.JoinHostPort(server, defaultIMAPSPort)
+		}
+		if err := config.ValidateServer(server); err != nil {
+			_, _ = fmt.Fprintf(w.out, "❌ %v\n", err)
+			continue
+		}
+		a.creds.Server = server
+		a.provider = nil
+		if p, ok := provider.Builtin().Detect(server); ok {
+			a.provider = &p
+			_, _ = fmt.Fprintf(w.out, "ℹ️  %s detected: %s\n", p.Name, p.Notes)
+		}
+		return nil
+	}
+}
+
+// askAuth asks for the authentication mechanism until it is a known one,
+// offering the one a detected provider prefers.
+func (w *configWizard) askAuth(ctx context.Context, a *wizardAccount) error {
+	def := cmp.Or(a.creds.Auth, config.AuthLogin)
+	if a.creds.Auth == "" && a.provider != nil && len(a.provider.Auth) > 0 {
+		def = a.provider.Auth[0]
+	}
+	for {
+		auth, err := w.p.Ask(ctx, "Authentication (login or cram-md5)", def)
+		if err != nil {
+			return err
+		}
+		auth = strings.ToLower(auth)
+		if err := config.ValidateAuth(auth); err != nil {
+			_, _ = fmt.Fprintf(w.out, "❌ %v\n", err)
+			continue
+		}
+		a.creds.Auth = auth
+		if auth == config.AuthLogin {
+			a.creds.Auth = ""
+		}
+		return nil
+	}
+}
+
+// askRateLimit offers the recommended limits of the detected providers:
+// downloads as the source's provider allows, uploads as the destination's,
+// and the lower connection cap of the two.
+func (w *configWizard) askRateLimit(ctx context.Context, src, dst *wizardAccount) (config.RateLimit, error) {
+	var rl config.RateLimit
+	var names []string
+	if p := src.provider; p != nil {
+		rl.DownBPS = p.DownBPS
+		rl.MaxConnections = p.MaxConnections
+		names = append(names, p.Name)
+	}
+	if p := dst.provider; p != nil {
+		rl.UpBPS = p.UpBPS
+		if p.MaxConnections > 0 && (rl.MaxConnections == 0 || p.MaxConnections < rl.MaxConnections) {
+			rl.MaxConnections = p.MaxConnections
+		}
+		if len(names) == 0 || names[0] != p.Name {
+			names = append(names, p.Name)
+		}
+	}
+	if rl == (config.RateLimit{}) {
+		return rl, nil
+	}
+
+	_, _ = fmt.Fprintf(w.out, "\n🚦 Recommended limits for %s: down %d B/s, up %d B/s, %d connections (0 = unlimited)\n",
+		strings.Join(names, " and "), rl.DownBPS, rl.UpBPS, rl.MaxConnections)
+	ok, err := w.p.Confirm(ctx, "Use them?", true)
+	if err != nil || !ok {
+		return config.RateLimit{}, err
+	}
+	return rl, nil
+}
+
+// askMappings lists the source folders and asks which to sync and under
+// what name. Syncing every folder as is needs no map at all. When both
+// providers are known, a special folder such as Sent is offered under the
+// destination's name for it.
+func (w *configWizard) askMappings(ctx context.Context, folders []string, src, dst *wizardAccount) ([]config.DirectoryMapping, error) {
+	_, _ = fmt.Fprintf(w.out, "\n📁 Source folders:\n")
+	for i, f := range folders {
+		_, _ = fmt.Fprintf(w.out, "  %3d  %s\n", i+1, f)
+	}
+	all, err := w.p.Confirm(ctx, "Sync every folder under the same name?", true)
+	if err != nil || all {
+		return nil, err
+	}
+
+	var picked []int
+	for {
+		answer, err := w.p.Ask(ctx, "Folders to sync (numbers and ranges, such as 1,3-5)", "")
+		if err != nil {
+			return nil, err
+		}
+		if picked, err = parseSelection(answer, len(folders)); err == nil {
+			break
+		}
+		_, _ = fmt.Fprintf(w.out, "❌ %v\n", err)
+	}
+
+	mappings := make([]config.DirectoryMapping, 0, len(picked))
+	for _, i := range picked {
+		def := folders[i]
+		if src.provider != nil && dst.provider != nil {
+			def = src.provider.Folders.Translate(def, dst.provider.Folders)
+		}
+		name, err := w.p.Ask(ctx, fmt.Sprintf("Destination for %s", folders[i]), def)
+		if err != nil {
+			return nil, err
+		}
+		mappings = append(mappings, config.DirectoryMapping{Source: folders[i], Destination: name})
+	}
+	return mappings, nil
+}
+
+// askPassStorage asks how the config should get the account's password:
+// from an environment variable, a command, or written into the file.
+func (w *configWizard) askPassStorage(ctx context.Context, a *wizardAccount) error {
+	_, _ = fmt.Fprintf(w.out, "\n🔑 Store the %s password as an environment variable (%s), a command such as `pass show ...` (%s), or plain text in the file (%s)\n",
+		a.role, passStoreEnv, passStoreCommand, passStorePlain)
+	for {
+		how, err := w.p.Ask(ctx, "Store as", passStoreEnv)
+		if err != nil {
+			return err
+		}
+		switch strings.ToLower(how) {
+		case passStoreEnv:
+			name, err := w.p.Ask(ctx, "Variable", "IMAPSYNC_"+strings.ToUpper(a.creds.Label)+"_PASS")
+			if err != nil {
+				return err
+			}
+			a.creds.PassEnv, a.creds.Pass = name, ""
+			_, _ = fmt.Fprintf(w.out, "ℹ️  Remember to export %s before running imapsync-go\n", name)
+			return nil
+		case passStoreCommand:
+			command, err := w.p.Ask(ctx, "Command", "")
+			if err != nil {
+				return err
+			}
+			if command == "" {
+				continue
+			}
+			a.creds.PassCommand, a.creds.Pass = command, ""
+			return nil
+		case passStorePlain:
+			return nil
+		default:
+			_, _ = fmt.Fprintf(w.out, "❌ Answer %s, %s or %s\n", passStoreEnv, passStoreCommand, passStorePlain)
+		}
+	}
+}
+
+// parseSelection reads "1,3-5" into zero-based indexes below n, in the
+// order given and without repeats.
+func parseSelection(s string, n int) ([]int, error) {
+	var out []int
+	seen := make(map[int]bool)
+	for part := range strings.SplitSeq(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		lo, hi, isRange := strings.Cut(part, "-")
+		first, err := strconv.Atoi(strings.TrimSpace(lo))
+		last := first
+		if err == nil && isRange {
+			last, err = strconv.Atoi(strings.TrimSpace(hi))
+		}
+		if err != nil || first < 1 || last > n || first > last {
+			return nil, fmt.Errorf("%q is not a folder number or range between 1 and %d", part, n)
+		}
+		for i := first - 1; i < last; i++ {
+			if !seen[i] {
+				seen[i] = true
+				out = append(out, i)
+			}
+		}
+	}
+	if len(out) == 0 {
+		return nil, errors.New("pick at least one folder")
+	}
+	return out, nil
+}
+
+// renderWizardConfig writes the config as commented YAML.
+func renderWizardConfig(src, dst *wizardAccount, rl config.RateLimit, mappings []config.DirectoryMapping) []byte {
+	var b strings.Builder
+	b.WriteString("# imapsync-go configuration, written by `imapsync-go config init`.\n")
+	b.WriteString("# Check it with `imapsync-go -c <this file> config validate`.\n")
+
+	for _, a := range []*wizardAccount{src, dst} {
+		key := "src"
+		if a.role == "destination" {
+			key = "dst"
+		}
+		fmt.Fprintf(&b, "\n# The %s account.\n%s:\n", a.role, key)
+		fmt.Fprintf(&b, "  label: %s\n", yamlScalar(a.creds.Label))
+		fmt.Fprintf(&b, "  server: %s\n", yamlScalar(a.creds.Server))
+		fmt.Fprintf(&b, "  user: %s\n", yamlScalar(a.creds.User))
+		switch {
+		case a.creds.PassEnv != "":
+			fmt.Fprintf(&b, "  # The password is read from this environment variable.\n  pass_env: %s\n", yamlScalar(a.creds.PassEnv))
+		case a.creds.PassCommand != "":
+			fmt.Fprintf(&b, "  # The password is the first line this command prints.\n  pass_command: %s\n", yamlScalar(a.creds.PassCommand))
+		default:
+			fmt.Fprintf(&b, "  pass: %s\n", yamlScalar(a.creds.Pass))
+		}
+		if a.creds.Auth != "" {
+			fmt.Fprintf(&b, "  auth: %s\n", yamlScalar(a.creds.Auth))
+		}
+	}
+
+	if rl != (config.RateLimit{}) {
+		b.WriteString("\n# Client-side limits, in bytes/sec and connections per side; 0 means\n")
+		b.WriteString("# unlimited. Prefilled with the provider's recommendations.\n")
+		fmt.Fprintf(&b, "rate_limit:\n  down_bps: %d\n  up_bps: %d\n  max_connections: %d\n", rl.DownBPS, rl.UpBPS, rl.MaxConnections)
+	}
+
+	if len(mappings) > 0 {
+		b.WriteString("\n# Folders to sync: src on the source, dst on the destination. The\n")
+		b.WriteString("# subfolders of each src are synced too.\nmap:\n")
+		for _, m := range mappings {
+			fmt.Fprintf(&b, "  - src: %s\n    dst: %s\n", yamlScalar(m.Source), yamlScalar(m.Destination))
+		}
+	} else {
+		b.WriteString("\n# No map: every source folder is synced under the same name.\n")
+	}
+	return []byte(b.String())
+}
+
+// yamlScalar renders s as a YAML scalar, quoted when it needs to be.
+func yamlScalar(s string) string {
+	out, err := yaml.Marshal(s)
+	if err != nil {
+		return strconv.Quote(s)
+	}
+	return strings.TrimSuffix(string(out), "\n")
+}
@@ -0,0 +1,139 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/greeddj/imapsync-go/internal/client"
+	"github.com/greeddj/imapsync-go/internal/config"
+	"github.com/greeddj/imapsync-go/internal/utils"
+)
+
+// Test_configWizard_run asserts that the wizard retries a failed login,
+// prefills the detected provider's limits, maps the picked folders under
+// their new names and writes a config that loads back as entered.
+func Test_configWizard_run(t *testing.T) {
+	t.Parallel()
+
+	srv := newFakeServer(t)
+	answers := strings.Join([]string{
+		// Source, first try: the login fails.
+		"", "imap.gmail.com", "me@gmail.com", "wrong", "",
+		"y",
+		// Source, second try: everything kept but the password.
+		"", "", "", "s3cret", "",
+		// Destination.
+		"", "mail.example.com:143", "dst@example.com", "d3st", "cram-md5",
+		// Use Gmail's limits, pick INBOX and rename it.
+		"", "n", "1", "Archive",
+		// Source password from an env var, destination in plain text.
+		"", "", "plain",
+	}, "\n")
+	logins := 0
+	w := &configWizard{
+		p:   utils.NewPrompter(strings.NewReader(answers), io.Discard),
+		out: io.Discard,
+		connect: func(ctx context.Context, a *wizardAccount) (*client.Client, error) {
+			if logins++; logins == 1 {
+				return nil, errors.New("authentication failed")
+			}
+			return client.New(ctx, srv.ln.Addr().String(), a.creds.User, a.creds.Pass, client.Options{UseTLS: false})
+		},
+	}
+
+	data, err := w.run(context.Background())
+	if err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	if logins != 3 {
+		t.Errorf("logins = %d; want 3", logins)
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v\n%s", err, data)
+	}
+	if cfg.Src.Server != "imap.gmail.com:993" || cfg.Src.PassEnv != "IMAPSYNC_SRC_PASS" || cfg.Src.Pass != "" {
+		t.Errorf("src = %+v; want gmail with pass_env", cfg.Src)
+	}
+	if cfg.Dst.Pass != "d3st" || cfg.Dst.Auth != config.AuthCramMD5 {
+		t.Errorf("dst = %+v; want the plain password and cram-md5", cfg.Dst)
+	}
+	want := config.RateLimit{DownBPS: 300_000, MaxConnections: 15}
+	if cfg.RateLimit != want {
+		t.Errorf("rate_limit = %+v; want %+v", cfg.RateLimit, want)
+	}
+	if len(cfg.Map) != 1 || cfg.Map[0].Source != "INBOX" || cfg.Map[0].Destination != "Archive" {
+		t.Errorf("map = %+v; want INBOX -> Archive", cfg.Map)
+	}
+}
+
+// Test_configWizard_canceled asserts that a canceled context stops the
+// wizard at the first question.
+func Test_configWizard_canceled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w := &configWizard{p: utils.NewPrompter(strings.NewReader(""), io.Discard), out: io.Discard}
+	if _, err := w.run(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("run() error = %v; want context.Canceled", err)
+	}
+}
+
+// Test_parseSelection covers lists, ranges, repeats and bad input.
+func Test_parseSelection(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in      string
+		want    []int
+		wantErr bool
+	}{
+		{in: "1", want: []int{0}},
+		{in: "3, 1-2", want: []int{2, 0, 1}},
+		{in: "1-3,2", want: []int{0, 1, 2}},
+		{in: "", wantErr: true},
+		{in: "0", wantErr: true},
+		{in: "4", wantErr: true},
+		{in: "3-2", wantErr: true},
+		{in: "a", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseSelection(tt.in, 3)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseSelection(%q) error = %v; wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("parseSelection(%q) = %v; want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+// Test_yamlScalar asserts that values YAML would misread are quoted.
+func Test_yamlScalar(t *testing.T) {
+	t.Parallel()
+
+	for in, want := range map[string]string{
+		"INBOX":     "INBOX",
+		"yes":       `"yes"`,
+		"a: b":      `'a: b'`,
+		"#hash":     `'#hash'`,
+		"p@ss w0rd": "p@ss w0rd",
+	} {
+		if got := yamlScalar(in); got != want {
+			t.Errorf("yamlScalar(%q) = %s; want %s", in, got, want)
+		}
+	}
+}
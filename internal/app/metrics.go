@@ -0,0 +1,28 @@
+package app
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/greeddj/imapsync-go/internal/metrics"
+	"github.com/urfave/cli/v3"
+)
+
+// startMetrics serves the run's metrics on --metrics-listen, for as long as
+// the command runs. It does nothing when the flag is unset; the returned
+// stop func is always safe to call.
+func startMetrics(c *cli.Command, quiet bool) (func(), error) {
+	addr := c.String("metrics-listen")
+	if addr == "" {
+		return func() {}, nil
+	}
+	bound, stop, err := metrics.Serve(addr)
+	if err != nil {
+		return nil, err
+	}
+	if !quiet {
+		fmt.Printf("📈 Metrics at http://%s%s\n", bound, metrics.Path)
+	}
+	slog.Info("metrics listening", "addr", bound)
+	return stop, nil
+}
@@ -0,0 +1,338 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/greeddj/imapsync-go/internal/client"
+	"github.com/greeddj/imapsync-go/internal/progress"
+	"github.com/greeddj/imapsync-go/internal/tui"
+	"github.com/greeddj/imapsync-go/internal/utils"
+)
+
+// Sizes of the monitor's panes, borders included.
+const (
+	workerPaneHeight = 5
+	workerPaneWidth  = 44
+	minLogHeight     = 3
+)
+
+// tuiMonitor copies the plans the user picked, on a worker pool of its own,
+// and keeps what the monitor screen shows. run fills it in while the tui
+// loop reads it.
+type tuiMonitor struct {
+	started     time.Time
+	finished    time.Time
+	err         error
+	failuresErr error
+	stage       atomic.Pointer[string]
+	plans       []FolderSyncPlan
+	creates     []string
+	trackers    []*progress.Tracker
+	workers     []*tuiWorker
+	overall     progress.Meter
+	synced      int
+	errors      int
+	failed      int
+	mu          sync.Mutex
+	canceled    bool
+}
+
+// tuiWorker is one worker's pane: the plan it copies, its throughput on the
+// wire and the state of its two connections.
+type tuiWorker struct {
+	w     *syncWorker
+	tr    *progress.Tracker
+	title string
+	src   tuiLink
+	dst   tuiLink
+	meter progress.Meter
+	n     int
+	mu    sync.Mutex
+}
+
+// newTUIMonitor prepares the copy of plans, creating the folders in creates
+// first.
+func newTUIMonitor(plans []FolderSyncPlan, creates []string) *tuiMonitor {
+	m := &tuiMonitor{plans: plans, creates: creates, started: time.Now()}
+	m.trackers = make([]*progress.Tracker, len(plans))
+	for i, p := range plans {
+		m.trackers[i] = progress.NewTracker(planTitle(p), int64(p.NewMessages))
+		m.trackers[i].SetBytesTotal(int64(p.NewSize))
+	}
+	m.setStage("Starting")
+	return m
+}
+
+// setStage names what the copy is doing.
+func (m *tuiMonitor) setStage(stage string) { m.stage.Store(&stage) }
+
+// run creates the missing folders and copies the plans, whole folders at a
+// time, on a worker pool connected with a's options.
+func (m *tuiMonitor) run(ctx context.Context, a *tuiApp) {
+	m.setStage("Creating folders")
+	for _, folder := range m.creates {
+		if _, err := a.dst.CreateMailbox(ctx, folder); err != nil {
+			m.stop(fmt.Errorf("failed to create folder %q: %w", folder, err))
+			return
+		}
+	}
+
+	n := computeEffectiveWorkers(a.cfg.Workers, a.cfg.RateLimit.MaxConnections, len(m.plans))
+	m.setStage(fmt.Sprintf("Connecting %d workers", n))
+	pool, err := newSyncWorkerPool(ctx, a.cfg, a.srcOpts, a.dstOpts, n)
+	if err != nil {
+		m.stop(err)
+		return
+	}
+	defer pool.close()
+
+	budget := newByteBudget(int64(a.inflight))
+	failures := newFailureLog(a.failures)
+	workers := make([]*tuiWorker, len(pool.all))
+	for i, w := range pool.all {
+		w.budget, w.failures = budget, failures
+		tw := &tuiWorker{w: w, n: i + 1}
+		w.src.SetProgressTracker(&tw.src)
+		w.dst.SetProgressTracker(&tw.dst)
+		workers[i] = tw
+	}
+	m.mu.Lock()
+	m.workers = workers
+	m.mu.Unlock()
+
+	m.setStage("Copying")
+	pw := progress.NewLogWriter(a.log)
+	queue := make(chan int)
+	var wg sync.WaitGroup
+	for _, tw := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				m.copyPlan(ctx, tw, i, pw)
+			}
+		}()
+	}
+feed:
+	for i := range m.plans {
+		select {
+		case queue <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	failed, failuresErr := failures.close()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failed, m.failuresErr, m.canceled = failed, failuresErr, ctx.Err() != nil
+	m.finished = time.Now()
+	switch {
+	case m.canceled:
+		m.setStage("Canceled")
+	case m.errors > 0:
+		m.setStage("Finished with errors")
+	default:
+		m.setStage("Finished")
+	}
+	slog.Info("copy finished", "synced", m.synced, "errors", m.errors, "failures_recorded", failed)
+}
+
+// stop ends the copy before it started, for err.
+func (m *tuiMonitor) stop(err error) {
+	slog.Error("sync stopped", "error", err)
+	m.mu.Lock()
+	m.err = err
+	m.mu.Unlock()
+	m.setStage("Failed: " + err.Error())
+}
+
+// copyPlan copies plan i on tw.
+func (m *tuiMonitor) copyPlan(ctx context.Context, tw *tuiWorker, i int, pw *progress.Writer) {
+	tr := m.trackers[i]
+	tw.mu.Lock()
+	tw.tr, tw.title = tr, planTitle(m.plans[i])
+	tw.mu.Unlock()
+
+	synced, errors := runFolderSync(ctx, tw.w, m.plans[i], tr, i, len(m.plans), pw, false)
+
+	tw.mu.Lock()
+	tw.tr = nil
+	tw.mu.Unlock()
+	m.mu.Lock()
+	m.synced += synced
+	m.errors += errors
+	m.mu.Unlock()
+}
+
+// plan returns the tracker and title of the plan tw copies, nil when idle.
+func (tw *tuiWorker) plan() (*progress.Tracker, string) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	return tw.tr, tw.title
+}
+
+// sample updates the transfer rates from the workers' wire traffic; the
+// tui calls it every refresh.
+func (m *tuiMonitor) sample(now time.Time) {
+	var total int64
+	for _, tw := range m.workerList() {
+		n := tw.w.traffic.Read()
+		tw.meter.Update(n, now)
+		total += n
+	}
+	m.overall.Update(total, now)
+}
+
+// workerList returns the workers, once they are connected.
+func (m *tuiMonitor) workerList() []*tuiWorker {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.workers
+}
+
+// view renders the monitor: overall progress, a pane per worker, the
+// folders, the log and footer.
+func (m *tuiMonitor) view(header string, log *tuiLog, footer string, width, height int) []string {
+	var messages, total int64
+	var bytesDone, bytesTotal int64
+	for _, tr := range m.trackers {
+		messages += tr.Value()
+		total += tr.TotalValue()
+		done, all := tr.Bytes()
+		bytesDone += done
+		bytesTotal += all
+	}
+	m.mu.Lock()
+	errors, elapsed := m.errors, time.Since(m.started)
+	if !m.finished.IsZero() {
+		elapsed = m.finished.Sub(m.started)
+	}
+	m.mu.Unlock()
+
+	overall := fmt.Sprintf("%s: %d/%d messages, %s of %s, %s/s",
+		*m.stage.Load(), messages, total, utils.FormatSize(uint64(bytesDone)), utils.FormatSize(uint64(bytesTotal)),
+		utils.FormatSize(uint64(m.overall.Rate())))
+	if rate := m.overall.Rate(); rate > 0 && bytesTotal > bytesDone {
+		overall += ", ETA " + formatETA(time.Duration(float64(bytesTotal-bytesDone)/rate*float64(time.Second)))
+	}
+	overall += ", elapsed " + formatETA(elapsed)
+	if errors > 0 {
+		overall += tuiWarningStyle.Sprintf(", %d errors", errors)
+	}
+	lines := []string{header, overall}
+
+	// Worker panes fill the rows they need, up to half the screen.
+	workers := m.workerList()
+	cols := max(1, min(len(workers), width/workerPaneWidth))
+	rows := min((len(workers)+cols-1)/cols, max(1, (height/2)/workerPaneHeight))
+	for r := range rows {
+		var blocks [][]string
+		var widths []int
+		for c := range cols {
+			i := r*cols + c
+			if i >= len(workers) {
+				break
+			}
+			blocks = append(blocks, workers[i].pane(width/cols))
+			widths = append(widths, width/cols)
+		}
+		lines = append(lines, tui.SideBySide(blocks, widths)...)
+	}
+
+	rest := height - len(lines) - 1
+	folderHeight := min(len(m.plans)+2, max(3, rest-minLogHeight))
+	lines = append(lines, tui.Box("Folders", m.folderLines(folderHeight-2), width, folderHeight)...)
+	logHeight := max(minLogHeight, height-len(lines)-1)
+	lines = append(lines, tui.Box("Log", log.tail(logHeight-2), width, logHeight)...)
+	return append(lines, footer)
+}
+
+// pane renders the worker's box.
+func (tw *tuiWorker) pane(width int) []string {
+	tr, title := tw.plan()
+	first := tuiDimStyle.Sprint("idle")
+	if tr != nil {
+		first = fmt.Sprintf("%s  %d/%d  %s/s", title, tr.Value(), tr.TotalValue(), utils.FormatSize(uint64(tw.meter.Rate())))
+	}
+	var current string
+	if p := tw.w.current.Load(); p != nil && tr != nil {
+		current = "✉ " + *p
+	}
+	link := fmt.Sprintf("src %s  dst %s", linkState(tw.w.src, &tw.src), linkState(tw.w.dst, &tw.dst))
+	return tui.Box(fmt.Sprintf("worker %d", tw.n), []string{first, current, link}, width, workerPaneHeight)
+}
+
+// linkState describes a worker connection: reconnecting, with the client's
+// last message, or up, with the reconnects so far.
+func linkState(c *client.Client, l *tuiLink) string {
+	switch n := c.Reconnects(); {
+	case c.Reconnecting():
+		return tuiWarningStyle.Sprint("reconnecting: " + l.Text())
+	case n > 0:
+		return fmt.Sprintf("ok (%d reconnects)", n)
+	}
+	return "ok"
+}
+
+// folderLines renders n plans, scrolled to keep the first unfinished one
+// in view.
+func (m *tuiMonitor) folderLines(n int) []string {
+	first := 0
+	for first < len(m.trackers)-1 && (m.trackers[first].IsDone() || m.trackers[first].IsErrored()) {
+		first++
+	}
+	start, end := tui.Window(len(m.plans), first, 0, n)
+	lines := make([]string, 0, end-start)
+	for i := start; i < end; i++ {
+		tr := m.trackers[i]
+		glyph := tuiDimStyle.Sprint("·")
+		switch {
+		case tr.IsErrored():
+			glyph = tuiWarningStyle.Sprint("✗")
+		case tr.IsDone():
+			glyph = tuiNewStyle.Sprint("✓")
+		case tr.IsStarted():
+			glyph = "▸"
+		}
+		done, all := tr.Bytes()
+		lines = append(lines, fmt.Sprintf("%s %s  %d/%d  %s of %s", glyph, planTitle(m.plans[i]),
+			tr.Value(), m.plans[i].NewMessages, utils.FormatSize(uint64(done)), utils.FormatSize(uint64(all))))
+	}
+	return lines
+}
+
+// report prints the outcome once the screen is gone, as sync does, and
+// returns ErrSilentExit when messages failed or the copy was canceled.
+// failuresPath is where failed messages were written.
+func (m *tuiMonitor) report(failuresPath string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	if m.failuresErr != nil {
+		fmt.Printf("⚠️ %v\n", m.failuresErr)
+	}
+	switch {
+	case m.canceled:
+		fmt.Printf("❌ Sync canceled. %d messages uploaded, %d errors occurred\n", m.synced, m.errors)
+	case m.errors > 0:
+		fmt.Printf("❌ Sync completed with errors. %d messages uploaded, %d errors occurred\n", m.synced, m.errors)
+	default:
+		fmt.Println("✨ Sync completed successfully. ✨")
+		slog.Info("sync finished")
+		return nil
+	}
+	if m.failed > 0 {
+		fmt.Printf("📝 %d failed messages written to %s; retry them with: %s\n", m.failed, failuresPath, retryHint(failuresPath))
+	}
+	return ErrSilentExit
+}
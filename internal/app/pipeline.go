@@ -0,0 +1,201 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/greeddj/imapsync-go/internal/client"
+	"github.com/greeddj/imapsync-go/internal/metrics"
+	"github.com/greeddj/imapsync-go/internal/progress"
+	"golang.org/x/sync/semaphore"
+)
+
+const (
+	// pipelineDepth bounds how many fetched messages wait between a worker's
+	// source and destination connections. The byte budget is the real
+	// memory cap; this only stops one worker from hoarding it.
+	pipelineDepth = 16
+	// multiAppendMaxItems caps how many queued messages one MULTIAPPEND carries.
+	multiAppendMaxItems = 16
+	// multiAppendMaxBytes caps the literal bytes of one MULTIAPPEND, so a
+	// rejected batch does not waste a large upload.
+	multiAppendMaxBytes = 8 << 20
+	// appendAttempts bounds how often one message is sent after a transient
+	// failure. AppendItems has already reconnected by the time it returns
+	// such an error, so each attempt runs on a fresh session.
+	appendAttempts = 3
+)
+
+// byteBudget caps the message bodies held in memory by all workers together.
+// A nil *byteBudget is valid and means "unlimited".
+//
+// The cap covers bodies queued between fetch and APPEND. go-imap parses up
+// to messageChanBuffer messages ahead of the fetch callback, so the real peak
+// is the budget plus that read-ahead per worker.
+type byteBudget struct {
+	sem *semaphore.Weighted
+	max int64
+}
+
+// newByteBudget returns a budget of maxBytes, or nil when maxBytes <= 0.
+func newByteBudget(maxBytes int64) *byteBudget {
+	if maxBytes <= 0 {
+		return nil
+	}
+	return &byteBudget{sem: semaphore.NewWeighted(maxBytes), max: maxBytes}
+}
+
+// acquire reserves n bytes, blocking until they are free. A message larger
+// than the whole budget reserves the whole budget instead, so it still goes
+// through, alone. The returned amount must be handed back to release.
+func (b *byteBudget) acquire(ctx context.Context, n int64) (int64, error) {
+	if b == nil {
+		return 0, nil
+	}
+	n = min(n, b.max)
+	if err := b.sem.Acquire(ctx, n); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// release returns n bytes reserved by acquire.
+func (b *byteBudget) release(n int64) {
+	if b == nil || n == 0 {
+		return
+	}
+	b.sem.Release(n)
+}
+
+// pendingAppend is a fetched message waiting for its APPEND.
+type pendingAppend struct {
+	msgID    string
+	item     client.AppendItem
+	reserved int64
+	uid      uint32
+}
+
+// chunkAppender is the destination half of one chunk's pipeline. It runs on
+// a single goroutine, so lastUpdate needs no lock.
+type chunkAppender struct {
+	lastUpdate time.Time
+	w          *syncWorker
+	r          *planRun
+	pw         *progress.Writer
+	multi      bool
+	verbose    bool
+}
+
+// run consumes queue until it is closed. Every dequeued message releases its
+// budget reservation, including when ctx is canceled, so the producer and
+// other workers never wait on bytes nobody will upload.
+func (a *chunkAppender) run(ctx context.Context, queue <-chan pendingAppend) {
+	batch := make([]pendingAppend, 0, multiAppendMaxItems)
+	for first := range queue {
+		batch = append(batch[:0], first)
+		if a.multi {
+			batch = drainBatch(queue, batch)
+		}
+		metrics.QueueDepth.Add(-float64(len(batch)))
+		if ctx.Err() == nil {
+			a.appendBatch(ctx, batch)
+		}
+		for _, pa := range batch {
+			a.w.budget.release(pa.reserved)
+		}
+	}
+}
+
+// drainBatch adds already-queued messages to batch without blocking, up to
+// the MULTIAPPEND item and byte caps.
+func drainBatch(queue <-chan pendingAppend, batch []pendingAppend) []pendingAppend {
+	size := 0
+	for _, pa := range batch {
+		size += len(pa.item.Body)
+	}
+	for len(batch) < multiAppendMaxItems && size < multiAppendMaxBytes {
+		select {
+		case pa, ok := <-queue:
+			if !ok {
+				return batch
+			}
+			batch = append(batch, pa)
+			size += len(pa.item.Body)
+		default:
+			return batch
+		}
+	}
+	return batch
+}
+
+// appendBatch uploads batch, as one MULTIAPPEND when it holds several
+// messages. MULTIAPPEND is all-or-nothing, so a rejected batch is resent
+// message by message to pin the failure on the right one.
+func (a *chunkAppender) appendBatch(ctx context.Context, batch []pendingAppend) {
+	folder := a.r.plan.DestinationFolder
+	if len(batch) > 1 {
+		items := make([]client.AppendItem, len(batch))
+		for i, pa := range batch {
+			items[i] = pa.item
+		}
+		err := a.w.dst.AppendItems(ctx, folder, items)
+		if err == nil {
+			for _, pa := range batch {
+				a.synced(pa)
+			}
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+	for _, pa := range batch {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := appendWithRetry(ctx, a.w.dst, folder, pa.item); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			a.r.failAppend(a.w, pa, err, a.pw, a.verbose)
+			a.throttledUpdate()
+			continue
+		}
+		a.synced(pa)
+	}
+}
+
+// appendWithRetry sends one message, resending it up to appendAttempts times
+// while the failure is transient. Throttled, permanent and unclassified
+// errors are returned at once: the plan's retry pass gets one more go at
+// those, after the rest of the folder.
+func appendWithRetry(ctx context.Context, dst *client.Client, folder string, item client.AppendItem) error {
+	var err error
+	for range appendAttempts {
+		err = dst.AppendItems(ctx, folder, []client.AppendItem{item})
+		if err == nil || ctx.Err() != nil || client.Classify(err) != client.ClassTransient {
+			return err
+		}
+	}
+	return err
+}
+
+// synced records one successful APPEND.
+func (a *chunkAppender) synced(pa pendingAppend) {
+	n := a.r.synced.Add(1)
+	a.r.tr.Increment(1)
+	a.r.appended(len(pa.item.Body))
+	a.throttledUpdate()
+	if a.verbose {
+		a.pw.Log("Synced %d/%d to %s, processed msg id %s",
+			n, a.r.plan.NewMessages, a.r.plan.DestinationFolder, pa.msgID)
+	}
+}
+
+// throttledUpdate redraws the tracker line at most every 100ms.
+func (a *chunkAppender) throttledUpdate() {
+	if now := time.Now(); now.Sub(a.lastUpdate) > 100*time.Millisecond {
+		a.lastUpdate = now
+		a.r.updateMessage()
+	}
+}
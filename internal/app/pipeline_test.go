@@ -0,0 +1,65 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestByteBudget_nilIsUnlimited(t *testing.T) {
+	t.Parallel()
+
+	var b *byteBudget
+	if newByteBudget(0) != nil {
+		t.Fatal("newByteBudget(0) should return nil")
+	}
+	n, err := b.acquire(context.Background(), 1<<40)
+	if err != nil || n != 0 {
+		t.Errorf("nil acquire = (%d, %v), want (0, nil)", n, err)
+	}
+	b.release(n)
+}
+
+func TestByteBudget_oversizedMessageTakesWholeBudget(t *testing.T) {
+	t.Parallel()
+
+	b := newByteBudget(100)
+	n, err := b.acquire(context.Background(), 1000)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	if n != 100 {
+		t.Errorf("reserved %d, want 100", n)
+	}
+
+	// The budget is exhausted: a second acquire must block until release.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := b.acquire(ctx, 1); err == nil {
+		t.Error("second acquire succeeded while budget exhausted")
+	}
+
+	b.release(n)
+	if _, err := b.acquire(context.Background(), 50); err != nil {
+		t.Errorf("acquire after release: %v", err)
+	}
+}
+
+func TestDrainBatch_stopsAtItemCapAndEmptyQueue(t *testing.T) {
+	t.Parallel()
+
+	queue := make(chan pendingAppend, multiAppendMaxItems+4)
+	for range multiAppendMaxItems + 4 {
+		queue <- pendingAppend{}
+	}
+	batch := drainBatch(queue, []pendingAppend{{}})
+	if len(batch) != multiAppendMaxItems {
+		t.Errorf("batch=%d, want %d", len(batch), multiAppendMaxItems)
+	}
+
+	// Five left in the queue; the drain must return without blocking.
+	batch = drainBatch(queue, []pendingAppend{{}})
+	if len(batch) != 6 {
+		t.Errorf("batch=%d, want 6", len(batch))
+	}
+}
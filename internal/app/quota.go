@@ -0,0 +1,140 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/greeddj/imapsync-go/internal/client"
+	"github.com/greeddj/imapsync-go/internal/utils"
+)
+
+// quotaWarnShare is how full a quota may be after the sync before the
+// preview warns that the destination is nearly out of room.
+const quotaWarnShare = 0.9
+
+// quotaMailbox is the mailbox whose quota roots are checked. Servers almost
+// always keep one root per account, which INBOX belongs to.
+const quotaMailbox = "INBOX"
+
+// quotaCheck is the outcome of comparing a plan with the destination quota.
+// lines describe every limited resource for the preview; exceeded names the
+// resources the plan does not fit in, and tight those it leaves nearly full.
+type quotaCheck struct {
+	lines    []string
+	exceeded []string
+	tight    []string
+}
+
+// checkQuota compares what a plan adds to the destination with the
+// remaining room under each quota root. A limit of 0 is read as unlimited.
+func checkQuota(quotas []client.Quota, needBytes uint64, needMsgs int) quotaCheck {
+	var qc quotaCheck
+	for _, q := range quotas {
+		root := q.Root
+		if root == "" {
+			root = "account"
+		}
+		if r, ok := q.Resource(client.QuotaStorage); ok && r.Limit > 0 {
+			// STORAGE counts units of 1024 octets.
+			used, limit := r.Usage*1024, r.Limit*1024
+			free := limit - min(used, limit)
+			qc.lines = append(qc.lines, fmt.Sprintf("%s storage: %s of %s used, %s free, plan adds %s",
+				root, utils.FormatSize(used), utils.FormatSize(limit), utils.FormatSize(free), utils.FormatSize(needBytes)))
+			qc.judge(fmt.Sprintf("%s storage (%s free, %s needed)", root, utils.FormatSize(free), utils.FormatSize(needBytes)),
+				float64(used+needBytes), float64(limit))
+		}
+		if r, ok := q.Resource(client.QuotaMessages); ok && r.Limit > 0 {
+			free := r.Limit - min(r.Usage, r.Limit)
+			qc.lines = append(qc.lines, fmt.Sprintf("%s messages: %d of %d used, %d free, plan adds %d",
+				root, r.Usage, r.Limit, free, needMsgs))
+			qc.judge(fmt.Sprintf("%s messages (%d free, %d needed)", root, free, needMsgs),
+				float64(r.Usage)+float64(needMsgs), float64(r.Limit))
+		}
+	}
+	return qc
+}
+
+// judge records what is in the way once after reaches limit or its warning
+// share.
+func (qc *quotaCheck) judge(what string, after, limit float64) {
+	switch {
+	case after > limit:
+		qc.exceeded = append(qc.exceeded, what)
+	case after > limit*quotaWarnShare:
+		qc.tight = append(qc.tight, what)
+	}
+}
+
+// print shows the quota lines and warnings under the sync preview.
+func (qc quotaCheck) print() {
+	if len(qc.lines) == 0 {
+		return
+	}
+	fmt.Printf("\n💾 Destination quota:\n")
+	for _, l := range qc.lines {
+		fmt.Printf("• %s\n", l)
+	}
+	for _, w := range qc.tight {
+		fmt.Printf("⚠️  The sync leaves the destination over %.0f%% full: %s\n", quotaWarnShare*100, w)
+	}
+	for _, w := range qc.exceeded {
+		fmt.Printf("❌ The plan does not fit in the destination quota: %s\n", w)
+	}
+}
+
+// refusal returns the error that stops a sync which does not fit, unless
+// ignore is set.
+func (qc quotaCheck) refusal(ignore bool) error {
+	if len(qc.exceeded) == 0 || ignore {
+		return nil
+	}
+	return fmt.Errorf("plan exceeds the destination quota: %s; free up space or pass --ignore-quota", strings.Join(qc.exceeded, ", "))
+}
+
+// destinationQuota checks the forward plans against the destination quota.
+// A server without QUOTA, or one that fails the query, yields an empty
+// check: a missing quota must not block a sync.
+func destinationQuota(ctx context.Context, dstClient *client.Client, plans []FolderSyncPlan, verbose bool) quotaCheck {
+	quotas, err := dstClient.QuotaRoot(ctx, quotaMailbox)
+	if err != nil {
+		if verbose {
+			fmt.Printf("ℹ️  Cannot read the destination quota: %v\n", err)
+		}
+		return quotaCheck{}
+	}
+	var needBytes uint64
+	var needMsgs int
+	for _, p := range plans {
+		if !p.Reverse {
+			needBytes += p.NewSize
+			needMsgs += p.NewMessages
+		}
+	}
+	return checkQuota(quotas, needBytes, needMsgs)
+}
+
+// formatQuota renders the quota roots of an account for show, one line
+// per root, or "" when the server reports none.
+func formatQuota(quotas []client.Quota) string {
+	var lines []string
+	for _, q := range quotas {
+		var parts []string
+		if r, ok := q.Resource(client.QuotaStorage); ok && r.Limit > 0 {
+			parts = append(parts, fmt.Sprintf("%s of %s (%.0f%%)",
+				utils.FormatSize(r.Usage*1024), utils.FormatSize(r.Limit*1024), 100*float64(r.Usage)/float64(r.Limit)))
+		}
+		if r, ok := q.Resource(client.QuotaMessages); ok && r.Limit > 0 {
+			parts = append(parts, fmt.Sprintf("%d of %d messages", r.Usage, r.Limit))
+		}
+		if len(parts) == 0 {
+			continue
+		}
+		line := strings.Join(parts, ", ")
+		if q.Root != "" {
+			line = fmt.Sprintf("%s: %s", q.Root, line)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,84 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/greeddj/imapsync-go/internal/client"
+)
+
+func storageQuota(usedKiB, limitKiB uint64) []client.Quota {
+	return []client.Quota{{Resources: []client.QuotaResource{{Name: client.QuotaStorage, Usage: usedKiB, Limit: limitKiB}}}}
+}
+
+// Test_checkQuota_levels asserts that a plan is fine, tight or refused by
+// how full it leaves the quota, and that --ignore-quota lifts the refusal.
+func Test_checkQuota_levels(t *testing.T) {
+	t.Parallel()
+
+	const mib = 1 << 20
+	tests := []struct {
+		name          string
+		need          uint64
+		tight, exceed bool
+	}{
+		{"fits", 10 * mib, false, false},
+		{"tight", 45 * mib, true, false},
+		{"exceeds", 60 * mib, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			// 50 MiB used of 100 MiB.
+			qc := checkQuota(storageQuota(50*1024, 100*1024), tt.need, 1)
+			if len(qc.lines) != 1 {
+				t.Fatalf("lines = %v, want 1", qc.lines)
+			}
+			if (len(qc.tight) > 0) != tt.tight || (len(qc.exceeded) > 0) != tt.exceed {
+				t.Errorf("tight = %v, exceeded = %v", qc.tight, qc.exceeded)
+			}
+			if err := qc.refusal(false); (err != nil) != tt.exceed {
+				t.Errorf("refusal = %v, want error %v", err, tt.exceed)
+			}
+			if err := qc.refusal(true); err != nil {
+				t.Errorf("refusal with ignore = %v", err)
+			}
+		})
+	}
+}
+
+// Test_checkQuota_messageLimitAndUnlimited asserts that the MESSAGE limit is
+// checked on its own and that a zero limit means unlimited.
+func Test_checkQuota_messageLimitAndUnlimited(t *testing.T) {
+	t.Parallel()
+
+	quotas := []client.Quota{{Root: "user", Resources: []client.QuotaResource{
+		{Name: client.QuotaStorage, Usage: 10, Limit: 0},
+		{Name: client.QuotaMessages, Usage: 90, Limit: 100},
+	}}}
+	qc := checkQuota(quotas, 1<<30, 20)
+	if len(qc.lines) != 1 || !strings.HasPrefix(qc.lines[0], "user messages") {
+		t.Errorf("lines = %v, want only the message line", qc.lines)
+	}
+	if len(qc.exceeded) != 1 {
+		t.Errorf("exceeded = %v, want the message limit", qc.exceeded)
+	}
+	if qc := checkQuota(nil, 1<<30, 20); qc.refusal(false) != nil || len(qc.lines) != 0 {
+		t.Errorf("no quota: %+v, want empty check", qc)
+	}
+}
+
+func Test_formatQuota(t *testing.T) {
+	t.Parallel()
+
+	got := formatQuota([]client.Quota{{Root: "", Resources: []client.QuotaResource{
+		{Name: client.QuotaStorage, Usage: 512, Limit: 2048},
+		{Name: client.QuotaMessages, Usage: 3, Limit: 10},
+	}}})
+	if !strings.Contains(got, "(25%)") || !strings.Contains(got, "3 of 10 messages") {
+		t.Errorf("formatQuota = %q", got)
+	}
+	if got := formatQuota(nil); got != "" {
+		t.Errorf("formatQuota(nil) = %q, want empty", got)
+	}
+}
@@ -3,8 +3,12 @@ package app
 
 import (
 	"context"
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"strconv"
 
 	"github.com/greeddj/imapsync-go/internal/client"
 	"github.com/greeddj/imapsync-go/internal/config"
@@ -16,18 +20,67 @@ import (
 	"golang.org/x/sync/errgroup"
 )
 
-// ActionShow displays information about mailboxes in source and destination IMAP accounts.
+// formatTable and formatCSV are the output formats of show besides JSON.
+const (
+	formatTable = "table"
+	formatCSV   = "csv"
+)
+
+// shownAccount is one account in the JSON output of show.
+type shownAccount struct {
+	Role    string         `json:"role"`
+	Label   string         `json:"label"`
+	Server  string         `json:"server"`
+	User    string         `json:"user"`
+	Quota   []shownQuota   `json:"quota,omitempty"`
+	Folders []shownMailbox `json:"folders"`
+}
+
+// shownQuota is one quota resource of an account; usage and limit are in the
+// resource's units, KiB for storage.
+type shownQuota struct {
+	Root     string `json:"root"`
+	Resource string `json:"resource"`
+	Usage    uint64 `json:"usage"`
+	Limit    uint64 `json:"limit"`
+}
+
+// shownMailbox is one folder of an account; the size is in bytes.
+type shownMailbox struct {
+	Name     string `json:"name"`
+	Messages uint32 `json:"messages"`
+	Size     uint64 `json:"size"`
+}
+
+// accountResult is what show loaded for one account: the open client, so
+// that the caller can log out, its mailboxes and its quota.
+type accountResult struct {
+	cli       *client.Client
+	mailboxes []*client.MailboxInfo
+	quotas    []client.Quota
+}
+
+// ActionShow displays information about mailboxes in source and destination
+// IMAP accounts, as tables, JSON or CSV. With --compare it lines up every
+// source folder with the destination folder sync maps it to.
 func ActionShow(ctx context.Context, c *cli.Command) error {
 	if err := ctx.Err(); err != nil {
 		return err
 	}
 
-	verbose := c.Bool("verbose")
-	quiet := c.Bool("quiet")
+	format := c.String("format")
+	if format != formatTable && format != formatJSON && format != formatCSV {
+		return fmt.Errorf("--format must be %s, %s or %s, got %q", formatTable, formatJSON, formatCSV, format)
+	}
+	compare := c.Bool("compare")
+	withUnique := c.Bool("with-unique")
+	if withUnique && !compare {
+		return fmt.Errorf("--with-unique requires --compare")
+	}
 
-	pw := progress.NewWriter(2, quiet)
-	pw.Start()
-	defer pw.Stop()
+	verbose := c.Bool("verbose")
+	// Progress bars would corrupt JSON and CSV on stdout.
+	quiet := c.Bool("quiet") || format != formatTable
 
 	// No pw.Log before AppendTracker: go-pretty's redraw cycle counts
 	// only tracker rows when computing how far cursor-up needs to go,
@@ -40,32 +93,44 @@ func ActionShow(ctx context.Context, c *cli.Command) error {
 	if err := ctx.Err(); err != nil {
 		return err
 	}
+	sources := cfg.SourceList()
+	trace, closeTrace, err := openTrace(c)
+	if err != nil {
+		return err
+	}
+	defer closeTrace()
 
-	srcTracker := progress.NewTracker(fmt.Sprintf("[%s] Loading mailboxes", cfg.Src.Label), 100)
+	pw := progress.NewWriter(len(sources)+1, quiet)
+	pw.Start()
+	defer pw.Stop()
+
+	srcTrackers := make([]*progress.Tracker, len(sources))
+	for i, src := range sources {
+		srcTrackers[i] = progress.NewTracker(fmt.Sprintf("[%s] Loading mailboxes", src.Label), 100)
+		traceTracker("show-src", srcTrackers[i].Message)
+		pw.AppendTracker(srcTrackers[i])
+	}
 	dstTracker := progress.NewTracker(fmt.Sprintf("[%s] Loading mailboxes", cfg.Dst.Label), 100)
-	traceTracker("show-src", srcTracker.Message)
-	pw.AppendTracker(srcTracker)
 	traceTracker("show-dst", dstTracker.Message)
 	pw.AppendTracker(dstTracker)
 
-	// loadAccount fetches mailboxes for one side and returns both the open
-	// client (so we can Logout from the caller) and the mailbox slice.
-	type accountResult struct {
-		cli       *client.Client
-		mailboxes []*client.MailboxInfo
-	}
-	loadAccount := func(ctx context.Context, label string, creds config.Credentials, tr *progress.Tracker) (accountResult, error) {
+	// loadAccount fetches mailboxes for one side ("source" or
+	// "destination") and returns both the open client (so we can Logout
+	// from the caller) and the mailbox slice.
+	loadAccount := func(ctx context.Context, side, label string, creds config.Credentials, tr *progress.Tracker) (accountResult, error) {
 		tr.UpdateMessage(fmt.Sprintf("[%s] Connecting...", label))
 		cli, err := client.New(ctx, creds.Server, creds.User, creds.Pass, client.Options{
 			UseTLS:  true,
 			Auth:    creds.Auth,
+			Label:   label,
 			Verbose: verbose,
+			Trace:   trace,
+			Logger:  accountLogger(side, label),
 		})
 		if err != nil {
 			tr.MarkAsErrored()
 			return accountResult{}, fmt.Errorf("[%s] connect: %w", label, err)
 		}
-		cli.SetPrefix(label)
 		cli.SetProgressWriter(pw)
 		cli.SetProgressTracker(tr)
 
@@ -75,22 +140,36 @@ func ActionShow(ctx context.Context, c *cli.Command) error {
 			tr.MarkAsErrored()
 			return accountResult{cli: cli}, fmt.Errorf("[%s] list mailboxes: %w", label, err)
 		}
+		// Quota is extra detail: a server that fails GETQUOTAROOT still
+		// gets its folder table.
+		quotas, err := cli.QuotaRoot(ctx, quotaMailbox)
+		if err != nil {
+			if ctx.Err() != nil {
+				return accountResult{cli: cli}, ctx.Err()
+			}
+			if verbose {
+				pw.Log("[%s] ⚠️ Cannot read quota: %v", label, err)
+			}
+		}
 		tr.MarkAsDone()
-		return accountResult{cli: cli, mailboxes: mailboxes}, nil
+		return accountResult{cli: cli, mailboxes: mailboxes, quotas: quotas}, nil
 	}
 
-	// Run both sides in parallel; errgroup propagates the first error and
-	// keeps WithContext-derived ctx in sync so the loser cancels promptly.
+	// Run every account in parallel; errgroup propagates the first error
+	// and keeps WithContext-derived ctx in sync so the losers cancel promptly.
 	g, gCtx := errgroup.WithContext(ctx)
-	var srcRes, dstRes accountResult
+	srcRes := make([]accountResult, len(sources))
+	var dstRes accountResult
 
+	for i, src := range sources {
+		g.Go(func() error {
+			r, err := loadAccount(gCtx, "source", src.Label, src.Credentials, srcTrackers[i])
+			srcRes[i] = r
+			return err
+		})
+	}
 	g.Go(func() error {
-		r, err := loadAccount(gCtx, cfg.Src.Label, cfg.Src, srcTracker)
-		srcRes = r
-		return err
-	})
-	g.Go(func() error {
-		r, err := loadAccount(gCtx, cfg.Dst.Label, cfg.Dst, dstTracker)
+		r, err := loadAccount(gCtx, "destination", cfg.Dst.Label, cfg.Dst, dstTracker)
 		dstRes = r
 		return err
 	})
@@ -100,8 +179,10 @@ func ActionShow(ctx context.Context, c *cli.Command) error {
 	pw.StopAndClear()
 
 	defer func() {
-		if srcRes.cli != nil {
-			_ = srcRes.cli.Logout()
+		for _, r := range srcRes {
+			if r.cli != nil {
+				_ = r.cli.Logout()
+			}
 		}
 		if dstRes.cli != nil {
 			_ = dstRes.cli.Logout()
@@ -115,15 +196,147 @@ func ActionShow(ctx context.Context, c *cli.Command) error {
 		return groupErr
 	}
 
-	printAccountInfo("Source", cfg.Src.Server, cfg.Src.User, srcRes.mailboxes)
-	fmt.Println()
-	printAccountInfo("Destination", cfg.Dst.Server, cfg.Dst.User, dstRes.mailboxes)
+	if compare {
+		return showComparison(ctx, format, sources, srcRes, dstRes, withUnique, quiet)
+	}
+
+	switch format {
+	case formatJSON, formatCSV:
+		accounts := make([]shownAccount, 0, len(sources)+1)
+		for i, src := range sources {
+			accounts = append(accounts, newShownAccount("source", src.Label, src.Credentials, srcRes[i].mailboxes, srcRes[i].quotas))
+		}
+		accounts = append(accounts, newShownAccount("destination", cfg.Dst.Label, cfg.Dst, dstRes.mailboxes, dstRes.quotas))
+		write := writeAccountsJSON
+		if format == formatCSV {
+			write = writeAccountsCSV
+		}
+		if err := write(os.Stdout, accounts); err != nil {
+			return fmt.Errorf("write output: %w", err)
+		}
+		return nil
+	}
+
+	for i, src := range sources {
+		title := "Source"
+		if len(sources) > 1 {
+			title = fmt.Sprintf("Source [%s]", src.Label)
+		}
+		printAccountInfo(title, src.Server, src.User, srcRes[i].mailboxes, srcRes[i].quotas)
+		fmt.Println()
+	}
+	printAccountInfo("Destination", cfg.Dst.Server, cfg.Dst.User, dstRes.mailboxes, dstRes.quotas)
 
 	return nil
 }
 
+// showComparison aligns every source's folders with the destination through
+// the mapping rules of sync and writes the comparison in format. With
+// withUnique, the Message-Ids of each pair are scanned to count what the
+// destination lacks.
+func showComparison(ctx context.Context, format string, sources []config.Source, srcRes []accountResult, dstRes accountResult, withUnique, quiet bool) error {
+	dstDelimiter := dstRes.cli.GetDelimiter()
+	var rows []folderComparison
+	srcClients := make(map[string]*client.Client, len(sources))
+	for i, src := range sources {
+		key := ""
+		if len(sources) > 1 {
+			key = src.Label
+		}
+		srcClients[key] = srcRes[i].cli
+		mappings, err := compareMappings(ctx, srcRes[i].cli, src, srcRes[i].mailboxes, dstDelimiter)
+		if err != nil {
+			return fmt.Errorf("[%s] expand mappings: %w", src.Label, err)
+		}
+		rows = append(rows, alignFolders(key, mappings, srcRes[i].mailboxes, dstRes.mailboxes)...)
+	}
+	rows = appendUnmapped(rows, dstRes.mailboxes)
+
+	if withUnique {
+		scanned := 0
+		for _, r := range rows {
+			if r.SourceExists {
+				scanned++
+			}
+		}
+		pw := progress.NewWriter(1, quiet)
+		pw.Start()
+		tr := progress.NewTracker("Scanning Message-Ids", int64(scanned))
+		pw.AppendTracker(tr)
+		dstRes.cli.SetProgressWriter(pw)
+		for _, r := range srcRes {
+			r.cli.SetProgressWriter(pw)
+		}
+		err := countMissing(ctx, rows, srcClients, dstRes.cli, tr)
+		if err != nil {
+			tr.MarkAsErrored()
+		} else {
+			tr.MarkAsDone()
+		}
+		pw.StopAndClear()
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	var err error
+	switch format {
+	case formatJSON:
+		err = writeComparisonJSON(os.Stdout, rows)
+	case formatCSV:
+		err = writeComparisonCSV(os.Stdout, rows, withUnique)
+	default:
+		printComparison(os.Stdout, rows, withUnique)
+	}
+	if err != nil {
+		return fmt.Errorf("write output: %w", err)
+	}
+	return nil
+}
+
+// newShownAccount returns the JSON and CSV view of one account.
+func newShownAccount(role, label string, creds config.Credentials, mailboxes []*client.MailboxInfo, quotas []client.Quota) shownAccount {
+	a := shownAccount{Role: role, Label: label, Server: creds.Server, User: creds.User, Folders: make([]shownMailbox, 0, len(mailboxes))}
+	for _, q := range quotas {
+		for _, r := range q.Resources {
+			a.Quota = append(a.Quota, shownQuota{Root: q.Root, Resource: r.Name, Usage: r.Usage, Limit: r.Limit})
+		}
+	}
+	for _, mbox := range mailboxes {
+		a.Folders = append(a.Folders, shownMailbox{Name: mbox.Name, Messages: mbox.Messages, Size: mbox.Size})
+	}
+	return a
+}
+
+// writeAccountsJSON writes the accounts as one JSON document.
+func writeAccountsJSON(out io.Writer, accounts []shownAccount) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		Accounts []shownAccount `json:"accounts"`
+	}{accounts})
+}
+
+// writeAccountsCSV writes one CSV row per folder of every account, with a
+// header row; sizes are in bytes.
+func writeAccountsCSV(out io.Writer, accounts []shownAccount) error {
+	w := csv.NewWriter(out)
+	_ = w.Write([]string{"role", "label", "server", "user", "folder", "messages", "size"})
+	for _, a := range accounts {
+		for _, f := range a.Folders {
+			_ = w.Write([]string{a.Role, a.Label, a.Server, a.User, f.Name,
+				strconv.FormatUint(uint64(f.Messages), 10), strconv.FormatUint(f.Size, 10)})
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
 // printAccountInfo displays mailbox information in a formatted table.
-func printAccountInfo(title, server, user string, mailboxes []*client.MailboxInfo) {
+func printAccountInfo(title, server, user string, mailboxes []*client.MailboxInfo, quotas []client.Quota) {
 	headerTable := table.NewWriter()
 	headerTable.SetOutputMirror(os.Stdout)
 	headerTable.Style().Options.DrawBorder = false
@@ -136,6 +349,9 @@ func printAccountInfo(title, server, user string, mailboxes []*client.MailboxInf
 		{"Server", server},
 		{"User", user},
 	})
+	if q := formatQuota(quotas); q != "" {
+		headerTable.AppendRow(table.Row{"Quota", q})
+	}
 	headerTable.Render()
 	fmt.Println()
 
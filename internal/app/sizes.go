@@ -0,0 +1,127 @@
+package app
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/greeddj/imapsync-go/internal/utils"
+)
+
+// sizeBucketBounds are the exclusive upper bounds of the size histogram
+// buckets in the sync preview. A last, open bucket holds everything larger;
+// 25 MiB is where most providers start refusing attachments.
+var sizeBucketBounds = []uint64{10 << 10, 100 << 10, 1 << 20, 10 << 20, 25 << 20}
+
+// largestShown is how many of the largest planned messages the preview lists.
+const largestShown = 5
+
+// sizeBucket is one histogram bar: the messages below max (0 for the open
+// last bucket) and not in an earlier bucket.
+type sizeBucket struct {
+	max   uint64
+	count int
+	bytes uint64
+}
+
+// largeMessage is one of the largest planned messages.
+type largeMessage struct {
+	plan string
+	uid  uint32
+	size uint32
+}
+
+// sizeStats describes the size spread of a set of plans.
+type sizeStats struct {
+	buckets []sizeBucket
+	largest []largeMessage
+}
+
+// planSizeStats sorts every planned message with a known size into the
+// histogram and keeps the top largest of them, biggest first.
+func planSizeStats(plans []FolderSyncPlan, top int) sizeStats {
+	st := sizeStats{buckets: make([]sizeBucket, len(sizeBucketBounds)+1)}
+	for i, b := range sizeBucketBounds {
+		st.buckets[i].max = b
+	}
+	for _, p := range plans {
+		for _, uid := range p.SrcUIDs {
+			size, ok := p.Sizes[uid]
+			if !ok {
+				continue
+			}
+			i, _ := slices.BinarySearch(sizeBucketBounds, uint64(size)+1)
+			st.buckets[i].count++
+			st.buckets[i].bytes += uint64(size)
+
+			if len(st.largest) == top && size <= st.largest[top-1].size {
+				continue
+			}
+			m := largeMessage{plan: planTitle(p), uid: uid, size: size}
+			at, _ := slices.BinarySearchFunc(st.largest, m, func(a, b largeMessage) int { return cmp.Compare(b.size, a.size) })
+			st.largest = slices.Insert(st.largest, at, m)
+			if len(st.largest) > top {
+				st.largest = st.largest[:top]
+			}
+		}
+	}
+	return st
+}
+
+// print shows the histogram and the largest messages under the sync
+// preview. Empty buckets are left out.
+func (st sizeStats) print() {
+	if len(st.largest) == 0 {
+		return
+	}
+	fmt.Printf("\n📊 Message sizes:\n")
+	lower := uint64(0)
+	for _, b := range st.buckets {
+		if b.count > 0 {
+			label := fmt.Sprintf("%s – %s", utils.FormatSize(lower), utils.FormatSize(b.max))
+			if b.max == 0 {
+				label = fmt.Sprintf("≥ %s", utils.FormatSize(lower))
+			}
+			fmt.Printf("• %-22s %7d messages (%s)\n", label, b.count, utils.FormatSize(b.bytes))
+		}
+		lower = b.max
+	}
+	fmt.Printf("\n🐘 Largest messages:\n")
+	for _, m := range st.largest {
+		fmt.Printf("• %s  %s (UID %d)\n", utils.FormatSize(uint64(m.size)), m.plan, m.uid)
+	}
+}
+
+// transferETA is how long copying bytes takes at the configured rate limits.
+// Every byte is downloaded once and uploaded once, concurrently, so the
+// slower of the two limits decides. ok is false when neither is set.
+func transferETA(bytes uint64, downBPS, upBPS int) (eta time.Duration, bps int, ok bool) {
+	for _, limit := range []int{downBPS, upBPS} {
+		if limit > 0 && (bps == 0 || limit < bps) {
+			bps = limit
+		}
+	}
+	if bps == 0 {
+		return 0, 0, false
+	}
+	return time.Duration(float64(bytes) / float64(bps) * float64(time.Second)), bps, true
+}
+
+// formatETA renders d to the nearest minute, or second below one minute,
+// e.g. "2d 3h 10m", "45m" or "20s".
+func formatETA(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%ds", int(d.Round(time.Second)/time.Second))
+	}
+	mins := int(d.Round(time.Minute) / time.Minute)
+	days, hours, mins := mins/(24*60), mins/60%24, mins%60
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh %dm", days, hours, mins)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, mins)
+	default:
+		return fmt.Sprintf("%dm", mins)
+	}
+}
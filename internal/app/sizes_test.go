@@ -0,0 +1,66 @@
+package app
+
+import (
+	"testing"
+	"time"
+)
+
+// Test_planSizeStats_bucketsAndLargest asserts that sizes land in the right
+// buckets, bounds being exclusive, that only planned UIDs count, and that
+// the largest messages come back biggest first.
+func Test_planSizeStats_bucketsAndLargest(t *testing.T) {
+	t.Parallel()
+
+	plans := []FolderSyncPlan{
+		{SourceFolder: "INBOX", DestinationFolder: "INBOX", SrcUIDs: []uint32{1, 2, 3},
+			Sizes: map[uint32]uint32{1: 500, 2: 10 << 10, 3: 30 << 20, 9: 99 << 20}},
+		{SourceFolder: "Sent", DestinationFolder: "Sent", SrcUIDs: []uint32{4, 5},
+			Sizes: map[uint32]uint32{4: 2 << 20}},
+	}
+	st := planSizeStats(plans, 2)
+
+	wantCounts := []int{1, 1, 0, 1, 0, 1}
+	for i, b := range st.buckets {
+		if b.count != wantCounts[i] {
+			t.Errorf("bucket %d count = %d, want %d", i, b.count, wantCounts[i])
+		}
+	}
+	if st.buckets[5].max != 0 || st.buckets[5].bytes != 30<<20 {
+		t.Errorf("open bucket = %+v, want the 30 MiB message", st.buckets[5])
+	}
+	if len(st.largest) != 2 || st.largest[0].uid != 3 || st.largest[1].uid != 4 || st.largest[1].plan != "Sent → Sent" {
+		t.Errorf("largest = %+v, want UID 3 then Sent UID 4", st.largest)
+	}
+}
+
+// Test_transferETA asserts that the slower configured limit decides and
+// that no limit means no ETA.
+func Test_transferETA(t *testing.T) {
+	t.Parallel()
+
+	eta, bps, ok := transferETA(600_000, 300_000, 100_000)
+	if !ok || bps != 100_000 || eta != 6*time.Second {
+		t.Errorf("transferETA = (%v, %d, %v), want (6s, 100000, true)", eta, bps, ok)
+	}
+	if _, bps, ok := transferETA(600_000, 0, 200_000); !ok || bps != 200_000 {
+		t.Errorf("only up limit: bps = %d, ok = %v", bps, ok)
+	}
+	if _, _, ok := transferETA(600_000, 0, 0); ok {
+		t.Error("no limits: ok = true, want false")
+	}
+}
+
+func Test_formatETA(t *testing.T) {
+	t.Parallel()
+
+	for d, want := range map[time.Duration]string{
+		20 * time.Second:                            "20s",
+		45*time.Minute + 20*time.Second:             "45m",
+		3*time.Hour + 5*time.Minute:                 "3h 5m",
+		50*time.Hour + 10*time.Minute + time.Second: "2d 2h 10m",
+	} {
+		if got := formatETA(d); got != want {
+			t.Errorf("formatETA(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package app
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/greeddj/imapsync-go/internal/client"
+)
+
+// spoolMemoryLimit is the largest body a failed message keeps in memory until
+// the plan's retry pass. Larger bodies go to a temp file, so a folder full of
+// rejected attachments does not pin them all in RAM after their byte-budget
+// reservation has been released.
+const spoolMemoryLimit = 256 << 10
+
+// spooledAppend is a failed message kept aside for the retry pass. Exactly one
+// of item.Body and path holds the body; size remembers its length either way.
+type spooledAppend struct {
+	cause error
+	msgID string
+	item  client.AppendItem
+	path  string
+	size  int
+	uid   uint32
+}
+
+// spool keeps a copy of pa's body that survives the pipeline: small bodies
+// stay in memory, the rest are written to a temp file. cause is the failure
+// that put the message aside.
+func spool(pa pendingAppend, cause error) (*spooledAppend, error) {
+	s := &spooledAppend{cause: cause, msgID: pa.msgID, item: pa.item, size: len(pa.item.Body), uid: pa.uid}
+	if len(pa.item.Body) <= spoolMemoryLimit {
+		return s, nil
+	}
+	f, err := os.CreateTemp("", "imapsync-spool-*.eml")
+	if err != nil {
+		return nil, fmt.Errorf("spool message: %w", err)
+	}
+	if _, err := f.Write(pa.item.Body); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return nil, fmt.Errorf("spool message: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(f.Name())
+		return nil, fmt.Errorf("spool message: %w", err)
+	}
+	s.path = f.Name()
+	s.item.Body = nil
+	return s, nil
+}
+
+// load returns the spooled message ready for APPEND.
+func (s *spooledAppend) load() (client.AppendItem, error) {
+	if s.path == "" {
+		return s.item, nil
+	}
+	body, err := os.ReadFile(s.path)
+	if err != nil {
+		return client.AppendItem{}, fmt.Errorf("read spooled message: %w", err)
+	}
+	item := s.item
+	item.Body = body
+	return item, nil
+}
+
+// discard drops the spooled body, removing its temp file if any.
+func (s *spooledAppend) discard() {
+	if s.path != "" {
+		_ = os.Remove(s.path)
+		s.path = ""
+	}
+	s.item.Body = nil
+}
@@ -0,0 +1,82 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/greeddj/imapsync-go/internal/client"
+	"github.com/greeddj/imapsync-go/internal/progress"
+)
+
+func TestSpool_smallBodyStaysInMemory(t *testing.T) {
+	t.Parallel()
+
+	s, err := spool(pendingAppend{item: client.AppendItem{Body: []byte("hello")}}, nil)
+	if err != nil {
+		t.Fatalf("spool: %v", err)
+	}
+	defer s.discard()
+	if s.path != "" {
+		t.Errorf("small body spooled to %s", s.path)
+	}
+	item, err := s.load()
+	if err != nil || string(item.Body) != "hello" {
+		t.Errorf("load = (%q, %v)", item.Body, err)
+	}
+}
+
+func TestSpool_largeBodyRoundTripsThroughFile(t *testing.T) {
+	t.Parallel()
+
+	body := bytes.Repeat([]byte("x"), spoolMemoryLimit+1)
+	s, err := spool(pendingAppend{msgID: "<big@x>", item: client.AppendItem{Body: body}}, nil)
+	if err != nil {
+		t.Fatalf("spool: %v", err)
+	}
+	if s.path == "" || s.item.Body != nil {
+		t.Fatal("large body kept in memory")
+	}
+	item, err := s.load()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if !bytes.Equal(item.Body, body) {
+		t.Error("spooled body differs from original")
+	}
+
+	path := s.path
+	s.discard()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("temp file %s not removed: %v", path, err)
+	}
+}
+
+// TestSpool_dropSpooledRecordsAndRemovesFile asserts that the spool a plan
+// never retried goes to the failures file and leaves no temp file behind.
+func TestSpool_dropSpooledRecordsAndRemovesFile(t *testing.T) {
+	t.Parallel()
+
+	body := bytes.Repeat([]byte("x"), spoolMemoryLimit+1)
+	s, err := spool(pendingAppend{uid: 7, msgID: "<big@x>", item: client.AppendItem{Body: body}}, errors.New("connection reset"))
+	if err != nil {
+		t.Fatalf("spool: %v", err)
+	}
+	path := s.path
+	run := newPlanRun(FolderSyncPlan{SourceFolder: "INBOX", DestinationFolder: "INBOX"}, progress.NewTracker("test", 1), 0, 1, 1)
+	run.failed = []*spooledAppend{s}
+	failures := newFailureLog(filepath.Join(t.TempDir(), "failures.jsonl"))
+
+	run.dropSpooled(failures)
+	if n, err := failures.close(); n != 1 || err != nil {
+		t.Errorf("failures recorded = %d, %v; want 1", n, err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("temp file %s not removed: %v", path, err)
+	}
+	if len(run.failed) != 0 {
+		t.Errorf("spool not drained: %d left", len(run.failed))
+	}
+}
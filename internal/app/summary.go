@@ -0,0 +1,193 @@
+package app
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/greeddj/imapsync-go/internal/client"
+	"github.com/greeddj/imapsync-go/internal/utils"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+)
+
+// summaryReasons is how many of the most frequent reasons the summary lists
+// for each error class.
+const summaryReasons = 3
+
+// summaryReasonWidth is the widest a reason may be in the summary; longer
+// server responses are cut.
+const summaryReasonWidth = 80
+
+// failureCause is why a message was lost: the class of the error and the
+// server's response or the error text.
+type failureCause struct {
+	reason string
+	class  client.ErrClass
+}
+
+// planSummary is one row of the sync summary. Skipped messages were planned
+// but neither copied nor failed: the sync was canceled first, or they left
+// the source while it ran.
+type planSummary struct {
+	started  time.Time
+	finished time.Time
+	title    string
+	bytes    int64
+	planned  int
+	copied   int
+	failed   int
+	skipped  int
+}
+
+// summary returns the plan's row of the sync summary.
+func (r *planRun) summary() planSummary {
+	synced, errors := r.result()
+	bytes, _ := r.tr.Bytes()
+	s := planSummary{
+		title:   planTitle(r.plan),
+		planned: r.plan.NewMessages,
+		copied:  synced,
+		failed:  errors,
+		skipped: max(0, r.plan.NewMessages-synced-errors),
+		bytes:   bytes,
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.started.IsZero() && !r.finished.IsZero() {
+		s.started, s.finished = r.started, r.finished
+	}
+	return s
+}
+
+// printSyncSummary writes a table of every plan's outcome to out, and when
+// messages were lost, the most frequent reasons for each error class. The
+// totals time the whole copy, from the first plan's start to the last
+// plan's end.
+func printSyncSummary(out io.Writer, runs []*planRun) {
+	t := table.NewWriter()
+	t.SetOutputMirror(out)
+	t.Style().Options.DrawBorder = false
+	t.Style().Options.SeparateColumns = false
+	t.SetTitle(text.Colors{text.Bold, text.FgHiCyan}.Sprint("Sync summary"))
+	// Footer totals carry units; go-pretty would upper-case them.
+	t.Style().Format.Footer = text.FormatDefault
+	t.AppendHeader(table.Row{"Folder", "Planned", "Copied", "Failed", "Skipped", "Bytes", "Duration", "Avg rate"})
+
+	var total planSummary
+	causes := make(map[failureCause]int)
+	for _, r := range runs {
+		s := r.summary()
+		d := s.finished.Sub(s.started)
+		t.AppendRow(table.Row{s.title, s.planned, s.copied, s.failed, s.skipped,
+			utils.FormatSize(uint64(s.bytes)), formatSummaryDuration(d), formatRate(s.bytes, d)})
+		total.planned += s.planned
+		total.copied += s.copied
+		total.failed += s.failed
+		total.skipped += s.skipped
+		total.bytes += s.bytes
+		if !s.started.IsZero() && (total.started.IsZero() || s.started.Before(total.started)) {
+			total.started = s.started
+		}
+		if s.finished.After(total.finished) {
+			total.finished = s.finished
+		}
+
+		r.mu.Lock()
+		for c, n := range r.causes {
+			causes[c] += n
+		}
+		r.mu.Unlock()
+	}
+	elapsed := total.finished.Sub(total.started)
+	t.AppendFooter(table.Row{
+		text.Bold.Sprintf("total folders %d", len(runs)),
+		text.Bold.Sprintf("%d", total.planned),
+		text.Bold.Sprintf("%d", total.copied),
+		text.Bold.Sprintf("%d", total.failed),
+		text.Bold.Sprintf("%d", total.skipped),
+		text.Bold.Sprint(utils.FormatSize(uint64(total.bytes))),
+		text.Bold.Sprint(formatSummaryDuration(elapsed)),
+		text.Bold.Sprint(formatRate(total.bytes, elapsed)),
+	})
+	configs := []table.ColumnConfig{{Number: 1, Align: text.AlignLeft, AlignHeader: text.AlignCenter}}
+	for n := 2; n <= 8; n++ {
+		configs = append(configs, table.ColumnConfig{Number: n, Align: text.AlignRight, AlignHeader: text.AlignCenter, AlignFooter: text.AlignRight})
+	}
+	t.SetColumnConfigs(configs)
+	t.Render()
+
+	if len(causes) > 0 {
+		_, _ = fmt.Fprintln(out)
+		printFailureCauses(out, causes)
+	}
+}
+
+// printFailureCauses writes the error classes, most frequent first, each
+// with its most frequent reasons.
+func printFailureCauses(out io.Writer, causes map[failureCause]int) {
+	byClass := make(map[client.ErrClass][]failureCause)
+	classTotals := make(map[client.ErrClass]int)
+	for c, n := range causes {
+		byClass[c.class] = append(byClass[c.class], c)
+		classTotals[c.class] += n
+	}
+	classes := make([]client.ErrClass, 0, len(byClass))
+	for class := range byClass {
+		classes = append(classes, class)
+	}
+	slices.SortFunc(classes, func(a, b client.ErrClass) int {
+		return cmp.Or(cmp.Compare(classTotals[b], classTotals[a]), cmp.Compare(a, b))
+	})
+
+	t := table.NewWriter()
+	t.SetOutputMirror(out)
+	t.Style().Options.DrawBorder = false
+	t.Style().Options.SeparateColumns = false
+	t.SetTitle(text.Colors{text.Bold, text.FgHiCyan}.Sprint("Top error reasons"))
+	t.AppendHeader(table.Row{"Class", "Count", "Reason"})
+	for _, class := range classes {
+		list := byClass[class]
+		slices.SortFunc(list, func(a, b failureCause) int {
+			return cmp.Or(cmp.Compare(causes[b], causes[a]), cmp.Compare(a.reason, b.reason))
+		})
+		label := fmt.Sprintf("%s (%d)", class, classTotals[class])
+		for i, c := range list[:min(len(list), summaryReasons)] {
+			if i > 0 {
+				label = ""
+			}
+			t.AppendRow(table.Row{label, causes[c], text.Snip(c.reason, summaryReasonWidth, "…")})
+		}
+		if rest := len(list) - summaryReasons; rest > 0 {
+			t.AppendRow(table.Row{"", "", text.FgHiBlack.Sprintf("and %d more reasons", rest)})
+		}
+	}
+	t.SetColumnConfigs([]table.ColumnConfig{
+		{Number: 1, Align: text.AlignLeft, AlignHeader: text.AlignCenter},
+		{Number: 2, Align: text.AlignRight, AlignHeader: text.AlignCenter},
+		{Number: 3, Align: text.AlignLeft, AlignHeader: text.AlignCenter},
+	})
+	t.Render()
+}
+
+// formatSummaryDuration renders d to the second, or "-" for a plan that
+// never ran.
+func formatSummaryDuration(d time.Duration) string {
+	switch {
+	case d <= 0:
+		return "-"
+	case d < time.Second:
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(time.Second).String()
+}
+
+// formatRate renders bytes over d as a rate, or "-" when there is none.
+func formatRate(bytes int64, d time.Duration) string {
+	if bytes <= 0 || d <= 0 {
+		return "-"
+	}
+	return utils.FormatSize(uint64(float64(bytes)/d.Seconds())) + "/s"
+}
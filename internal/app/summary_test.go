@@ -0,0 +1,92 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/greeddj/imapsync-go/internal/progress"
+	"github.com/jedib0t/go-pretty/v6/text"
+)
+
+// Test_printSyncSummary_rowsAndReasons asserts that the summary has a row per
+// plan with its counts, bytes, duration and rate, totals timed from the first
+// start to the last end, and the lost messages' reasons grouped by class.
+func Test_printSyncSummary_rowsAndReasons(t *testing.T) {
+	t.Parallel()
+
+	at := time.Unix(0, 0)
+	inbox := newPlanRun(FolderSyncPlan{SourceFolder: "INBOX", DestinationFolder: "INBOX", NewMessages: 5}, progress.NewTracker("INBOX", 5), 0, 3, 1)
+	inbox.synced.Store(3)
+	inbox.tr.AddBytes(4 << 20)
+	inbox.started, inbox.finished = at, at.Add(2*time.Second)
+	w := &syncWorker{}
+	inbox.lost(w, 4, "<a@x>", 10, errors.New("NO [TRYCREATE] No such mailbox"))
+	inbox.lost(w, 5, "<b@x>", 10, errors.New("NO [TRYCREATE] No such mailbox"))
+	inbox.errors.Store(2)
+
+	sent := newPlanRun(FolderSyncPlan{SourceFolder: "Sent", DestinationFolder: "Sent", NewMessages: 1}, progress.NewTracker("Sent", 1), 1, 3, 1)
+	sent.lost(w, 0, "", 0, io.EOF)
+	sent.errors.Store(1)
+	sent.started, sent.finished = at.Add(time.Second), at.Add(4*time.Second)
+
+	never := newPlanRun(FolderSyncPlan{SourceFolder: "Trash", DestinationFolder: "Trash", NewMessages: 7}, progress.NewTracker("Trash", 7), 2, 3, 1)
+
+	var buf bytes.Buffer
+	printSyncSummary(&buf, []*planRun{inbox, sent, never})
+	out := text.StripEscape(buf.String())
+
+	for _, want := range []string{
+		"Sync summary",
+		"INBOX → INBOX          5       3       2        0  4.00 MB        2s  2.00 MB/s",
+		"Trash → Trash          7       0       0        7      0 B         -          -",
+		"total folders 3       13       3       3        7  4.00 MB        4s  1.00 MB/s",
+		"Top error reasons",
+		"permanent (2)      2  NO [TRYCREATE] No such mailbox",
+		"transient (1)      1  EOF",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("summary lacks %q:\n%s", want, out)
+		}
+	}
+}
+
+// Test_printSyncSummary_noReasonsWhenClean asserts that a sync that lost
+// nothing prints no reasons table.
+func Test_printSyncSummary_noReasonsWhenClean(t *testing.T) {
+	t.Parallel()
+
+	r := newPlanRun(FolderSyncPlan{SourceFolder: "INBOX", DestinationFolder: "INBOX", NewMessages: 1}, progress.NewTracker("INBOX", 1), 0, 1, 1)
+	r.synced.Store(1)
+	var buf bytes.Buffer
+	printSyncSummary(&buf, []*planRun{r})
+	if strings.Contains(buf.String(), "Top error reasons") {
+		t.Errorf("clean sync lists error reasons:\n%s", buf.String())
+	}
+}
+
+// Test_printFailureCauses_topReasons asserts that each class lists only its
+// most frequent reasons, most frequent first, and says how many it left out.
+func Test_printFailureCauses_topReasons(t *testing.T) {
+	t.Parallel()
+
+	w := &syncWorker{}
+	r := newPlanRun(FolderSyncPlan{}, progress.NewTracker("x", 1), 0, 1, 1)
+	for i, reason := range []string{"NO one", "NO two", "NO two", "NO three", "NO three", "NO three", "NO four"} {
+		r.lost(w, uint32(i+1), "", 0, errors.New(reason))
+	}
+	var buf bytes.Buffer
+	printFailureCauses(&buf, r.causes)
+	out := text.StripEscape(buf.String())
+
+	three, two := strings.Index(out, "NO three"), strings.Index(out, "NO two")
+	if three < 0 || two < 0 || three > two {
+		t.Errorf("reasons not most frequent first:\n%s", out)
+	}
+	if strings.Contains(out, "NO one") == strings.Contains(out, "NO four") || !strings.Contains(out, "and 1 more reasons") {
+		t.Errorf("want exactly three reasons and a count of the rest:\n%s", out)
+	}
+}
@@ -4,15 +4,19 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/greeddj/imapsync-go/internal/client"
 	"github.com/greeddj/imapsync-go/internal/config"
+	"github.com/greeddj/imapsync-go/internal/metrics"
 	"github.com/greeddj/imapsync-go/internal/progress"
+	"github.com/greeddj/imapsync-go/internal/provider"
 	"github.com/greeddj/imapsync-go/internal/ratelimit"
 	"github.com/greeddj/imapsync-go/internal/utils"
 	"github.com/urfave/cli/v3"
@@ -40,26 +44,38 @@ func traceTracker(site, message string) {
 // missing on dst; bodies are deliberately not fetched at planning time so a
 // confirm prompt can show counts without materializing potentially many GB
 // of mail in memory.
+//
+// Source is the label of the source account the folder belongs to. It is
+// empty when the config has a single source.
+//
+// Reverse plans come from a two-way sync and copy the other way: the
+// SourceFolder is on the destination account and the DestinationFolder on
+// the source account.
+//
+// Sizes maps each UID in SrcUIDs to its RFC822.SIZE, where known. It may hold
+// UIDs a later step dropped from SrcUIDs, so iterate SrcUIDs, not Sizes.
 type FolderSyncPlan struct {
+	Sizes                   map[uint32]uint32
+	Source                  string
 	SourceFolder            string
 	DestinationFolder       string
 	SrcUIDs                 []uint32
 	NewMessages             int
 	NewSize                 uint64
 	DestinationFolderExists bool
+	Reverse                 bool
 }
 
 // SyncSummary aggregates the per-folder plans along with total message counts
-// and an estimated total byte volume.
+// and the total byte volume, summed from the RFC822.SIZE of every message.
 //
-// TotalNewSize is approximate: it scales the source folder's full RFC822.SIZE
-// total by the new-to-total message ratio (NewMessages / SourceMessages). The
-// exact size would require an extra UID FETCH per new UID, which is not worth
-// the round-trips for what is only a preview number.
+// Excluded counts the source messages left out by date and size limits,
+// summed over all folders.
 type SyncSummary struct {
 	Plans        []FolderSyncPlan
 	TotalNew     int
 	TotalNewSize uint64
+	Excluded     client.FilterStats
 }
 
 // ActionSync copies messages between IMAP servers according to the provided configuration.
@@ -73,6 +89,8 @@ func ActionSync(ctx context.Context, c *cli.Command) error {
 	quiet := c.Bool("quiet")
 	verbose := c.Bool("verbose")
 	autoConfirm := c.Bool("confirm")
+	retryFile := c.String("retry-failures")
+	ignoreQuota := c.Bool("ignore-quota")
 	if !quiet && verbose {
 		fmt.Println("Fetching config...")
 	}
@@ -83,64 +101,96 @@ func ActionSync(ctx context.Context, c *cli.Command) error {
 	if err := ctx.Err(); err != nil {
 		return err
 	}
+	sources := cfg.SourceList()
+
+	providerMode := strings.ToLower(c.String("provider-limits"))
+	limitsFrom, err := resolveProviderLimits(cfg, providerMode)
+	if err != nil {
+		return err
+	}
 
 	if !quiet && verbose {
 		fmt.Printf("Starting sync with %d workers\n", cfg.Workers)
 	}
+	slog.Info("sync started", "sources", len(sources), "destination", cfg.Dst.Server, "workers", cfg.Workers)
 
 	// Rate-limit budgets are shared across every Client that talks to the
-	// same side: src.ReadLimiter governs all download traffic, dst.WriteLimiter
-	// all upload traffic. Either may be nil ("unlimited").
+	// same side: src.ReadLimiter governs all download traffic (from every
+	// source account), dst.WriteLimiter all upload traffic. Either may be
+	// nil ("unlimited").
 	srcReadLim := ratelimit.NewLimiter(cfg.RateLimit.DownBPS)
 	dstWriteLim := ratelimit.NewLimiter(cfg.RateLimit.UpBPS)
-	srcOpts := client.Options{
-		UseTLS:      true,
-		Auth:        cfg.Src.Auth,
-		Verbose:     verbose,
-		ReadLimiter: srcReadLim,
+	trace, closeTrace, err := openTrace(c)
+	if err != nil {
+		return err
+	}
+	defer closeTrace()
+	stopMetrics, err := startMetrics(c, quiet)
+	if err != nil {
+		return err
+	}
+	defer stopMetrics()
+	srcOpts := make([]client.Options, len(sources))
+	for i, src := range sources {
+		srcOpts[i] = client.Options{
+			UseTLS:      true,
+			Auth:        src.Auth,
+			Label:       src.Label,
+			Verbose:     verbose,
+			ReadLimiter: srcReadLim,
+			Trace:       trace,
+			Logger:      accountLogger("source", src.Label),
+		}
 	}
 	dstOpts := client.Options{
 		UseTLS:       true,
 		Auth:         cfg.Dst.Auth,
+		Label:        cfg.Dst.Label,
 		Verbose:      verbose,
 		WriteLimiter: dstWriteLim,
+		Trace:        trace,
+		Logger:       accountLogger("destination", cfg.Dst.Label),
 	}
 
-	if !quiet {
+	if !quiet && providerMode != providerLimitsOff {
 		if w := buildProviderWarning(cfg, srcReadLim, dstWriteLim); w != "" {
 			fmt.Print(w)
 		}
 	}
 
-	var mappings []config.DirectoryMapping
-
+	// In retry mode the plan comes from the failures file, so folders are
+	// neither listed nor scanned. Read it before connecting to fail fast.
+	var retryRecords []failureRecord
 	switch {
-	case srcFolder != "" && dstFolder != "":
-		mappings = []config.DirectoryMapping{
-			{Source: srcFolder, Destination: dstFolder},
+	case retryFile != "":
+		if srcFolder != "" || dstFolder != "" {
+			return errors.New("--retry-failures cannot be combined with --src-folder or --dest-folder")
+		}
+		if err := checkRetryPaths(retryFile, c.String("failures-file")); err != nil {
+			return err
+		}
+		retryRecords, err = readFailures(retryFile)
+		if err != nil {
+			return err
 		}
-	case srcFolder != "" || dstFolder != "":
+	case (srcFolder != "") != (dstFolder != ""):
 		return errors.New("both --src-folder and --dest-folder must be specified")
-	default:
-		if len(cfg.Map) > 0 {
-			mappings = cfg.Map
-		} else {
-			// dynamically build the mappings from the source folders
-			c, err := client.New(ctx, cfg.Src.Server, cfg.Src.User, cfg.Src.Pass, srcOpts)
-			if err != nil {
-				return fmt.Errorf("source connection failed: %w", err)
-			}
-			mailboxes, err := c.ListMailboxes(ctx)
-			if err != nil {
-				return fmt.Errorf("source connection list mailbox failed: %w", err)
-			}
-			for _, mb := range mailboxes {
-				mappings = append(mappings, config.DirectoryMapping{
-					Source:      mb.Name,
-					Destination: mb.Name,
-				})
-			}
+	case srcFolder != "" && len(sources) > 1:
+		return errors.New("--src-folder and --dest-folder need a config with a single source account")
+	}
+
+	var twoWay twoWayOptions
+	if c.Bool("two-way") {
+		switch {
+		case retryFile != "":
+			return errors.New("--two-way cannot be combined with --retry-failures")
+		case len(sources) > 1:
+			return errors.New("--two-way needs a config with a single source account")
 		}
+		if twoWay, err = twoWayOptionsFrom(c); err != nil {
+			return err
+		}
+		twoWay.limits = formatLimits(cfg.RateLimit, limitsFrom)
 	}
 
 	if !quiet && verbose {
@@ -152,32 +202,38 @@ func ActionSync(ctx context.Context, c *cli.Command) error {
 	}
 
 	// TLS handshake to a remote IMAP server is the dominant cost of startup;
-	// running src+dst in parallel halves time-to-first-fetch when both sides
-	// are slow (e.g. residential mail providers + commercial IMAP).
-	var srcClient, dstClient *client.Client
+	// connecting every source and the destination in parallel keeps
+	// time-to-first-fetch at that of the slowest server.
+	srcClients := make([]*client.Client, len(sources))
+	var dstClient *client.Client
 	g, gCtx := errgroup.WithContext(ctx)
-	g.Go(func() error {
-		c, err := client.New(gCtx, cfg.Src.Server, cfg.Src.User, cfg.Src.Pass, srcOpts)
-		if err != nil {
-			return fmt.Errorf("source connection failed: %w", err)
-		}
-		c.SetPrefix(cfg.Src.Label)
-		srcClient = c
-		return nil
-	})
+	for i, src := range sources {
+		g.Go(func() error {
+			c, err := client.New(gCtx, src.Server, src.User, src.Pass, srcOpts[i])
+			if err != nil {
+				if len(sources) > 1 {
+					return fmt.Errorf("source [%s] connection failed: %w", src.Label, err)
+				}
+				return fmt.Errorf("source connection failed: %w", err)
+			}
+			srcClients[i] = c
+			return nil
+		})
+	}
 	g.Go(func() error {
 		c, err := client.New(gCtx, cfg.Dst.Server, cfg.Dst.User, cfg.Dst.Pass, dstOpts)
 		if err != nil {
 			return fmt.Errorf("destination connection failed: %w", err)
 		}
-		c.SetPrefix(cfg.Dst.Label)
 		dstClient = c
 		return nil
 	})
 	groupErr := g.Wait()
 	defer func() {
-		if srcClient != nil {
-			_ = srcClient.Logout()
+		for _, c := range srcClients {
+			if c != nil {
+				_ = c.Logout()
+			}
 		}
 		if dstClient != nil {
 			_ = dstClient.Logout()
@@ -191,39 +247,89 @@ func ActionSync(ctx context.Context, c *cli.Command) error {
 	}
 
 	// Check delimiters
-	srcDelimiter := srcClient.GetDelimiter()
 	dstDelimiter := dstClient.GetDelimiter()
+	planSources := make([]planSource, len(sources))
+	for i, src := range sources {
+		planSources[i] = planSource{client: srcClients[i], label: src.Label, delimiter: srcClients[i].GetDelimiter()}
+		if len(sources) > 1 {
+			planSources[i].key = src.Label
+		}
+	}
 
 	if !quiet && verbose {
 		fmt.Printf("📁 Server delimiters:\n")
-		fmt.Printf("  Source [%s]: %q\n", cfg.Src.Label, srcDelimiter)
+		for _, ps := range planSources {
+			fmt.Printf("  Source [%s]: %q\n", ps.label, ps.delimiter)
+		}
 		fmt.Printf("  Destination [%s]: %q\n\n", cfg.Dst.Label, dstDelimiter)
 	}
 
+	for i, src := range sources {
+		ps := &planSources[i]
+		switch {
+		case retryFile != "":
+			// mappings stay empty; see planFromFailures below.
+		case srcFolder != "":
+			ps.mappings = []config.DirectoryMapping{
+				{Source: srcFolder, Destination: dstFolder},
+			}
+		case len(src.Map) > 0:
+			ps.mappings = slices.Clone(src.Map)
+		default:
+			// dynamically build the mappings from the source folders
+			mailboxes, err := ps.client.ListMailboxes(ctx)
+			if err != nil {
+				return fmt.Errorf("source [%s] list mailbox failed: %w", ps.label, err)
+			}
+			for _, mb := range mailboxes {
+				ps.mappings = append(ps.mappings, config.DirectoryMapping{
+					Source:      mb.Name,
+					Destination: mb.Name,
+				})
+			}
+		}
+
+		// Top-level limits (config plus CLI flags) fill in whatever a
+		// mapping leaves unset; subfolder expansion copies them, and the
+		// prefixed destination, to each child.
+		for j := range ps.mappings {
+			ps.mappings[j].Limits = cfg.Limits.Merge(ps.mappings[j].Limits)
+			if src.Prefix != "" {
+				ps.mappings[j].Destination = prefixFolder(src.Prefix, ps.mappings[j].Destination, dstDelimiter)
+			}
+		}
+	}
+
 	// Validate folder paths compatibility with server delimiters
 	var validationErrors []string
 	needsFix := false
-	for i, mapping := range mappings {
-		if err := ctx.Err(); err != nil {
-			return err
-		}
-		// Check source folder compatibility
-		if srcDelimiter != "" {
-			if oldDelim, ok := folderDelimiter(mapping.Source, srcDelimiter); !ok {
-				validationErrors = append(validationErrors,
-					fmt.Sprintf("Mapping %d: Source folder %q uses delimiter %q, server expects %q",
-						i+1, mapping.Source, oldDelim, srcDelimiter))
-				needsFix = true
+	for _, ps := range planSources {
+		for i, mapping := range ps.mappings {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			name := fmt.Sprintf("Mapping %d", i+1)
+			if ps.key != "" {
+				name = fmt.Sprintf("[%s] Mapping %d", ps.label, i+1)
+			}
+			// Check source folder compatibility
+			if ps.delimiter != "" {
+				if oldDelim, ok := folderDelimiter(mapping.Source, ps.delimiter); !ok {
+					validationErrors = append(validationErrors,
+						fmt.Sprintf("%s: Source folder %q uses delimiter %q, server expects %q",
+							name, mapping.Source, oldDelim, ps.delimiter))
+					needsFix = true
+				}
 			}
-		}
 
-		// Check destination folder compatibility
-		if dstDelimiter != "" {
-			if oldDelim, ok := folderDelimiter(mapping.Destination, dstDelimiter); !ok {
-				validationErrors = append(validationErrors,
-					fmt.Sprintf("Mapping %d: Destination folder %q uses delimiter %q, server expects %q",
-						i+1, mapping.Destination, oldDelim, dstDelimiter))
-				needsFix = true
+			// Check destination folder compatibility
+			if dstDelimiter != "" {
+				if oldDelim, ok := folderDelimiter(mapping.Destination, dstDelimiter); !ok {
+					validationErrors = append(validationErrors,
+						fmt.Sprintf("%s: Destination folder %q uses delimiter %q, server expects %q",
+							name, mapping.Destination, oldDelim, dstDelimiter))
+					needsFix = true
+				}
 			}
 		}
 	}
@@ -251,19 +357,22 @@ func ActionSync(ctx context.Context, c *cli.Command) error {
 		}
 
 		if shouldFix {
-			for i := range mappings {
-				if srcDelimiter != "" {
-					if oldDelim, _ := folderDelimiter(mappings[i].Source, srcDelimiter); oldDelim != "none" && oldDelim != srcDelimiter {
-						oldPath := mappings[i].Source
-						mappings[i].Source = strings.ReplaceAll(mappings[i].Source, oldDelim, srcDelimiter)
-						fmt.Printf("  ✓ Fixed source: %q → %q\n", oldPath, mappings[i].Source)
+			for _, ps := range planSources {
+				mappings := ps.mappings
+				for i := range mappings {
+					if ps.delimiter != "" {
+						if oldDelim, _ := folderDelimiter(mappings[i].Source, ps.delimiter); oldDelim != "none" && oldDelim != ps.delimiter {
+							oldPath := mappings[i].Source
+							mappings[i].Source = strings.ReplaceAll(mappings[i].Source, oldDelim, ps.delimiter)
+							fmt.Printf("  ✓ Fixed source: %q → %q\n", oldPath, mappings[i].Source)
+						}
 					}
-				}
-				if dstDelimiter != "" {
-					if oldDelim, _ := folderDelimiter(mappings[i].Destination, dstDelimiter); oldDelim != "none" && oldDelim != dstDelimiter {
-						oldPath := mappings[i].Destination
-						mappings[i].Destination = strings.ReplaceAll(mappings[i].Destination, oldDelim, dstDelimiter)
-						fmt.Printf("  ✓ Fixed destination: %q → %q\n", oldPath, mappings[i].Destination)
+					if dstDelimiter != "" {
+						if oldDelim, _ := folderDelimiter(mappings[i].Destination, dstDelimiter); oldDelim != "none" && oldDelim != dstDelimiter {
+							oldPath := mappings[i].Destination
+							mappings[i].Destination = strings.ReplaceAll(mappings[i].Destination, oldDelim, dstDelimiter)
+							fmt.Printf("  ✓ Fixed destination: %q → %q\n", oldPath, mappings[i].Destination)
+						}
 					}
 				}
 			}
@@ -274,54 +383,43 @@ func ActionSync(ctx context.Context, c *cli.Command) error {
 		fmt.Println()
 	}
 
-	// Expand mappings to include subfolders
-	if !quiet && verbose {
-		fmt.Println("Checking for subfolders...")
-	}
-	expandedMappings, err := expandMappingsWithSubfolders(ctx, srcClient, mappings, srcDelimiter, dstDelimiter, verbose, quiet)
-	if err != nil {
-		return fmt.Errorf("failed to expand mappings: %w", err)
-	}
-	if len(expandedMappings) > len(mappings) && !quiet && verbose {
-		fmt.Printf("📂 Found %d subfolders, total folders to sync: %d\n", len(expandedMappings)-len(mappings), len(expandedMappings))
+	if c.Bool("two-way") {
+		return runTwoWay(ctx, c, cfg, planSources[0], dstClient, dstDelimiter, srcOpts[0], dstOpts, twoWay, verbose, quiet, autoConfirm)
 	}
-	mappings = expandedMappings
-
-	// Setup progress writer for scanning phase
-	pw := progress.NewWriter(2, quiet)
-	pw.Start()
-
-	// Create trackers for source and destination scanning
-	srcTracker := progress.NewTracker(fmt.Sprintf("[%s] Scanning folders", cfg.Src.Label), 100)
-	dstTracker := progress.NewTracker(fmt.Sprintf("[%s] Scanning folders", cfg.Dst.Label), 100)
 
-	traceTracker("scan-src", srcTracker.Message)
-	pw.AppendTracker(srcTracker)
-	traceTracker("scan-dst", dstTracker.Message)
-	pw.AppendTracker(dstTracker)
-
-	srcClient.SetProgressWriter(pw)
-	srcClient.SetProgressTracker(srcTracker)
-	dstClient.SetProgressWriter(pw)
-	dstClient.SetProgressTracker(dstTracker)
-
-	summary, err := buildSyncPlan(ctx, srcClient, dstClient, mappings, srcTracker, dstTracker, pw, cfg.Src.Label, cfg.Dst.Label, verbose)
-	if err != nil {
-		pw.Stop()
-		return err
+	var summary *SyncSummary
+	if retryFile != "" {
+		var skipped int
+		summary, skipped, err = planFromFailures(ctx, planSources[0].client, dstClient, retryRecords)
+		if err != nil {
+			return err
+		}
+		if skipped > 0 && !quiet {
+			fmt.Printf("ℹ️  %d folder-level failures in %s have no UID and are not retried\n", skipped, retryFile)
+		}
+		for _, plan := range summary.Plans {
+			if !slices.ContainsFunc(planSources, func(ps planSource) bool { return ps.key == plan.Source }) {
+				return fmt.Errorf("failures file %s names source %q, which is not in the config", retryFile, plan.Source)
+			}
+		}
+	} else {
+		summary, err = scanMappings(ctx, planSources, dstClient, cfg.Dst.Label, dstDelimiter, verbose, quiet)
+		if err != nil {
+			return err
+		}
 	}
-
-	// Mark scanning as complete
-	srcTracker.MarkAsDone()
-	dstTracker.MarkAsDone()
-
-	// Stop and clear progress
-	pw.StopAndClear()
 	if err := ctx.Err(); err != nil {
 		return err
 	}
 
+	if !quiet {
+		printFilterExclusions(summary.Excluded)
+	}
+
+	slog.Info("sync planned", "folders", len(summary.Plans), "messages", summary.TotalNew, "bytes", summary.TotalNewSize,
+		"down_bps", cfg.RateLimit.DownBPS, "up_bps", cfg.RateLimit.UpBPS, "max_connections", cfg.RateLimit.MaxConnections)
 	if summary.TotalNew > 0 {
+		quota := destinationQuota(ctx, dstClient, summary.Plans, verbose)
 		if !quiet {
 			fmt.Printf("📤 Messages to be copied to destination:\n")
 			foldersToCreate := make([]string, 0, len(summary.Plans))
@@ -329,12 +427,13 @@ func ActionSync(ctx context.Context, c *cli.Command) error {
 				// Preview only the folders we will actually create — the
 				// real creation loop below filters the same way, so showing
 				// already-existing folders here just misleads the user.
-				if !plan.DestinationFolderExists {
+				// Several sources may share one new folder; list it once.
+				if !plan.DestinationFolderExists && !slices.Contains(foldersToCreate, plan.DestinationFolder) {
 					foldersToCreate = append(foldersToCreate, plan.DestinationFolder)
 				}
 				if plan.NewMessages > 0 {
-					fmt.Printf("• %s → %s will copy %d messages (≈ %s)\n",
-						plan.SourceFolder, plan.DestinationFolder, plan.NewMessages, utils.FormatSize(plan.NewSize))
+					fmt.Printf("• %s will copy %d messages (%s)\n",
+						planTitle(plan), plan.NewMessages, utils.FormatSize(plan.NewSize))
 					if verbose {
 						// Dumping every UID before the confirm-prompt
 						// drowns the user in screens of integers — a
@@ -359,25 +458,38 @@ func ActionSync(ctx context.Context, c *cli.Command) error {
 					fmt.Printf("• %s\n", folder)
 				}
 			}
-			fmt.Printf("\n📨 Total new messages to sync: %d (≈ %s)\n", summary.TotalNew, utils.FormatSize(summary.TotalNewSize))
-
-			if !autoConfirm {
-				if err := ctx.Err(); err != nil {
-					return err
-				}
-				confirmed, err := utils.AskConfirm(ctx, "✍️ Proceed with synchronization?")
-				if err != nil {
-					return err
-				}
-				if !confirmed {
-					fmt.Println("❌ Sync canceled by user")
-					return nil
-				}
+			fmt.Printf("\n📨 Total new messages to sync: %d (%s)\n", summary.TotalNew, utils.FormatSize(summary.TotalNewSize))
+			planSizeStats(summary.Plans, largestShown).print()
+			fmt.Printf("\n🚦 Limits: %s\n", formatLimits(cfg.RateLimit, limitsFrom))
+			if eta, bps, ok := transferETA(summary.TotalNewSize, cfg.RateLimit.DownBPS, cfg.RateLimit.UpBPS); ok {
+				fmt.Printf("\n⏱️ At the configured limit of %s/s the copy takes at least %s\n", utils.FormatSize(uint64(bps)), formatETA(eta))
+			}
+			quota.print()
+		}
+		if err := quota.refusal(ignoreQuota); err != nil {
+			return err
+		}
+		if !quiet && !autoConfirm {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			confirmed, err := utils.AskConfirm(ctx, "✍️ Proceed with synchronization?")
+			if err != nil {
+				return err
+			}
+			if !confirmed {
+				fmt.Println("❌ Sync canceled by user")
+				slog.Info("sync canceled by user")
+				return nil
 			}
 		}
 	} else {
 		if !quiet {
-			fmt.Println("✅ All folders already synced!")
+			if retryFile != "" {
+				fmt.Printf("✅ Nothing left to retry in %s\n", retryFile)
+			} else {
+				fmt.Println("✅ All folders already synced!")
+			}
 		}
 		return nil
 	}
@@ -392,235 +504,534 @@ func ActionSync(ctx context.Context, c *cli.Command) error {
 
 	// Pre-creation MUST stay a pre-stage: each worker holds its own mailbox
 	// cache, so a worker-side CreateMailbox would race against other workers'
-	// stale caches.
+	// stale caches. It also runs once for folders shared by several sources.
+	// Reverse plans (from a two-way run's failures) write to the source.
 	foldersToCreate := make(map[string]bool)
+	srcFoldersToCreate := make(map[string]bool)
 	for _, plan := range activePlans {
-		if !plan.DestinationFolderExists {
+		switch {
+		case plan.DestinationFolderExists:
+		case plan.Reverse:
+			srcFoldersToCreate[plan.DestinationFolder] = true
+		default:
 			foldersToCreate[plan.DestinationFolder] = true
 		}
 	}
+	if err := createFolders(ctx, dstClient, foldersToCreate, verbose, quiet); err != nil {
+		return err
+	}
+	if err := createFolders(ctx, planSources[0].client, srcFoldersToCreate, verbose, quiet); err != nil {
+		return err
+	}
 
-	if len(foldersToCreate) > 0 {
-		if !quiet {
-			fmt.Println("\n📁 Creating destination folders...")
-		}
+	// Sources are copied one after another, each on a worker pool logged
+	// in to that account, so the destination never sees more than one
+	// pool's worth of connections.
+	lanes := make([]copyLane, 0, len(planSources)+1)
+	for i, ps := range planSources {
+		srcCfg := *cfg
+		srcCfg.Src = sources[i].Credentials
+		lanes = append(lanes, copyLane{
+			cfg:     &srcCfg,
+			srcOpts: srcOpts[i],
+			dstOpts: dstOpts,
+			match:   func(p FolderSyncPlan) bool { return !p.Reverse && p.Source == ps.key },
+		})
+	}
+	if len(sources) == 1 {
+		lanes = append(lanes, reverseLane(cfg, srcOpts[0], dstOpts, false))
+	}
+	if err := copyPlans(ctx, c, lanes, activePlans, verbose, quiet); err != nil {
+		return err
+	}
 
-		creationPW := progress.NewWriter(1, quiet)
-		creationPW.Start()
-		creationTracker := progress.NewTracker("Creating folders", int64(len(foldersToCreate)))
-		traceTracker("folder-create", creationTracker.Message)
-		creationPW.AppendTracker(creationTracker)
+	fmt.Println("✨ Sync completed successfully. ✨")
+	slog.Info("sync finished")
+	return nil
+}
 
-		createdCount := 0
-		failedCount := 0
+// createFolders creates folders on c ahead of the copy, with a progress line
+// of its own. Folders that already exist count as created.
+func createFolders(ctx context.Context, c *client.Client, folders map[string]bool, verbose, quiet bool) error {
+	if len(folders) == 0 {
+		return nil
+	}
+	if !quiet {
+		fmt.Println("\n📁 Creating destination folders...")
+	}
 
-		for folder := range foldersToCreate {
-			if err := ctx.Err(); err != nil {
-				creationPW.Stop()
-				return err
+	creationPW := progress.NewWriter(1, quiet)
+	creationPW.Start()
+	creationTracker := progress.NewTracker("Creating folders", int64(len(folders)))
+	traceTracker("folder-create", creationTracker.Message)
+	creationPW.AppendTracker(creationTracker)
+
+	createdCount := 0
+	failedCount := 0
+
+	for folder := range folders {
+		if err := ctx.Err(); err != nil {
+			creationPW.Stop()
+			return err
+		}
+		creationTracker.UpdateMessage(fmt.Sprintf("(%d/%d) Creating %s", createdCount+failedCount+1, len(folders), folder))
+
+		created, err := c.CreateMailbox(ctx, folder)
+		switch {
+		case err != nil && ctx.Err() != nil:
+			creationPW.Stop()
+			return ctx.Err()
+		case err != nil:
+			creationPW.Log("Failed to create folder %q: %v", folder, err)
+			failedCount++
+		case created:
+			if verbose {
+				creationPW.Log("Created folder %q", folder)
 			}
-			creationTracker.UpdateMessage(fmt.Sprintf("(%d/%d) Creating %s", createdCount+failedCount+1, len(foldersToCreate), folder))
-
-			created, err := dstClient.CreateMailbox(ctx, folder)
-			switch {
-			case err != nil && ctx.Err() != nil:
-				creationPW.Stop()
-				return ctx.Err()
-			case err != nil:
-				creationPW.Log("Failed to create folder %q: %v", folder, err)
-				failedCount++
-			case created:
-				if verbose {
-					creationPW.Log("Created folder %q", folder)
-				}
-				createdCount++
-			default:
-				if verbose {
-					creationPW.Log("Folder %s already exists", folder)
-				}
-				createdCount++
+			createdCount++
+		default:
+			if verbose {
+				creationPW.Log("Folder %s already exists", folder)
 			}
-
-			creationTracker.Increment(1)
+			createdCount++
 		}
 
-		creationTracker.UpdateMessage(fmt.Sprintf("Created %d folders", createdCount))
-		creationTracker.MarkAsDone()
-		creationPW.StopAndClear()
+		creationTracker.Increment(1)
+	}
 
-		if failedCount > 0 {
-			return fmt.Errorf("failed to create %d folders", failedCount)
-		}
+	creationTracker.UpdateMessage(fmt.Sprintf("Created %d folders", createdCount))
+	creationTracker.MarkAsDone()
+	creationPW.StopAndClear()
+
+	if failedCount > 0 {
+		return fmt.Errorf("failed to create %d folders", failedCount)
 	}
+	return nil
+}
 
+// copyLane is one direction of traffic between two accounts: the plans for
+// which match reports true are copied on a worker pool that reads cfg.Src
+// and writes cfg.Dst. keepFlags carries each message's flags over to the
+// copy instead of marking it \Seen.
+type copyLane struct {
+	cfg       *config.Config
+	match     func(FolderSyncPlan) bool
+	srcOpts   client.Options
+	dstOpts   client.Options
+	keepFlags bool
+}
+
+// reverseLane copies reverse plans from the destination account back to the
+// single source account, swapping the forward srcOpts and dstOpts. Rate
+// limits follow the traffic: reads from the destination count against
+// --bps-down, writes to the source against --bps-up.
+func reverseLane(cfg *config.Config, srcOpts, dstOpts client.Options, keepFlags bool) copyLane {
+	rev := *cfg
+	rev.Src, rev.Dst = cfg.Dst, cfg.Src
+	revSrc, revDst := dstOpts, srcOpts
+	revSrc.ReadLimiter, revSrc.WriteLimiter = srcOpts.ReadLimiter, nil
+	revDst.ReadLimiter, revDst.WriteLimiter = nil, dstOpts.WriteLimiter
+	return copyLane{
+		cfg:       &rev,
+		match:     func(p FolderSyncPlan) bool { return p.Reverse },
+		srcOpts:   revSrc,
+		dstOpts:   revDst,
+		keepFlags: keepFlags,
+	}
+}
+
+// copyPlans copies the messages of activePlans, lane by lane, behind one
+// progress writer. When messages failed it prints the totals and returns
+// ErrSilentExit; on success it prints nothing.
+func copyPlans(ctx context.Context, c *cli.Command, lanes []copyLane, activePlans []FolderSyncPlan, verbose, quiet bool) error {
 	if !quiet {
 		fmt.Println("\n📥 Syncing messages...")
 	}
 
-	effectiveWorkers := computeEffectiveWorkers(cfg.Workers, cfg.RateLimit.MaxConnections, len(activePlans))
-
-	workers, err := newSyncWorkerPool(ctx, cfg, srcOpts, dstOpts, effectiveWorkers)
-	if err != nil {
-		return err
-	}
-	defer workers.close()
+	budget := newByteBudget(int64(c.Int("max-inflight-bytes")))
+	failures := newFailureLog(c.String("failures-file"))
 
 	// One progress writer for the whole sync, with a tracker per plan
 	// up front. Reusing the writer across all plans replaces the older
 	// "writer-per-chunk" approach that produced visible flicker and forced
 	// a 100ms sleep between chunks.
-	syncPW := progress.NewWriter(len(activePlans), quiet)
+	// Each line shows bytes, with the rate taken from the worker's wire
+	// traffic, and a last line totals the whole sync.
+	syncPW := progress.NewWriter(len(activePlans)+1, quiet)
+	syncPW.ShowBytes()
 	syncPW.Start()
 	defer syncPW.Stop()
 
 	trackers := make([]*progress.Tracker, len(activePlans))
+	var totalMessages int
+	var totalBytes uint64
 	for i, plan := range activePlans {
 		trackers[i] = progress.NewTracker(
-			fmt.Sprintf("%d/%d Waiting: %s → %s", i+1, len(activePlans), plan.SourceFolder, plan.DestinationFolder),
+			fmt.Sprintf("%d/%d Waiting: %s", i+1, len(activePlans), planTitle(plan)),
 			100,
 		)
+		trackers[i].SetBytesTotal(int64(plan.NewSize))
 		traceTracker("plan", trackers[i].Message)
 		syncPW.AppendTracker(trackers[i])
+		totalMessages += plan.NewMessages
+		totalBytes += plan.NewSize
+	}
+	overall := progress.NewTotalTracker(fmt.Sprintf("Total: %d folders", len(activePlans)), int64(totalMessages), trackers)
+	overall.SetBytesTotal(int64(totalBytes))
+	syncPW.AppendTracker(overall)
+
+	// Large plans are split into UID-range chunks so that a mailbox with
+	// most of its mail in one folder still keeps every worker busy.
+	runs, chunks := splitPlans(activePlans, trackers)
+
+	for _, lane := range lanes {
+		var mine []planChunk
+		for _, ch := range chunks {
+			if lane.match(ch.run.plan) {
+				mine = append(mine, ch)
+			}
+		}
+		if len(mine) == 0 {
+			continue
+		}
+		if err := runChunks(ctx, lane, mine, budget, failures, syncPW, verbose); err != nil {
+			for _, r := range runs {
+				r.dropSpooled(failures)
+			}
+			_, _ = failures.close()
+			return err
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	// Whatever a plan left spooled, finished or not, goes to the failures
+	// file rather than staying behind as temp files.
+	for _, r := range runs {
+		r.dropSpooled(failures)
+	}
+	failedN, failuresErr := failures.close()
+
+	// The bars stay on screen in their final state, above the summary
+	// that goes into migration tickets; a canceled sync gets one too.
+	syncPW.Stop()
+	syncPW.WaitForRenderDone()
+	if !quiet {
+		fmt.Println()
+		printSyncSummary(os.Stdout, runs)
+		fmt.Println()
+	}
+	if failuresErr != nil {
+		fmt.Printf("⚠️ %v\n", failuresErr)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 
+	var totalSyncedN, totalErrorsN int
+	for _, r := range runs {
+		synced, errs := r.result()
+		totalSyncedN += synced
+		totalErrorsN += errs
+	}
+
+	slog.Info("copy finished", "synced", totalSyncedN, "errors", totalErrorsN, "failures_recorded", failedN)
+	if totalErrorsN > 0 {
+		fmt.Printf("❌ Sync completed with errors. %d messages uploaded, %d errors occurred\n", totalSyncedN, totalErrorsN)
+		if failedN > 0 {
+			path := c.String("failures-file")
+			fmt.Printf("📝 %d failed messages written to %s; retry them with: %s\n", failedN, path, retryHint(path))
+		}
+		// Friendly summary already printed; signal non-zero exit without
+		// asking main to repeat the same information through stderr.
+		return ErrSilentExit
+	}
+	return nil
+}
+
+// runChunks copies chunks, all of one lane, on a worker pool connected to
+// the lane's accounts. Workers are sized by chunk count, not plan count:
+// one big folder split into many chunks can use every worker on its own.
+func runChunks(ctx context.Context, lane copyLane, chunks []planChunk, budget *byteBudget, failures *failureLog, pw *progress.Writer, verbose bool) error {
+	effectiveWorkers := computeEffectiveWorkers(lane.cfg.Workers, lane.cfg.RateLimit.MaxConnections, len(chunks))
+
+	workers, err := newSyncWorkerPool(ctx, lane.cfg, lane.srcOpts, lane.dstOpts, effectiveWorkers)
+	if err != nil {
+		return err
+	}
+	defer workers.close()
+
 	// free is a bounded semaphore of pre-built workers. Whoever runs first
-	// pulls a worker, syncs one plan, returns the worker.
+	// pulls a worker, syncs one chunk, returns the worker.
 	free := make(chan *syncWorker, effectiveWorkers)
 	for _, w := range workers.all {
+		w.budget = budget
+		w.failures = failures
+		w.keepFlags = lane.keepFlags
 		free <- w
 	}
 
-	var (
-		wg          sync.WaitGroup
-		totalSynced atomic.Int64
-		totalErrors atomic.Int64
-	)
-
-	for i, plan := range activePlans {
-		if ctx.Err() != nil {
-			break
-		}
+	var wg sync.WaitGroup
+	for i, ch := range chunks {
 		var w *syncWorker
-		select {
-		case w = <-free:
-		case <-ctx.Done():
+		if ctx.Err() == nil {
+			select {
+			case w = <-free:
+			case <-ctx.Done():
+			}
 		}
 		if w == nil {
+			// Canceled: the chunks left still count down their plans,
+			// or no plan with undispatched chunks would ever settle.
+			for _, rest := range chunks[i:] {
+				rest.run.skipChunk(ctx, failures, pw, verbose)
+			}
 			break
 		}
 		wg.Add(1)
-		go func(idx int, p FolderSyncPlan, w *syncWorker, tr *progress.Tracker) {
+		go func(ch planChunk, w *syncWorker) {
 			defer wg.Done()
 			defer func() { free <- w }()
-			synced, errs := runFolderSync(ctx, w, p, tr, idx, len(activePlans), syncPW, verbose)
-			totalSynced.Add(int64(synced))
-			totalErrors.Add(int64(errs))
-		}(i, plan, w, trackers[i])
+			metrics.ActiveWorkers.Inc()
+			defer metrics.ActiveWorkers.Dec()
+			runPlanChunk(ctx, w, ch, pw, verbose)
+		}(ch, w)
 	}
 	wg.Wait()
+	return nil
+}
 
-	if err := ctx.Err(); err != nil {
-		return err
+// planTitle renders "src → dst" for a plan, led by the source label when the
+// config has several sources. Reverse plans read "src ← dst", so the source
+// account's folder always comes first.
+func planTitle(p FolderSyncPlan) string {
+	if p.Reverse {
+		return fmt.Sprintf("%s ← %s", p.DestinationFolder, p.SourceFolder)
 	}
+	if p.Source != "" {
+		return fmt.Sprintf("[%s] %s → %s", p.Source, p.SourceFolder, p.DestinationFolder)
+	}
+	return fmt.Sprintf("%s → %s", p.SourceFolder, p.DestinationFolder)
+}
 
-	totalSyncedN := int(totalSynced.Load())
-	totalErrorsN := int(totalErrors.Load())
+// prefixFolder places folder under prefix on a server with the given
+// hierarchy delimiter. A server without hierarchy gets "/".
+func prefixFolder(prefix, folder, delimiter string) string {
+	if delimiter == "" {
+		delimiter = "/"
+	}
+	return strings.TrimSuffix(prefix, delimiter) + delimiter + folder
+}
 
-	if totalErrorsN > 0 {
-		fmt.Printf("❌ Sync completed with errors. %d messages uploaded, %d errors occurred\n", totalSyncedN, totalErrorsN)
-		// Friendly summary already printed; signal non-zero exit without
-		// asking main to repeat the same information through stderr.
-		return ErrSilentExit
+// autoDestination returns where a source folder goes without a map: the same
+// name in the destination's delimiter, under prefix when one is set.
+func autoDestination(name, prefix, srcDelimiter, dstDelimiter string) string {
+	if srcDelimiter != "" && dstDelimiter != "" && srcDelimiter != dstDelimiter {
+		name = strings.ReplaceAll(name, srcDelimiter, dstDelimiter)
 	}
+	if prefix != "" {
+		name = prefixFolder(prefix, name, dstDelimiter)
+	}
+	return name
+}
 
-	fmt.Println("✨ Sync completed successfully. ✨")
-	return nil
+// scanMappings expands each source's mappings with their subfolders and
+// scans all sources and the destination to build the sync plan, showing scan
+// progress on its own writer.
+func scanMappings(ctx context.Context, sources []planSource, dstClient *client.Client, dstLabel, dstDelimiter string, verbose, quiet bool) (*SyncSummary, error) {
+	// Expand mappings to include subfolders
+	if !quiet && verbose {
+		fmt.Println("Checking for subfolders...")
+	}
+	labels := make([]string, len(sources))
+	for i := range sources {
+		ps := &sources[i]
+		labels[i] = ps.label
+		expandedMappings, err := expandMappingsWithSubfolders(ctx, ps.client, ps.mappings, ps.delimiter, dstDelimiter, verbose, quiet)
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand mappings: %w", err)
+		}
+		if len(expandedMappings) > len(ps.mappings) && !quiet && verbose {
+			fmt.Printf("📂 [%s] Found %d subfolders, total folders to sync: %d\n", ps.label, len(expandedMappings)-len(ps.mappings), len(expandedMappings))
+		}
+		ps.mappings = expandedMappings
+	}
+
+	// Setup progress writer for scanning phase
+	pw := progress.NewWriter(2, quiet)
+	pw.Start()
+
+	// Create trackers for source and destination scanning
+	srcTracker := progress.NewTracker(fmt.Sprintf("[%s] Scanning folders", strings.Join(labels, ", ")), 100)
+	dstTracker := progress.NewTracker(fmt.Sprintf("[%s] Scanning folders", dstLabel), 100)
+
+	traceTracker("scan-src", srcTracker.Message)
+	pw.AppendTracker(srcTracker)
+	traceTracker("scan-dst", dstTracker.Message)
+	pw.AppendTracker(dstTracker)
+
+	for _, ps := range sources {
+		ps.client.SetProgressWriter(pw)
+		ps.client.SetProgressTracker(srcTracker)
+	}
+	dstClient.SetProgressWriter(pw)
+	dstClient.SetProgressTracker(dstTracker)
+
+	summary, err := buildSyncPlan(ctx, sources, dstClient, srcTracker, dstTracker, pw, dstLabel, verbose)
+	if err != nil {
+		pw.Stop()
+		return nil, err
+	}
+
+	// Mark scanning as complete
+	srcTracker.MarkAsDone()
+	dstTracker.MarkAsDone()
+
+	// Stop and clear progress
+	pw.StopAndClear()
+	return summary, nil
+}
+
+// planSource is one source account as buildSyncPlan sees it: the planning
+// connection and the mappings to scan on it. key ends up in
+// FolderSyncPlan.Source; it is the account label when the config has several
+// sources and empty otherwise.
+type planSource struct {
+	client    *client.Client
+	key       string
+	label     string
+	delimiter string
+	mappings  []config.DirectoryMapping
 }
 
 // folderScan holds the per-slot results from the parallel src and dst scans.
 // done is incremented by each side; when it reaches 2, maybeDiff fires.
 // Pointer fields precede non-pointer fields to minimise the GC scan range.
 //
-// srcFolderSize and srcFolderCount are recorded before srcMap is freed so the
-// proportional size estimate in maybeDiff can run after the diff.
+// keepIDs is set for slots whose destination folder is shared with another
+// slot; maybeDiff then keeps the Message-Id of every new UID in newIDs so the
+// final pass can drop messages already planned by an earlier slot.
 type folderScan struct {
-	srcMap         map[string]uint32
-	dstIDs         map[string]struct{}
-	srcErr         error
-	dstErr         error
-	srcFolderSize  uint64
-	srcFolderCount int
-	dstExists      bool
-	mu             sync.Mutex
-	done           atomic.Int32
+	srcMap      map[string]client.MessageRef
+	dstIDs      map[string]struct{}
+	newIDs      map[uint32]string
+	srcErr      error
+	dstErr      error
+	srcExcluded client.FilterStats
+	dstExists   bool
+	keepIDs     bool
+	mu          sync.Mutex
+	done        atomic.Int32
 }
 
-func buildSyncPlan(ctx context.Context, srcClient, dstClient *client.Client, mappings []config.DirectoryMapping, srcTracker, dstTracker *progress.Tracker, pw *progress.Writer, srcLabel, dstLabel string, verbose bool) (*SyncSummary, error) {
+// dstFolderScan is the cached destination side of one folder, shared by every
+// slot that maps into it.
+type dstFolderScan struct {
+	ids    map[string]struct{}
+	err    error
+	exists bool
+}
+
+// buildSyncPlan scans every mapping of every source against the destination
+// and returns the messages to copy. Each source is scanned by its own
+// goroutine; the destination is scanned by one goroutine that reads each
+// destination folder once, however many mappings point at it. Messages with
+// a Message-Id that an earlier mapping (in source and map order) already
+// plans to copy into the same folder are dropped, so merging accounts that
+// share mail does not duplicate it.
+func buildSyncPlan(ctx context.Context, sources []planSource, dstClient *client.Client, srcTracker, dstTracker *progress.Tracker, pw *progress.Writer, dstLabel string, verbose bool) (*SyncSummary, error) {
 	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
 
+	// Flatten the sources into slots; owner maps a slot back to its source.
+	var mappings []config.DirectoryMapping
+	var owner []int
+	for si, src := range sources {
+		for _, m := range src.mappings {
+			mappings = append(mappings, m)
+			owner = append(owner, si)
+		}
+	}
 	n := len(mappings)
+	filters := make([]client.Filter, n)
+	now := time.Now()
+	for idx, m := range mappings {
+		b, err := m.Limits.Resolve(now)
+		if err != nil {
+			return nil, fmt.Errorf("limits for %s: %w", m.Source, err)
+		}
+		filters[idx] = client.Filter{Since: b.Since, Before: b.Before, Search: b.Search, MinSize: b.MinSize, MaxSize: b.MaxSize}
+	}
 	srcTracker.UpdateTotal(int64(n))
 	dstTracker.UpdateTotal(int64(n))
 
 	scans := make([]folderScan, n)
 	plans := make([]FolderSyncPlan, n)
-	var totalNew atomic.Int64
-	var totalNewSize atomic.Uint64
+	dstUses := make(map[string]int)
+	for _, m := range mappings {
+		dstUses[m.Destination]++
+	}
+	for idx, m := range mappings {
+		scans[idx].keepIDs = dstUses[m.Destination] > 1
+	}
 
-	// errgroup.WithContext: if either goroutine returns a non-nil error,
-	// gCtx is cancelled, which causes the other goroutine's next gCtx.Err()
-	// check to bail out immediately — no need to drain all remaining folders.
+	// errgroup.WithContext: if any goroutine returns a non-nil error, gCtx
+	// is cancelled, which causes the others' next gCtx.Err() check to bail
+	// out immediately — no need to drain all remaining folders.
 	g, gCtx := errgroup.WithContext(ctx)
 
-	g.Go(func() error {
-		for idx, m := range mappings {
-			if err := gCtx.Err(); err != nil {
-				return err
-			}
-			srcTracker.UpdateMessage(fmt.Sprintf("[%s] Scanning %s (%d/%d)", srcLabel, m.Source, idx+1, n))
-			mp, size, err := srcClient.FetchMessageMap(gCtx, m.Source)
-			if err != nil {
-				scans[idx].srcErr = err
-			} else {
-				scans[idx].srcMap = mp
-				scans[idx].srcFolderSize = size
-				scans[idx].srcFolderCount = len(mp)
+	first := 0
+	for _, src := range sources {
+		offset := first
+		first += len(src.mappings)
+		g.Go(func() error {
+			for i, m := range src.mappings {
+				if err := gCtx.Err(); err != nil {
+					return err
+				}
+				idx := offset + i
+				srcTracker.UpdateMessage(fmt.Sprintf("[%s] Scanning %s (%d/%d)", src.label, m.Source, idx+1, n))
+				mp, _, excluded, err := src.client.FetchMessageRefs(gCtx, m.Source, filters[idx])
+				if err != nil {
+					scans[idx].srcErr = err
+				} else {
+					scans[idx].srcExcluded = excluded
+					scans[idx].srcMap = mp
+				}
+				srcTracker.UpdateMessage(fmt.Sprintf("[%s] Scanned %s (%d/%d)", src.label, m.Source, idx+1, n))
+				srcTracker.Increment(1)
+				maybeDiff(&scans[idx], idx, mappings, plans)
 			}
-			srcTracker.UpdateMessage(fmt.Sprintf("[%s] Scanned %s (%d/%d)", srcLabel, m.Source, idx+1, n))
-			srcTracker.Increment(1)
-			maybeDiff(&scans[idx], idx, mappings, plans, &totalNew, &totalNewSize)
-		}
-		return nil
-	})
+			return nil
+		})
+	}
 
 	g.Go(func() error {
+		// A destination folder stays cached until its last slot is served.
+		cache := make(map[string]*dstFolderScan)
 		for idx, m := range mappings {
 			if err := gCtx.Err(); err != nil {
 				return err
 			}
 			dstTracker.UpdateMessage(fmt.Sprintf("[%s] Scanning %s (%d/%d)", dstLabel, m.Destination, idx+1, n))
-			exists, err := dstClient.MailboxExists(gCtx, m.Destination)
-			switch {
-			case err != nil:
-				scans[idx].dstErr = err
-			case !exists:
-				// dstExists stays false, dstIDs stays nil — benign; folder will be created.
-			default:
-				scans[idx].dstExists = true
-				ids, err := dstClient.FetchMessageIDSet(gCtx, m.Destination)
-				if err != nil {
-					scans[idx].dstErr = err
-				} else {
-					scans[idx].dstIDs = ids
-				}
+			d, ok := cache[m.Destination]
+			if !ok {
+				d = scanDestination(gCtx, dstClient, m.Destination)
+				cache[m.Destination] = d
 			}
+			if dstUses[m.Destination]--; dstUses[m.Destination] == 0 {
+				delete(cache, m.Destination)
+			}
+			scans[idx].mu.Lock()
+			scans[idx].dstExists, scans[idx].dstIDs, scans[idx].dstErr = d.exists, d.ids, d.err
+			scans[idx].mu.Unlock()
 			dstTracker.UpdateMessage(fmt.Sprintf("[%s] Scanned %s (%d/%d)", dstLabel, m.Destination, idx+1, n))
 			dstTracker.Increment(1)
-			maybeDiff(&scans[idx], idx, mappings, plans, &totalNew, &totalNewSize)
-			if scans[idx].dstErr != nil {
-				return fmt.Errorf("scan destination folder %q: %w", m.Destination, scans[idx].dstErr)
+			maybeDiff(&scans[idx], idx, mappings, plans)
+			if d.err != nil {
+				return fmt.Errorf("scan destination folder %q: %w", m.Destination, d.err)
 			}
 		}
 		return nil
@@ -634,33 +1045,86 @@ func buildSyncPlan(ctx context.Context, srcClient, dstClient *client.Client, map
 	}
 
 	summary := &SyncSummary{Plans: make([]FolderSyncPlan, 0, n)}
+	claimed := make(map[string]map[string]struct{})
 	for idx := range scans {
+		src := sources[owner[idx]]
 		if scans[idx].srcErr != nil {
 			if verbose {
 				pw.Log("⚠️ Failed to fetch source folder %s, skipping by error: %v", mappings[idx].Source, scans[idx].srcErr)
 			}
 			continue
 		}
-		if plans[idx].SourceFolder == "" {
+		summary.Excluded.Add(scans[idx].srcExcluded)
+		plan := plans[idx]
+		if plan.SourceFolder == "" {
 			continue
 		}
-		summary.Plans = append(summary.Plans, plans[idx])
+		if scans[idx].keepIDs {
+			before := plan.NewMessages
+			plan = claimNew(plan, scans[idx].newIDs, claimed)
+			if dropped := before - plan.NewMessages; dropped > 0 && verbose {
+				pw.Log("ℹ️ [%s] %s: %d messages already planned for %s from another mapping", src.label, plan.SourceFolder, dropped, plan.DestinationFolder)
+			}
+			if plan.NewMessages == 0 {
+				continue
+			}
+		}
+		plan.Source = src.key
+		summary.Plans = append(summary.Plans, plan)
+		summary.TotalNew += plan.NewMessages
+		summary.TotalNewSize += plan.NewSize
 	}
-	summary.TotalNew = int(totalNew.Load())
-	summary.TotalNewSize = totalNewSize.Load()
 	return summary, nil
 }
 
+// scanDestination reports whether folder exists on the destination and, if
+// so, which Message-Ids it already holds. A missing folder is not an error:
+// it is created before the copy starts.
+func scanDestination(ctx context.Context, dstClient *client.Client, folder string) *dstFolderScan {
+	exists, err := dstClient.MailboxExists(ctx, folder)
+	if err != nil || !exists {
+		return &dstFolderScan{err: err}
+	}
+	ids, err := dstClient.FetchMessageIDSet(ctx, folder)
+	return &dstFolderScan{exists: true, ids: ids, err: err}
+}
+
+// claimNew drops from p every message whose Message-Id is already claimed
+// for p's destination folder and claims the rest, and recounts NewSize.
+func claimNew(p FolderSyncPlan, ids map[uint32]string, claimed map[string]map[string]struct{}) FolderSyncPlan {
+	seen := claimed[p.DestinationFolder]
+	if seen == nil {
+		seen = make(map[string]struct{}, len(p.SrcUIDs))
+		claimed[p.DestinationFolder] = seen
+	}
+	kept := make([]uint32, 0, len(p.SrcUIDs))
+	for _, uid := range p.SrcUIDs {
+		id := ids[uid]
+		if _, dup := seen[id]; dup {
+			continue
+		}
+		seen[id] = struct{}{}
+		kept = append(kept, uid)
+	}
+	if len(kept) < len(p.SrcUIDs) {
+		p.NewSize = 0
+		for _, uid := range kept {
+			p.NewSize += uint64(p.Sizes[uid])
+		}
+	}
+	p.SrcUIDs = kept
+	p.NewMessages = len(kept)
+	return p
+}
+
 // maybeDiff fires the Message-Id diff exactly once, when both the src and dst
 // sides have completed for slot idx. Returns false if only one side is done.
 // Frees srcMap and dstIDs immediately after the diff to keep peak memory
 // proportional to one folder at a time, not len(mappings).
 //
-// NewSize is estimated as srcFolderSize × newCount / srcFolderCount — the
-// exact size would require an extra UID FETCH RFC822.SIZE for each new UID
-// before the user has even confirmed, which is too eager. The proportion is
-// good enough for the preview header.
-func maybeDiff(s *folderScan, idx int, mappings []config.DirectoryMapping, plans []FolderSyncPlan, totalNew *atomic.Int64, totalNewSize *atomic.Uint64) bool {
+// The source scan fetched RFC822.SIZE with every Message-Id, so NewSize is
+// exact and the plan keeps the size of each new UID in Sizes.
+func maybeDiff(s *folderScan, idx int, mappings []config.DirectoryMapping, plans []FolderSyncPlan) bool {
 	if s.done.Add(1) != 2 {
 		return false
 	}
@@ -674,9 +1138,19 @@ func maybeDiff(s *folderScan, idx int, mappings []config.DirectoryMapping, plans
 		return true
 	}
 	newUIDs := make([]uint32, 0, len(s.srcMap))
-	for id, uid := range s.srcMap {
+	sizes := make(map[uint32]uint32)
+	var newSize uint64
+	for id, ref := range s.srcMap {
 		if _, present := s.dstIDs[id]; !present {
-			newUIDs = append(newUIDs, uid)
+			newUIDs = append(newUIDs, ref.UID)
+			sizes[ref.UID] = ref.Size
+			newSize += uint64(ref.Size)
+			if s.keepIDs {
+				if s.newIDs == nil {
+					s.newIDs = make(map[uint32]string)
+				}
+				s.newIDs[ref.UID] = id
+			}
 		}
 	}
 	s.srcMap, s.dstIDs = nil, nil
@@ -685,10 +1159,6 @@ func maybeDiff(s *folderScan, idx int, mappings []config.DirectoryMapping, plans
 	}
 	// Sort for stable preview ordering and compactable UID FETCH ranges.
 	slices.Sort(newUIDs)
-	var newSize uint64
-	if s.srcFolderCount > 0 {
-		newSize = uint64(float64(s.srcFolderSize) * float64(len(newUIDs)) / float64(s.srcFolderCount))
-	}
 	plans[idx] = FolderSyncPlan{
 		SourceFolder:            mappings[idx].Source,
 		DestinationFolder:       mappings[idx].Destination,
@@ -696,12 +1166,35 @@ func maybeDiff(s *folderScan, idx int, mappings []config.DirectoryMapping, plans
 		NewMessages:             len(newUIDs),
 		NewSize:                 newSize,
 		SrcUIDs:                 newUIDs,
+		Sizes:                   sizes,
 	}
-	totalNew.Add(int64(len(newUIDs)))
-	totalNewSize.Add(newSize)
 	return true
 }
 
+// printFilterExclusions reports how many source messages each date, size and
+// search limit left out of the plan. Nothing is printed when no limit excluded any.
+func printFilterExclusions(ex client.FilterStats) {
+	if ex.Total() == 0 {
+		return
+	}
+	fmt.Printf("🔎 Messages excluded by filters:\n")
+	for _, line := range []struct {
+		what string
+		n    int
+	}{
+		{"older than --since/--max-age", ex.Since},
+		{"dated on or after --before", ex.Before},
+		{"smaller than --min-size", ex.MinSize},
+		{"larger than --max-size", ex.MaxSize},
+		{"not matching the filter expression", ex.Search},
+	} {
+		if line.n > 0 {
+			fmt.Printf("• %d %s\n", line.n, line.what)
+		}
+	}
+	fmt.Println()
+}
+
 // folderDelimiter inspects path and reports which of the common IMAP
 // hierarchy delimiters it appears to use, plus whether that delimiter agrees
 // with the server's. ok is true also when path contains no delimiter at all
@@ -772,6 +1265,7 @@ func expandMappingsWithSubfolders(ctx context.Context, srcClient *client.Client,
 			expanded = append(expanded, config.DirectoryMapping{
 				Source:      subfolder,
 				Destination: dstPath,
+				Limits:      mapping.Limits,
 			})
 
 			if verbose && !quiet {
@@ -793,7 +1287,7 @@ func expandMappingsWithSubfolders(ctx context.Context, srcClient *client.Client,
 }
 
 // computeEffectiveWorkers caps the worker count by both the configured cap
-// and the number of plans actually scheduled. maxConn is the per-side IMAP
+// and the number of plan chunks actually scheduled. maxConn is the per-side IMAP
 // connection budget; the planning client is still open when workers start,
 // so we reserve one slot for it (maxConn-1). Returns at least 1.
 func computeEffectiveWorkers(workers, maxConn, planCount int) int {
@@ -844,17 +1338,20 @@ func buildProviderWarning(cfg *config.Config, srcReadLim, dstWriteLim *rate.Limi
 		limiter  *rate.Limiter
 		side     string // "source" / "destination"
 		host     string
-		provider client.Provider
+		provider provider.Provider
 		isUpload bool
 	}
 
+	providers := cfg.ProviderTable()
 	var hits []sideHit
-	if p, ok := client.DetectProvider(cfg.Src.Server); ok {
-		hits = append(hits, sideHit{
-			limiter: srcReadLim, side: "source", host: cfg.Src.Server, provider: p, isUpload: false,
-		})
+	for _, src := range cfg.SourceList() {
+		if p, ok := providers.Detect(src.Server); ok {
+			hits = append(hits, sideHit{
+				limiter: srcReadLim, side: "source", host: src.Server, provider: p, isUpload: false,
+			})
+		}
 	}
-	if p, ok := client.DetectProvider(cfg.Dst.Server); ok {
+	if p, ok := providers.Detect(cfg.Dst.Server); ok {
 		hits = append(hits, sideHit{
 			limiter: dstWriteLim, side: "destination", host: cfg.Dst.Server, provider: p, isUpload: true,
 		})
@@ -869,7 +1366,8 @@ func buildProviderWarning(cfg *config.Config, srcReadLim, dstWriteLim *rate.Limi
 		fmt.Fprintf(&b, "   %s [%s] — %s\n", h.side, h.host, h.provider.Name)
 		if h.provider.MaxConnections > 0 {
 			fmt.Fprintf(&b, "     • max simultaneous connections: %d\n", h.provider.MaxConnections)
-			if cfg.Workers >= h.provider.MaxConnections {
+			capped := cfg.RateLimit.MaxConnections > 0 && cfg.RateLimit.MaxConnections <= h.provider.MaxConnections
+			if cfg.Workers >= h.provider.MaxConnections && !capped {
 				fmt.Fprintf(&b, "       (current --workers=%d may exceed this; consider lowering)\n", cfg.Workers)
 			}
 		}
@@ -895,3 +1393,86 @@ func buildProviderWarning(cfg *config.Config, srcReadLim, dstWriteLim *rate.Limi
 	b.WriteString("\n")
 	return b.String()
 }
+
+// Modes of --provider-limits: apply a detected provider's recommended
+// limits, only print them (the default, so a run is never throttled unasked),
+// or neither.
+const (
+	providerLimitsAuto = "auto"
+	providerLimitsWarn = "warn"
+	providerLimitsOff  = "off"
+)
+
+// appliedLimits names the provider each rate limit was taken from; empty
+// when the limit came from the config or the flags, or is unset.
+type appliedLimits struct {
+	down, up, conns string
+}
+
+// resolveProviderLimits applies the recommended limits of the detected
+// providers to cfg in auto mode; warn and off leave cfg alone.
+func resolveProviderLimits(cfg *config.Config, mode string) (appliedLimits, error) {
+	switch mode {
+	case providerLimitsAuto:
+		from := applyProviderLimits(cfg)
+		if from != (appliedLimits{}) {
+			rl := cfg.RateLimit
+			slog.Info("provider limits applied", "down_bps", rl.DownBPS, "up_bps", rl.UpBPS, "max_connections", rl.MaxConnections)
+		}
+		return from, nil
+	case providerLimitsWarn, providerLimitsOff:
+		return appliedLimits{}, nil
+	}
+	return appliedLimits{}, fmt.Errorf("--provider-limits must be %s, %s or %s, got %q", providerLimitsAuto, providerLimitsWarn, providerLimitsOff, mode)
+}
+
+// applyProviderLimits fills the rate limits left at 0 by the config and the
+// flags with the recommendations of the detected providers: downloads as
+// the strictest source provider allows, uploads as the destination's, and
+// the lowest connection cap of any side. The caller builds the limiters,
+// and computeEffectiveWorkers reads the connection cap, from cfg.RateLimit
+// afterwards.
+func applyProviderLimits(cfg *config.Config) appliedLimits {
+	var from appliedLimits
+	rl := &cfg.RateLimit
+	explicit := *rl
+	lower := func(limit *int, from *string, explicitly, recommended int, name string) {
+		if explicitly != 0 || recommended <= 0 {
+			return
+		}
+		if *limit == 0 || recommended < *limit {
+			*limit, *from = recommended, name
+		}
+	}
+	providers := cfg.ProviderTable()
+	for _, src := range cfg.SourceList() {
+		if p, ok := providers.Detect(src.Server); ok {
+			lower(&rl.DownBPS, &from.down, explicit.DownBPS, p.DownBPS, p.Name)
+			lower(&rl.MaxConnections, &from.conns, explicit.MaxConnections, p.MaxConnections, p.Name)
+		}
+	}
+	if p, ok := providers.Detect(cfg.Dst.Server); ok {
+		lower(&rl.UpBPS, &from.up, explicit.UpBPS, p.UpBPS, p.Name)
+		lower(&rl.MaxConnections, &from.conns, explicit.MaxConnections, p.MaxConnections, p.Name)
+	}
+	return from
+}
+
+// formatLimits renders the effective rate limits for the plan preview,
+// naming the provider a limit was taken from.
+func formatLimits(rl config.RateLimit, from appliedLimits) string {
+	part := func(v int, unit, unset, provider string) string {
+		out := unset
+		if v > 0 {
+			out = fmt.Sprintf(unit, v)
+		}
+		if provider != "" {
+			out += fmt.Sprintf(" (%s)", provider)
+		}
+		return out
+	}
+	down := part(rl.DownBPS, "down %d B/s", "down unlimited", from.down)
+	up := part(rl.UpBPS, "up %d B/s", "up unlimited", from.up)
+	conns := part(rl.MaxConnections, "%d connections per side", "connections by workers", from.conns)
+	return down + ", " + up + ", " + conns
+}
@@ -12,6 +12,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/greeddj/imapsync-go/internal/client"
 	"github.com/greeddj/imapsync-go/internal/config"
 	"github.com/greeddj/imapsync-go/internal/progress"
 	"github.com/greeddj/imapsync-go/internal/ratelimit"
@@ -99,6 +100,71 @@ func TestBuildProviderWarning_gmailDst_nilLimiter_recommendsBPSUp(t *testing.T)
 	}
 }
 
+func TestApplyProviderLimits_fillsUnsetLimits(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{
+		Src:     config.Credentials{Server: "imap.gmail.com:993"},
+		Dst:     config.Credentials{Server: "outlook.office365.com:993"},
+		Workers: 20,
+	}
+	from := applyProviderLimits(cfg)
+	want := config.RateLimit{DownBPS: 300_000, MaxConnections: 15}
+	if cfg.RateLimit != want {
+		t.Errorf("RateLimit = %+v, want %+v", cfg.RateLimit, want)
+	}
+	if from != (appliedLimits{down: "Gmail", conns: "Gmail"}) {
+		t.Errorf("applied = %+v, want down and connections from Gmail", from)
+	}
+	// The applied cap reaches the worker pool, and the banner stops nagging.
+	if got := computeEffectiveWorkers(cfg.Workers, cfg.RateLimit.MaxConnections, 100); got != 14 {
+		t.Errorf("computeEffectiveWorkers = %d, want 14", got)
+	}
+	if got := buildProviderWarning(cfg, ratelimit.NewLimiter(cfg.RateLimit.DownBPS), nil); strings.Contains(got, "may exceed") || strings.Contains(got, "--bps-down") {
+		t.Errorf("warning after applying limits still recommends them: %q", got)
+	}
+	if got := formatLimits(cfg.RateLimit, from); got != "down 300000 B/s (Gmail), up unlimited, 15 connections per side (Gmail)" {
+		t.Errorf("formatLimits = %q", got)
+	}
+}
+
+func TestApplyProviderLimits_keepsExplicitLimits(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{
+		Src:       config.Credentials{Server: "imap.gmail.com:993"},
+		Dst:       config.Credentials{Server: "imap.gmail.com:993"},
+		RateLimit: config.RateLimit{DownBPS: 1_000_000, MaxConnections: 30},
+	}
+	from := applyProviderLimits(cfg)
+	want := config.RateLimit{DownBPS: 1_000_000, UpBPS: 300_000, MaxConnections: 30}
+	if cfg.RateLimit != want {
+		t.Errorf("RateLimit = %+v, want %+v", cfg.RateLimit, want)
+	}
+	if from != (appliedLimits{up: "Gmail"}) {
+		t.Errorf("applied = %+v, want only up from Gmail", from)
+	}
+}
+
+func TestResolveProviderLimits_onlyAutoApplies(t *testing.T) {
+	t.Parallel()
+
+	for _, mode := range []string{providerLimitsWarn, providerLimitsOff} {
+		cfg := &config.Config{Src: config.Credentials{Server: "imap.gmail.com:993"}, Dst: config.Credentials{Server: "imap.gmail.com:993"}}
+		from, err := resolveProviderLimits(cfg, mode)
+		if err != nil || from != (appliedLimits{}) || cfg.RateLimit != (config.RateLimit{}) {
+			t.Errorf("%s: applied %+v, limits %+v, err %v; want nothing applied", mode, from, cfg.RateLimit, err)
+		}
+	}
+	cfg := &config.Config{Src: config.Credentials{Server: "imap.gmail.com:993"}, Dst: config.Credentials{Server: "imap.gmail.com:993"}}
+	if from, err := resolveProviderLimits(cfg, providerLimitsAuto); err != nil || from.down != "Gmail" || cfg.RateLimit.DownBPS != 300_000 {
+		t.Errorf("auto: applied %+v, limits %+v, err %v; want Gmail's", from, cfg.RateLimit, err)
+	}
+	if _, err := resolveProviderLimits(cfg, "always"); err == nil || !strings.Contains(err.Error(), "--provider-limits") {
+		t.Errorf("unknown mode: err = %v, want one naming --provider-limits", err)
+	}
+}
+
 func TestFolderDelimiter(t *testing.T) {
 	t.Parallel()
 
@@ -260,7 +326,7 @@ func Test_buildSyncPlan_emptySrc_returnsNoPlans(t *testing.T) {
 	pw, srcTr, dstTr := makePlanPW()
 	mappings := []config.DirectoryMapping{{Source: "INBOX", Destination: "INBOX"}}
 
-	summary, err := buildSyncPlan(context.Background(), srcC, dstC, mappings, srcTr, dstTr, pw, "src", "dst", false)
+	summary, err := buildSyncPlan(context.Background(), []planSource{{client: srcC, label: "src", mappings: mappings}}, dstC, srcTr, dstTr, pw, "dst", false)
 	if err != nil {
 		t.Fatalf("buildSyncPlan: %v", err)
 	}
@@ -307,7 +373,7 @@ func Test_buildSyncPlan_messageIdDiff_correct(t *testing.T) {
 	pw, srcTr, dstTr := makePlanPW()
 	mappings := []config.DirectoryMapping{{Source: "INBOX", Destination: "INBOX"}}
 
-	summary, err := buildSyncPlan(context.Background(), srcC, dstC, mappings, srcTr, dstTr, pw, "src", "dst", false)
+	summary, err := buildSyncPlan(context.Background(), []planSource{{client: srcC, label: "src", mappings: mappings}}, dstC, srcTr, dstTr, pw, "dst", false)
 	if err != nil {
 		t.Fatalf("buildSyncPlan: %v", err)
 	}
@@ -330,6 +396,60 @@ func Test_buildSyncPlan_messageIdDiff_correct(t *testing.T) {
 	}
 }
 
+// Test_buildSyncPlan_sourcesShareDestination merges two source accounts into
+// one destination folder: the folder is scanned once, and a Message-Id both
+// sources hold is planned only from the first one.
+func Test_buildSyncPlan_sourcesShareDestination(t *testing.T) {
+	srcASrv := newFakeServer(t)
+	srcBSrv := newFakeServer(t)
+	dstSrv := newFakeServer(t)
+
+	type msg = struct {
+		msgID string
+		uid   uint32
+	}
+	srcASrv.addConnHandler(msgIDFetchHandler(srcASrv, []string{"INBOX"}, map[string][]msg{
+		"INBOX": {{uid: 1, msgID: "a@x"}, {uid: 2, msgID: "shared@x"}},
+	}))
+	srcBSrv.addConnHandler(msgIDFetchHandler(srcBSrv, []string{"Support"}, map[string][]msg{
+		"Support": {{uid: 1, msgID: "shared@x"}, {uid: 2, msgID: "b@x"}, {uid: 3, msgID: "old@x"}},
+	}))
+	dstSrv.addConnHandler(msgIDFetchHandler(dstSrv, []string{"Merged"}, map[string][]msg{
+		"Merged": {{uid: 1, msgID: "old@x"}},
+	}))
+
+	srcA := newAppClient(t, srcASrv, "a")
+	srcB := newAppClient(t, srcBSrv, "b")
+	dstC := newAppClient(t, dstSrv, "dst")
+
+	pw, srcTr, dstTr := makePlanPW()
+	sources := []planSource{
+		{client: srcA, key: "a", label: "a", mappings: []config.DirectoryMapping{{Source: "INBOX", Destination: "Merged"}}},
+		{client: srcB, key: "b", label: "b", mappings: []config.DirectoryMapping{{Source: "Support", Destination: "Merged"}}},
+	}
+
+	summary, err := buildSyncPlan(context.Background(), sources, dstC, srcTr, dstTr, pw, "dst", false)
+	if err != nil {
+		t.Fatalf("buildSyncPlan: %v", err)
+	}
+	if len(summary.Plans) != 2 {
+		t.Fatalf("Plans=%d, want 2: %+v", len(summary.Plans), summary.Plans)
+	}
+	a, b := summary.Plans[0], summary.Plans[1]
+	if a.Source != "a" || !slices.Equal(a.SrcUIDs, []uint32{1, 2}) {
+		t.Errorf("plan a = %s %v, want a [1 2]", a.Source, a.SrcUIDs)
+	}
+	if b.Source != "b" || !slices.Equal(b.SrcUIDs, []uint32{2}) || b.NewMessages != 1 {
+		t.Errorf("plan b = %s %v (%d new), want b [2] (1 new)", b.Source, b.SrcUIDs, b.NewMessages)
+	}
+	if summary.TotalNew != 3 {
+		t.Errorf("TotalNew=%d, want 3", summary.TotalNew)
+	}
+	if n := dstSrv.callCount("EXAMINE") + dstSrv.callCount("SELECT"); n != 1 {
+		t.Errorf("destination folder opened %d times, want 1", n)
+	}
+}
+
 // Test_buildSyncPlan_dstMissing_setsDestinationFolderExistsFalse asserts that
 // when the destination folder is absent from the mailbox cache, the plan sets
 // DestinationFolderExists=false and no FETCH is issued on the dst side.
@@ -354,7 +474,7 @@ func Test_buildSyncPlan_dstMissing_setsDestinationFolderExistsFalse(t *testing.T
 	pw, srcTr, dstTr := makePlanPW()
 	mappings := []config.DirectoryMapping{{Source: "INBOX", Destination: "INBOX"}}
 
-	summary, err := buildSyncPlan(context.Background(), srcC, dstC, mappings, srcTr, dstTr, pw, "src", "dst", false)
+	summary, err := buildSyncPlan(context.Background(), []planSource{{client: srcC, label: "src", mappings: mappings}}, dstC, srcTr, dstTr, pw, "dst", false)
 	if err != nil {
 		t.Fatalf("buildSyncPlan: %v", err)
 	}
@@ -400,7 +520,7 @@ func Test_buildSyncPlan_dstErrorIsFatal(t *testing.T) {
 	pw, srcTr, dstTr := makePlanPW()
 	mappings := []config.DirectoryMapping{{Source: "INBOX", Destination: "INBOX"}}
 
-	_, err := buildSyncPlan(context.Background(), srcC, dstC, mappings, srcTr, dstTr, pw, "src", "dst", false)
+	_, err := buildSyncPlan(context.Background(), []planSource{{client: srcC, label: "src", mappings: mappings}}, dstC, srcTr, dstTr, pw, "dst", false)
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
@@ -432,7 +552,7 @@ func Test_buildSyncPlan_srcErrorContinues_dstFineSkipsMapping(t *testing.T) {
 		{Source: "INBOX", Destination: "INBOX"},
 	}
 
-	summary, err := buildSyncPlan(context.Background(), srcC, dstC, mappings, srcTr, dstTr, pw, "src", "dst", false)
+	summary, err := buildSyncPlan(context.Background(), []planSource{{client: srcC, label: "src", mappings: mappings}}, dstC, srcTr, dstTr, pw, "dst", false)
 	if err != nil {
 		t.Fatalf("buildSyncPlan returned error: %v", err)
 	}
@@ -468,7 +588,7 @@ func Test_buildSyncPlan_srcErrorVerboseLogging(t *testing.T) {
 
 	// verbose=true exercises the pw.Log("⚠️ Failed to fetch source folder...")
 	// branch for the "Missing" folder error.
-	summary, err := buildSyncPlan(context.Background(), srcC, dstC, mappings, srcTr, dstTr, pw, "src", "dst", true)
+	summary, err := buildSyncPlan(context.Background(), []planSource{{client: srcC, label: "src", mappings: mappings}}, dstC, srcTr, dstTr, pw, "dst", true)
 	if err != nil {
 		t.Fatalf("buildSyncPlan returned error: %v", err)
 	}
@@ -774,9 +894,9 @@ func Test_buildSyncPlan_canceledContext(t *testing.T) {
 	cancel()
 
 	pw, srcTr, dstTr := makePlanPW()
-	_, err := buildSyncPlan(ctx, srcC, dstC, []config.DirectoryMapping{
+	_, err := buildSyncPlan(ctx, []planSource{{client: srcC, label: "src", mappings: []config.DirectoryMapping{
 		{Source: "INBOX", Destination: "INBOX"},
-	}, srcTr, dstTr, pw, "src", "dst", false)
+	}}}, dstC, srcTr, dstTr, pw, "dst", false)
 	if err == nil {
 		t.Fatal("expected error from canceled context, got nil")
 	}
@@ -843,18 +963,14 @@ func Test_maybeDiff_freesMaps(t *testing.T) {
 			{Source: "INBOX", Destination: "INBOX"},
 		}
 		plans := make([]FolderSyncPlan, 1)
-		var totalNew atomic.Int64
-		var totalNewSize atomic.Uint64
 
 		s := &folderScan{
-			srcMap:         map[string]uint32{"a@x": 1, "b@x": 2},
-			dstIDs:         map[string]struct{}{"b@x": {}},
-			srcFolderSize:  2000, // 2 messages summed to 2000 bytes
-			srcFolderCount: 2,
+			srcMap: map[string]client.MessageRef{"a@x": {UID: 1, Size: 1900}, "b@x": {UID: 2, Size: 100}},
+			dstIDs: map[string]struct{}{"b@x": {}},
 		}
 
 		// First call: only one side arrived — must not diff yet.
-		if maybeDiff(s, 0, mappings, plans, &totalNew, &totalNewSize) {
+		if maybeDiff(s, 0, mappings, plans) {
 			t.Fatal("maybeDiff returned true on first call, want false")
 		}
 		if s.srcMap == nil || s.dstIDs == nil {
@@ -862,7 +978,7 @@ func Test_maybeDiff_freesMaps(t *testing.T) {
 		}
 
 		// Second call: both sides arrived — diff fires.
-		if !maybeDiff(s, 0, mappings, plans, &totalNew, &totalNewSize) {
+		if !maybeDiff(s, 0, mappings, plans) {
 			t.Fatal("maybeDiff returned false on second call, want true")
 		}
 		if s.srcMap != nil {
@@ -875,15 +991,13 @@ func Test_maybeDiff_freesMaps(t *testing.T) {
 		if !slices.Equal(plans[0].SrcUIDs, want) {
 			t.Errorf("SrcUIDs=%v, want %v", plans[0].SrcUIDs, want)
 		}
-		if totalNew.Load() != 1 {
-			t.Errorf("totalNew=%d, want 1", totalNew.Load())
-		}
-		// 1 new of 2 total at 2000 bytes → 1000 bytes estimated.
-		if got := totalNewSize.Load(); got != 1000 {
-			t.Errorf("totalNewSize=%d, want 1000 (2000 × 1/2)", got)
+		if plans[0].NewMessages != 1 {
+			t.Errorf("NewMessages=%d, want 1", plans[0].NewMessages)
 		}
-		if plans[0].NewSize != 1000 {
-			t.Errorf("plans[0].NewSize=%d, want 1000", plans[0].NewSize)
+		// NewSize is the exact size of the one new message, not half the
+		// folder total.
+		if plans[0].NewSize != 1900 || plans[0].Sizes[1] != 1900 {
+			t.Errorf("NewSize=%d Sizes=%v, want 1900 for UID 1", plans[0].NewSize, plans[0].Sizes)
 		}
 	})
 
@@ -893,23 +1007,21 @@ func Test_maybeDiff_freesMaps(t *testing.T) {
 			{Source: "INBOX", Destination: "INBOX"},
 		}
 		plans := make([]FolderSyncPlan, 1)
-		var totalNew atomic.Int64
-		var totalNewSize atomic.Uint64
 
 		s := &folderScan{
 			srcErr: fmt.Errorf("src scan failed"),
-			srcMap: map[string]uint32{"a@x": 1},
+			srcMap: map[string]client.MessageRef{"a@x": {UID: 1}},
 			dstIDs: map[string]struct{}{"b@x": {}},
 		}
 
 		// First call: one side arrived — no diff yet.
-		if maybeDiff(s, 0, mappings, plans, &totalNew, &totalNewSize) {
+		if maybeDiff(s, 0, mappings, plans) {
 			t.Fatal("maybeDiff returned true on first call, want false")
 		}
 
 		// Second call: both sides arrived — error branch must free maps and
 		// leave the plan empty.
-		if !maybeDiff(s, 0, mappings, plans, &totalNew, &totalNewSize) {
+		if !maybeDiff(s, 0, mappings, plans) {
 			t.Fatal("maybeDiff returned false on second call, want true")
 		}
 		if s.srcMap != nil {
@@ -921,8 +1033,8 @@ func Test_maybeDiff_freesMaps(t *testing.T) {
 		if plans[0].SourceFolder != "" {
 			t.Errorf("plan populated despite srcErr: %+v", plans[0])
 		}
-		if totalNew.Load() != 0 {
-			t.Errorf("totalNew=%d, want 0", totalNew.Load())
+		if plans[0].NewMessages != 0 {
+			t.Errorf("NewMessages=%d, want 0", plans[0].NewMessages)
 		}
 	})
 
@@ -932,23 +1044,21 @@ func Test_maybeDiff_freesMaps(t *testing.T) {
 			{Source: "INBOX", Destination: "INBOX"},
 		}
 		plans := make([]FolderSyncPlan, 1)
-		var totalNew atomic.Int64
-		var totalNewSize atomic.Uint64
 
 		s := &folderScan{
 			dstErr: fmt.Errorf("dst scan failed"),
-			srcMap: map[string]uint32{"a@x": 1},
+			srcMap: map[string]client.MessageRef{"a@x": {UID: 1}},
 			dstIDs: map[string]struct{}{"b@x": {}},
 		}
 
 		// First call: one side arrived — no diff yet.
-		if maybeDiff(s, 0, mappings, plans, &totalNew, &totalNewSize) {
+		if maybeDiff(s, 0, mappings, plans) {
 			t.Fatal("maybeDiff returned true on first call, want false")
 		}
 
 		// Second call: both sides arrived — dstErr branch must free maps and
 		// leave the plan empty.
-		if !maybeDiff(s, 0, mappings, plans, &totalNew, &totalNewSize) {
+		if !maybeDiff(s, 0, mappings, plans) {
 			t.Fatal("maybeDiff returned false on second call, want true")
 		}
 		if s.srcMap != nil {
@@ -960,8 +1070,8 @@ func Test_maybeDiff_freesMaps(t *testing.T) {
 		if plans[0].SourceFolder != "" {
 			t.Errorf("plan populated despite dstErr: %+v", plans[0])
 		}
-		if totalNew.Load() != 0 {
-			t.Errorf("totalNew=%d, want 0", totalNew.Load())
+		if plans[0].NewMessages != 0 {
+			t.Errorf("NewMessages=%d, want 0", plans[0].NewMessages)
 		}
 	})
 }
@@ -996,7 +1106,7 @@ func Test_buildSyncPlan_srcAdvancesAheadOfDst(t *testing.T) {
 	done := make(chan struct{})
 	go func() {
 		defer close(done)
-		_, _ = buildSyncPlan(context.Background(), srcC, dstC, mappings, srcTr, dstTr, pw, "src", "dst", false)
+		_, _ = buildSyncPlan(context.Background(), []planSource{{client: srcC, label: "src", mappings: mappings}}, dstC, srcTr, dstTr, pw, "dst", false)
 	}()
 
 	// Sample after src should have finished but dst is still sleeping.
@@ -1093,7 +1203,7 @@ func Test_buildSyncPlan_dstErrorMidStream(t *testing.T) {
 	}
 
 	start := time.Now()
-	_, err := buildSyncPlan(context.Background(), srcC, dstC, mappings, srcTr, dstTr, pw, "src", "dst", false)
+	_, err := buildSyncPlan(context.Background(), []planSource{{client: srcC, label: "src", mappings: mappings}}, dstC, srcTr, dstTr, pw, "dst", false)
 	elapsed := time.Since(start)
 
 	if err == nil {
@@ -1141,7 +1251,7 @@ func Test_buildSyncPlan_cancelHaltsBothSides(t *testing.T) {
 	done := make(chan error, 1)
 	go func() {
 		done <- func() error {
-			_, err := buildSyncPlan(ctx, srcC, dstC, mappings, srcTr, dstTr, pw, "src", "dst", false)
+			_, err := buildSyncPlan(ctx, []planSource{{client: srcC, label: "src", mappings: mappings}}, dstC, srcTr, dstTr, pw, "dst", false)
 			return err
 		}()
 	}()
@@ -1253,7 +1363,7 @@ func Test_buildSyncPlan_orderPreserved(t *testing.T) {
 		{Source: "C", Destination: "C"},
 	}
 
-	summary, err := buildSyncPlan(context.Background(), srcC, dstC, mappings, srcTr, dstTr, pw, "src", "dst", false)
+	summary, err := buildSyncPlan(context.Background(), []planSource{{client: srcC, label: "src", mappings: mappings}}, dstC, srcTr, dstTr, pw, "dst", false)
 	if err != nil {
 		t.Fatalf("buildSyncPlan: %v", err)
 	}
@@ -1266,3 +1376,46 @@ func Test_buildSyncPlan_orderPreserved(t *testing.T) {
 		}
 	}
 }
+
+// Test_expandMappingsWithSubfolders_childrenInheritLimits asserts that the
+// date and size limits of a mapping carry over to its expanded subfolders.
+func Test_expandMappingsWithSubfolders_childrenInheritLimits(t *testing.T) {
+	t.Parallel()
+
+	srcSrv := newFakeServer(t)
+	srcSrv.addConnHandler(customListHandler(srcSrv, []string{"INBOX", "INBOX/Sub"}))
+	srcC := newAppClient(t, srcSrv, "src")
+	delimiter := srcC.GetDelimiter()
+
+	limits := config.Limits{MaxAge: "2y", MaxSize: "25M"}
+	mappings := []config.DirectoryMapping{{Source: "INBOX", Destination: "INBOX", Limits: limits}}
+	got, err := expandMappingsWithSubfolders(context.Background(), srcC, mappings, delimiter, delimiter, false, true)
+	if err != nil {
+		t.Fatalf("expandMappingsWithSubfolders: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("len=%d, want 2", len(got))
+	}
+	for _, m := range got {
+		if m.Limits != limits {
+			t.Errorf("%s limits = %+v, want %+v", m.Source, m.Limits, limits)
+		}
+	}
+}
+
+// Test_prefixFolder checks that the source prefix is joined with the
+// destination delimiter, without doubling a trailing one.
+func Test_prefixFolder(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		prefix, folder, delim, want string
+	}{
+		{"Legacy/SupportA", "INBOX", "/", "Legacy/SupportA/INBOX"},
+		{"Legacy.SupportA.", "Sent", ".", "Legacy.SupportA.Sent"},
+		{"Legacy", "INBOX", "", "Legacy/INBOX"},
+	} {
+		if got := prefixFolder(tt.prefix, tt.folder, tt.delim); got != tt.want {
+			t.Errorf("prefixFolder(%q, %q, %q) = %q, want %q", tt.prefix, tt.folder, tt.delim, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package app
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/greeddj/imapsync-go/internal/client"
+	"github.com/greeddj/imapsync-go/internal/secret"
+	"github.com/urfave/cli/v3"
+)
+
+// openTrace opens the --trace-imap file, truncating an earlier trace. It
+// returns a nil Tracer when the flag is unset; the returned close func is
+// always safe to call.
+func openTrace(c *cli.Command) (*client.Tracer, func(), error) {
+	path := c.String("trace-imap")
+	if path == "" {
+		return nil, func() {}, nil
+	}
+	// Traces hold folder names, addresses and message excerpts: keep
+	// them private to the user.
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open IMAP trace: %w", err)
+	}
+	return client.NewTracer(secret.NewWriter(f), c.Int("trace-literal-limit")), func() { _ = f.Close() }, nil
+}
+
+// accountLogger returns the default logger with the attributes of one
+// account: side is "source" or "destination".
+func accountLogger(side, label string) *slog.Logger {
+	return slog.With("side", side, "account", label)
+}
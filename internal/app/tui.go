@@ -0,0 +1,870 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/greeddj/imapsync-go/internal/client"
+	"github.com/greeddj/imapsync-go/internal/config"
+	"github.com/greeddj/imapsync-go/internal/progress"
+	"github.com/greeddj/imapsync-go/internal/ratelimit"
+	"github.com/greeddj/imapsync-go/internal/tui"
+	"github.com/greeddj/imapsync-go/internal/utils"
+	"github.com/jedib0t/go-pretty/v6/text"
+	"github.com/urfave/cli/v3"
+	"golang.org/x/sync/errgroup"
+)
+
+// tuiRefresh is how often the tui redraws and samples transfer rates.
+const tuiRefresh = 250 * time.Millisecond
+
+// tuiLogLines is how many log lines the tui keeps for its log pane.
+const tuiLogLines = 200
+
+// Styles of the tui.
+var (
+	tuiTitleStyle   = text.Colors{text.Bold, text.FgHiCyan}
+	tuiCursorStyle  = text.Colors{text.ReverseVideo}
+	tuiDimStyle     = text.Colors{text.FgHiBlack}
+	tuiNewStyle     = text.Colors{text.FgGreen}
+	tuiWarningStyle = text.Colors{text.FgYellow}
+)
+
+// tuiPhase is the screen the tui shows.
+type tuiPhase int
+
+const (
+	tuiLoading tuiPhase = iota
+	tuiPicking
+	tuiSyncing
+	tuiFinished
+)
+
+// tuiFolder is one source folder in the picker. plan is the result of the
+// last scan of the folder against its current destination, nil until that
+// scan is done; gen counts destination edits, so that a late result for an
+// old name is dropped.
+type tuiFolder struct {
+	plan     *FolderSyncPlan
+	info     *client.MailboxInfo
+	mapping  config.DirectoryMapping
+	gen      int
+	selected bool
+	scanning bool
+}
+
+// scanRequest asks the scanner to plan one folder; scanResult answers it.
+type scanRequest struct {
+	mapping config.DirectoryMapping
+	idx     int
+	gen     int
+}
+
+type scanResult struct {
+	plan *FolderSyncPlan
+	err  error
+	idx  int
+	gen  int
+}
+
+// tuiLoad is what the tui loads before the picker opens.
+type tuiLoad struct {
+	err      error
+	src      *client.Client
+	dst      *client.Client
+	folders  []*tuiFolder
+	dstBoxes []*client.MailboxInfo
+}
+
+// tuiApp is the state of the tui. It belongs to the goroutine running run;
+// the loader, the scanner and the monitor report back over channels.
+type tuiApp struct {
+	cfg       *config.Config
+	src       *client.Client
+	dst       *client.Client
+	log       *tuiLog
+	status    *tuiLink
+	mon       *tuiMonitor
+	reqs      chan scanRequest
+	results   chan scanResult
+	source    config.Source
+	notice    string
+	failures  string
+	limits    string
+	edit      []rune
+	folders   []*tuiFolder
+	dstBoxes  []*client.MailboxInfo
+	queue     []int
+	dstOpts   client.Options
+	srcOpts   client.Options
+	phase     tuiPhase
+	cursor    int
+	top       int
+	inflight  int
+	editing   bool
+	scanBusy  bool
+	quitArmed bool
+}
+
+// ActionTUI runs the full-screen folder picker: both accounts' folder trees
+// side by side, with the plan of every ticked folder scanned as it is
+// ticked, then a monitor of the copy with a pane per worker.
+func ActionTUI(ctx context.Context, c *cli.Command) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	cfg, err := config.New(c)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	sources := cfg.SourceList()
+	if len(sources) > 1 {
+		return errors.New("the tui needs a config with a single source account")
+	}
+	cfg.Src = sources[0].Credentials
+	providerMode := strings.ToLower(c.String("provider-limits"))
+	limitsFrom, err := resolveProviderLimits(cfg, providerMode)
+	if err != nil {
+		return err
+	}
+
+	// Warnings and errors from every part of the sync land in the log
+	// pane, and still in the log file when there is one. Loggers derived
+	// below pick the handler up.
+	events := newTUILog(tuiLogLines)
+	prev := slog.Default()
+	slog.SetDefault(slog.New(&tuiLogHandler{next: prev.Handler(), log: events}))
+	defer slog.SetDefault(prev)
+	// The tui has no banner; warn mode puts the recommendation in the log
+	// pane instead.
+	if providerMode == providerLimitsWarn {
+		rec := *cfg
+		if from := applyProviderLimits(&rec); from != (appliedLimits{}) {
+			slog.Warn("provider limits not applied, --provider-limits auto would set them", "limits", formatLimits(rec.RateLimit, from))
+		}
+	}
+
+	trace, closeTrace, err := openTrace(c)
+	if err != nil {
+		return err
+	}
+	defer closeTrace()
+
+	a := &tuiApp{
+		cfg:    cfg,
+		log:    events,
+		status: &tuiLink{},
+		source: sources[0],
+		srcOpts: client.Options{
+			UseTLS:      true,
+			Auth:        cfg.Src.Auth,
+			Label:       cfg.Src.Label,
+			ReadLimiter: ratelimit.NewLimiter(cfg.RateLimit.DownBPS),
+			Trace:       trace,
+			Logger:      accountLogger("source", cfg.Src.Label),
+		},
+		dstOpts: client.Options{
+			UseTLS:       true,
+			Auth:         cfg.Dst.Auth,
+			Label:        cfg.Dst.Label,
+			WriteLimiter: ratelimit.NewLimiter(cfg.RateLimit.UpBPS),
+			Trace:        trace,
+			Logger:       accountLogger("destination", cfg.Dst.Label),
+		},
+		limits:   formatLimits(cfg.RateLimit, limitsFrom),
+		inflight: c.Int("max-inflight-bytes"),
+		failures: c.String("failures-file"),
+	}
+
+	screen, err := tui.Open()
+	if err != nil {
+		return err
+	}
+	defer screen.Close()
+	err = a.run(ctx, screen)
+	screen.Close()
+	if a.src != nil {
+		_ = a.src.Logout()
+	}
+	if a.dst != nil {
+		_ = a.dst.Logout()
+	}
+	if err != nil || a.mon == nil {
+		return err
+	}
+	return a.mon.report(a.failures)
+}
+
+// run shows the tui until the user leaves it or ctx is canceled.
+func (a *tuiApp) run(ctx context.Context, s *tui.Screen) error {
+	// Loading and scanning stop when the tui does.
+	planCtx, stopPlan := context.WithCancel(ctx)
+	defer stopPlan()
+	loaded := make(chan tuiLoad, 1)
+	go func() { loaded <- a.load(planCtx) }()
+
+	// The copy runs under its own context, so that the user can stop it
+	// and still look at the outcome.
+	syncCtx, stopSync := context.WithCancel(ctx)
+	defer stopSync()
+	var synced chan struct{}
+
+	a.results = make(chan scanResult, 1)
+	ticker := time.NewTicker(tuiRefresh)
+	defer ticker.Stop()
+	for {
+		s.Draw(a.view(s.Size()))
+		select {
+		case <-ctx.Done():
+			a.waitFor(loaded, synced)
+			return ctx.Err()
+		case l := <-loaded:
+			loaded = nil
+			if l.err != nil {
+				return l.err
+			}
+			a.opened(planCtx, l)
+		case r := <-a.results:
+			a.scanned(r)
+		case <-synced:
+			synced = nil
+			a.phase = tuiFinished
+			a.notice = "Sync finished, press q to leave"
+		case now := <-ticker.C:
+			if a.mon != nil {
+				a.mon.sample(now)
+			}
+		case k, ok := <-s.Keys():
+			if !ok {
+				k = tui.Key{Code: tui.KeyCtrlC}
+			}
+			switch a.key(k) {
+			case tuiQuit:
+				stopPlan()
+				stopSync()
+				a.waitFor(loaded, synced)
+				return nil
+			case tuiStart:
+				if synced = a.start(syncCtx); synced != nil {
+					a.phase = tuiSyncing
+				}
+			}
+		}
+		a.pump()
+	}
+}
+
+// waitFor waits for the loader and the copy to notice their canceled
+// context. Connections the loader made are left for ActionTUI to close.
+func (a *tuiApp) waitFor(loaded chan tuiLoad, synced chan struct{}) {
+	if loaded != nil {
+		if l := <-loaded; l.err == nil {
+			a.src, a.dst = l.src, l.dst
+		}
+	}
+	if synced != nil {
+		<-synced
+	}
+}
+
+// load connects to both accounts and lists their folders.
+func (a *tuiApp) load(ctx context.Context) tuiLoad {
+	var l tuiLoad
+	var srcBoxes []*client.MailboxInfo
+	g, gCtx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		a.status.UpdateMessage("Connecting to the source...")
+		c, err := client.New(gCtx, a.cfg.Src.Server, a.cfg.Src.User, a.cfg.Src.Pass, a.srcOpts)
+		if err != nil {
+			return fmt.Errorf("source connection failed: %w", err)
+		}
+		l.src = c
+		c.SetProgressTracker(a.status)
+		if srcBoxes, err = c.ListMailboxes(gCtx); err != nil {
+			return fmt.Errorf("source list mailbox failed: %w", err)
+		}
+		return nil
+	})
+	g.Go(func() error {
+		a.status.UpdateMessage("Connecting to the destination...")
+		c, err := client.New(gCtx, a.cfg.Dst.Server, a.cfg.Dst.User, a.cfg.Dst.Pass, a.dstOpts)
+		if err != nil {
+			return fmt.Errorf("destination connection failed: %w", err)
+		}
+		l.dst = c
+		c.SetProgressTracker(a.status)
+		if l.dstBoxes, err = c.ListMailboxes(gCtx); err != nil {
+			return fmt.Errorf("destination list mailbox failed: %w", err)
+		}
+		return nil
+	})
+	l.err = g.Wait()
+
+	if l.err == nil && len(a.source.Map) > 0 {
+		var mapped []config.DirectoryMapping
+		mapped, l.err = expandMappingsWithSubfolders(ctx, l.src, a.prefixed(a.source.Map, l.dst.GetDelimiter()),
+			l.src.GetDelimiter(), l.dst.GetDelimiter(), false, true)
+		l.folders = tuiFolders(a.cfg.Limits, a.source.Prefix, srcBoxes, mapped, l.src.GetDelimiter(), l.dst.GetDelimiter())
+	} else if l.err == nil {
+		l.folders = tuiFolders(a.cfg.Limits, a.source.Prefix, srcBoxes, nil, l.src.GetDelimiter(), l.dst.GetDelimiter())
+	}
+	if l.err != nil {
+		for _, c := range []*client.Client{l.src, l.dst} {
+			if c != nil {
+				_ = c.Logout()
+			}
+		}
+		l.src, l.dst = nil, nil
+	}
+	return l
+}
+
+// prefixed returns mappings with the source's prefix put before each
+// destination, as sync does.
+func (a *tuiApp) prefixed(mappings []config.DirectoryMapping, dstDelimiter string) []config.DirectoryMapping {
+	out := slices.Clone(mappings)
+	if a.source.Prefix != "" {
+		for i := range out {
+			out[i].Destination = prefixFolder(a.source.Prefix, out[i].Destination, dstDelimiter)
+		}
+	}
+	return out
+}
+
+// tuiFolders lists every source folder for the picker, sorted by name. When
+// the config maps folders, only the mapped ones, subfolders included, start
+// ticked, with their mapped destinations; otherwise every folder starts
+// ticked and keeps its name in the destination's hierarchy delimiter, under
+// prefix if set. limits fill in what a mapping leaves unset.
+func tuiFolders(limits config.Limits, prefix string, boxes []*client.MailboxInfo, mapped []config.DirectoryMapping, srcDelimiter, dstDelimiter string) []*tuiFolder {
+	byName := make(map[string]config.DirectoryMapping, len(mapped))
+	for _, m := range mapped {
+		byName[m.Source] = m
+	}
+	folders := make([]*tuiFolder, 0, len(boxes))
+	for _, b := range boxes {
+		m, ok := byName[b.Name]
+		if !ok {
+			m = config.DirectoryMapping{Source: b.Name, Destination: autoDestination(b.Name, prefix, srcDelimiter, dstDelimiter)}
+		}
+		m.Limits = limits.Merge(m.Limits)
+		folders = append(folders, &tuiFolder{info: b, mapping: m, selected: ok || len(mapped) == 0})
+	}
+	slices.SortFunc(folders, func(x, y *tuiFolder) int { return strings.Compare(x.mapping.Source, y.mapping.Source) })
+	return folders
+}
+
+// opened shows the picker for what load found, and starts scanning the
+// ticked folders.
+func (a *tuiApp) opened(ctx context.Context, l tuiLoad) {
+	a.src, a.dst, a.folders, a.dstBoxes = l.src, l.dst, l.folders, l.dstBoxes
+	a.phase = tuiPicking
+	a.reqs = make(chan scanRequest, 1)
+	go a.scanner(ctx, a.reqs, a.results)
+	for i, f := range a.folders {
+		if f.selected {
+			a.enqueue(i)
+		}
+	}
+}
+
+// scanner plans the folders it is asked to, one at a time, on the planning
+// connections. It stops with ctx.
+func (a *tuiApp) scanner(ctx context.Context, reqs <-chan scanRequest, results chan<- scanResult) {
+	pw := progress.NewLogWriter(a.log)
+	for {
+		var req scanRequest
+		select {
+		case <-ctx.Done():
+			return
+		case req = <-reqs:
+		}
+		r := scanResult{idx: req.idx, gen: req.gen}
+		source := planSource{client: a.src, label: a.cfg.Src.Label, delimiter: a.src.GetDelimiter(), mappings: []config.DirectoryMapping{req.mapping}}
+		summary, err := buildSyncPlan(ctx, []planSource{source}, a.dst, progress.NewTracker("scan", 1), progress.NewTracker("scan", 1), pw, a.cfg.Dst.Label, false)
+		switch {
+		case err != nil:
+			r.err = err
+		case len(summary.Plans) > 0:
+			r.plan = &summary.Plans[0]
+		default:
+			// Nothing new: buildSyncPlan drops such plans.
+			exists, _ := a.dst.MailboxExists(ctx, req.mapping.Destination)
+			r.plan = &FolderSyncPlan{SourceFolder: req.mapping.Source, DestinationFolder: req.mapping.Destination, DestinationFolderExists: exists}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case results <- r:
+		}
+	}
+}
+
+// enqueue asks for a scan of folder i unless one is queued or under way.
+func (a *tuiApp) enqueue(i int) {
+	f := a.folders[i]
+	if f.scanning {
+		return
+	}
+	f.scanning = true
+	a.queue = append(a.queue, i)
+}
+
+// pump hands the next queued scan to the scanner when it is idle.
+func (a *tuiApp) pump() {
+	if a.scanBusy || len(a.queue) == 0 || a.reqs == nil {
+		return
+	}
+	i := a.queue[0]
+	a.queue = a.queue[1:]
+	a.scanBusy = true
+	a.reqs <- scanRequest{idx: i, gen: a.folders[i].gen, mapping: a.folders[i].mapping}
+}
+
+// scanned records a scan result. A result for a destination the user has
+// renamed since is dropped, and the folder is scanned again.
+func (a *tuiApp) scanned(r scanResult) {
+	a.scanBusy = false
+	f := a.folders[r.idx]
+	f.scanning = false
+	if r.gen != f.gen {
+		if f.selected {
+			a.enqueue(r.idx)
+		}
+		return
+	}
+	if r.err != nil {
+		a.log.add(fmt.Sprintf("%s scan failed: %v", f.mapping.Source, r.err))
+		a.notice = fmt.Sprintf("Scanning %s failed, see the log", f.mapping.Source)
+		return
+	}
+	f.plan = r.plan
+}
+
+// tuiAction is what a key press asks of the run loop.
+type tuiAction int
+
+const (
+	tuiNone tuiAction = iota
+	tuiQuit
+	tuiStart
+)
+
+// key handles one key press.
+func (a *tuiApp) key(k tui.Key) tuiAction {
+	if a.editing {
+		a.editKey(k)
+		return tuiNone
+	}
+	quit := k.Code == tui.KeyCtrlC || k.Code == tui.KeyEscape || (k.Code == tui.KeyRune && k.Rune == 'q')
+	if quit {
+		// Leaving mid-copy cancels it: ask first.
+		if a.phase == tuiSyncing && !a.quitArmed {
+			a.quitArmed = true
+			a.notice = "Press q again to cancel the sync"
+			return tuiNone
+		}
+		return tuiQuit
+	}
+	a.quitArmed = false
+	if a.phase != tuiPicking || len(a.folders) == 0 {
+		return tuiNone
+	}
+	a.notice = ""
+	switch k.Code {
+	case tui.KeyUp:
+		a.cursor = max(0, a.cursor-1)
+	case tui.KeyDown:
+		a.cursor = min(len(a.folders)-1, a.cursor+1)
+	case tui.KeyPageUp:
+		a.cursor = max(0, a.cursor-10)
+	case tui.KeyPageDown:
+		a.cursor = min(len(a.folders)-1, a.cursor+10)
+	case tui.KeyHome:
+		a.cursor = 0
+	case tui.KeyEnd:
+		a.cursor = len(a.folders) - 1
+	case tui.KeyEnter:
+		a.startEdit()
+	case tui.KeyRune:
+		switch k.Rune {
+		case 'k':
+			a.cursor = max(0, a.cursor-1)
+		case 'j':
+			a.cursor = min(len(a.folders)-1, a.cursor+1)
+		case ' ':
+			a.toggle(a.cursor, !a.folders[a.cursor].selected)
+		case 'a':
+			all := !slices.ContainsFunc(a.folders, func(f *tuiFolder) bool { return !f.selected })
+			for i := range a.folders {
+				a.toggle(i, !all)
+			}
+		case 'e', 'r':
+			a.startEdit()
+		case 's':
+			return tuiStart
+		}
+	}
+	return tuiNone
+}
+
+// toggle ticks or unticks folder i. A ticked folder without a plan for its
+// destination is scanned.
+func (a *tuiApp) toggle(i int, on bool) {
+	f := a.folders[i]
+	f.selected = on
+	if on && f.plan == nil {
+		a.enqueue(i)
+	}
+}
+
+// startEdit opens the destination name of the folder under the cursor for
+// editing.
+func (a *tuiApp) startEdit() {
+	a.editing = true
+	a.edit = []rune(a.folders[a.cursor].mapping.Destination)
+}
+
+// editKey handles a key press while a destination name is edited.
+func (a *tuiApp) editKey(k tui.Key) {
+	switch k.Code {
+	case tui.KeyRune:
+		a.edit = append(a.edit, k.Rune)
+	case tui.KeyBackspace:
+		if len(a.edit) > 0 {
+			a.edit = a.edit[:len(a.edit)-1]
+		}
+	case tui.KeyCtrlU:
+		a.edit = a.edit[:0]
+	case tui.KeyEscape, tui.KeyCtrlC:
+		a.editing = false
+	case tui.KeyEnter:
+		a.editing = false
+		name := strings.TrimSpace(string(a.edit))
+		f := a.folders[a.cursor]
+		if name == "" || name == f.mapping.Destination {
+			return
+		}
+		f.mapping.Destination = name
+		f.plan = nil
+		f.gen++
+		f.selected = true
+		a.enqueue(a.cursor)
+	}
+}
+
+// tuiTotals sums the plans of the ticked folders.
+type tuiTotals struct {
+	creates  []string
+	bytes    uint64
+	folders  int
+	messages int
+	scanning int
+	active   int
+}
+
+// totals sums the plans of the ticked folders.
+func (a *tuiApp) totals() tuiTotals {
+	var t tuiTotals
+	for _, f := range a.folders {
+		if !f.selected {
+			continue
+		}
+		t.folders++
+		switch {
+		case f.scanning || f.plan == nil:
+			t.scanning++
+		case f.plan.NewMessages > 0:
+			t.active++
+			t.messages += f.plan.NewMessages
+			t.bytes += f.plan.NewSize
+			if !f.plan.DestinationFolderExists && !slices.Contains(t.creates, f.plan.DestinationFolder) {
+				t.creates = append(t.creates, f.plan.DestinationFolder)
+			}
+		}
+	}
+	return t
+}
+
+// start begins the copy of the ticked folders, unless a scan is still
+// under way or there is nothing to copy. The returned channel is closed
+// when the copy is over.
+func (a *tuiApp) start(ctx context.Context) chan struct{} {
+	if a.phase != tuiPicking {
+		return nil
+	}
+	t := a.totals()
+	switch {
+	case t.scanning > 0:
+		a.notice = fmt.Sprintf("Still scanning %d folders", t.scanning)
+		return nil
+	case t.active == 0:
+		a.notice = "Nothing to copy in the ticked folders"
+		return nil
+	}
+	var plans []FolderSyncPlan
+	for _, f := range a.folders {
+		if f.selected && f.plan.NewMessages > 0 {
+			plans = append(plans, *f.plan)
+		}
+	}
+	a.mon = newTUIMonitor(plans, t.creates)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		a.mon.run(ctx, a)
+	}()
+	return done
+}
+
+// view renders the current screen as lines.
+func (a *tuiApp) view(width, height int) []string {
+	header := fmt.Sprintf("%s  %s@%s → %s@%s", tuiTitleStyle.Sprint("imapsync-go"),
+		a.cfg.Src.User, a.cfg.Src.Server, a.cfg.Dst.User, a.cfg.Dst.Server)
+	switch a.phase {
+	case tuiLoading:
+		lines := []string{header, "", "Loading folders... " + tuiDimStyle.Sprint(a.status.Text())}
+		for len(lines) < height-1 {
+			lines = append(lines, "")
+		}
+		return append(lines, tuiDimStyle.Sprint("q quit"))
+	case tuiPicking:
+		return a.pickView(header, width, height)
+	}
+	footer := tuiDimStyle.Sprint("q cancel the sync")
+	switch {
+	case a.notice != "":
+		footer = tuiWarningStyle.Sprint(a.notice)
+	case a.phase == tuiFinished:
+		footer = tuiDimStyle.Sprint("q quit")
+	}
+	return a.mon.view(header, a.log, footer, width, height)
+}
+
+// pickView renders the picker: the source folders with their plans beside
+// the destination tree, the plan totals, the log and the keys.
+func (a *tuiApp) pickView(header string, width, height int) []string {
+	const logHeight = 5
+	t := a.totals()
+	plan := fmt.Sprintf("Plan: %d of %d folders ticked, %d new messages (%s)",
+		t.folders, len(a.folders), t.messages, utils.FormatSize(t.bytes))
+	if len(t.creates) > 0 {
+		plan += fmt.Sprintf(", %d folders to create", len(t.creates))
+	}
+	if t.scanning > 0 {
+		plan += tuiWarningStyle.Sprintf(", scanning %d", t.scanning)
+	}
+	if eta, _, ok := transferETA(t.bytes, a.cfg.RateLimit.DownBPS, a.cfg.RateLimit.UpBPS); ok && t.bytes > 0 {
+		plan += fmt.Sprintf(", at least %s at the limit", formatETA(eta))
+	}
+
+	paneHeight := max(3, height-logHeight-5)
+	leftWidth := width * 3 / 5
+	a.top, _ = tui.Window(len(a.folders), a.cursor, a.top, paneHeight-2)
+	left := tui.Box(fmt.Sprintf("Source %s", a.cfg.Src.Label), a.sourceLines(a.top, paneHeight-2), leftWidth, paneHeight)
+	right := tui.Box(fmt.Sprintf("Destination %s", a.cfg.Dst.Label), a.destinationLines(), width-leftWidth, paneHeight)
+
+	lines := []string{header, tuiDimStyle.Sprint("Limits: " + a.limits)}
+	lines = append(lines, tui.SideBySide([][]string{left, right}, []int{leftWidth, width - leftWidth})...)
+	lines = append(lines, plan)
+	lines = append(lines, tui.Box("Log", a.log.tail(logHeight-2), width, logHeight)...)
+	switch {
+	case a.editing:
+		lines = append(lines, fmt.Sprintf("Destination for %s: %s█", a.folders[a.cursor].mapping.Source, string(a.edit)))
+	case a.notice != "":
+		lines = append(lines, tuiWarningStyle.Sprint(a.notice))
+	default:
+		lines = append(lines, tuiDimStyle.Sprint("↑↓ move  space tick  a tick all  e rename  s start  q quit"))
+	}
+	return lines
+}
+
+// sourceLines renders n source folders from start: tick, names and plan.
+func (a *tuiApp) sourceLines(start, n int) []string {
+	var lines []string
+	for i := start; i < len(a.folders) && len(lines) < n; i++ {
+		f := a.folders[i]
+		tick := "[ ]"
+		if f.selected {
+			tick = "[x]"
+		}
+		var state string
+		switch {
+		case f.scanning:
+			state = tuiWarningStyle.Sprint("scanning...")
+		case f.plan == nil:
+			state = tuiDimStyle.Sprintf("%d messages", f.info.Messages)
+		case f.plan.NewMessages == 0:
+			state = tuiDimStyle.Sprint("up to date")
+		default:
+			state = tuiNewStyle.Sprintf("%d new (%s)", f.plan.NewMessages, utils.FormatSize(f.plan.NewSize))
+		}
+		line := fmt.Sprintf("%s %s → %s  %s", tick, f.mapping.Source, f.mapping.Destination, state)
+		if i == a.cursor {
+			line = tuiCursorStyle.Sprint(text.StripEscape(line))
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// destinationLines renders the destination's folder tree with the folders
+// the plan creates, and how many messages each ticked folder brings in.
+func (a *tuiApp) destinationLines() []string {
+	incoming := make(map[string]int)
+	names := make([]string, 0, len(a.dstBoxes))
+	counts := make(map[string]uint32, len(a.dstBoxes))
+	for _, b := range a.dstBoxes {
+		names = append(names, b.Name)
+		counts[b.Name] = b.Messages
+	}
+	for _, f := range a.folders {
+		if !f.selected {
+			continue
+		}
+		dst := f.mapping.Destination
+		if f.plan != nil {
+			incoming[dst] += f.plan.NewMessages
+		}
+		if !slices.Contains(names, dst) {
+			names = append(names, dst)
+		}
+	}
+	slices.Sort(names)
+
+	delimiter := a.dst.GetDelimiter()
+	lines := make([]string, 0, len(names))
+	for _, name := range names {
+		depth, leaf := 0, name
+		if delimiter != "" {
+			parts := strings.Split(name, delimiter)
+			depth, leaf = len(parts)-1, parts[len(parts)-1]
+		}
+		line := strings.Repeat("  ", depth) + leaf
+		if n, ok := counts[name]; ok {
+			line += tuiDimStyle.Sprintf("  %d", n)
+		} else {
+			line += tuiNewStyle.Sprint("  (new)")
+		}
+		if n := incoming[name]; n > 0 {
+			line += tuiNewStyle.Sprintf("  +%d", n)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// tuiLog keeps the last lines logged, for the log pane. It is an io.Writer
+// so that a progress log writer can feed it.
+type tuiLog struct {
+	lines []string
+	max   int
+	mu    sync.Mutex
+}
+
+// newTUILog returns a log keeping max lines.
+func newTUILog(maxLines int) *tuiLog {
+	return &tuiLog{max: maxLines}
+}
+
+// add appends one line, dropping the oldest beyond max.
+func (l *tuiLog) add(line string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, text.StripEscape(line))
+	if len(l.lines) > l.max {
+		l.lines = slices.Delete(l.lines, 0, len(l.lines)-l.max)
+	}
+}
+
+// Write adds every line of p.
+func (l *tuiLog) Write(p []byte) (int, error) {
+	for line := range strings.SplitSeq(strings.TrimRight(string(p), "\n"), "\n") {
+		l.add(line)
+	}
+	return len(p), nil
+}
+
+// tail returns the last n lines.
+func (l *tuiLog) tail(n int) []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return slices.Clone(l.lines[max(0, len(l.lines)-n):])
+}
+
+// tuiLogHandler passes records on to the next handler and copies warnings
+// and errors into the tui's log.
+type tuiLogHandler struct {
+	next  slog.Handler
+	log   *tuiLog
+	attrs []slog.Attr
+}
+
+// Enabled implements slog.Handler.
+func (h *tuiLogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= slog.LevelWarn || h.next.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler.
+func (h *tuiLogHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= slog.LevelWarn {
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "%s %s %s", r.Time.Format(time.TimeOnly), r.Level, r.Message)
+		for _, attr := range h.attrs {
+			fmt.Fprintf(&sb, " %s", attr)
+		}
+		r.Attrs(func(attr slog.Attr) bool {
+			fmt.Fprintf(&sb, " %s", attr)
+			return true
+		})
+		h.log.add(sb.String())
+	}
+	if h.next.Enabled(ctx, r.Level) {
+		return h.next.Handle(ctx, r)
+	}
+	return nil
+}
+
+// WithAttrs implements slog.Handler.
+func (h *tuiLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &tuiLogHandler{next: h.next.WithAttrs(attrs), log: h.log, attrs: append(slices.Clip(h.attrs), attrs...)}
+}
+
+// WithGroup implements slog.Handler. Groups only apply to the next handler;
+// the log pane shows attributes unqualified.
+func (h *tuiLogHandler) WithGroup(name string) slog.Handler {
+	return &tuiLogHandler{next: h.next.WithGroup(name), log: h.log, attrs: h.attrs}
+}
+
+// tuiLink records the last message a Client reported, such as a reconnect
+// attempt. It implements client.ProgressTracker.
+type tuiLink struct {
+	msg atomic.Pointer[string]
+}
+
+// UpdateMessage records msg.
+func (l *tuiLink) UpdateMessage(msg string) { l.msg.Store(&msg) }
+
+// UpdateTotal implements client.ProgressTracker.
+func (l *tuiLink) UpdateTotal(int64) {}
+
+// Increment implements client.ProgressTracker.
+func (l *tuiLink) Increment(int64) {}
+
+// MarkAsErrored implements client.ProgressTracker.
+func (l *tuiLink) MarkAsErrored() {}
+
+// Text returns the last message.
+func (l *tuiLink) Text() string {
+	if p := l.msg.Load(); p != nil {
+		return *p
+	}
+	return ""
+}
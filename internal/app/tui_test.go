@@ -0,0 +1,213 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/greeddj/imapsync-go/internal/client"
+	"github.com/greeddj/imapsync-go/internal/config"
+	"github.com/greeddj/imapsync-go/internal/tui"
+)
+
+// newPickerApp returns a tui in the picker with the given folders, none
+// scanned or being scanned.
+func newPickerApp(folders ...*tuiFolder) *tuiApp {
+	return &tuiApp{cfg: &config.Config{}, log: newTUILog(10), folders: folders, phase: tuiPicking}
+}
+
+// runeKey returns the key press of r.
+func runeKey(r rune) tui.Key { return tui.Key{Code: tui.KeyRune, Rune: r} }
+
+// Test_tuiFolders_mappedAndUnmapped asserts that with mappings only the mapped
+// folders start ticked, with their destinations, while the rest keep their
+// names in the destination's delimiter, under the prefix.
+func Test_tuiFolders_mappedAndUnmapped(t *testing.T) {
+	t.Parallel()
+
+	boxes := []*client.MailboxInfo{{Name: "Work.Reports"}, {Name: "INBOX"}}
+	mapped := []config.DirectoryMapping{{Source: "INBOX", Destination: "Old/Inbox", Limits: config.Limits{MaxSize: "1M"}}}
+	folders := tuiFolders(config.Limits{Since: "2024-01-01"}, "Old", boxes, mapped, ".", "/")
+
+	if len(folders) != 2 || folders[0].mapping.Source != "INBOX" {
+		t.Fatalf("folders not sorted by source: %+v", folders)
+	}
+	inbox, work := folders[0], folders[1]
+	if !inbox.selected || inbox.mapping.Destination != "Old/Inbox" {
+		t.Errorf("INBOX = %+v, want ticked to Old/Inbox", inbox.mapping)
+	}
+	if inbox.mapping.Limits.Since != "2024-01-01" || inbox.mapping.Limits.MaxSize != "1M" {
+		t.Errorf("INBOX limits = %+v, want global since and mapped max size", inbox.mapping.Limits)
+	}
+	if work.selected || work.mapping.Destination != "Old/Work/Reports" {
+		t.Errorf("Work.Reports = %+v selected %v, want unticked to Old/Work/Reports", work.mapping, work.selected)
+	}
+
+	all := tuiFolders(config.Limits{}, "", boxes, nil, ".", ".")
+	if !all[0].selected || !all[1].selected || all[1].mapping.Destination != "Work.Reports" {
+		t.Errorf("without mappings = %+v %+v, want all ticked and unchanged", all[0], all[1])
+	}
+}
+
+// Test_tuiApp_tickQueuesOneScan asserts that ticking a folder queues one scan
+// for it however often it is ticked, and that pump hands it to the scanner
+// only while the scanner is idle.
+func Test_tuiApp_tickQueuesOneScan(t *testing.T) {
+	t.Parallel()
+
+	a := newPickerApp(&tuiFolder{info: &client.MailboxInfo{Name: "INBOX"}, mapping: config.DirectoryMapping{Source: "INBOX", Destination: "INBOX"}})
+	a.reqs = make(chan scanRequest, 1)
+	a.key(runeKey(' '))
+	a.key(runeKey(' '))
+	a.key(runeKey(' '))
+	if len(a.queue) != 1 || !a.folders[0].selected {
+		t.Fatalf("queue = %v, selected = %v; want one scan of a ticked folder", a.queue, a.folders[0].selected)
+	}
+
+	a.pump()
+	a.pump()
+	if len(a.reqs) != 1 || !a.scanBusy {
+		t.Fatalf("%d requests sent, busy %v; want 1 and busy", len(a.reqs), a.scanBusy)
+	}
+	req := <-a.reqs
+	a.scanned(scanResult{idx: req.idx, gen: req.gen, plan: &FolderSyncPlan{NewMessages: 3}})
+	if a.scanBusy || a.folders[0].scanning || a.folders[0].plan.NewMessages != 3 {
+		t.Errorf("after the result: busy %v, folder %+v", a.scanBusy, a.folders[0])
+	}
+}
+
+// Test_tuiApp_renameDropsStaleScan asserts that renaming a destination while
+// it is scanned drops the result for the old name and scans again.
+func Test_tuiApp_renameDropsStaleScan(t *testing.T) {
+	t.Parallel()
+
+	a := newPickerApp(&tuiFolder{mapping: config.DirectoryMapping{Source: "INBOX", Destination: "INBOX"}, selected: true})
+	a.enqueue(0)
+	a.queue = nil // taken by the scanner
+
+	a.key(runeKey('e'))
+	a.key(tui.Key{Code: tui.KeyCtrlU})
+	for _, r := range "Archive" {
+		a.key(runeKey(r))
+	}
+	a.key(tui.Key{Code: tui.KeyEnter})
+	if a.editing || a.folders[0].mapping.Destination != "Archive" || a.folders[0].gen != 1 {
+		t.Fatalf("after the edit: editing %v, folder %+v", a.editing, a.folders[0])
+	}
+
+	a.scanned(scanResult{idx: 0, gen: 0, plan: &FolderSyncPlan{DestinationFolder: "INBOX", NewMessages: 5}})
+	if a.folders[0].plan != nil {
+		t.Errorf("stale plan kept: %+v", a.folders[0].plan)
+	}
+	if len(a.queue) != 1 || !a.folders[0].scanning {
+		t.Errorf("queue = %v, want the folder scanned again", a.queue)
+	}
+}
+
+// Test_tuiApp_startWaitsForScans asserts that the copy does not start while a
+// ticked folder is being scanned or when the ticked folders have nothing new,
+// and that totals only count ticked folders.
+func Test_tuiApp_startWaitsForScans(t *testing.T) {
+	t.Parallel()
+
+	a := newPickerApp(
+		&tuiFolder{selected: true, plan: &FolderSyncPlan{DestinationFolder: "A", NewMessages: 2, NewSize: 100}},
+		&tuiFolder{selected: true, scanning: true},
+		&tuiFolder{plan: &FolderSyncPlan{DestinationFolder: "C", NewMessages: 7, NewSize: 700}},
+	)
+	if a.start(context.Background()) != nil || !strings.Contains(a.notice, "scanning") {
+		t.Fatalf("started while scanning, notice %q", a.notice)
+	}
+
+	a.folders[1].scanning = false
+	a.folders[1].plan = &FolderSyncPlan{DestinationFolder: "B", NewMessages: 1, NewSize: 10}
+	got := a.totals()
+	if got.folders != 2 || got.messages != 3 || got.bytes != 110 || len(got.creates) != 2 {
+		t.Errorf("totals = %+v, want 2 folders, 3 messages, 110 bytes, 2 creates", got)
+	}
+
+	a.folders[0].selected, a.folders[1].selected = false, false
+	if a.start(context.Background()) != nil || !strings.Contains(a.notice, "Nothing to copy") {
+		t.Errorf("started with nothing to copy, notice %q", a.notice)
+	}
+}
+
+// Test_tuiApp_quitWhileSyncingAsksTwice asserts that leaving mid-copy takes a
+// second q, and that any other key disarms the first.
+func Test_tuiApp_quitWhileSyncingAsksTwice(t *testing.T) {
+	t.Parallel()
+
+	a := newPickerApp()
+	a.phase = tuiSyncing
+	if got := a.key(runeKey('q')); got != tuiNone {
+		t.Fatalf("first q = %v, want tuiNone", got)
+	}
+	a.key(runeKey('j'))
+	if got := a.key(runeKey('q')); got != tuiNone {
+		t.Fatalf("q after another key = %v, want tuiNone", got)
+	}
+	if got := a.key(runeKey('q')); got != tuiQuit {
+		t.Errorf("second q = %v, want tuiQuit", got)
+	}
+
+	a.phase = tuiPicking
+	a.quitArmed = false
+	if got := a.key(tui.Key{Code: tui.KeyEscape}); got != tuiQuit {
+		t.Errorf("escape in the picker = %v, want tuiQuit", got)
+	}
+}
+
+// Test_tuiLogHandler_copiesWarnings asserts that warnings reach the log pane
+// with their attributes, info records do not, and both go on to the next
+// handler.
+func Test_tuiLogHandler_copiesWarnings(t *testing.T) {
+	t.Parallel()
+
+	var next strings.Builder
+	l := newTUILog(10)
+	logger := slog.New(&tuiLogHandler{next: slog.NewTextHandler(&next, nil), log: l}).With("side", "source")
+	logger.Info("connected")
+	logger.Warn("reconnecting", "attempt", 2)
+
+	tail := l.tail(10)
+	if len(tail) != 1 || !strings.Contains(tail[0], "WARN reconnecting side=source attempt=2") {
+		t.Errorf("log pane = %q", tail)
+	}
+	if !strings.Contains(next.String(), "msg=connected") || !strings.Contains(next.String(), "msg=reconnecting") {
+		t.Errorf("next handler got %q", next.String())
+	}
+}
+
+// Test_tuiLog_keepsLast asserts that the log keeps its last lines, split on
+// newlines and stripped of escape sequences.
+func Test_tuiLog_keepsLast(t *testing.T) {
+	t.Parallel()
+
+	l := newTUILog(2)
+	_, _ = l.Write([]byte("one\n\x1b[31mtwo\x1b[0m\nthree\n"))
+	if got := l.tail(5); len(got) != 2 || got[0] != "two" || got[1] != "three" {
+		t.Errorf("tail = %q, want [two three]", got)
+	}
+}
+
+// Test_tuiMonitor_viewFillsScreen asserts that the monitor fills the screen
+// exactly and lists every plan before any worker is connected.
+func Test_tuiMonitor_viewFillsScreen(t *testing.T) {
+	t.Parallel()
+
+	m := newTUIMonitor([]FolderSyncPlan{
+		{SourceFolder: "INBOX", DestinationFolder: "INBOX", NewMessages: 3, NewSize: 3000},
+		{SourceFolder: "Sent", DestinationFolder: "Sent", NewMessages: 1, NewSize: 10},
+	}, nil)
+	lines := m.view("header", newTUILog(5), "footer", 80, 24)
+	if len(lines) != 24 || lines[len(lines)-1] != "footer" {
+		t.Fatalf("got %d lines ending %q, want 24 ending with the footer", len(lines), lines[len(lines)-1])
+	}
+	view := strings.Join(lines, "\n")
+	for _, want := range []string{"Starting: 0/4 messages", "INBOX → INBOX  0/3", "Sent → Sent  0/1"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view lacks %q:\n%s", want, view)
+		}
+	}
+}
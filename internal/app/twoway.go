@@ -0,0 +1,712 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-imap"
+	"github.com/greeddj/imapsync-go/internal/client"
+	"github.com/greeddj/imapsync-go/internal/config"
+	"github.com/greeddj/imapsync-go/internal/progress"
+	"github.com/greeddj/imapsync-go/internal/utils"
+	"github.com/urfave/cli/v3"
+	"golang.org/x/sync/errgroup"
+)
+
+// Conflict rules for --conflict: which side's flags win when both sides
+// changed a message's flags since the last two-way run.
+const (
+	conflictSource      = "source"
+	conflictDestination = "destination"
+	conflictUnion       = "union"
+	conflictNewest      = "newest"
+)
+
+// twoWayStateVersion is the format version written to the state file.
+const twoWayStateVersion = 1
+
+// twoWayOptions are the sync --two-way settings.
+type twoWayOptions struct {
+	statePath string
+	conflict  string
+	limits    string // the effective rate limits, for the preview
+	flags     bool
+	deletes   bool
+}
+
+// twoWayOptionsFrom reads and checks the two-way flags.
+func twoWayOptionsFrom(c *cli.Command) (twoWayOptions, error) {
+	o := twoWayOptions{
+		statePath: c.String("state-file"),
+		conflict:  strings.ToLower(c.String("conflict")),
+		flags:     c.Bool("sync-flags"),
+		deletes:   c.Bool("sync-deletes"),
+	}
+	if o.statePath == "" {
+		return o, errors.New("--two-way needs a --state-file")
+	}
+	switch o.conflict {
+	case conflictSource, conflictDestination, conflictUnion, conflictNewest:
+	default:
+		return o, fmt.Errorf("--conflict must be %s, %s, %s or %s, got %q", conflictSource, conflictDestination, conflictUnion, conflictNewest, o.conflict)
+	}
+	if o.deletes {
+		if err := checkQuietConfirm(c.Bool("quiet"), c.Bool("confirm"), "propagate deletions"); err != nil {
+			return o, err
+		}
+	}
+	return o, nil
+}
+
+// twoWayState is what a two-way sync remembers between runs: for every
+// folder pair, the messages that were on both sides when the last run
+// ended, with the flags each side had. A message in the state that is now
+// missing on one side was deleted there; a message not in the state is new.
+//
+// Source and Destination identify the accounts ("user@server") so a state
+// file is never applied to a different pair of mailboxes.
+type twoWayState struct {
+	Source      string       `json:"source"`
+	Destination string       `json:"destination"`
+	Pairs       []*pairState `json:"pairs"`
+	Version     int          `json:"version"`
+}
+
+// pairState is the state of one folder pair, keyed by Message-Id.
+type pairState struct {
+	Messages    map[string]seenMessage `json:"messages"`
+	Source      string                 `json:"src"`
+	Destination string                 `json:"dst"`
+}
+
+// seenMessage holds the flags a message had on each side, and since when.
+// IMAP records no time for a flag change, so SrcAt and DstAt are the times
+// of the runs that first saw each side's flags as they are.
+type seenMessage struct {
+	SrcAt time.Time `json:"src_at,omitzero"`
+	DstAt time.Time `json:"dst_at,omitzero"`
+	Src   []string  `json:"src,omitempty"`
+	Dst   []string  `json:"dst,omitempty"`
+}
+
+// stamp returns next with the times its flags were first seen: those of prev
+// for a side whose flags did not change, now for the others.
+func stamp(prev *seenMessage, next seenMessage, now time.Time) seenMessage {
+	next.SrcAt, next.DstAt = now, now
+	if prev != nil {
+		if slices.Equal(next.Src, prev.Src) {
+			next.SrcAt = prev.SrcAt
+		}
+		if slices.Equal(next.Dst, prev.Dst) {
+			next.DstAt = prev.DstAt
+		}
+	}
+	return next
+}
+
+// accountID names an account in the state file.
+func accountID(c config.Credentials) string {
+	return c.User + "@" + c.Server
+}
+
+// loadTwoWayState reads the state file at path. A missing file is a first
+// run and yields an empty state for the given accounts.
+func loadTwoWayState(path, src, dst string) (*twoWayState, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &twoWayState{Version: twoWayStateVersion, Source: src, Destination: dst}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read state file: %w", err)
+	}
+	var st twoWayState
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, fmt.Errorf("state file %s: %w", path, err)
+	}
+	if st.Version != twoWayStateVersion {
+		return nil, fmt.Errorf("state file %s has version %d, want %d", path, st.Version, twoWayStateVersion)
+	}
+	if st.Source != src || st.Destination != dst {
+		return nil, fmt.Errorf("state file %s belongs to %s ⇄ %s, not %s ⇄ %s", path, st.Source, st.Destination, src, dst)
+	}
+	return &st, nil
+}
+
+// save writes the state to path through a temporary file, so an interrupted
+// write never leaves a truncated state behind.
+func (s *twoWayState) save(path string) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write state file: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("write state file: %w", err)
+	}
+	return nil
+}
+
+// messages returns the remembered messages of a folder pair, or nil.
+func (s *twoWayState) messages(src, dst string) map[string]seenMessage {
+	for _, p := range s.Pairs {
+		if p.Source == src && p.Destination == dst {
+			return p.Messages
+		}
+	}
+	return nil
+}
+
+// set replaces the remembered messages of a folder pair.
+func (s *twoWayState) set(src, dst string, msgs map[string]seenMessage) {
+	for _, p := range s.Pairs {
+		if p.Source == src && p.Destination == dst {
+			p.Messages = msgs
+			return
+		}
+	}
+	s.Pairs = append(s.Pairs, &pairState{Source: src, Destination: dst, Messages: msgs})
+}
+
+// syncableFlags returns flags sorted, deduplicated and without the flags
+// that are never copied: \Recent cannot be set by a client, and \Deleted is
+// treated as a deletion rather than a flag. The result is never nil, so an
+// APPEND built from it sends an explicit empty flag list.
+func syncableFlags(flags []string) []string {
+	out := make([]string, 0, len(flags))
+	for _, f := range flags {
+		// Only system flags are rewritten: a keyword keeps the spelling
+		// the user gave it.
+		if strings.HasPrefix(f, "\\") {
+			f = imap.CanonicalFlag(f)
+		}
+		if f == imap.RecentFlag || f == imap.DeletedFlag || slices.Contains(out, f) {
+			continue
+		}
+		out = append(out, f)
+	}
+	slices.Sort(out)
+	return out
+}
+
+// flagChange is one flag update on one message.
+type flagChange struct {
+	add    []string
+	remove []string
+	uid    uint32
+}
+
+// diffFlags returns the change that turns have into want, and whether there
+// is anything to change. Both sets must come from syncableFlags.
+func diffFlags(uid uint32, have, want []string) (flagChange, bool) {
+	ch := flagChange{uid: uid}
+	for _, f := range want {
+		if !slices.Contains(have, f) {
+			ch.add = append(ch.add, f)
+		}
+	}
+	for _, f := range have {
+		if !slices.Contains(want, f) {
+			ch.remove = append(ch.remove, f)
+		}
+	}
+	return ch, len(ch.add) > 0 || len(ch.remove) > 0
+}
+
+// reconcileFlags picks the flags both copies of a message should end up
+// with. A side whose flags changed since the last run wins over one that did
+// not; otherwise the conflict rule decides and conflict is true.
+//
+// The newest rule picks the side whose flags were first seen later. That
+// only tells the sides apart when neither changed since the last run, e.g.
+// after a failed flag update; changes first seen by the same run, or without
+// a previous run, are merged as for union.
+func reconcileFlags(prev *seenMessage, src, dst []string, rule string) (want []string, conflict bool) {
+	if slices.Equal(src, dst) {
+		return src, false
+	}
+	if prev != nil {
+		srcChanged := !slices.Equal(src, prev.Src)
+		dstChanged := !slices.Equal(dst, prev.Dst)
+		switch {
+		case srcChanged && !dstChanged:
+			return src, false
+		case dstChanged && !srcChanged:
+			return dst, false
+		}
+	}
+	switch rule {
+	case conflictDestination:
+		return dst, true
+	case conflictNewest:
+		if prev != nil && slices.Equal(src, prev.Src) && slices.Equal(dst, prev.Dst) {
+			switch {
+			case prev.SrcAt.After(prev.DstAt):
+				return src, true
+			case prev.DstAt.After(prev.SrcAt):
+				return dst, true
+			}
+		}
+		return syncableFlags(append(slices.Clone(src), dst...)), true
+	case conflictUnion:
+		return syncableFlags(append(slices.Clone(src), dst...)), true
+	default:
+		return src, true
+	}
+}
+
+// twoWayPair is the plan for one folder pair of a two-way sync. forward
+// copies source → destination, reverse destination → source; either may be
+// empty. seen is the pair's next state for the messages already on both
+// sides, assuming the flag changes are applied.
+type twoWayPair struct {
+	seen      map[string]seenMessage
+	srcFlags  []flagChange
+	dstFlags  []flagChange
+	deleteSrc []uint32
+	deleteDst []uint32
+	forward   FolderSyncPlan
+	reverse   FolderSyncPlan
+	conflicts int
+	guarded   bool
+}
+
+// idle reports whether the pair needs no work at all.
+func (p *twoWayPair) idle() bool {
+	return p.forward.NewMessages == 0 && p.reverse.NewMessages == 0 &&
+		len(p.srcFlags) == 0 && len(p.dstFlags) == 0 &&
+		len(p.deleteSrc) == 0 && len(p.deleteDst) == 0
+}
+
+// planTwoWay compares the two sides of a folder pair with the pair's state
+// from the previous run. Messages flagged \Deleted count as absent.
+//
+// A message the scan filter kept on one side may still be on the other,
+// just outside the filter: a copy's date comes from its Date header, not
+// from the original's INTERNALDATE. Such a message is neither copied nor
+// deleted, and is left out of the state.
+//
+// Deletions are only propagated when opts.deletes is set. Without it, and
+// when one side has no messages at all while the state remembers some (a
+// folder that was emptied or replaced is more likely than a user deleting
+// everything), a message missing on one side is copied back instead.
+func planTwoWay(m config.DirectoryMapping, prev map[string]seenMessage, scan pairScan, opts twoWayOptions, now time.Time) twoWayPair {
+	live := func(states map[string]client.MessageState) map[string]client.MessageState {
+		out := make(map[string]client.MessageState, len(states))
+		for id, s := range states {
+			if !slices.ContainsFunc(s.Flags, func(f string) bool { return imap.CanonicalFlag(f) == imap.DeletedFlag }) {
+				s.Flags = syncableFlags(s.Flags)
+				out[id] = s
+			}
+		}
+		return out
+	}
+	src, dst := live(scan.src), live(scan.dst)
+
+	p := twoWayPair{
+		seen:    make(map[string]seenMessage),
+		forward: FolderSyncPlan{SourceFolder: m.Source, DestinationFolder: m.Destination, DestinationFolderExists: scan.dstExists},
+		reverse: FolderSyncPlan{SourceFolder: m.Destination, DestinationFolder: m.Source, DestinationFolderExists: true, Reverse: true},
+	}
+	deletes := opts.deletes
+	if deletes && len(prev) > 0 && (len(src) == 0 || len(dst) == 0) {
+		deletes = false
+		p.guarded = true
+	}
+
+	for id, s := range src {
+		d, onDst := dst[id]
+		_, known := prev[id]
+		switch {
+		case onDst:
+			var old *seenMessage
+			if o, ok := prev[id]; ok {
+				old = &o
+			}
+			want := seenMessage{Src: s.Flags, Dst: d.Flags}
+			if opts.flags {
+				flags, conflict := reconcileFlags(old, s.Flags, d.Flags, opts.conflict)
+				if conflict {
+					p.conflicts++
+				}
+				if ch, ok := diffFlags(s.UID, s.Flags, flags); ok {
+					p.srcFlags = append(p.srcFlags, ch)
+				}
+				if ch, ok := diffFlags(d.UID, d.Flags, flags); ok {
+					p.dstFlags = append(p.dstFlags, ch)
+				}
+				want = seenMessage{Src: flags, Dst: flags}
+			}
+			p.seen[id] = stamp(old, want, now)
+		case scan.filtered(scan.dstIDs, id):
+			// On the destination, outside the filter.
+		case known && deletes:
+			p.deleteSrc = append(p.deleteSrc, s.UID)
+		default:
+			p.forward.SrcUIDs = append(p.forward.SrcUIDs, s.UID)
+			p.forward.NewSize += uint64(s.Size)
+		}
+	}
+	for id, d := range dst {
+		if _, onSrc := src[id]; onSrc || scan.filtered(scan.srcIDs, id) {
+			continue
+		}
+		if _, known := prev[id]; known && deletes {
+			p.deleteDst = append(p.deleteDst, d.UID)
+			continue
+		}
+		p.reverse.SrcUIDs = append(p.reverse.SrcUIDs, d.UID)
+		p.reverse.NewSize += uint64(d.Size)
+	}
+
+	for _, plan := range []*FolderSyncPlan{&p.forward, &p.reverse} {
+		slices.Sort(plan.SrcUIDs)
+		plan.NewMessages = len(plan.SrcUIDs)
+	}
+	slices.Sort(p.deleteSrc)
+	slices.Sort(p.deleteDst)
+	return p
+}
+
+// pairScan is what scanPair read of a folder pair. With a filter, srcIDs
+// and dstIDs hold every Message-Id of each side, filtered out or not;
+// without one they are nil and the states cover the whole folders.
+type pairScan struct {
+	src, dst       map[string]client.MessageState
+	srcIDs, dstIDs map[string]struct{}
+	dstExists      bool
+}
+
+// filtered reports whether id is on a side whose scan filtered it out. ids
+// is that side's srcIDs or dstIDs.
+func (s pairScan) filtered(ids map[string]struct{}, id string) bool {
+	_, ok := ids[id]
+	return ok
+}
+
+// scanPair reads both sides of a folder pair. The destination folder may be
+// missing, in which case it has no messages. With a filter, the Message-Ids
+// of both whole folders are read too.
+func scanPair(ctx context.Context, srcClient, dstClient *client.Client, m config.DirectoryMapping, f client.Filter) (pairScan, error) {
+	var scan pairScan
+	g, gCtx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		var err error
+		if scan.src, _, err = srcClient.FetchMessageStates(gCtx, m.Source, f); err != nil {
+			return fmt.Errorf("scan source folder %q: %w", m.Source, err)
+		}
+		if !f.IsZero() {
+			if scan.srcIDs, err = srcClient.FetchMessageIDSet(gCtx, m.Source); err != nil {
+				return fmt.Errorf("scan source folder %q: %w", m.Source, err)
+			}
+		}
+		return nil
+	})
+	g.Go(func() error {
+		var err error
+		if scan.dstExists, err = dstClient.MailboxExists(gCtx, m.Destination); err != nil || !scan.dstExists {
+			return err
+		}
+		if scan.dst, _, err = dstClient.FetchMessageStates(gCtx, m.Destination, f); err != nil {
+			return fmt.Errorf("scan destination folder %q: %w", m.Destination, err)
+		}
+		if !f.IsZero() {
+			if scan.dstIDs, err = dstClient.FetchMessageIDSet(gCtx, m.Destination); err != nil {
+				return fmt.Errorf("scan destination folder %q: %w", m.Destination, err)
+			}
+		}
+		return nil
+	})
+	if err := g.Wait(); err != nil {
+		return pairScan{}, err
+	}
+	return scan, nil
+}
+
+// mappingFilter resolves the limits of a mapping into a scan filter.
+func mappingFilter(m config.DirectoryMapping, now time.Time) (client.Filter, error) {
+	b, err := m.Limits.Resolve(now)
+	if err != nil {
+		return client.Filter{}, fmt.Errorf("limits for %s: %w", m.Source, err)
+	}
+	return client.Filter{Since: b.Since, Before: b.Before, Search: b.Search, MinSize: b.MinSize, MaxSize: b.MaxSize}, nil
+}
+
+// runTwoWay is sync --two-way: it copies missing messages in both
+// directions and, on request, reconciles flags and deletions, remembering
+// what both sides held in the state file. Limits apply to both sides, so a
+// message outside them on either side is neither copied nor deleted.
+func runTwoWay(ctx context.Context, c *cli.Command, cfg *config.Config, src planSource, dstClient *client.Client, dstDelimiter string, srcOpts, dstOpts client.Options, opts twoWayOptions, verbose, quiet, autoConfirm bool) error {
+	state, err := loadTwoWayState(opts.statePath, accountID(cfg.Src), accountID(cfg.Dst))
+	if err != nil {
+		return err
+	}
+
+	mappings, err := expandMappingsWithSubfolders(ctx, src.client, src.mappings, src.delimiter, dstDelimiter, verbose, quiet)
+	if err != nil {
+		return fmt.Errorf("failed to expand mappings: %w", err)
+	}
+	// A destination folder fed by two source folders has no single place
+	// to copy its own new messages back to.
+	for i, m := range mappings {
+		for _, o := range mappings[:i] {
+			if o.Destination == m.Destination {
+				return fmt.Errorf("--two-way: %s and %s both map to %s", o.Source, m.Source, m.Destination)
+			}
+		}
+	}
+
+	pw := progress.NewWriter(1, quiet)
+	pw.Start()
+	tracker := progress.NewTracker(fmt.Sprintf("[%s ⇄ %s] Scanning folders", src.label, cfg.Dst.Label), int64(len(mappings)))
+	traceTracker("scan-two-way", tracker.Message)
+	pw.AppendTracker(tracker)
+
+	now := time.Now()
+	filters := make([]client.Filter, len(mappings))
+	pairs := make([]*twoWayPair, len(mappings))
+	for i, m := range mappings {
+		if filters[i], err = mappingFilter(m, now); err != nil {
+			pw.Stop()
+			return err
+		}
+		tracker.UpdateMessage(fmt.Sprintf("[%s ⇄ %s] Scanning %s (%d/%d)", src.label, cfg.Dst.Label, m.Source, i+1, len(mappings)))
+		scan, err := scanPair(ctx, src.client, dstClient, m, filters[i])
+		if err != nil {
+			pw.Stop()
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		p := planTwoWay(m, state.messages(m.Source, m.Destination), scan, opts, now)
+		pairs[i] = &p
+		tracker.Increment(1)
+	}
+	tracker.MarkAsDone()
+	pw.StopAndClear()
+
+	var toDst, toSrc, deletions, flagUpdates, conflicts int
+	var totalSize uint64
+	for i, p := range pairs {
+		toDst += p.forward.NewMessages
+		toSrc += p.reverse.NewMessages
+		deletions += len(p.deleteSrc) + len(p.deleteDst)
+		flagUpdates += len(p.srcFlags) + len(p.dstFlags)
+		conflicts += p.conflicts
+		totalSize += p.forward.NewSize + p.reverse.NewSize
+		if p.guarded && !quiet {
+			fmt.Printf("⚠️  %s ⇄ %s: one side is empty, deletions are not propagated for this pair\n", mappings[i].Source, mappings[i].Destination)
+		}
+	}
+
+	if toDst+toSrc+deletions+flagUpdates == 0 {
+		for i, p := range pairs {
+			state.set(mappings[i].Source, mappings[i].Destination, p.seen)
+		}
+		if err := state.save(opts.statePath); err != nil {
+			return err
+		}
+		if !quiet {
+			fmt.Println("✅ Both sides already in sync!")
+		}
+		return nil
+	}
+
+	if !quiet {
+		fmt.Printf("🔁 Two-way changes:\n")
+		for i, p := range pairs {
+			if p.idle() {
+				continue
+			}
+			fmt.Printf("• %s ⇄ %s: %d → %s, %d ← %s, %d deletions, %d flag updates",
+				mappings[i].Source, mappings[i].Destination,
+				p.forward.NewMessages, cfg.Dst.Label, p.reverse.NewMessages, src.label,
+				len(p.deleteSrc)+len(p.deleteDst), len(p.srcFlags)+len(p.dstFlags))
+			if p.conflicts > 0 {
+				fmt.Printf(" (%d conflicts, %s wins)", p.conflicts, opts.conflict)
+			}
+			fmt.Println()
+		}
+		fmt.Printf("\n📨 Total: %d messages to copy (%s), %d deletions, %d flag updates\n",
+			toDst+toSrc, utils.FormatSize(totalSize), deletions, flagUpdates)
+		fmt.Printf("🚦 Limits: %s\n", opts.limits)
+
+		if !autoConfirm {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			confirmed, err := utils.AskConfirm(ctx, "✍️ Proceed with two-way synchronization?")
+			if err != nil {
+				return err
+			}
+			if !confirmed {
+				fmt.Println("❌ Sync canceled by user")
+				return nil
+			}
+		}
+	}
+
+	foldersToCreate := make(map[string]bool)
+	var activePlans []FolderSyncPlan
+	for _, p := range pairs {
+		if p.forward.NewMessages > 0 {
+			activePlans = append(activePlans, p.forward)
+			if !p.forward.DestinationFolderExists {
+				foldersToCreate[p.forward.DestinationFolder] = true
+			}
+		}
+		if p.reverse.NewMessages > 0 {
+			activePlans = append(activePlans, p.reverse)
+		}
+	}
+	if err := createFolders(ctx, dstClient, foldersToCreate, verbose, quiet); err != nil {
+		return err
+	}
+
+	// Flags and deletions go through the planning connections; they are
+	// small next to the copy. A pair whose updates failed is rescanned for
+	// its state below instead of trusting the plan.
+	updateErrors := 0
+	rescan := make([]bool, len(pairs))
+	for i, p := range pairs {
+		m := mappings[i]
+		for _, step := range []struct {
+			err  error
+			what string
+		}{
+			{applyFlagChanges(ctx, src.client, m.Source, p.srcFlags), "update flags in " + m.Source},
+			{applyFlagChanges(ctx, dstClient, m.Destination, p.dstFlags), "update flags in " + m.Destination},
+			{deleteMessages(ctx, src.client, m.Source, p.deleteSrc, verbose), "delete from " + m.Source},
+			{deleteMessages(ctx, dstClient, m.Destination, p.deleteDst, verbose), "delete from " + m.Destination},
+		} {
+			if step.err == nil {
+				continue
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			fmt.Printf("⚠️ Failed to %s: %v\n", step.what, step.err)
+			updateErrors++
+			rescan[i] = true
+		}
+		if p.forward.NewMessages > 0 || p.reverse.NewMessages > 0 {
+			rescan[i] = true
+		}
+	}
+
+	var copyErr error
+	if len(activePlans) > 0 {
+		lanes := []copyLane{
+			{cfg: cfg, match: func(p FolderSyncPlan) bool { return !p.Reverse }, keepFlags: opts.flags,
+				srcOpts: srcOpts, dstOpts: dstOpts},
+			reverseLane(cfg, srcOpts, dstOpts, opts.flags),
+		}
+		copyErr = copyPlans(ctx, c, lanes, activePlans, verbose, quiet)
+		if copyErr != nil && !errors.Is(copyErr, ErrSilentExit) {
+			return copyErr
+		}
+	}
+
+	// What actually landed is only known after the copy: rescan the pairs
+	// that changed, so a failed copy is not remembered as present.
+	for i, p := range pairs {
+		m := mappings[i]
+		if !rescan[i] {
+			state.set(m.Source, m.Destination, p.seen)
+			continue
+		}
+		scan, err := scanPair(ctx, src.client, dstClient, m, filters[i])
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			fmt.Printf("⚠️ Cannot record state for %s ⇄ %s: %v\n", m.Source, m.Destination, err)
+			continue
+		}
+		state.set(m.Source, m.Destination, bothSides(state.messages(m.Source, m.Destination), scan.src, scan.dst, now))
+	}
+	if err := state.save(opts.statePath); err != nil {
+		return err
+	}
+
+	if copyErr != nil {
+		return copyErr
+	}
+	if updateErrors > 0 {
+		fmt.Printf("❌ Two-way sync completed with %d failed flag or deletion updates\n", updateErrors)
+		return ErrSilentExit
+	}
+	fmt.Println("✨ Two-way sync completed successfully. ✨")
+	return nil
+}
+
+// bothSides builds a pair's state from fresh scans of both sides, stamped
+// against the pair's state from the previous run.
+func bothSides(prev map[string]seenMessage, src, dst map[string]client.MessageState, now time.Time) map[string]seenMessage {
+	out := make(map[string]seenMessage)
+	for id, s := range src {
+		if d, ok := dst[id]; ok {
+			var old *seenMessage
+			if o, ok := prev[id]; ok {
+				old = &o
+			}
+			out[id] = stamp(old, seenMessage{Src: syncableFlags(s.Flags), Dst: syncableFlags(d.Flags)}, now)
+		}
+	}
+	return out
+}
+
+// applyFlagChanges stores the flag changes on folder, one UID STORE per
+// distinct set of added or removed flags.
+func applyFlagChanges(ctx context.Context, c *client.Client, folder string, changes []flagChange) error {
+	type group struct {
+		flags []string
+		uids  []uint32
+	}
+	for _, op := range []imap.FlagsOp{imap.AddFlags, imap.RemoveFlags} {
+		var groups []*group
+		byKey := make(map[string]*group)
+		for _, ch := range changes {
+			flags := ch.add
+			if op == imap.RemoveFlags {
+				flags = ch.remove
+			}
+			if len(flags) == 0 {
+				continue
+			}
+			key := strings.Join(flags, " ")
+			g := byKey[key]
+			if g == nil {
+				g = &group{flags: flags}
+				byKey[key] = g
+				groups = append(groups, g)
+			}
+			g.uids = append(g.uids, ch.uid)
+		}
+		for _, g := range groups {
+			if err := c.StoreFlags(ctx, folder, g.uids, op, g.flags); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// deleteMessages removes uids from folder. Servers without UIDPLUS keep the
+// messages flagged \Deleted, which later two-way runs treat as absent.
+func deleteMessages(ctx context.Context, c *client.Client, folder string, uids []uint32, verbose bool) error {
+	expunged, err := c.DeleteMessages(ctx, folder, uids)
+	if err == nil && !expunged && len(uids) > 0 && verbose {
+		fmt.Printf("ℹ️  %d messages in %s flagged \\Deleted; the server has no UIDPLUS, so they are not expunged\n", len(uids), folder)
+	}
+	return err
+}
@@ -0,0 +1,279 @@
+package app
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/greeddj/imapsync-go/internal/client"
+	"github.com/greeddj/imapsync-go/internal/config"
+	"github.com/urfave/cli/v3"
+)
+
+var twoWayMapping = config.DirectoryMapping{Source: "INBOX", Destination: "Inbox"}
+
+// Test_syncableFlags asserts that flags are canonicalized, sorted and
+// deduplicated, and that \Recent and \Deleted are dropped.
+func Test_syncableFlags(t *testing.T) {
+	t.Parallel()
+
+	got := syncableFlags([]string{"\\seen", "$Label", "\\Recent", "\\Seen", "\\Deleted", "\\Flagged"})
+	want := []string{"$Label", "\\Flagged", "\\Seen"}
+	if !slices.Equal(got, want) {
+		t.Errorf("syncableFlags = %v, want %v", got, want)
+	}
+	if got := syncableFlags(nil); got == nil {
+		t.Error("syncableFlags(nil) = nil, want empty slice")
+	}
+}
+
+// Test_planTwoWay_newAndDeleted asserts that messages unknown to the state
+// are copied across, and that known messages missing on one side are deleted
+// on the other only with deletes enabled.
+func Test_planTwoWay_newAndDeleted(t *testing.T) {
+	t.Parallel()
+
+	prev := map[string]seenMessage{"<both@x>": {}, "<gone-dst@x>": {}, "<gone-src@x>": {}}
+	src := map[string]client.MessageState{
+		"<both@x>":     {UID: 1},
+		"<gone-dst@x>": {UID: 2},
+		"<new-src@x>":  {UID: 3, Size: 100},
+	}
+	dst := map[string]client.MessageState{
+		"<both@x>":     {UID: 10},
+		"<gone-src@x>": {UID: 11},
+		"<new-dst@x>":  {UID: 12, Size: 50},
+		"<trashed@x>":  {UID: 13, Flags: []string{"\\Deleted"}},
+	}
+
+	p := planTwoWay(twoWayMapping, prev, pairScan{src: src, dst: dst, dstExists: true}, twoWayOptions{deletes: true}, time.Time{})
+	if !slices.Equal(p.forward.SrcUIDs, []uint32{3}) || p.forward.NewSize != 100 {
+		t.Errorf("forward = %v (%d bytes), want [3] (100 bytes)", p.forward.SrcUIDs, p.forward.NewSize)
+	}
+	if !p.reverse.Reverse || p.reverse.SourceFolder != "Inbox" || !slices.Equal(p.reverse.SrcUIDs, []uint32{12}) {
+		t.Errorf("reverse = %+v, want Inbox → INBOX with [12]", p.reverse)
+	}
+	if !slices.Equal(p.deleteSrc, []uint32{2}) || !slices.Equal(p.deleteDst, []uint32{11}) {
+		t.Errorf("deletes = src %v dst %v, want src [2] dst [11]", p.deleteSrc, p.deleteDst)
+	}
+	if len(p.seen) != 1 {
+		t.Errorf("seen = %v, want only <both@x>", p.seen)
+	}
+
+	p = planTwoWay(twoWayMapping, prev, pairScan{src: src, dst: dst, dstExists: true}, twoWayOptions{}, time.Time{})
+	if len(p.deleteSrc)+len(p.deleteDst) != 0 {
+		t.Errorf("deletes without opts.deletes: src %v dst %v", p.deleteSrc, p.deleteDst)
+	}
+	if !slices.Equal(p.forward.SrcUIDs, []uint32{2, 3}) || !slices.Equal(p.reverse.SrcUIDs, []uint32{11, 12}) {
+		t.Errorf("copies = forward %v reverse %v, want [2 3] and [11 12]", p.forward.SrcUIDs, p.reverse.SrcUIDs)
+	}
+}
+
+// Test_planTwoWay_emptySideGuardsDeletes asserts that an emptied side does
+// not wipe the other one: deletions are withheld and the messages copied back.
+func Test_planTwoWay_emptySideGuardsDeletes(t *testing.T) {
+	t.Parallel()
+
+	prev := map[string]seenMessage{"<a@x>": {}, "<b@x>": {}}
+	src := map[string]client.MessageState{"<a@x>": {UID: 1}, "<b@x>": {UID: 2}}
+
+	p := planTwoWay(twoWayMapping, prev, pairScan{src: src}, twoWayOptions{deletes: true}, time.Time{})
+	if !p.guarded || len(p.deleteSrc) != 0 {
+		t.Errorf("guarded = %v, deleteSrc = %v; want guard and no deletions", p.guarded, p.deleteSrc)
+	}
+	if !slices.Equal(p.forward.SrcUIDs, []uint32{1, 2}) || p.forward.DestinationFolderExists {
+		t.Errorf("forward = %+v, want both messages into a missing folder", p.forward)
+	}
+}
+
+// Test_planTwoWay_filterDrift asserts that a known message the filter kept
+// on one side only, because the copy's date drifted across the boundary, is
+// neither deleted nor copied while the other side still holds it.
+func Test_planTwoWay_filterDrift(t *testing.T) {
+	t.Parallel()
+
+	prev := map[string]seenMessage{"<edge-src@x>": {}, "<edge-dst@x>": {}, "<gone@x>": {}}
+	scan := pairScan{
+		// The filter kept these on one side only.
+		src: map[string]client.MessageState{"<edge-src@x>": {UID: 1}, "<gone@x>": {UID: 2}},
+		dst: map[string]client.MessageState{"<edge-dst@x>": {UID: 10}},
+		// Unfiltered, each side still has the other's edge message.
+		srcIDs:    map[string]struct{}{"<edge-src@x>": {}, "<edge-dst@x>": {}, "<gone@x>": {}},
+		dstIDs:    map[string]struct{}{"<edge-src@x>": {}, "<edge-dst@x>": {}},
+		dstExists: true,
+	}
+
+	for _, deletes := range []bool{true, false} {
+		p := planTwoWay(twoWayMapping, prev, scan, twoWayOptions{deletes: deletes}, time.Time{})
+		if len(p.deleteDst) != 0 || len(p.reverse.SrcUIDs) != 0 {
+			t.Errorf("deletes=%v: deleteDst %v, reverse %v; want the edge message left alone", deletes, p.deleteDst, p.reverse.SrcUIDs)
+		}
+		wantDelete, wantCopy := []uint32{2}, []uint32(nil)
+		if !deletes {
+			wantDelete, wantCopy = nil, []uint32{2}
+		}
+		if !slices.Equal(p.deleteSrc, wantDelete) || !slices.Equal(p.forward.SrcUIDs, wantCopy) {
+			t.Errorf("deletes=%v: deleteSrc %v, forward %v; want %v and %v", deletes, p.deleteSrc, p.forward.SrcUIDs, wantDelete, wantCopy)
+		}
+		if len(p.seen) != 0 {
+			t.Errorf("deletes=%v: seen = %v, want nothing", deletes, p.seen)
+		}
+	}
+}
+
+// Test_reconcileFlags covers one-sided changes and every conflict rule,
+// including newest breaking a tie by when each side's flags were first seen.
+func Test_reconcileFlags(t *testing.T) {
+	t.Parallel()
+
+	seen := []string{"\\Seen"}
+	flagged := []string{"\\Flagged"}
+	prev := &seenMessage{Src: nil, Dst: nil}
+	earlier := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	later := earlier.Add(time.Hour)
+	tests := []struct {
+		name     string
+		prev     *seenMessage
+		src, dst []string
+		rule     string
+		want     []string
+		conflict bool
+	}{
+		{"equal", prev, seen, seen, conflictSource, seen, false},
+		{"only source changed", prev, seen, nil, conflictDestination, seen, false},
+		{"only destination changed", prev, nil, flagged, conflictSource, flagged, false},
+		{"both changed, source wins", prev, seen, flagged, conflictSource, seen, true},
+		{"both changed, destination wins", prev, seen, flagged, conflictDestination, flagged, true},
+		{"both changed, union", prev, seen, flagged, conflictUnion, []string{"\\Flagged", "\\Seen"}, true},
+		{"no state is a conflict", nil, seen, nil, conflictSource, seen, true},
+		{"newest, source seen later", &seenMessage{Src: seen, Dst: flagged, SrcAt: later, DstAt: earlier}, seen, flagged, conflictNewest, seen, true},
+		{"newest, destination seen later", &seenMessage{Src: seen, Dst: flagged, SrcAt: earlier, DstAt: later}, seen, flagged, conflictNewest, flagged, true},
+		{"newest, both changed in one run", prev, seen, flagged, conflictNewest, []string{"\\Flagged", "\\Seen"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, conflict := reconcileFlags(tt.prev, tt.src, tt.dst, tt.rule)
+			if !slices.Equal(got, tt.want) || conflict != tt.conflict {
+				t.Errorf("reconcileFlags = (%v, %v), want (%v, %v)", got, conflict, tt.want, tt.conflict)
+			}
+		})
+	}
+}
+
+// Test_planTwoWay_flagUpdates asserts that a flag change on one side is
+// planned as a STORE on the other, and that the next state assumes it landed.
+func Test_planTwoWay_flagUpdates(t *testing.T) {
+	t.Parallel()
+
+	prev := map[string]seenMessage{"<a@x>": {Src: []string{}, Dst: []string{}}}
+	src := map[string]client.MessageState{"<a@x>": {UID: 1, Flags: []string{"\\Seen"}}}
+	dst := map[string]client.MessageState{"<a@x>": {UID: 7}}
+
+	p := planTwoWay(twoWayMapping, prev, pairScan{src: src, dst: dst, dstExists: true}, twoWayOptions{flags: true, conflict: conflictDestination}, time.Time{})
+	if len(p.srcFlags) != 0 || len(p.dstFlags) != 1 {
+		t.Fatalf("flag changes = src %v dst %v, want one on dst", p.srcFlags, p.dstFlags)
+	}
+	if ch := p.dstFlags[0]; ch.uid != 7 || !slices.Equal(ch.add, []string{"\\Seen"}) || len(ch.remove) != 0 {
+		t.Errorf("dst change = %+v, want +\\Seen on UID 7", ch)
+	}
+	if s := p.seen["<a@x>"]; !slices.Equal(s.Src, []string{"\\Seen"}) || !slices.Equal(s.Dst, []string{"\\Seen"}) {
+		t.Errorf("seen = %+v, want \\Seen on both sides", s)
+	}
+	if p.conflicts != 0 {
+		t.Errorf("conflicts = %d, want 0", p.conflicts)
+	}
+}
+
+// Test_bothSides_stampsChanges asserts that a side whose flags are as the
+// previous run left them keeps its time, and a changed side gets the run's.
+func Test_bothSides_stampsChanges(t *testing.T) {
+	t.Parallel()
+
+	earlier := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := earlier.Add(time.Hour)
+	prev := map[string]seenMessage{"<a@x>": {Src: []string{}, Dst: []string{}, SrcAt: earlier, DstAt: earlier}}
+	src := map[string]client.MessageState{"<a@x>": {UID: 1, Flags: []string{"\\Seen"}}, "<new@x>": {UID: 2}}
+	dst := map[string]client.MessageState{"<a@x>": {UID: 7}, "<new@x>": {UID: 8}}
+
+	got := bothSides(prev, src, dst, now)
+	if a := got["<a@x>"]; !a.SrcAt.Equal(now) || !a.DstAt.Equal(earlier) {
+		t.Errorf("<a@x> at src %v dst %v, want src %v dst %v", a.SrcAt, a.DstAt, now, earlier)
+	}
+	if n := got["<new@x>"]; !n.SrcAt.Equal(now) || !n.DstAt.Equal(now) {
+		t.Errorf("<new@x> at src %v dst %v, want both %v", n.SrcAt, n.DstAt, now)
+	}
+}
+
+// Test_twoWayState_roundTrip asserts that a saved state loads back, that a
+// missing file is an empty state, and that another account pair is refused.
+func Test_twoWayState_roundTrip(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "state.json")
+	st, err := loadTwoWayState(path, "a@src", "b@dst")
+	if err != nil || len(st.Pairs) != 0 {
+		t.Fatalf("load missing = (%+v, %v), want empty state", st, err)
+	}
+	st.set("INBOX", "Inbox", map[string]seenMessage{"<a@x>": {Src: []string{"\\Seen"}}})
+	st.set("INBOX", "Inbox", map[string]seenMessage{"<b@x>": {}})
+	if err := st.save(path); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	got, err := loadTwoWayState(path, "a@src", "b@dst")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(got.Pairs) != 1 {
+		t.Fatalf("pairs = %d, want 1", len(got.Pairs))
+	}
+	if msgs := got.messages("INBOX", "Inbox"); len(msgs) != 1 || msgs["<b@x>"].Src != nil {
+		t.Errorf("messages = %+v, want only <b@x>", msgs)
+	}
+
+	if _, err := loadTwoWayState(path, "a@src", "c@dst"); err == nil || !strings.Contains(err.Error(), "belongs to") {
+		t.Errorf("load with other account: err = %v, want mismatch", err)
+	}
+}
+
+// Test_twoWayOptionsFrom_quietDeletesNeedConfirm asserts that --sync-deletes
+// with --quiet is refused unless --confirm is also given.
+func Test_twoWayOptionsFrom_quietDeletesNeedConfirm(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		args    []string
+		wantErr bool
+	}{
+		{[]string{"--sync-deletes", "--quiet"}, true},
+		{[]string{"--sync-deletes", "--quiet", "--confirm"}, false},
+		{[]string{"--sync-deletes"}, false},
+		{[]string{"--quiet"}, false},
+	} {
+		var err error
+		cmd := &cli.Command{
+			Flags: []cli.Flag{
+				&cli.StringFlag{Name: "state-file", Value: "state.json"},
+				&cli.StringFlag{Name: "conflict", Value: conflictSource},
+				&cli.BoolFlag{Name: "sync-flags"},
+				&cli.BoolFlag{Name: "sync-deletes"},
+				&cli.BoolFlag{Name: "quiet"},
+				&cli.BoolFlag{Name: "confirm"},
+			},
+			Action: func(_ context.Context, c *cli.Command) error {
+				_, err = twoWayOptionsFrom(c)
+				return nil
+			},
+		}
+		if runErr := cmd.Run(context.Background(), append([]string{"sync"}, tc.args...)); runErr != nil {
+			t.Fatalf("Run %v: %v", tc.args, runErr)
+		}
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%v: err = %v, wantErr %v", tc.args, err, tc.wantErr)
+		}
+	}
+}
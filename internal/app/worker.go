@@ -1,14 +1,20 @@
 package app
 
 import (
+	"cmp"
 	"context"
 	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/emersion/go-imap"
 	"github.com/greeddj/imapsync-go/internal/client"
 	"github.com/greeddj/imapsync-go/internal/config"
+	"github.com/greeddj/imapsync-go/internal/metrics"
 	"github.com/greeddj/imapsync-go/internal/progress"
+	"github.com/greeddj/imapsync-go/internal/ratelimit"
 	"github.com/jedib0t/go-pretty/v6/text"
 )
 
@@ -24,9 +30,25 @@ var (
 // pre-allocated once per sync and reused across every plan handed to them,
 // so we pay the TLS handshake + LOGIN + LIST cost exactly once per worker
 // instead of once per plan-chunk.
+//
+// budget and failures are shared by every worker of a sync: budget caps the
+// fetched bodies waiting for APPEND, failures collects the messages that
+// could not be copied. Either may be nil. keepFlags copies each message's
+// flags instead of marking the copy \Seen. current names the message last
+// fetched, by subject or else Message-Id, for the tui. traffic counts the
+// bytes on both connections, so that the trackers of the plans the worker
+// copies can take their rate from it; it is nil for workers built outside
+// newSyncWorkerPool. logger is the destination connection's, carrying the
+// side, account and worker; nil means the default logger.
 type syncWorker struct {
-	src *client.Client
-	dst *client.Client
+	src       *client.Client
+	dst       *client.Client
+	logger    *slog.Logger
+	traffic   *ratelimit.Counter
+	budget    *byteBudget
+	failures  *failureLog
+	current   atomic.Pointer[string]
+	keepFlags bool
 }
 
 // syncWorkerPool owns a fixed-size set of syncWorkers. close() Logs out of
@@ -54,127 +76,448 @@ func newSyncWorkerPool(ctx context.Context, cfg *config.Config, srcOpts, dstOpts
 			pool.close()
 			return nil, err
 		}
-		s, err := client.New(ctx, cfg.Src.Server, cfg.Src.User, cfg.Src.Pass, srcOpts)
+		// Message bodies are read on the source, or on the destination
+		// for a reverse plan; either way the bytes read are the copy.
+		traffic := &ratelimit.Counter{}
+		wSrc := workerOptions(srcOpts, cfg.Src.Label, i+1, traffic)
+		wDst := workerOptions(dstOpts, cfg.Dst.Label, i+1, traffic)
+		s, err := client.New(ctx, cfg.Src.Server, cfg.Src.User, cfg.Src.Pass, wSrc)
 		if err != nil {
 			pool.close()
 			return nil, fmt.Errorf("worker %d source connect: %w", i+1, err)
 		}
-		s.SetPrefix(fmt.Sprintf("%s-w%d", cfg.Src.Label, i+1))
 
-		d, err := client.New(ctx, cfg.Dst.Server, cfg.Dst.User, cfg.Dst.Pass, dstOpts)
+		d, err := client.New(ctx, cfg.Dst.Server, cfg.Dst.User, cfg.Dst.Pass, wDst)
 		if err != nil {
 			_ = s.Logout()
 			pool.close()
 			return nil, fmt.Errorf("worker %d destination connect: %w", i+1, err)
 		}
-		d.SetPrefix(fmt.Sprintf("%s-w%d", cfg.Dst.Label, i+1))
 
-		pool.all = append(pool.all, &syncWorker{src: s, dst: d})
+		pool.all = append(pool.all, &syncWorker{src: s, dst: d, logger: wDst.Logger, traffic: traffic})
 	}
 	return pool, nil
 }
 
-// runFolderSync executes one FolderSyncPlan on the given worker and returns
-// (synced, errors). It owns the lifecycle of one tracker and posts log lines
-// to pw on a per-message error.
+// workerOptions returns worker n's copy of an account's options: its label
+// and logger carry the worker number, and its bytes go to traffic. Each
+// copy starts from the account's, so the numbers never pile up.
+func workerOptions(opts client.Options, label string, n int, traffic *ratelimit.Counter) client.Options {
+	opts.Label = fmt.Sprintf("%s-w%d", label, n)
+	opts.Logger = workerLogger(opts.Logger, n)
+	opts.Counter = traffic
+	return opts
+}
+
+// log returns the worker's logger, or the default one.
+func (w *syncWorker) log() *slog.Logger {
+	if w.logger == nil {
+		return slog.Default()
+	}
+	return w.logger
+}
+
+// workerLogger adds the worker number to an account logger; a nil logger
+// stays nil.
+func workerLogger(l *slog.Logger, n int) *slog.Logger {
+	if l == nil {
+		return nil
+	}
+	return l.With("worker", n)
+}
+
+// planChunkSize is the number of source UIDs one worker takes from a plan at
+// a time. It equals the client's UID FETCH batch, so a chunk costs exactly one
+// body-fetch round-trip; plans at or below this size are never split.
+const planChunkSize = 500
+
+// planRun is the state shared by every chunk of one FolderSyncPlan. A large
+// plan is cut into UID-range chunks that several workers process at once;
+// the counters are atomic and whichever chunk finishes last settles the
+// tracker, so the folder still reports on a single progress line.
 //
+// Messages whose APPEND failed are spooled into failed and sent once more by
+// that last chunk before the plan is settled; each recovered message moves
+// from the errors counter to synced. For the summary, started and finished
+// time the plan and causes tallies why messages were finally lost.
+type planRun struct {
+	started  time.Time
+	finished time.Time
+	tr       *progress.Tracker
+	lastErr  atomic.Pointer[string]
+	causes   map[failureCause]int
+	failed   []*spooledAppend
+	plan     FolderSyncPlan
+	idx      int
+	count    int
+	synced   atomic.Int64
+	errors   atomic.Int64
+	pending  atomic.Int32
+	start    sync.Once
+	mu       sync.Mutex
+}
+
+// planChunk is one unit of work handed to a syncWorker.
+type planChunk struct {
+	run  *planRun
+	uids []uint32
+}
+
+// newPlanRun prepares the shared state for plan p split into chunks pieces.
 // planIdx is zero-based; planCount is len(activePlans) for human display.
-func runFolderSync(ctx context.Context, w *syncWorker, p FolderSyncPlan, tr *progress.Tracker, planIdx, planCount int, pw *progress.Writer, verbose bool) (synced, errors int) {
-	if err := ctx.Err(); err != nil {
-		tr.UpdateMessage(fmt.Sprintf("%d/%d Canceled", planIdx+1, planCount))
-		tr.MarkAsErrored()
-		return 0, 0
-	}
-
-	tr.UpdateTotal(int64(p.NewMessages))
-	tr.UpdateMessage(fmt.Sprintf("%d/%d %s → %s", planIdx+1, planCount, p.SourceFolder, p.DestinationFolder))
-
-	// lastUpdate throttles the per-message message-string update — it lives
-	// on the worker goroutine, so an unsynchronized time.Time is fine.
-	var lastUpdate time.Time
-	var lastErrMsg string
-
-	updateTrackerMsg := func() {
-		syncedPart := trackerSyncedStyle.Sprintf("%d↑", synced)
-		totalPart := trackerTotalStyle.Sprintf("Σ%d", p.NewMessages)
-		base := fmt.Sprintf("%d/%d (%s %s) %s → %s",
-			planIdx+1, planCount, syncedPart, totalPart, p.SourceFolder, p.DestinationFolder)
-		if errors == 0 {
-			tr.UpdateMessage(base)
-			return
+func newPlanRun(p FolderSyncPlan, tr *progress.Tracker, planIdx, planCount, chunks int) *planRun {
+	r := &planRun{tr: tr, plan: p, idx: planIdx, count: planCount}
+	r.pending.Store(int32(chunks))
+	return r
+}
+
+// chunkCount reports how many planChunkSize pieces p is split into.
+func chunkCount(p FolderSyncPlan) int {
+	return max(1, (len(p.SrcUIDs)+planChunkSize-1)/planChunkSize)
+}
+
+// splitPlans turns the active plans into a FIFO of chunks. Chunks of one
+// plan are contiguous UID ranges and stay adjacent in the queue, so workers
+// converge on the first unfinished folder instead of spreading thin across
+// all of them. The trade-off is that the destination receives a split
+// folder's messages in interleaved order.
+func splitPlans(plans []FolderSyncPlan, trackers []*progress.Tracker) ([]*planRun, []planChunk) {
+	runs := make([]*planRun, len(plans))
+	var chunks []planChunk
+	for i, p := range plans {
+		runs[i] = newPlanRun(p, trackers[i], i, len(plans), chunkCount(p))
+		for start := 0; ; start += planChunkSize {
+			end := min(start+planChunkSize, len(p.SrcUIDs))
+			chunks = append(chunks, planChunk{run: runs[i], uids: p.SrcUIDs[start:end]})
+			if end >= len(p.SrcUIDs) {
+				break
+			}
 		}
-		// Trim the inline reason so a long server message doesn't wrap
-		// the tracker line and break the rendered bar.
-		reason := lastErrMsg
-		if len(reason) > 40 {
-			reason = reason[:37] + "..."
+	}
+	return runs, chunks
+}
+
+// result returns the per-plan totals accumulated so far.
+func (r *planRun) result() (synced, errors int) {
+	return int(r.synced.Load()), int(r.errors.Load())
+}
+
+// label renders the "i/n" prefix used on every tracker line of the plan,
+// followed by the source label when the config has several sources, or by
+// "[reverse]" for a plan copying back to the source.
+func (r *planRun) label() string {
+	switch {
+	case r.plan.Reverse:
+		return fmt.Sprintf("%d/%d [reverse]", r.idx+1, r.count)
+	case r.plan.Source != "":
+		return fmt.Sprintf("%d/%d [%s]", r.idx+1, r.count, r.plan.Source)
+	}
+	return fmt.Sprintf("%d/%d", r.idx+1, r.count)
+}
+
+// updateMessage redraws the in-progress tracker line from the shared counters.
+func (r *planRun) updateMessage() {
+	synced, errors := r.result()
+	syncedPart := trackerSyncedStyle.Sprintf("%d↑", synced)
+	totalPart := trackerTotalStyle.Sprintf("Σ%d", r.plan.NewMessages)
+	base := fmt.Sprintf("%s (%s %s) %s → %s",
+		r.label(), syncedPart, totalPart, r.plan.SourceFolder, r.plan.DestinationFolder)
+	if errors == 0 {
+		r.tr.UpdateMessage(base)
+		return
+	}
+	// Trim the inline reason so a long server message doesn't wrap
+	// the tracker line and break the rendered bar.
+	var reason string
+	if p := r.lastErr.Load(); p != nil {
+		reason = *p
+	}
+	if len(reason) > 40 {
+		reason = reason[:37] + "..."
+	}
+	errPart := trackerErrorStyle.Sprintf("%d✗", errors)
+	// reason rendered without Sprintf %q so it reads as the original
+	// server message — no \"escaped\" quoting, no inline colour. The
+	// preceding ANSI reset from errPart hands control back to the
+	// terminal's default foreground.
+	r.tr.UpdateMessage(fmt.Sprintf("%s (%s %s)", base, errPart, reason))
+}
+
+// finishChunk records that one chunk is done. The last chunk of the plan
+// retries the spooled failures on its worker, then settles the tracker as
+// done, errored or canceled.
+func (r *planRun) finishChunk(ctx context.Context, w *syncWorker, pw *progress.Writer, verbose bool) {
+	if r.pending.Add(-1) != 0 {
+		return
+	}
+	r.retryFailed(ctx, w, pw, verbose)
+	r.mu.Lock()
+	r.finished = time.Now()
+	r.mu.Unlock()
+	p := r.plan
+	synced, errors := r.result()
+	level := slog.LevelInfo
+	if errors > 0 || ctx.Err() != nil {
+		level = slog.LevelWarn
+	}
+	w.log().Log(ctx, level, "folder finished", "source", p.Source, "source_folder", p.SourceFolder,
+		"destination_folder", p.DestinationFolder, "reverse", p.Reverse, "synced", synced, "errors", errors,
+		"canceled", ctx.Err() != nil)
+	switch {
+	case ctx.Err() != nil && synced == 0 && errors == 0:
+		r.tr.UpdateMessage(fmt.Sprintf("%s Canceled", r.label()))
+		r.tr.MarkAsErrored()
+	case ctx.Err() != nil:
+		r.tr.UpdateMessage(fmt.Sprintf("%s Canceled %s → %s", r.label(), p.SourceFolder, p.DestinationFolder))
+		r.tr.MarkAsErrored()
+	case errors > 0:
+		r.tr.UpdateMessage(fmt.Sprintf("%s Synced messages %d (errors: %d) %s → %s",
+			r.label(), synced, errors, p.SourceFolder, p.DestinationFolder))
+		r.tr.MarkAsErrored()
+	default:
+		r.tr.UpdateMessage(fmt.Sprintf("%s Synced messages %d %s → %s",
+			r.label(), synced, p.SourceFolder, p.DestinationFolder))
+		r.tr.MarkAsDone()
+	}
+}
+
+// skipChunk settles a chunk that was never handed to a worker because the
+// sync was canceled. Without a worker there is nothing to retry on, so a
+// last chunk only records the spooled failures as lost and settles the
+// tracker as canceled.
+func (r *planRun) skipChunk(ctx context.Context, failures *failureLog, pw *progress.Writer, verbose bool) {
+	r.finishChunk(ctx, &syncWorker{failures: failures}, pw, verbose)
+}
+
+// runFolderSync executes one whole FolderSyncPlan on the given worker and
+// returns (synced, errors). It owns the lifecycle of one tracker and posts
+// log lines to pw on a per-message error.
+//
+// planIdx is zero-based; planCount is len(activePlans) for human display.
+func runFolderSync(ctx context.Context, w *syncWorker, p FolderSyncPlan, tr *progress.Tracker, planIdx, planCount int, pw *progress.Writer, verbose bool) (synced, errors int) {
+	run := newPlanRun(p, tr, planIdx, planCount, 1)
+	runPlanChunk(ctx, w, planChunk{run: run, uids: p.SrcUIDs}, pw, verbose)
+	return run.result()
+}
+
+// sides returns the account roles the plan fetches from and appends to,
+// as the metrics label them: a reverse plan reads the destination account.
+func (r *planRun) sides() (fetch, store string) {
+	if r.plan.Reverse {
+		return "destination", "source"
+	}
+	return "source", "destination"
+}
+
+// fetched counts one message read from the plan's source folder.
+func (r *planRun) fetched(size int) {
+	side, _ := r.sides()
+	metrics.Messages.Inc(side, r.plan.SourceFolder)
+	metrics.Bytes.Add(float64(size), side, r.plan.SourceFolder)
+}
+
+// appended counts one message stored in the plan's destination folder.
+func (r *planRun) appended(size int) {
+	_, side := r.sides()
+	r.tr.AddBytes(int64(size))
+	metrics.Messages.Inc(side, r.plan.DestinationFolder)
+	metrics.Bytes.Add(float64(size), side, r.plan.DestinationFolder)
+}
+
+// fail records the failure of the message with uid and msgID on the plan,
+// logging it on w's logger.
+func (r *planRun) fail(w *syncWorker, uid uint32, msgID string, err error, pw *progress.Writer, verbose bool) {
+	r.errors.Add(1)
+	reason := err.Error()
+	r.lastErr.Store(&reason)
+	w.log().Warn("message copy failed", "source_folder", r.plan.SourceFolder, "destination_folder", r.plan.DestinationFolder,
+		"uid", uid, "message_id", msgID, "class", client.Classify(err).String(), "error", err)
+	// Without --verbose we never persist per-message failures to the log
+	// writer: at high error rates that floods the screen with hundreds of
+	// lines and scrolls the progress bars out. Operators still see the
+	// counter and the last reason via the tracker line.
+	if verbose {
+		pw.Log("Failed to append message to %s: %v", r.plan.DestinationFolder, err)
+	}
+}
+
+// failAppend records a failed APPEND and, unless the server refused the
+// message for good, spools it for the plan's retry pass. Messages that are
+// not retried go to the failures file straight away.
+func (r *planRun) failAppend(w *syncWorker, pa pendingAppend, err error, pw *progress.Writer, verbose bool) {
+	r.fail(w, pa.uid, pa.msgID, err, pw, verbose)
+	metrics.AppendFailures.Inc(client.Classify(err).String())
+	if client.Classify(err) == client.ClassPermanent {
+		r.lost(w, pa.uid, pa.msgID, len(pa.item.Body), err)
+		return
+	}
+	s, serr := spool(pa, err)
+	if serr != nil {
+		if verbose {
+			pw.Log("Cannot keep message %s for retry: %v", pa.msgID, serr)
 		}
-		errPart := trackerErrorStyle.Sprintf("%d✗", errors)
-		// reason rendered without Sprintf %q so it reads as the original
-		// server message — no \"escaped\" quoting, no inline colour. The
-		// preceding ANSI reset from errPart hands control back to the
-		// terminal's default foreground.
-		tr.UpdateMessage(fmt.Sprintf("%s (%s %s)", base, errPart, reason))
+		r.lost(w, pa.uid, pa.msgID, len(pa.item.Body), err)
+		return
+	}
+	r.mu.Lock()
+	r.failed = append(r.failed, s)
+	r.mu.Unlock()
+}
+
+// lost records a message that could not be copied, or with uid 0 a folder
+// error, in the failures file and in the plan's tally of causes.
+func (r *planRun) lost(w *syncWorker, uid uint32, msgID string, size int, err error) {
+	w.failures.record(r.plan, uid, msgID, size, err)
+	cause := failureCause{class: client.Classify(err), reason: err.Error()}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.causes == nil {
+		r.causes = make(map[failureCause]int)
 	}
+	r.causes[cause]++
+}
+
+// retryFailed sends every spooled failure once more. It runs after all
+// chunks of the plan are done, so a transient outage that outlasted the
+// per-message attempts has had the rest of the folder to clear.
+func (r *planRun) retryFailed(ctx context.Context, w *syncWorker, pw *progress.Writer, verbose bool) {
+	r.mu.Lock()
+	failed := r.failed
+	r.failed = nil
+	r.mu.Unlock()
 
-	streamErr := w.src.StreamMessagesByUIDs(ctx, p.SourceFolder, p.SrcUIDs, func(msg *imap.Message) error {
-		if err := w.dst.AppendMessage(ctx, p.DestinationFolder, msg); err != nil {
+	for _, s := range failed {
+		if ctx.Err() != nil {
+			r.lost(w, s.uid, s.msgID, s.size, s.cause)
+			s.discard()
+			continue
+		}
+		item, err := s.load()
+		if err == nil {
+			err = appendWithRetry(ctx, w.dst, r.plan.DestinationFolder, item)
+		}
+		s.discard()
+		if err != nil {
 			if ctx.Err() != nil {
-				return ctx.Err()
+				err = s.cause
+			} else {
+				metrics.AppendFailures.Inc(client.Classify(err).String())
+				w.log().Warn("message retry failed", "source_folder", r.plan.SourceFolder, "destination_folder", r.plan.DestinationFolder,
+					"uid", s.uid, "message_id", s.msgID, "class", client.Classify(err).String(), "error", err)
+				if verbose {
+					pw.Log("Retry failed for message %s in %s: %v", s.msgID, r.plan.DestinationFolder, err)
+				}
 			}
-			errors++
-			lastErrMsg = err.Error()
-			// Without --verbose we never persist per-message failures to
-			// the log writer: at high error rates that floods the screen
-			// with hundreds of lines and scrolls the progress bars out.
-			// Operators still see the counter and the last reason via the
-			// tracker line below.
-			if verbose {
-				pw.Log("Failed to append message to %s: %v", p.DestinationFolder, err)
-			}
-			if now := time.Now(); now.Sub(lastUpdate) > 100*time.Millisecond {
-				lastUpdate = now
-				updateTrackerMsg()
-			}
-			return nil
-		}
-		synced++
-		tr.Increment(1)
-		if now := time.Now(); now.Sub(lastUpdate) > 100*time.Millisecond {
-			lastUpdate = now
-			updateTrackerMsg()
+			r.lost(w, s.uid, s.msgID, s.size, err)
+			continue
 		}
+		r.errors.Add(-1)
+		r.synced.Add(1)
+		r.tr.Increment(1)
+		r.appended(len(item.Body))
 		if verbose {
-			pw.Log("Synced %d/%d to %s, processed msg id %s",
-				synced, p.NewMessages, p.DestinationFolder, msg.Envelope.MessageId)
+			pw.Log("Recovered message %s into %s on retry", s.msgID, r.plan.DestinationFolder)
 		}
-		// AppendMessage that completed in flight after ctx cancel must not
-		// continue iterating; the next message would race with Cancel() racing
-		// against the worker.
+	}
+}
+
+// dropSpooled records every failure still spooled for the plan as lost and
+// deletes its spool file. The last chunk normally drains the spool; this
+// catches a plan that never got that far, such as when the workers could
+// not connect.
+func (r *planRun) dropSpooled(failures *failureLog) {
+	r.mu.Lock()
+	failed := r.failed
+	r.failed = nil
+	r.mu.Unlock()
+
+	w := &syncWorker{failures: failures}
+	for _, s := range failed {
+		r.lost(w, s.uid, s.msgID, s.size, s.cause)
+		s.discard()
+	}
+}
+
+// runPlanChunk copies one chunk of a plan on the given worker, adding its
+// results to the shared planRun.
+//
+// The source and destination connections work as a producer/consumer pair:
+// the fetch callback queues each body and returns at once, so the next
+// bodies arrive while the current one is being appended. The shared byte
+// budget bounds how much fetched mail waits in memory.
+func runPlanChunk(ctx context.Context, w *syncWorker, ch planChunk, pw *progress.Writer, verbose bool) {
+	r := ch.run
+	defer r.finishChunk(ctx, w, pw, verbose)
+	if ctx.Err() != nil {
+		return
+	}
+	p := r.plan
+
+	r.start.Do(func() {
+		r.mu.Lock()
+		r.started = time.Now()
+		r.mu.Unlock()
+		r.tr.UpdateTotal(int64(p.NewMessages))
+		r.tr.SetBytesTotal(int64(p.NewSize))
+		r.tr.UpdateMessage(fmt.Sprintf("%s %s → %s", r.label(), p.SourceFolder, p.DestinationFolder))
+	})
+	if w.traffic != nil {
+		defer r.tr.CountWire(w.traffic.Read)()
+	}
+
+	queue := make(chan pendingAppend, pipelineDepth)
+	appender := &chunkAppender{w: w, r: r, pw: pw, multi: w.dst.HasCapability("MULTIAPPEND"), verbose: verbose}
+	appendDone := make(chan struct{})
+	go func() {
+		defer close(appendDone)
+		appender.run(ctx, queue)
+	}()
+
+	streamErr := w.src.StreamMessagesByUIDs(ctx, p.SourceFolder, ch.uids, func(msg *imap.Message) error {
 		if err := ctx.Err(); err != nil {
 			return err
 		}
-		return nil
+		pa := pendingAppend{uid: msg.Uid}
+		if msg.Envelope != nil {
+			pa.msgID = msg.Envelope.MessageId
+			current := cmp.Or(msg.Envelope.Subject, pa.msgID)
+			w.current.Store(&current)
+		}
+		item, err := client.NewAppendItem(msg)
+		if err != nil {
+			r.fail(w, pa.uid, pa.msgID, err, pw, verbose)
+			r.lost(w, pa.uid, pa.msgID, 0, err)
+			r.updateMessage()
+			return nil
+		}
+		if w.keepFlags {
+			item.Flags = syncableFlags(msg.Flags)
+		}
+		pa.item = item
+		r.fetched(len(item.Body))
+		reserved, err := w.budget.acquire(ctx, int64(len(item.Body)))
+		if err != nil {
+			return err
+		}
+		pa.reserved = reserved
+		select {
+		case queue <- pa:
+			metrics.QueueDepth.Inc()
+			return nil
+		case <-ctx.Done():
+			w.budget.release(reserved)
+			return ctx.Err()
+		}
 	})
+	close(queue)
+	<-appendDone
+
 	if ctx.Err() != nil {
-		tr.UpdateMessage(fmt.Sprintf("%d/%d Canceled %s → %s",
-			planIdx+1, planCount, p.SourceFolder, p.DestinationFolder))
-		tr.MarkAsErrored()
-		return synced, errors
+		return
 	}
 	if streamErr != nil {
 		pw.Log("Stream error for folder %s: %v", p.SourceFolder, streamErr)
-		errors++
-	}
-
-	if errors > 0 {
-		tr.UpdateMessage(fmt.Sprintf("%d/%d Synced messages %d (errors: %d) %s → %s",
-			planIdx+1, planCount, synced, errors, p.SourceFolder, p.DestinationFolder))
-		tr.MarkAsErrored()
-	} else {
-		tr.UpdateMessage(fmt.Sprintf("%d/%d Synced messages %d %s → %s",
-			planIdx+1, planCount, synced, p.SourceFolder, p.DestinationFolder))
-		tr.MarkAsDone()
+		r.errors.Add(1)
+		r.lost(w, 0, "", 0, streamErr)
 	}
-	return synced, errors
 }
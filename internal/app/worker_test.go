@@ -4,15 +4,21 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net"
+	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
 
 	"github.com/greeddj/imapsync-go/internal/client"
 	"github.com/greeddj/imapsync-go/internal/config"
+	"github.com/greeddj/imapsync-go/internal/metrics"
 	"github.com/greeddj/imapsync-go/internal/progress"
+	"github.com/greeddj/imapsync-go/internal/ratelimit"
 )
 
 // Test_runFolderSync_successCounter asserts that runFolderSync returns
@@ -66,6 +72,93 @@ func Test_runFolderSync_successCounter(t *testing.T) {
 	}
 }
 
+// Test_runFolderSync_countsWireTraffic asserts that the plan's tracker takes
+// the worker's wire traffic while the plan runs: at least the message bodies,
+// and nothing the worker moved before.
+func Test_runFolderSync_countsWireTraffic(t *testing.T) {
+	srcSrv := newFakeServer(t)
+	dstSrv := newFakeServer(t)
+
+	body := imapFullBody("wire@x")
+	srcBodies := map[string][]struct {
+		body string
+		uid  uint32
+	}{
+		"INBOX": {{uid: 1, body: body}},
+	}
+	srcSrv.addConnHandler(uidFetchBodyHandler(srcSrv, []string{"INBOX"}, srcBodies, ""))
+	dstSrv.addConnHandler(uidFetchBodyHandler(dstSrv, []string{"INBOX"}, nil, ""))
+
+	traffic := &ratelimit.Counter{}
+	connect := func(srv *fakeServer) *client.Client {
+		c, err := client.New(context.Background(), srv.ln.Addr().String(), "user", "pass", client.Options{Counter: traffic})
+		if err != nil {
+			t.Fatalf("connect: %v", err)
+		}
+		t.Cleanup(func() { _ = c.Logout() })
+		return c
+	}
+	w := &syncWorker{src: connect(srcSrv), dst: connect(dstSrv), traffic: traffic}
+	before := traffic.Read()
+	if before == 0 {
+		t.Fatal("login and LIST were not counted")
+	}
+
+	plan := FolderSyncPlan{SourceFolder: "INBOX", DestinationFolder: "INBOX", SrcUIDs: []uint32{1}, NewMessages: 1, DestinationFolderExists: true}
+	tr := progress.NewTracker("test", 1)
+	if synced, errors := runFolderSync(context.Background(), w, plan, tr, 0, 1, progress.NewWriter(1, true), false); synced != 1 || errors != 0 {
+		t.Fatalf("runFolderSync = (%d, %d), want (1, 0)", synced, errors)
+	}
+
+	n, ok := tr.WireBytes()
+	if !ok || n < int64(len(body)) || n > traffic.Read()-before {
+		t.Errorf("WireBytes = %d, %v; want between %d and %d", n, ok, len(body), traffic.Read()-before)
+	}
+}
+
+// Test_runFolderSync_countsMetrics asserts that a reverse plan counts its
+// fetches on the destination side and its APPENDs on the source side, with
+// the bytes of each message.
+func Test_runFolderSync_countsMetrics(t *testing.T) {
+	srcSrv := newFakeServer(t)
+	dstSrv := newFakeServer(t)
+
+	body := imapFullBody("metrics@x")
+	srcBodies := map[string][]struct {
+		body string
+		uid  uint32
+	}{
+		"MetricsIn": {{uid: 1, body: body}},
+	}
+	srcSrv.addConnHandler(uidFetchBodyHandler(srcSrv, []string{"MetricsIn"}, srcBodies, ""))
+	dstSrv.addConnHandler(uidFetchBodyHandler(dstSrv, []string{"MetricsOut"}, nil, ""))
+
+	w := &syncWorker{src: newAppClient(t, srcSrv, "src"), dst: newAppClient(t, dstSrv, "dst")}
+	plan := FolderSyncPlan{
+		SourceFolder:            "MetricsIn",
+		DestinationFolder:       "MetricsOut",
+		SrcUIDs:                 []uint32{1},
+		NewMessages:             1,
+		DestinationFolderExists: true,
+		Reverse:                 true,
+	}
+	pw := progress.NewWriter(1, true)
+	if synced, _ := runFolderSync(context.Background(), w, plan, progress.NewTracker("test", 1), 0, 1, pw, false); synced != 1 {
+		t.Fatalf("synced=%d, want 1", synced)
+	}
+
+	if got := metrics.Messages.Value("destination", "MetricsIn"); got != 1 {
+		t.Errorf("fetched messages = %v, want 1", got)
+	}
+	if got := metrics.Messages.Value("source", "MetricsOut"); got != 1 {
+		t.Errorf("appended messages = %v, want 1", got)
+	}
+	fetched := metrics.Bytes.Value("destination", "MetricsIn")
+	if fetched == 0 || fetched != metrics.Bytes.Value("source", "MetricsOut") {
+		t.Errorf("bytes fetched %v and appended %v differ", fetched, metrics.Bytes.Value("source", "MetricsOut"))
+	}
+}
+
 // Test_runFolderSync_appendErrorIncrementsErrorsCounter asserts that when dst
 // rejects APPEND with NO, the errors counter is incremented, synced stays 0,
 // and the tracker is marked as errored.
@@ -471,3 +564,216 @@ func badUIDFetchHandler(srv *fakeServer) func(net.Conn) {
 		}
 	}
 }
+
+// Test_splitPlans_chunksLargePlans asserts that a plan above planChunkSize is
+// cut into contiguous UID ranges sharing one planRun, while small plans stay
+// whole.
+func Test_splitPlans_chunksLargePlans(t *testing.T) {
+	t.Parallel()
+
+	big := make([]uint32, 2*planChunkSize+1)
+	for i := range big {
+		big[i] = uint32(i + 1)
+	}
+	plans := []FolderSyncPlan{
+		{SourceFolder: "INBOX", DestinationFolder: "INBOX", SrcUIDs: big, NewMessages: len(big)},
+		{SourceFolder: "Sent", DestinationFolder: "Sent", SrcUIDs: []uint32{7, 9}, NewMessages: 2},
+	}
+	trackers := []*progress.Tracker{progress.NewTracker("a", 1), progress.NewTracker("b", 1)}
+
+	runs, chunks := splitPlans(plans, trackers)
+	if len(runs) != 2 {
+		t.Fatalf("runs=%d, want 2", len(runs))
+	}
+	if len(chunks) != 4 {
+		t.Fatalf("chunks=%d, want 4", len(chunks))
+	}
+	for i := range 3 {
+		if chunks[i].run != runs[0] {
+			t.Errorf("chunk %d belongs to run %p, want INBOX run", i, chunks[i].run)
+		}
+	}
+	if got := len(chunks[0].uids) + len(chunks[1].uids) + len(chunks[2].uids); got != len(big) {
+		t.Errorf("INBOX chunks cover %d UIDs, want %d", got, len(big))
+	}
+	if chunks[1].uids[0] != planChunkSize+1 {
+		t.Errorf("second chunk starts at UID %d, want %d", chunks[1].uids[0], planChunkSize+1)
+	}
+	if chunks[3].run != runs[1] || len(chunks[3].uids) != 2 {
+		t.Errorf("small plan chunk = %+v, want the whole Sent plan", chunks[3])
+	}
+	if got := runs[0].pending.Load(); got != 3 {
+		t.Errorf("INBOX pending=%d, want 3", got)
+	}
+}
+
+// Test_runPlanChunk_sharedAccountingAcrossWorkers asserts that two chunks of
+// one plan processed by different workers add up on the shared planRun, and
+// that the tracker is settled only once the last chunk finishes.
+func Test_runPlanChunk_sharedAccountingAcrossWorkers(t *testing.T) {
+	newWorker := func(msgID string, uid uint32) *syncWorker {
+		srcSrv := newFakeServer(t)
+		dstSrv := newFakeServer(t)
+		srcBodies := map[string][]struct {
+			body string
+			uid  uint32
+		}{
+			"INBOX": {{uid: uid, body: imapFullBody(msgID)}},
+		}
+		srcSrv.addConnHandler(uidFetchBodyHandler(srcSrv, []string{"INBOX"}, srcBodies, ""))
+		dstSrv.addConnHandler(uidFetchBodyHandler(dstSrv, []string{"INBOX"}, nil, ""))
+		return &syncWorker{src: newAppClient(t, srcSrv, "src"), dst: newAppClient(t, dstSrv, "dst")}
+	}
+	w1 := newWorker("c1@x", 1)
+	w2 := newWorker("c2@x", 2)
+
+	plan := FolderSyncPlan{
+		SourceFolder:            "INBOX",
+		DestinationFolder:       "INBOX",
+		SrcUIDs:                 []uint32{1, 2},
+		NewMessages:             2,
+		DestinationFolderExists: true,
+	}
+	pw := progress.NewWriter(1, true)
+	tr := progress.NewTracker("test", 10)
+	run := newPlanRun(plan, tr, 0, 1, 2)
+
+	runPlanChunk(context.Background(), w1, planChunk{run: run, uids: []uint32{1}}, pw, false)
+	if tr.IsDone() {
+		t.Fatal("tracker settled before the last chunk finished")
+	}
+
+	runPlanChunk(context.Background(), w2, planChunk{run: run, uids: []uint32{2}}, pw, false)
+	synced, errs := run.result()
+	if synced != 2 || errs != 0 {
+		t.Errorf("result=(%d, %d), want (2, 0)", synced, errs)
+	}
+	if !tr.IsDone() || tr.IsErrored() {
+		t.Errorf("tracker done=%v errored=%v, want done and not errored", tr.IsDone(), tr.IsErrored())
+	}
+}
+
+// Test_runFolderSync_retryPassRecoversFailure asserts that a message whose
+// APPEND was rejected is sent again after the rest of the folder, and that a
+// successful retry moves it from the errors counter to synced.
+func Test_runFolderSync_retryPassRecoversFailure(t *testing.T) {
+	t.Parallel()
+
+	srcSrv := newFakeServer(t)
+	dstSrv := newFakeServer(t)
+
+	srcBodies := map[string][]struct {
+		body string
+		uid  uint32
+	}{
+		"INBOX": {
+			{uid: 1, body: imapFullBody("first@x")},
+			{uid: 2, body: imapFullBody("second@x")},
+		},
+	}
+	srcSrv.addConnHandler(uidFetchBodyHandler(srcSrv, []string{"INBOX"}, srcBodies, ""))
+	dstSrv.addConnHandler(flakyAppendHandler(dstSrv, 1))
+
+	w := &syncWorker{src: newAppClient(t, srcSrv, "src"), dst: newAppClient(t, dstSrv, "dst")}
+	plan := FolderSyncPlan{
+		SourceFolder:            "INBOX",
+		DestinationFolder:       "INBOX",
+		SrcUIDs:                 []uint32{1, 2},
+		NewMessages:             2,
+		DestinationFolderExists: true,
+	}
+
+	tr := progress.NewTracker("test", 2)
+	synced, errors := runFolderSync(context.Background(), w, plan, tr, 0, 1, progress.NewWriter(1, true), false)
+
+	if synced != 2 || errors != 0 {
+		t.Errorf("synced=%d errors=%d, want (2, 0)", synced, errors)
+	}
+	if got := dstSrv.callCount("APPEND"); got != 3 {
+		t.Errorf("APPEND count=%d, want 3", got)
+	}
+	if tr.IsErrored() {
+		t.Error("tracker marked as errored after a recovered failure")
+	}
+}
+
+// Test_planRun_skipChunkSettlesCanceledPlan asserts that after a cancel the
+// chunks no worker took still count down the plan, so the last of them
+// settles the tracker as canceled and records the spooled failures.
+func Test_planRun_skipChunkSettlesCanceledPlan(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	path := filepath.Join(t.TempDir(), "failures.jsonl")
+	failures := newFailureLog(path)
+	tr := progress.NewTracker("test", 3)
+	run := newPlanRun(FolderSyncPlan{SourceFolder: "INBOX", DestinationFolder: "INBOX", NewMessages: 3}, tr, 0, 1, 2)
+	run.failed = []*spooledAppend{{uid: 1, msgID: "<a@x>", size: 10, cause: errors.New("connection reset")}}
+	pw := progress.NewWriter(1, true)
+
+	run.skipChunk(ctx, failures, pw, false)
+	if tr.IsErrored() || tr.IsDone() {
+		t.Fatal("tracker settled before the last chunk")
+	}
+	run.skipChunk(ctx, failures, pw, false)
+	if !tr.IsErrored() {
+		t.Error("tracker not settled as canceled after the last chunk")
+	}
+	if n, err := failures.close(); n != 1 || err != nil {
+		t.Errorf("failures recorded = %d, %v; want 1 spooled message", n, err)
+	}
+	if len(run.failed) != 0 {
+		t.Errorf("spool not drained: %d left", len(run.failed))
+	}
+}
+
+// Test_workerOptions_oneWorkerAttribute asserts that every worker's logger
+// carries its own number once, rather than those of the workers before it.
+func Test_workerOptions_oneWorkerAttribute(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	account := client.Options{Logger: slog.New(slog.NewTextHandler(&buf, nil)).With("side", "source")}
+	traffic := &ratelimit.Counter{}
+	for n := 1; n <= 3; n++ {
+		o := workerOptions(account, "src", n, traffic)
+		if o.Label != fmt.Sprintf("src-w%d", n) || o.Counter != traffic {
+			t.Errorf("worker %d: label %q, counter %p", n, o.Label, o.Counter)
+		}
+		buf.Reset()
+		o.Logger.Info("connected")
+		if got := strings.Count(buf.String(), "worker="); got != 1 || !strings.Contains(buf.String(), fmt.Sprintf("worker=%d", n)) {
+			t.Errorf("worker %d logs %q, want one worker=%d", n, buf.String(), n)
+		}
+	}
+	if account.Label != "" || account.Counter != nil {
+		t.Errorf("account options changed: %+v", account)
+	}
+}
+
+// Test_planRun_failLogsOnWorkerLogger asserts that a message failure is
+// logged on the worker's logger, so the record carries the side and worker,
+// together with the folders and the message's UID and Message-Id.
+func Test_planRun_failLogsOnWorkerLogger(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	account := slog.New(slog.NewJSONHandler(&buf, nil)).With("side", "destination", "account", "new")
+	w := &syncWorker{logger: workerLogger(account, 2)}
+	run := newPlanRun(FolderSyncPlan{SourceFolder: "INBOX", DestinationFolder: "Archive", NewMessages: 1}, progress.NewTracker("test", 1), 0, 1, 1)
+
+	run.fail(w, 42, "<a@x>", errors.New("NO [OVERQUOTA] quota exceeded"), progress.NewWriter(1, true), false)
+
+	var rec map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatalf("log record %q: %v", buf.String(), err)
+	}
+	for key, want := range map[string]any{
+		"side": "destination", "worker": 2.0, "source_folder": "INBOX", "destination_folder": "Archive", "uid": 42.0, "message_id": "<a@x>",
+	} {
+		if rec[key] != want {
+			t.Errorf("record %s = %v, want %v", key, rec[key], want)
+		}
+	}
+}
@@ -1,23 +1,102 @@
 package client
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"strconv"
+	"time"
 
 	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/utf7"
 )
 
+// asyncLiteralLimit is the largest literal go-imap itself sends without
+// waiting for a continuation when the server advertises LITERAL+ or
+// LITERAL-. Above it we only skip the round-trip under LITERAL+ (RFC 7888),
+// which has no size restriction.
+const asyncLiteralLimit = 4096
+
+// AppendItem is one message queued for APPEND. Body is owned by the item, so
+// the same bytes can be sent again when a MULTIAPPEND has to fall back to
+// single APPENDs.
+type AppendItem struct {
+	Date  time.Time
+	Body  []byte
+	Flags []string
+}
+
+// NewAppendItem builds an AppendItem from a message fetched with
+// StreamMessagesByUIDs. The body literal is consumed; go-imap already holds
+// it in a bytes.Buffer, so no second copy is made in the common case.
+func NewAppendItem(msg *imap.Message) (AppendItem, error) {
+	body := msg.GetBody(fullBodyPeekSection)
+	if body == nil {
+		return AppendItem{}, errors.New("message has no body")
+	}
+	raw, err := literalBytes(body)
+	if err != nil {
+		return AppendItem{}, fmt.Errorf("read body: %w", err)
+	}
+	item := AppendItem{Body: raw, Flags: []string{imap.SeenFlag}}
+	if msg.Envelope != nil {
+		item.Date = msg.Envelope.Date
+	}
+	return item, nil
+}
+
+// literalBytes returns the content of l, borrowing the backing slice when l
+// is a *bytes.Buffer (what go-imap's parser produces).
+func literalBytes(l imap.Literal) ([]byte, error) {
+	if b, ok := l.(*bytes.Buffer); ok {
+		return b.Bytes(), nil
+	}
+	return io.ReadAll(l)
+}
+
+// HasCapability reports whether the server advertised the named capability.
+// go-imap caches the list from the greeting and LOGIN response, so this is
+// normally free; a lookup error is treated as "not supported".
+func (c *Client) HasCapability(name string) bool {
+	cli := c.c.Load()
+	if cli == nil {
+		return false
+	}
+	ok, err := cli.Support(name)
+	return err == nil && ok
+}
+
 // AppendMessage uploads a single message to the destination folder.
 //
-// The body is streamed straight from the *imap.Message (which already holds
-// it in memory) into the APPEND literal — no extra io.ReadAll buffer. The
-// trade-off is that the literal is not replayable: a transient network error
-// mid-APPEND cannot be retried, because the body has been partially sent.
-// That's acceptable for our flow: the next sync run is idempotent (the
-// Message-Id diff will pick up the message again), and avoiding the second
-// in-memory copy halves peak RAM with multi-MB attachments and 10 workers.
+// A failed APPEND is not retried here: the caller still holds the message
+// and decides whether the failure is worth another attempt. The connection is
+// repaired on a transient error, though, so the next message does not fail
+// at the IMAP layer too.
 func (c *Client) AppendMessage(ctx context.Context, folder string, msg *imap.Message) error {
+	item, err := NewAppendItem(msg)
+	if err != nil {
+		return fmt.Errorf("[%s] %w", c.prefix, err)
+	}
+	if err := c.AppendItems(ctx, folder, []AppendItem{item}); err != nil {
+		return err
+	}
+	if c.verbose {
+		c.log("[%s] Message %q appended to %s", c.prefix, msg.Envelope.MessageId, folder)
+	}
+	return nil
+}
+
+// AppendItems uploads items to folder in one command: a plain APPEND for a
+// single item, a MULTIAPPEND (RFC 3502) for several. MULTIAPPEND is atomic,
+// so on error none of the items were stored and the caller can resend them
+// one by one. Callers must only pass several items when the server
+// advertises MULTIAPPEND.
+//
+// Literals above asyncLiteralLimit are sent non-synchronizing when the
+// server advertises LITERAL+, saving one round-trip per message.
+func (c *Client) AppendItems(ctx context.Context, folder string, items []AppendItem) error {
 	stop := c.withCancel(ctx)
 	defer stop()
 
@@ -27,10 +106,8 @@ func (c *Client) AppendMessage(ctx context.Context, folder string, msg *imap.Mes
 	if c.isCancelled() {
 		return context.Canceled
 	}
-
-	body := msg.GetBody(fullBodyPeekSection)
-	if body == nil {
-		return fmt.Errorf("[%s] message has no body", c.prefix)
+	if len(items) == 0 {
+		return nil
 	}
 
 	cli := c.c.Load()
@@ -38,23 +115,119 @@ func (c *Client) AppendMessage(ctx context.Context, folder string, msg *imap.Mes
 		return errors.New("imap client not connected")
 	}
 
-	flags := []string{imap.SeenFlag}
-	if err := cli.Append(folder, flags, msg.Envelope.Date, body); err != nil {
+	cmd := &appendCmd{mailbox: folder, items: items, literalPlus: c.HasCapability("LITERAL+")}
+	w := cli.Writer()
+	restore := cmd.installWriter(w)
+	status, err := cli.Execute(cmd, nil)
+	restore()
+	if err == nil {
+		err = status.Err()
+	}
+	if err != nil {
 		if ctx.Err() != nil {
 			return ctx.Err()
 		}
-		// The literal has been partially consumed, so we can't retry THIS
-		// message — but repair the connection so the next APPEND in the
-		// same plan doesn't fail again at the IMAP layer. Without this,
-		// one server-side disconnect cascades into "Not logged in" for
-		// every remaining message in the folder.
+		// Repair the connection so the next APPEND in the same plan
+		// doesn't fail again at the IMAP layer. Without this, one
+		// server-side disconnect cascades into "Not logged in" for every
+		// remaining message in the folder.
 		if isRetryable(err) && !c.isCancelled() {
 			_ = c.reconnect()
 		}
 		return fmt.Errorf("[%s] append: %w", c.prefix, err)
 	}
-	if c.verbose {
-		c.log("[%s] Message %q appended to %s", c.prefix, msg.Envelope.MessageId, folder)
+	return nil
+}
+
+// appendCmd is an APPEND carrying one or more messages. go-imap's own
+// commands.Append only knows a single message, and always waits for a
+// continuation on literals above asyncLiteralLimit.
+type appendCmd struct {
+	plus        map[string][]byte // large LITERAL+ bodies by placeholder
+	mailbox     string
+	items       []AppendItem
+	literalPlus bool
+}
+
+// Command implements imap.Commander.
+func (cmd *appendCmd) Command() *imap.Command {
+	mailbox, _ := utf7.Encoding.NewEncoder().String(cmd.mailbox)
+	args := []any{imap.FormatMailboxName(mailbox)}
+	cmd.plus = nil
+	for _, it := range cmd.items {
+		if it.Flags != nil {
+			flags := make([]any, len(it.Flags))
+			for i, f := range it.Flags {
+				flags[i] = imap.RawString(f)
+			}
+			args = append(args, flags)
+		}
+		if !it.Date.IsZero() {
+			args = append(args, it.Date)
+		}
+		args = append(args, cmd.literal(it.Body))
+	}
+	return &imap.Command{Name: "APPEND", Arguments: args}
+}
+
+// literal renders body as a command argument. go-imap's writer handles the
+// synchronizing case (and small LITERAL+ literals) itself. A large LITERAL+
+// literal becomes a placeholder that the writer from installWriter replaces
+// with the "{n+}" marker and the body, so no continuation is awaited and the
+// body is not copied.
+func (cmd *appendCmd) literal(body []byte) any {
+	if !cmd.literalPlus || len(body) <= asyncLiteralLimit {
+		return bytes.NewReader(body)
+	}
+	if cmd.plus == nil {
+		cmd.plus = make(map[string][]byte)
+	}
+	// NUL cannot appear in a command, so no other argument matches.
+	placeholder := "\x00literal+" + strconv.Itoa(len(cmd.plus)) + "\x00"
+	cmd.plus[placeholder] = body
+	return imap.RawString(placeholder)
+}
+
+// installWriter routes w through a literalWriter for cmd and returns the
+// function that puts the original writer back. It does nothing without
+// LITERAL+.
+func (cmd *appendCmd) installWriter(w *imap.Writer) (restore func()) {
+	if !cmd.literalPlus {
+		return func() {}
+	}
+	orig := w.Writer
+	w.Writer = &literalWriter{Writer: orig, cmd: cmd}
+	return func() { w.Writer = orig }
+}
+
+// literalWriter writes the large LITERAL+ bodies of cmd in place of their
+// placeholders; everything else passes through. go-imap writes each
+// RawString argument with a single WriteString call.
+type literalWriter struct {
+	io.Writer
+	cmd *appendCmd
+}
+
+// WriteString implements io.StringWriter.
+func (w *literalWriter) WriteString(s string) (int, error) {
+	body, ok := w.cmd.plus[s]
+	if !ok {
+		return io.WriteString(w.Writer, s)
+	}
+	if _, err := io.WriteString(w.Writer, "{"+strconv.Itoa(len(body))+"+}\r\n"); err != nil {
+		return 0, err
+	}
+	if _, err := w.Writer.Write(body); err != nil {
+		return 0, err
+	}
+	return len(s), nil
+}
+
+// Flush flushes the wrapped writer, which go-imap does at the end of every
+// command line.
+func (w *literalWriter) Flush() error {
+	if f, ok := w.Writer.(interface{ Flush() error }); ok {
+		return f.Flush()
 	}
 	return nil
 }
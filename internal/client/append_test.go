@@ -0,0 +1,122 @@
+package client
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/emersion/go-imap"
+)
+
+// renderCommand writes cmd the way go-imap would put it on the wire. A plain
+// imap.Writer has no continuation channel, so synchronizing literals are
+// written straight through.
+func renderCommand(t *testing.T, cmd imap.Commander) string {
+	t.Helper()
+	var buf bytes.Buffer
+	w := imap.NewWriter(&buf)
+	if a, ok := cmd.(*appendCmd); ok {
+		defer a.installWriter(w)()
+	}
+	c := cmd.Command()
+	c.Tag = "A1"
+	if err := c.WriteTo(w); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	return buf.String()
+}
+
+func TestAppendCmd_multiAppendCarriesEveryMessage(t *testing.T) {
+	t.Parallel()
+
+	cmd := &appendCmd{mailbox: "INBOX", items: []AppendItem{
+		{Body: []byte("hello"), Flags: []string{imap.SeenFlag}},
+		{Body: []byte("world"), Flags: []string{imap.SeenFlag}},
+	}}
+	got := renderCommand(t, cmd)
+	want := "A1 APPEND INBOX (\\Seen) {5}\r\nhello (\\Seen) {5}\r\nworld\r\n"
+	if got != want {
+		t.Errorf("rendered %q, want %q", got, want)
+	}
+}
+
+func TestAppendCmd_literalPlusOnlyAboveAsyncLimit(t *testing.T) {
+	t.Parallel()
+
+	large := strings.Repeat("x", asyncLiteralLimit+1)
+	cmd := &appendCmd{mailbox: "INBOX", literalPlus: true, items: []AppendItem{
+		{Body: []byte("small")},
+		{Body: []byte(large)},
+	}}
+	got := renderCommand(t, cmd)
+	if !strings.Contains(got, "{5}\r\nsmall") {
+		t.Errorf("small literal should be left to go-imap: %q", got[:min(len(got), 80)])
+	}
+	if !strings.Contains(got, "{4097+}\r\n"+large) {
+		t.Error("large literal not sent as LITERAL+")
+	}
+}
+
+// writeRecorder keeps the slices passed to Write, to check they are not
+// copies.
+type writeRecorder struct {
+	bytes.Buffer
+	writes [][]byte
+}
+
+func (w *writeRecorder) Write(p []byte) (int, error) {
+	w.writes = append(w.writes, p)
+	return w.Buffer.Write(p)
+}
+
+func TestAppendCmd_literalPlusStreamsBody(t *testing.T) {
+	t.Parallel()
+
+	body := []byte(strings.Repeat("x", asyncLiteralLimit+1))
+	cmd := &appendCmd{mailbox: "INBOX", literalPlus: true, items: []AppendItem{{Body: body}}}
+	var rec writeRecorder
+	w := imap.NewWriter(&rec)
+	restore := cmd.installWriter(w)
+	c := cmd.Command()
+	c.Tag = "A1"
+	if err := c.WriteTo(w); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	restore()
+
+	if want := "A1 APPEND INBOX {4097+}\r\n" + string(body) + "\r\n"; rec.String() != want {
+		t.Errorf("rendered %q..., want %q...", rec.String()[:40], want[:40])
+	}
+	if !slices.ContainsFunc(rec.writes, func(p []byte) bool { return len(p) == len(body) && &p[0] == &body[0] }) {
+		t.Error("body was copied instead of written from the item")
+	}
+	if _, ok := w.Writer.(*writeRecorder); !ok {
+		t.Errorf("writer not restored: %T", w.Writer)
+	}
+}
+
+func TestNewAppendItem_missingBody(t *testing.T) {
+	t.Parallel()
+
+	msg := imap.NewMessage(1, []imap.FetchItem{imap.FetchEnvelope})
+	if _, err := NewAppendItem(msg); err == nil {
+		t.Fatal("expected error for message without body")
+	}
+}
+
+func TestNewAppendItem_copiesDateAndSeenFlag(t *testing.T) {
+	t.Parallel()
+
+	msg := buildTestIMAPMessage()
+	item, err := NewAppendItem(msg)
+	if err != nil {
+		t.Fatalf("NewAppendItem: %v", err)
+	}
+	if !strings.HasPrefix(string(item.Body), "From: test@example.com") {
+		t.Errorf("body = %q", item.Body)
+	}
+	if len(item.Flags) != 1 || item.Flags[0] != imap.SeenFlag {
+		t.Errorf("flags = %v, want [\\Seen]", item.Flags)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net"
 	"strings"
 	"sync"
@@ -17,6 +18,8 @@ import (
 
 	"github.com/emersion/go-imap"
 	imapclient "github.com/emersion/go-imap/client"
+	"github.com/greeddj/imapsync-go/internal/metrics"
+	"github.com/greeddj/imapsync-go/internal/provider"
 	"github.com/greeddj/imapsync-go/internal/ratelimit"
 	"golang.org/x/time/rate"
 )
@@ -60,14 +63,30 @@ type ProgressTracker interface {
 // Options carries the optional knobs for New. Zero-value is fine for plain
 // TLS connections without throttling.
 //
+// Label is the initial log prefix, as if SetPrefix had been called. Trace,
+// when non-nil, records every connection the Client makes. Logger receives
+// the Client's structured log, typically carrying side and worker
+// attributes; nil discards it.
+//
 // ReadLimiter and WriteLimiter, when non-nil, are typically shared across
 // every Client that talks to the same account so that the byte budget is a
 // global cap, not a per-connection cap.
+//
+// Counter, when non-nil, totals the bytes read and written on the wire by
+// every connection of the Client, reconnects included.
+//
+// Providers are the profiles Diagnose looks the server up in, usually the
+// config's; nil means the built-in ones.
 type Options struct {
 	TLSConfig    *tls.Config
 	ReadLimiter  *rate.Limiter
 	WriteLimiter *rate.Limiter
+	Counter      *ratelimit.Counter
+	Trace        *Tracer
+	Logger       *slog.Logger
 	Auth         string
+	Label        string
+	Providers    provider.Table
 	DialTimeout  time.Duration
 	UseTLS       bool
 	Verbose      bool
@@ -89,6 +108,9 @@ type Client struct {
 	tlsConfig      *tls.Config
 	readLimiter    *rate.Limiter
 	writeLimiter   *rate.Limiter
+	counter        *ratelimit.Counter
+	tracer         *Tracer
+	slogger        *slog.Logger
 	dialFn         dialFunc
 	folderLocks    map[string]*sync.Mutex
 	cancelCh       chan struct{}
@@ -96,6 +118,7 @@ type Client struct {
 	selectedFolder atomic.Pointer[string]
 	pw             atomic.Pointer[progressWriterRef]
 	tracker        atomic.Pointer[progressTrackerRef]
+	label          atomic.Pointer[string]
 	username       string
 	prefix         string
 	password       string
@@ -111,6 +134,7 @@ type Client struct {
 	mu             sync.Mutex
 	folderLocksMu  sync.Mutex
 	cancelled      atomic.Bool
+	reconnecting   atomic.Bool
 	useTLS         bool
 	verbose        bool
 }
@@ -146,6 +170,28 @@ func newDialer(timeout time.Duration) *net.Dialer {
 	return &net.Dialer{Timeout: timeout, KeepAlive: tcpKeepAlivePeriod}
 }
 
+// countedConn keeps the open-connections gauge in step with a connection's
+// life. go-imap closes a connection both on Logout and when it fails, so
+// Close may run twice; only the first one counts.
+type countedConn struct {
+	net.Conn
+	closed atomic.Bool
+}
+
+// countConn counts conn as open until it is closed.
+func countConn(conn net.Conn) net.Conn {
+	metrics.OpenConnections.Inc()
+	return &countedConn{Conn: conn}
+}
+
+// Close implements net.Conn.
+func (c *countedConn) Close() error {
+	if c.closed.CompareAndSwap(false, true) {
+		metrics.OpenConnections.Dec()
+	}
+	return c.Conn.Close()
+}
+
 // New establishes a connection and logs into the IMAP server.
 //
 // ctx is used only for the initial dial and login; once Client is returned,
@@ -172,8 +218,14 @@ func New(ctx context.Context, addr, username, password string, opts Options) (*C
 		folderLocks:  make(map[string]*sync.Mutex),
 		readLimiter:  opts.ReadLimiter,
 		writeLimiter: opts.WriteLimiter,
+		counter:      opts.Counter,
+		tracer:       opts.Trace,
+		slogger:      opts.Logger,
 		cancelCh:     make(chan struct{}),
 	}
+	if opts.Label != "" {
+		c.SetPrefix(opts.Label)
+	}
 
 	c.dialFn = func(ctx context.Context, addr string) (net.Conn, error) {
 		nd := newDialer(c.dialTimeout)
@@ -190,8 +242,12 @@ func New(ctx context.Context, addr, username, password string, opts Options) (*C
 		if err != nil {
 			return nil, err
 		}
-		if c.readLimiter != nil || c.writeLimiter != nil {
-			conn = ratelimit.New(conn, c.readLimiter, c.writeLimiter)
+		conn = countConn(conn)
+		if c.readLimiter != nil || c.writeLimiter != nil || c.counter != nil {
+			conn = ratelimit.New(conn, c.readLimiter, c.writeLimiter, c.counter)
+		}
+		if c.tracer != nil {
+			conn = c.tracer.wrap(conn, c.traceLabel)
 		}
 		return conn, nil
 	}
@@ -232,8 +288,22 @@ func (c *Client) selectIfNeeded(cli *imapclient.Client, folder string) (*imap.Ma
 	return mbox, nil
 }
 
-// SetPrefix configures the log prefix used in progress messages.
-func (c *Client) SetPrefix(p string) { c.prefix = p }
+// SetPrefix configures the log prefix used in progress messages and IMAP
+// traces.
+func (c *Client) SetPrefix(p string) {
+	c.prefix = p
+	c.label.Store(&p)
+}
+
+// traceLabel is the prefix for trace lines. The tracer reads it from the
+// connection's reader goroutine, hence the atomic copy of prefix; before
+// SetPrefix it falls back to the server address.
+func (c *Client) traceLabel() string {
+	if l := c.label.Load(); l != nil {
+		return *l
+	}
+	return c.serverAddr
+}
 
 // SetProgressWriter sets the progress writer for logging. Safe to call from
 // any goroutine, including while another goroutine is using the writer.
@@ -274,6 +344,12 @@ func (c *Client) progressTracker() ProgressTracker {
 // GetDelimiter returns the cached hierarchy delimiter for this server.
 func (c *Client) GetDelimiter() string { return c.delimiter }
 
+// Reconnects reports how many times the connection has been rebuilt.
+func (c *Client) Reconnects() uint64 { return c.connGen.Load() }
+
+// Reconnecting reports whether the Client is rebuilding its connection.
+func (c *Client) Reconnecting() bool { return c.reconnecting.Load() }
+
 // Logout terminates the IMAP session.
 func (c *Client) Logout() error {
 	cli := c.c.Load()
@@ -329,19 +405,33 @@ func (c *Client) internalContext() (context.Context, context.CancelFunc) {
 	return ctx, cancel
 }
 
-// log routes a formatted message to the tracker (preferred) or progress writer.
+// discardLogger stands in for a missing Options.Logger.
+var discardLogger = slog.New(slog.DiscardHandler)
+
+// logger returns the structured logger from Options, or one that discards.
+func (c *Client) logger() *slog.Logger {
+	if c.slogger == nil {
+		return discardLogger
+	}
+	return c.slogger
+}
+
+// log routes a formatted message to the tracker (preferred) or progress
+// writer, and to the structured log at debug level.
 func (c *Client) log(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	c.logger().Debug(msg)
 	// Tracker drives the live UX, so it updates regardless of verbose.
 	// The verbose check below only gates the secondary log-writer fallback.
 	if t := c.progressTracker(); t != nil {
-		t.UpdateMessage(fmt.Sprintf(format, args...))
+		t.UpdateMessage(msg)
 		return
 	}
 	if !c.verbose {
 		return
 	}
 	if pw := c.progressWriter(); pw != nil {
-		pw.Log(format, args...)
+		pw.Log("%s", msg)
 	}
 }
 
@@ -385,30 +475,23 @@ func (c *Client) connectAndLogin(ctx context.Context) error {
 	// flap leaves Login blocked until the kernel times the socket out.
 	c.c.Store(cli)
 
-	switch strings.ToLower(c.auth) {
-	case "cram-md5":
-		auth := &cramMD5Auth{
-			username: c.username,
-			password: c.password,
-		}
-
-		if err := cli.Authenticate(auth); err != nil {
-			_ = cli.Logout()
-			c.c.Store(nil)
-		}
-
-	default:
-		if err := cli.Login(c.username, c.password); err != nil {
-			_ = cli.Logout()
-			c.c.Store(nil)
-			return err
-		}
-
+	if err := login(cli, c.auth, c.username, c.password); err != nil {
+		_ = cli.Logout()
+		c.c.Store(nil)
+		return err
 	}
-
 	return nil
 }
 
+// login authenticates cli with the configured mechanism: "cram-md5" or, by
+// default, a plain LOGIN.
+func login(cli *imapclient.Client, auth, username, password string) error {
+	if strings.EqualFold(auth, "cram-md5") {
+		return cli.Authenticate(&cramMD5Auth{username: username, password: password})
+	}
+	return cli.Login(username, password)
+}
+
 // reconnect tears down and rebuilds the underlying IMAP session with backoff.
 // It honours error classification: permanent errors abort immediately, server
 // throttling triggers a long cool-down between attempts.
@@ -418,6 +501,8 @@ func (c *Client) reconnect() error {
 	}
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	c.reconnecting.Store(true)
+	defer c.reconnecting.Store(false)
 
 	ctx, cancel := c.internalContext()
 	defer cancel()
@@ -450,9 +535,11 @@ func (c *Client) reconnect() error {
 			return err
 		}
 		c.log("[%s] 🔄 Reconnect attempt %d...", c.prefix, i)
+		metrics.Reconnects.Inc(c.prefix)
 		err := c.connectAndLogin(ctx)
 		if err == nil {
 			c.log("[%s] 🔄 Reconnected successfully", c.prefix)
+			c.logger().Info("reconnected", "attempt", i)
 			c.lastReconnect = time.Now()
 			c.backoff = initialBackoff
 			return nil
@@ -463,16 +550,20 @@ func (c *Client) reconnect() error {
 		case ClassPermanent:
 			// Auth fails won't get better with retries; bail out fast.
 			c.log("[%s] 🔄 Permanent error, giving up: %v", c.prefix, err)
+			c.logger().Error("reconnect failed for good", "attempt", i, "error", err)
 			c.lastReconnect = time.Now()
 			return err
 		case ClassThrottled:
 			c.log("[%s] 🔄 Server throttled, backing off %s", c.prefix, throttledBackoff)
+			c.logger().Warn("server throttled", "attempt", i, "backoff", throttledBackoff, "error", err)
+			metrics.ThrottleBackoffs.Inc(c.prefix)
 			if serr := sleepCtx(ctx, throttledBackoff); serr != nil {
 				return serr
 			}
 			continue
 		case ClassTransient, ClassUnknown:
 			c.log("[%s] 🔄 Failed: %v, retrying in %s", c.prefix, err, delay)
+			c.logger().Warn("reconnect attempt failed", "attempt", i, "retry_in", delay, "error", err)
 			if serr := sleepCtx(ctx, delay); serr != nil {
 				return serr
 			}
@@ -481,6 +572,7 @@ func (c *Client) reconnect() error {
 	}
 
 	c.lastReconnect = time.Now()
+	c.logger().Error("reconnect gave up", "attempts", maxReconnectAttempts, "error", lastErr)
 	return fmt.Errorf("[%s] failed to reconnect after retries: %w", c.prefix, lastErr)
 }
 
@@ -510,6 +602,7 @@ func (c *Client) safeCall(fn func(cli *imapclient.Client) error) error {
 		return err
 	}
 
+	c.logger().Warn("connection lost, reconnecting", "error", err)
 	if rerr := c.reconnect(); rerr != nil {
 		return rerr
 	}
@@ -2,10 +2,12 @@ package client
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net"
 	"slices"
 	"strings"
@@ -164,6 +166,9 @@ func Test_reconnect_bumpsConnGen(t *testing.T) {
 	if c.connGen.Load() != gen0+1 {
 		t.Errorf("connGen = %d, want %d", c.connGen.Load(), gen0+1)
 	}
+	if c.Reconnects() != gen0+1 || c.Reconnecting() {
+		t.Errorf("Reconnects() = %d, Reconnecting() = %v; want %d, false", c.Reconnects(), c.Reconnecting(), gen0+1)
+	}
 }
 
 // Test_reconnect_clearsSelectedFolder asserts that reconnect resets the
@@ -239,6 +244,48 @@ func Test_reconnect_throttledBackoff(t *testing.T) {
 	}
 }
 
+// Test_reconnect_logsThrottle asserts that a throttled reconnect attempt and
+// the recovery are recorded in the structured log with the Client's
+// attributes. Sequential — swaps the package-level sleepCtx var.
+func Test_reconnect_logsThrottle(t *testing.T) {
+	srv := newFakeServer(t)
+	srv.addConnHandler(connHandlerWithLoginReply(srv, "OK LOGIN completed"))
+	srv.addConnHandler(connHandlerWithLoginReply(srv, "NO Account exceeded bandwidth limits"))
+
+	var buf bytes.Buffer
+	c := &Client{
+		serverAddr:   srv.ln.Addr().String(),
+		username:     "user",
+		password:     "pass",
+		dialTimeout:  5 * time.Second,
+		folderLocks:  make(map[string]*sync.Mutex),
+		cancelCh:     make(chan struct{}),
+		slogger:      slog.New(slog.NewTextHandler(&buf, nil)).With("side", "source"),
+		reconnectDur: 0,
+	}
+	c.dialFn = func(ctx context.Context, addr string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	}
+	t.Cleanup(func() { c.Cancel(); _ = c.Logout() })
+	if err := c.connectAndLogin(context.Background()); err != nil {
+		t.Fatalf("initial connectAndLogin: %v", err)
+	}
+
+	orig := sleepCtx
+	sleepCtx = func(_ context.Context, _ time.Duration) error { return nil }
+	t.Cleanup(func() { sleepCtx = orig })
+
+	if err := c.reconnect(); err != nil {
+		t.Fatalf("reconnect: %v", err)
+	}
+	got := buf.String()
+	for _, want := range []string{`level=WARN msg="server throttled" side=source attempt=1`, `level=INFO msg=reconnected side=source attempt=2`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("log lacks %q:\n%s", want, got)
+		}
+	}
+}
+
 // Test_selectIfNeeded_shortCircuitsOnNetwork asserts that a second call to
 // selectIfNeeded for the same folder on the same connection does not send
 // another EXAMINE to the server.
@@ -0,0 +1,369 @@
+package client
+
+import (
+	"cmp"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"maps"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-imap"
+	imapclient "github.com/emersion/go-imap/client"
+	"github.com/emersion/go-imap/responses"
+	"github.com/greeddj/imapsync-go/internal/provider"
+)
+
+// Extensions are the optional capabilities imapsync-go takes advantage of
+// when a server advertises them.
+var Extensions = []string{"UIDPLUS", "CONDSTORE", "MOVE", "LITERAL+", "QUOTA", "SPECIAL-USE"}
+
+// Diagnosis is the step-by-step outcome of Diagnose. A step that was never
+// reached, because an earlier one failed, is nil or empty. Error names the
+// step that stopped the check; it is empty when every required step passed.
+type Diagnosis struct {
+	DNS            *DNSCheck       `json:"dns,omitempty"`
+	TCP            *TCPCheck       `json:"tcp,omitempty"`
+	TLS            *TLSCheck       `json:"tls,omitempty"`
+	Login          *LoginCheck     `json:"login,omitempty"`
+	Namespaces     *NamespaceCheck `json:"namespaces,omitempty"`
+	Extensions     map[string]bool `json:"extensions,omitempty"`
+	Server         string          `json:"server"`
+	User           string          `json:"user"`
+	Provider       string          `json:"provider,omitempty"`
+	Delimiter      string          `json:"delimiter,omitempty"`
+	Error          string          `json:"error,omitempty"`
+	Capabilities   []string        `json:"capabilities,omitempty"`
+	AuthMechanisms []string        `json:"auth_mechanisms,omitempty"`
+}
+
+// OK reports whether every required step passed.
+func (d *Diagnosis) OK() bool { return d.Error == "" }
+
+// DNSCheck is the lookup of the server host name.
+type DNSCheck struct {
+	Host       string   `json:"host"`
+	Error      string   `json:"error,omitempty"`
+	Addresses  []string `json:"addresses,omitempty"`
+	DurationMS int64    `json:"duration_ms"`
+}
+
+// TCPCheck is the TCP connect to the server.
+type TCPCheck struct {
+	Address    string `json:"address,omitempty"`
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// TLSCheck is the TLS handshake and the verification of the certificate
+// chain the server presented.
+type TLSCheck struct {
+	Version      string        `json:"version,omitempty"`
+	CipherSuite  string        `json:"cipher_suite,omitempty"`
+	VerifyError  string        `json:"verify_error,omitempty"`
+	Error        string        `json:"error,omitempty"`
+	Certificates []Certificate `json:"certificates,omitempty"`
+	DurationMS   int64         `json:"duration_ms"`
+	Verified     bool          `json:"verified"`
+}
+
+// Certificate describes one certificate of the presented chain, leaf first.
+type Certificate struct {
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after"`
+	Subject   string    `json:"subject"`
+	Issuer    string    `json:"issuer"`
+	DNSNames  []string  `json:"dns_names,omitempty"`
+}
+
+// LoginCheck is the authentication with the configured mechanism.
+type LoginCheck struct {
+	Method     string `json:"method"`
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+	OK         bool   `json:"ok"`
+}
+
+// NamespaceCheck is the NAMESPACE reply (RFC 2342). Error is set when the
+// server advertises NAMESPACE but the command fails; that alone does not
+// fail the diagnosis.
+type NamespaceCheck struct {
+	Error    string      `json:"error,omitempty"`
+	Personal []Namespace `json:"personal,omitempty"`
+	Other    []Namespace `json:"other,omitempty"`
+	Shared   []Namespace `json:"shared,omitempty"`
+}
+
+// Namespace is one namespace prefix and its hierarchy delimiter ("" for a
+// flat namespace).
+type Namespace struct {
+	Prefix    string `json:"prefix"`
+	Delimiter string `json:"delimiter"`
+}
+
+// Diagnose walks through everything a sync needs from a server and records
+// each step: DNS, TCP, TLS with the certificate chain, CAPABILITY, login,
+// NAMESPACE and, through a regular New, the delimiter the sync would use.
+// It stops at the first failed step and never sends the password over a
+// connection whose certificate does not verify.
+func Diagnose(ctx context.Context, addr, username, password string, opts Options) *Diagnosis {
+	d := &Diagnosis{Server: addr, User: username}
+	providers := opts.Providers
+	if providers == nil {
+		providers = provider.Builtin()
+	}
+	if p, ok := providers.Detect(addr); ok {
+		d.Provider = p.Name
+	}
+	timeout := opts.DialTimeout
+	if timeout == 0 {
+		timeout = defaultDialTimeout
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		d.Error = fmt.Sprintf("server address: %v", err)
+		return d
+	}
+
+	start := time.Now()
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	d.DNS = &DNSCheck{Host: host, Addresses: addrs, DurationMS: time.Since(start).Milliseconds()}
+	if err != nil {
+		d.DNS.Error = err.Error()
+		d.Error = "DNS lookup failed"
+		return d
+	}
+
+	start = time.Now()
+	conn, err := newDialer(timeout).DialContext(ctx, "tcp", addr)
+	d.TCP = &TCPCheck{DurationMS: time.Since(start).Milliseconds()}
+	if err != nil {
+		d.TCP.Error = err.Error()
+		d.Error = "TCP connect failed"
+		return d
+	}
+	d.TCP.Address = conn.RemoteAddr().String()
+	defer func() { _ = conn.Close() }()
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	if opts.UseTLS {
+		tc, check := diagnoseTLS(ctx, conn, host, opts.TLSConfig, timeout)
+		d.TLS = check
+		switch {
+		case check.Error != "":
+			d.Error = "TLS handshake failed"
+			return d
+		case !check.Verified:
+			d.Error = "TLS certificate does not verify"
+			return d
+		}
+		conn = tc
+	}
+	if opts.Trace != nil {
+		label := cmp.Or(opts.Label, addr)
+		conn = opts.Trace.wrap(conn, func() string { return label })
+	}
+
+	cli, err := imapclient.New(conn)
+	if err != nil {
+		d.Error = fmt.Sprintf("IMAP greeting: %v", err)
+		return d
+	}
+	cli.Timeout = timeout
+	defer func() { _ = cli.Logout() }()
+
+	caps, err := cli.Capability()
+	if err != nil {
+		d.Error = fmt.Sprintf("CAPABILITY: %v", err)
+		return d
+	}
+	d.Capabilities = sortedCaps(caps)
+	for _, c := range d.Capabilities {
+		if mech, ok := strings.CutPrefix(c, "AUTH="); ok {
+			d.AuthMechanisms = append(d.AuthMechanisms, mech)
+		}
+	}
+
+	method := "LOGIN"
+	if strings.EqualFold(opts.Auth, "cram-md5") {
+		method = "CRAM-MD5"
+	}
+	start = time.Now()
+	err = login(cli, opts.Auth, username, password)
+	d.Login = &LoginCheck{Method: method, OK: err == nil, DurationMS: time.Since(start).Milliseconds()}
+	if err != nil {
+		d.Login.Error = err.Error()
+		d.Error = "login failed"
+		return d
+	}
+
+	// Servers commonly advertise more once authenticated.
+	if caps, err = cli.Capability(); err == nil {
+		d.Capabilities = sortedCaps(caps)
+	}
+	d.Extensions = make(map[string]bool, len(Extensions))
+	for _, ext := range Extensions {
+		d.Extensions[ext] = caps[ext]
+	}
+
+	if caps["NAMESPACE"] {
+		d.Namespaces = namespaces(cli)
+	}
+
+	// The delimiter comes from a regular client, so the check also covers
+	// the exact connect, login and LIST path a sync takes.
+	c, err := New(ctx, addr, username, password, opts)
+	if err != nil {
+		d.Error = fmt.Sprintf("sync connection: %v", err)
+		return d
+	}
+	d.Delimiter = c.GetDelimiter()
+	_ = c.Logout()
+
+	return d
+}
+
+// diagnoseTLS runs the handshake on conn without letting crypto/tls reject
+// the chain, then verifies the chain itself, so that an untrusted or expired
+// certificate is still reported in full.
+func diagnoseTLS(ctx context.Context, conn net.Conn, host string, base *tls.Config, timeout time.Duration) (*tls.Conn, *TLSCheck) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if base != nil {
+		cfg = base.Clone()
+	}
+	if cfg.ServerName == "" {
+		cfg.ServerName = host
+	}
+	roots := cfg.RootCAs
+	cfg.InsecureSkipVerify = true //nolint:gosec // the chain is verified below
+
+	check := &TLSCheck{}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	tc := tls.Client(conn, cfg)
+	err := tc.HandshakeContext(ctx)
+	check.DurationMS = time.Since(start).Milliseconds()
+	if err != nil {
+		check.Error = err.Error()
+		return nil, check
+	}
+
+	state := tc.ConnectionState()
+	check.Version = tls.VersionName(state.Version)
+	check.CipherSuite = tls.CipherSuiteName(state.CipherSuite)
+	for _, cert := range state.PeerCertificates {
+		check.Certificates = append(check.Certificates, Certificate{
+			Subject:   cert.Subject.String(),
+			Issuer:    cert.Issuer.String(),
+			NotBefore: cert.NotBefore,
+			NotAfter:  cert.NotAfter,
+			DNSNames:  cert.DNSNames,
+		})
+	}
+	if len(state.PeerCertificates) == 0 {
+		check.VerifyError = "no certificate presented"
+		return tc, check
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err = state.PeerCertificates[0].Verify(x509.VerifyOptions{
+		DNSName:       cfg.ServerName,
+		Roots:         roots,
+		Intermediates: intermediates,
+	})
+	if err != nil {
+		check.VerifyError = err.Error()
+		return tc, check
+	}
+	check.Verified = true
+	return tc, check
+}
+
+// sortedCaps returns the advertised capabilities in a stable order.
+func sortedCaps(caps map[string]bool) []string {
+	return slices.Sorted(maps.Keys(caps))
+}
+
+// namespaces issues NAMESPACE and records the reply or its error.
+func namespaces(cli *imapclient.Client) *NamespaceCheck {
+	var check NamespaceCheck
+	var parseErr error
+	handler := responses.HandlerFunc(func(resp imap.Resp) error {
+		name, fields, ok := imap.ParseNamedResp(resp)
+		if !ok || name != "NAMESPACE" {
+			return responses.ErrUnhandled
+		}
+		ns, err := parseNamespaces(fields)
+		if err != nil {
+			parseErr = err
+			return nil
+		}
+		check = ns
+		return nil
+	})
+	status, err := cli.Execute(&namespaceCmd{}, handler)
+	if err == nil {
+		err = status.Err()
+	}
+	if err == nil {
+		err = parseErr
+	}
+	if err != nil {
+		check.Error = err.Error()
+	}
+	return &check
+}
+
+// parseNamespaces reads the personal, other users' and shared namespace
+// lists of a NAMESPACE response. Each is NIL or a list of (prefix delimiter)
+// pairs, possibly followed by extension data that is ignored.
+func parseNamespaces(fields []any) (NamespaceCheck, error) {
+	if len(fields) < 3 {
+		return NamespaceCheck{}, fmt.Errorf("NAMESPACE response: %d fields, want 3", len(fields))
+	}
+	var check NamespaceCheck
+	for i, dst := range []*[]Namespace{&check.Personal, &check.Other, &check.Shared} {
+		if fields[i] == nil {
+			continue
+		}
+		list, ok := fields[i].([]any)
+		if !ok {
+			return NamespaceCheck{}, fmt.Errorf("NAMESPACE field %d: not a list", i+1)
+		}
+		for _, item := range list {
+			pair, ok := item.([]any)
+			if !ok || len(pair) < 2 {
+				return NamespaceCheck{}, fmt.Errorf("NAMESPACE field %d: malformed entry", i+1)
+			}
+			prefix, err := imap.ParseString(pair[0])
+			if err != nil {
+				return NamespaceCheck{}, fmt.Errorf("NAMESPACE prefix: %w", err)
+			}
+			var delim string
+			if pair[1] != nil {
+				if delim, err = imap.ParseString(pair[1]); err != nil {
+					return NamespaceCheck{}, fmt.Errorf("NAMESPACE delimiter: %w", err)
+				}
+			}
+			*dst = append(*dst, Namespace{Prefix: prefix, Delimiter: delim})
+		}
+	}
+	return check, nil
+}
+
+// namespaceCmd is NAMESPACE, which go-imap's core client lacks.
+type namespaceCmd struct{}
+
+// Command implements imap.Commander.
+func (cmd *namespaceCmd) Command() *imap.Command {
+	return &imap.Command{Name: "NAMESPACE"}
+}
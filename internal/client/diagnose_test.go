@@ -0,0 +1,108 @@
+package client
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNamespaceCmd_renders(t *testing.T) {
+	t.Parallel()
+
+	if got, want := renderCommand(t, &namespaceCmd{}), "A1 NAMESPACE\r\n"; got != want {
+		t.Errorf("rendered %q, want %q", got, want)
+	}
+}
+
+// Test_parseNamespaces_lists asserts that NIL lists stay empty, a NIL
+// delimiter reads as a flat namespace and trailing extension data is ignored.
+func Test_parseNamespaces_lists(t *testing.T) {
+	t.Parallel()
+
+	ns, err := parseNamespaces([]any{
+		[]any{[]any{"", "/"}},
+		nil,
+		[]any{[]any{"Shared/", nil, "X-EXT", []any{"a"}}},
+	})
+	if err != nil {
+		t.Fatalf("parseNamespaces: %v", err)
+	}
+	if len(ns.Personal) != 1 || ns.Personal[0] != (Namespace{Prefix: "", Delimiter: "/"}) {
+		t.Errorf("Personal = %+v, want one \"\" \"/\"", ns.Personal)
+	}
+	if len(ns.Other) != 0 {
+		t.Errorf("Other = %+v, want empty", ns.Other)
+	}
+	if len(ns.Shared) != 1 || ns.Shared[0] != (Namespace{Prefix: "Shared/"}) {
+		t.Errorf("Shared = %+v, want one flat \"Shared/\"", ns.Shared)
+	}
+}
+
+// Test_parseNamespaces_malformed asserts that short or malformed replies
+// are rejected.
+func Test_parseNamespaces_malformed(t *testing.T) {
+	t.Parallel()
+
+	for _, fields := range [][]any{
+		{nil, nil},
+		{"INBOX", nil, nil},
+		{[]any{[]any{""}}, nil, nil},
+	} {
+		if _, err := parseNamespaces(fields); err == nil {
+			t.Errorf("parseNamespaces(%v) = nil error", fields)
+		}
+	}
+}
+
+// Test_Diagnose_plainServer asserts that every step against a healthy
+// plaintext server is recorded and the extensions it lacks are reported.
+func Test_Diagnose_plainServer(t *testing.T) {
+	t.Parallel()
+
+	srv := newFakeServer(t)
+	d := Diagnose(context.Background(), srv.ln.Addr().String(), "user", "pass", Options{})
+	if !d.OK() {
+		t.Fatalf("Diagnose error = %q, want none", d.Error)
+	}
+	if d.DNS == nil || len(d.DNS.Addresses) == 0 {
+		t.Errorf("DNS = %+v, want the loopback address", d.DNS)
+	}
+	if d.TCP == nil || d.TCP.Address == "" {
+		t.Errorf("TCP = %+v, want the remote address", d.TCP)
+	}
+	if d.TLS != nil {
+		t.Errorf("TLS = %+v, want nil without UseTLS", d.TLS)
+	}
+	if d.Login == nil || !d.Login.OK || d.Login.Method != "LOGIN" {
+		t.Errorf("Login = %+v, want a successful LOGIN", d.Login)
+	}
+	if len(d.AuthMechanisms) != 0 {
+		t.Errorf("AuthMechanisms = %v, want none from a CAPABILITY without AUTH=", d.AuthMechanisms)
+	}
+	for _, ext := range Extensions {
+		if present, ok := d.Extensions[ext]; !ok || present {
+			t.Errorf("Extensions[%s] = %v (listed %v), want listed as absent", ext, present, ok)
+		}
+	}
+	if d.Namespaces != nil {
+		t.Errorf("Namespaces = %+v, want nil when NAMESPACE is not advertised", d.Namespaces)
+	}
+}
+
+// Test_Diagnose_loginFailure asserts that a rejected login stops the check
+// and records the server's reply.
+func Test_Diagnose_loginFailure(t *testing.T) {
+	t.Parallel()
+
+	srv := newFakeServer(t)
+	srv.addConnHandler(connHandlerWithLoginReply(srv, "NO [AUTHENTICATIONFAILED] bad password"))
+	d := Diagnose(context.Background(), srv.ln.Addr().String(), "user", "pass", Options{})
+	if d.OK() {
+		t.Fatal("Diagnose OK, want a login failure")
+	}
+	if d.Login == nil || d.Login.OK || d.Login.Error == "" {
+		t.Errorf("Login = %+v, want a failure with the server's reply", d.Login)
+	}
+	if d.Delimiter != "" {
+		t.Errorf("Delimiter = %q, want none after a failed login", d.Delimiter)
+	}
+}
@@ -91,6 +91,12 @@ func classifyError(err error) ErrClass {
 	return ClassUnknown
 }
 
+// Classify exposes classifyError to other packages, so callers that keep the
+// message themselves (the sync workers) can decide whether to send it again.
+func Classify(err error) ErrClass {
+	return classifyError(err)
+}
+
 // isRetryable reports whether reconnect-and-retry should be attempted for err.
 // ClassThrottled is intentionally NOT retryable here — the caller is expected
 // to surface it to the user, which gets clearer behaviour than a stuck loop.
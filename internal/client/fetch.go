@@ -3,10 +3,13 @@ package client
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"net/mail"
 	"slices"
+	"time"
 
 	"github.com/emersion/go-imap"
 	imapclient "github.com/emersion/go-imap/client"
@@ -49,24 +52,113 @@ var fullBodyPeekSection = &imap.BodySectionName{Peek: true}
 // and (with UIDs ignored) as the destination side; callers that only need
 // the keys can use FetchMessageIDSet for a slightly thinner allocation.
 func (c *Client) FetchMessageMap(ctx context.Context, folder string) (map[string]uint32, uint64, error) {
+	ids, size, _, err := c.FetchFilteredMessageMap(ctx, folder, Filter{})
+	return ids, size, err
+}
+
+// FetchFilteredMessageMap is FetchMessageMap restricted to the messages
+// matching f. The filter runs server-side as a UID SEARCH before the
+// Message-Id fetch, so excluded messages cost nothing beyond the search;
+// the returned size covers matching messages only, and stats reports what
+// each bound of f excluded.
+func (c *Client) FetchFilteredMessageMap(ctx context.Context, folder string, f Filter) (map[string]uint32, uint64, FilterStats, error) {
+	return scanFolder(ctx, c, folder, f, nil, func(msg *imap.Message) uint32 { return msg.Uid })
+}
+
+// MessageRef is a message's UID and RFC822.SIZE.
+type MessageRef struct {
+	UID  uint32
+	Size uint32
+}
+
+// FetchMessageRefs is FetchFilteredMessageMap with the size of every message
+// alongside its UID. RFC822.SIZE is in the same FETCH either way, so the
+// per-message sizes cost nothing extra on the wire.
+func (c *Client) FetchMessageRefs(ctx context.Context, folder string, f Filter) (map[string]MessageRef, uint64, FilterStats, error) {
+	return scanFolder(ctx, c, folder, f, nil, func(msg *imap.Message) MessageRef {
+		return MessageRef{UID: msg.Uid, Size: msg.Size}
+	})
+}
+
+// MessageState is one message as seen by FetchMessageStates.
+type MessageState struct {
+	Flags []string
+	UID   uint32
+	Size  uint32
+}
+
+// FetchMessageStates is FetchFilteredMessageMap with the flags and size of
+// every message alongside its UID, for callers that compare both sides of a
+// two-way sync.
+func (c *Client) FetchMessageStates(ctx context.Context, folder string, f Filter) (map[string]MessageState, FilterStats, error) {
+	states, _, stats, err := scanFolder(ctx, c, folder, f, []imap.FetchItem{imap.FetchFlags}, func(msg *imap.Message) MessageState {
+		return MessageState{Flags: msg.Flags, UID: msg.Uid, Size: msg.Size}
+	})
+	return states, stats, err
+}
+
+// FolderMessage is one message as listed by ListMessages.
+type FolderMessage struct {
+	Date  time.Time // INTERNALDATE: when the server stored the message
+	ID    string
+	Flags []string
+	UID   uint32
+	Size  uint32
+}
+
+// ListMessages returns every message in folder that has a Message-Id, with
+// its flags, size and INTERNALDATE. Unlike the maps returned by the other
+// scans it keeps every copy of a Message-Id, in folder order.
+func (c *Client) ListMessages(ctx context.Context, folder string) ([]FolderMessage, error) {
+	var out []FolderMessage
+	_, _, err := scanMessages(ctx, c, folder, Filter{}, []imap.FetchItem{imap.FetchFlags, imap.FetchInternalDate}, func(id string, msg *imap.Message) {
+		out = append(out, FolderMessage{Date: msg.InternalDate, ID: id, Flags: msg.Flags, UID: msg.Uid, Size: msg.Size})
+	}, func() { out = nil })
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// scanFolder fetches the Message-Id of every message in folder matching f,
+// together with extra fetch items, and maps each Message-Id to value(msg).
+// It also returns the RFC822.SIZE total of the matching messages.
+func scanFolder[T any](ctx context.Context, c *Client, folder string, f Filter, extra []imap.FetchItem, value func(*imap.Message) T) (map[string]T, uint64, FilterStats, error) {
+	ids := make(map[string]T)
+	size, stats, err := scanMessages(ctx, c, folder, f, extra, func(id string, msg *imap.Message) {
+		ids[id] = value(msg)
+	}, func() { ids = make(map[string]T) })
+	if err != nil {
+		return nil, 0, FilterStats{}, err
+	}
+	return ids, size, stats, nil
+}
+
+// scanMessages fetches the Message-Id of every message in folder matching
+// f, together with extra fetch items, and calls each for every message that
+// has one. reset is called before every attempt, so a reconnect mid-scan
+// does not report messages twice. It returns the RFC822.SIZE total of the
+// matching messages.
+func scanMessages(ctx context.Context, c *Client, folder string, f Filter, extra []imap.FetchItem, each func(id string, msg *imap.Message), reset func()) (uint64, FilterStats, error) {
 	stop := c.withCancel(ctx)
 	defer stop()
 
 	if err := ctx.Err(); err != nil {
-		return nil, 0, err
+		return 0, FilterStats{}, err
 	}
 
 	c.log("[%s] Fetching folder %s...", c.prefix, folder)
 
 	var (
-		ids          map[string]uint32
 		totalSize    uint64
 		missingCount int
+		stats        FilterStats
 	)
 	err := c.safeCall(func(cli *imapclient.Client) error {
-		ids = nil
+		reset()
 		totalSize = 0
 		missingCount = 0
+		stats = FilterStats{}
 		mbox, err := c.selectIfNeeded(cli, folder)
 		if err != nil {
 			return fmt.Errorf("[%s] cannot select folder %s: %w", c.prefix, folder, err)
@@ -86,35 +178,63 @@ func (c *Client) FetchMessageMap(ctx context.Context, folder string) (map[string
 		}
 		c.log("[%s] Selected folder %s (%d messages)", c.prefix, folder, total)
 		if total == 0 {
-			ids = make(map[string]uint32)
 			return nil
 		}
+		// Unfiltered scans address the whole folder by sequence number;
+		// filtered ones fetch exactly the UIDs the search matched, in
+		// batches so a sparse match does not become one overlong command.
+		var batches []*imap.SeqSet
+		fetch := cli.Fetch
+		if f.IsZero() {
+			seqset := new(imap.SeqSet)
+			seqset.AddRange(1, total)
+			batches = append(batches, seqset)
+		} else {
+			uids, fstats, err := searchFilter(cli, f)
+			if err != nil {
+				return fmt.Errorf("[%s] search %s: %w", c.prefix, folder, err)
+			}
+			stats = fstats
+			c.log("[%s] Filter matched %d of %d messages in %s", c.prefix, len(uids), total, folder)
+			if len(uids) == 0 {
+				return nil
+			}
+			for start := 0; start < len(uids); start += uidFetchBatchSize {
+				seqset := new(imap.SeqSet)
+				seqset.AddNum(uids[start:min(start+uidFetchBatchSize, len(uids))]...)
+				batches = append(batches, seqset)
+			}
+			fetch = cli.UidFetch
+			total = uint32(len(uids))
+		}
 		c.log("[%s] Fetching %d message IDs from %s...", c.prefix, total, folder)
 
-		ids = make(map[string]uint32, total)
-		seqset := new(imap.SeqSet)
-		seqset.AddRange(1, total)
-		messages := make(chan *imap.Message, messageChanBuffer)
-		done := make(chan error, 1)
 		// RFC822.SIZE is part of the same FETCH so the size total comes
 		// free with the diff scan — no extra round-trip per folder.
-		items := []imap.FetchItem{messageIDHeaderSection.FetchItem(), imap.FetchUid, imap.FetchRFC822Size}
-		go func() { done <- cli.Fetch(seqset, items, messages) }()
+		items := append([]imap.FetchItem{messageIDHeaderSection.FetchItem(), imap.FetchUid, imap.FetchRFC822Size}, extra...)
+		for _, seqset := range batches {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			messages := make(chan *imap.Message, messageChanBuffer)
+			done := make(chan error, 1)
+			go func() { done <- fetch(seqset, items, messages) }()
 
-		for msg := range messages {
-			if ctx.Err() != nil {
-				continue
+			for msg := range messages {
+				if ctx.Err() != nil {
+					continue
+				}
+				totalSize += uint64(msg.Size)
+				id := readMessageIDHeader(msg)
+				if id == "" {
+					missingCount++
+					continue
+				}
+				each(id, msg)
 			}
-			totalSize += uint64(msg.Size)
-			id := readMessageIDHeader(msg)
-			if id == "" {
-				missingCount++
-				continue
+			if err := <-done; err != nil {
+				return fmt.Errorf("[%s] fetch IDs: %w", c.prefix, err)
 			}
-			ids[id] = msg.Uid
-		}
-		if err := <-done; err != nil {
-			return fmt.Errorf("[%s] fetch IDs: %w", c.prefix, err)
 		}
 		return nil
 	})
@@ -127,14 +247,14 @@ func (c *Client) FetchMessageMap(ctx context.Context, folder string) (map[string
 	}
 	if err != nil {
 		if ctx.Err() != nil {
-			return nil, 0, ctx.Err()
+			return 0, FilterStats{}, ctx.Err()
 		}
-		return nil, 0, err
+		return 0, FilterStats{}, err
 	}
 	if err := ctx.Err(); err != nil {
-		return nil, 0, err
+		return 0, FilterStats{}, err
 	}
-	return ids, totalSize, nil
+	return totalSize, stats, nil
 }
 
 // FetchMessageIDSet returns the set of Message-Ids in folder, dropping the
@@ -256,7 +376,9 @@ func (c *Client) StreamMessagesByUIDs(ctx context.Context, folder string, uids [
 			}
 			messages := make(chan *imap.Message, messageChanBuffer)
 			batchDone := make(chan error, 1)
-			items := []imap.FetchItem{imap.FetchEnvelope, fullBodyPeekSection.FetchItem()}
+			// FLAGS is nearly free next to the body and lets callers
+			// carry the flags over to the copy.
+			items := []imap.FetchItem{imap.FetchEnvelope, imap.FetchFlags, fullBodyPeekSection.FetchItem()}
 			go func() { batchDone <- cli.UidFetch(uidSet, items, messages) }()
 
 			for msg := range messages {
@@ -286,3 +408,67 @@ func (c *Client) StreamMessagesByUIDs(ctx context.Context, folder string, uids [
 	}
 	return nil
 }
+
+// bodyTextPeekSection is the message body without its header. Copies of one
+// message stored by different tools differ in Received and similar headers,
+// but not in the body.
+var bodyTextPeekSection = &imap.BodySectionName{
+	BodyPartName: imap.BodyPartName{Specifier: imap.TextSpecifier},
+	Peek:         true,
+}
+
+// HashBodies returns a SHA-256 hex digest of the body (without headers) of
+// each message in uids. Bodies are hashed as they stream in and are never
+// held in memory as a whole batch.
+func (c *Client) HashBodies(ctx context.Context, folder string, uids []uint32) (map[uint32]string, error) {
+	stop := c.withCancel(ctx)
+	defer stop()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	uids = slices.Clone(uids)
+	slices.Sort(uids)
+
+	hashes := make(map[uint32]string, len(uids))
+	for start := 0; start < len(uids); start += uidFetchBatchSize {
+		batch := uids[start:min(start+uidFetchBatchSize, len(uids))]
+		err := c.safeCall(func(cli *imapclient.Client) error {
+			if _, err := c.selectIfNeeded(cli, folder); err != nil {
+				return fmt.Errorf("[%s] select folder %s: %w", c.prefix, folder, err)
+			}
+			uidSet := new(imap.SeqSet)
+			uidSet.AddNum(batch...)
+			messages := make(chan *imap.Message, messageChanBuffer)
+			done := make(chan error, 1)
+			items := []imap.FetchItem{imap.FetchUid, bodyTextPeekSection.FetchItem()}
+			go func() { done <- cli.UidFetch(uidSet, items, messages) }()
+
+			for msg := range messages {
+				if ctx.Err() != nil {
+					continue
+				}
+				body := msg.GetBody(bodyTextPeekSection)
+				if body == nil {
+					continue
+				}
+				h := sha256.New()
+				if _, err := io.Copy(h, body); err != nil {
+					continue
+				}
+				hashes[msg.Uid] = hex.EncodeToString(h.Sum(nil))
+			}
+			if err := <-done; err != nil {
+				return fmt.Errorf("[%s] body fetch: %w", c.prefix, err)
+			}
+			return nil
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, err
+		}
+	}
+	return hashes, nil
+}
@@ -3,6 +3,8 @@ package client
 import (
 	"context"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 
 	"github.com/emersion/go-imap"
@@ -141,6 +143,23 @@ func (c *Client) ListSubfolders(ctx context.Context, folder, delimiter string) (
 	return c.listSubfoldersFromCache(ctx, folder, delimiter)
 }
 
+// ListFolders returns the names of all folders, sorted. It is served from
+// the mailbox cache, so unlike ListMailboxes it costs at most one LIST.
+func (c *Client) ListFolders(ctx context.Context) ([]string, error) {
+	stop := c.withCancel(ctx)
+	defer stop()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := c.ensureMailboxCache(ctx); err != nil {
+		return nil, err
+	}
+	c.mailboxCacheMu.RLock()
+	defer c.mailboxCacheMu.RUnlock()
+	return slices.Sorted(maps.Keys(c.mailboxCache.folders)), nil
+}
+
 // ListMailboxes fetches all folders plus lightweight statistics for each.
 func (c *Client) ListMailboxes(ctx context.Context) ([]*MailboxInfo, error) {
 	stop := c.withCancel(ctx)
@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"slices"
 	"strings"
 	"testing"
 )
@@ -156,6 +157,31 @@ func Test_ListSubfolders_fromCache(t *testing.T) {
 	}
 }
 
+// Test_ListFolders_sortedFromCache asserts that ListFolders returns every
+// cached folder in sorted order without a server round-trip.
+func Test_ListFolders_sortedFromCache(t *testing.T) {
+	t.Parallel()
+
+	srv := newFakeServer(t)
+	c := newClientWithFake(t, srv)
+	c.mailboxCache = mailboxCache{
+		folders:   map[string]struct{}{"Work": {}, "Archive": {}, "INBOX": {}},
+		delimiter: "/",
+		loaded:    true,
+	}
+
+	got, err := c.ListFolders(context.Background())
+	if err != nil {
+		t.Fatalf("ListFolders: %v", err)
+	}
+	if want := []string{"Archive", "INBOX", "Work"}; !slices.Equal(got, want) {
+		t.Errorf("ListFolders = %v, want %v", got, want)
+	}
+	if got := srv.callCount("LIST"); got != 0 {
+		t.Errorf("LIST issued unexpectedly: count=%d, want 0", got)
+	}
+}
+
 // Test_loadMailboxCache_populatesDelimiter asserts that when the cache is not
 // yet loaded, ensureMailboxCache issues a LIST and populates the cache
 // including the hierarchy delimiter from the server response.
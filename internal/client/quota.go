@@ -0,0 +1,133 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/emersion/go-imap"
+	imapclient "github.com/emersion/go-imap/client"
+	"github.com/emersion/go-imap/responses"
+	"github.com/emersion/go-imap/utf7"
+)
+
+// Quota resource names from RFC 9208. STORAGE is counted in units of 1024
+// octets, MESSAGE in messages.
+const (
+	QuotaStorage  = "STORAGE"
+	QuotaMessages = "MESSAGE"
+)
+
+// QuotaResource is the usage and limit of one resource of a quota root.
+type QuotaResource struct {
+	Name  string
+	Usage uint64
+	Limit uint64
+}
+
+// Quota is one quota root and its resources.
+type Quota struct {
+	Root      string
+	Resources []QuotaResource
+}
+
+// Resource returns the named resource of q, if the root limits it.
+func (q Quota) Resource(name string) (QuotaResource, bool) {
+	for _, r := range q.Resources {
+		if strings.EqualFold(r.Name, name) {
+			return r, true
+		}
+	}
+	return QuotaResource{}, false
+}
+
+// QuotaRoot returns the quota roots that apply to mailbox (GETQUOTAROOT,
+// RFC 2087 and RFC 9208). It returns nil without asking when the server does
+// not advertise QUOTA.
+func (c *Client) QuotaRoot(ctx context.Context, mailbox string) ([]Quota, error) {
+	stop := c.withCancel(ctx)
+	defer stop()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if !c.HasCapability("QUOTA") {
+		return nil, nil
+	}
+
+	var quotas []Quota
+	err := c.safeCall(func(cli *imapclient.Client) error {
+		quotas = nil
+		handler := responses.HandlerFunc(func(resp imap.Resp) error {
+			name, fields, ok := imap.ParseNamedResp(resp)
+			if !ok || name != "QUOTA" {
+				return responses.ErrUnhandled
+			}
+			q, err := parseQuota(fields)
+			if err != nil {
+				return err
+			}
+			quotas = append(quotas, q)
+			return nil
+		})
+		status, err := cli.Execute(&getQuotaRootCmd{mailbox: mailbox}, handler)
+		if err != nil {
+			return err
+		}
+		return status.Err()
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("[%s] get quota root of %s: %w", c.prefix, mailbox, err)
+	}
+	return quotas, nil
+}
+
+// parseQuota reads the fields of a "QUOTA root (name usage limit ...)"
+// response.
+func parseQuota(fields []any) (Quota, error) {
+	if len(fields) < 2 {
+		return Quota{}, fmt.Errorf("QUOTA response: %d fields, want 2", len(fields))
+	}
+	root, err := imap.ParseString(fields[0])
+	if err != nil {
+		return Quota{}, fmt.Errorf("QUOTA root: %w", err)
+	}
+	list, ok := fields[1].([]any)
+	if !ok || len(list)%3 != 0 {
+		return Quota{}, fmt.Errorf("QUOTA %q: malformed resource list", root)
+	}
+	q := Quota{Root: root}
+	for i := 0; i < len(list); i += 3 {
+		var vals [3]string
+		for j := range vals {
+			if vals[j], err = imap.ParseString(list[i+j]); err != nil {
+				return Quota{}, fmt.Errorf("QUOTA %q: %w", root, err)
+			}
+		}
+		usage, err := strconv.ParseUint(vals[1], 10, 64)
+		if err != nil {
+			return Quota{}, fmt.Errorf("QUOTA %q %s usage: %w", root, vals[0], err)
+		}
+		limit, err := strconv.ParseUint(vals[2], 10, 64)
+		if err != nil {
+			return Quota{}, fmt.Errorf("QUOTA %q %s limit: %w", root, vals[0], err)
+		}
+		q.Resources = append(q.Resources, QuotaResource{Name: strings.ToUpper(vals[0]), Usage: usage, Limit: limit})
+	}
+	return q, nil
+}
+
+// getQuotaRootCmd is GETQUOTAROOT, which go-imap's core client lacks.
+type getQuotaRootCmd struct {
+	mailbox string
+}
+
+// Command implements imap.Commander.
+func (cmd *getQuotaRootCmd) Command() *imap.Command {
+	mailbox, _ := utf7.Encoding.NewEncoder().String(cmd.mailbox)
+	return &imap.Command{Name: "GETQUOTAROOT", Arguments: []any{imap.FormatMailboxName(mailbox)}}
+}
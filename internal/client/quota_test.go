@@ -0,0 +1,49 @@
+package client
+
+import (
+	"testing"
+)
+
+func TestGetQuotaRootCmd_rendersMailbox(t *testing.T) {
+	t.Parallel()
+
+	got := renderCommand(t, &getQuotaRootCmd{mailbox: "INBOX"})
+	if want := "A1 GETQUOTAROOT INBOX\r\n"; got != want {
+		t.Errorf("rendered %q, want %q", got, want)
+	}
+}
+
+// Test_parseQuota_resources asserts that every resource triple of a QUOTA
+// response is read, with names upper-cased.
+func Test_parseQuota_resources(t *testing.T) {
+	t.Parallel()
+
+	q, err := parseQuota([]any{"", []any{"storage", "10", "512", "MESSAGE", "5", "1000"}})
+	if err != nil {
+		t.Fatalf("parseQuota: %v", err)
+	}
+	st, ok := q.Resource(QuotaStorage)
+	if !ok || st.Usage != 10 || st.Limit != 512 {
+		t.Errorf("STORAGE = %+v (found %v), want 10 of 512", st, ok)
+	}
+	msgs, ok := q.Resource(QuotaMessages)
+	if !ok || msgs.Usage != 5 || msgs.Limit != 1000 {
+		t.Errorf("MESSAGE = %+v (found %v), want 5 of 1000", msgs, ok)
+	}
+}
+
+// Test_parseQuota_malformed asserts that a resource list that is not made of
+// name, usage and limit triples is rejected.
+func Test_parseQuota_malformed(t *testing.T) {
+	t.Parallel()
+
+	for _, fields := range [][]any{
+		{""},
+		{"", []any{"STORAGE", "10"}},
+		{"", []any{"STORAGE", "ten", "512"}},
+	} {
+		if _, err := parseQuota(fields); err == nil {
+			t.Errorf("parseQuota(%v) = nil error", fields)
+		}
+	}
+}
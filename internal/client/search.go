@@ -0,0 +1,114 @@
+package client
+
+import (
+	"math"
+	"time"
+
+	"github.com/emersion/go-imap"
+	imapclient "github.com/emersion/go-imap/client"
+	"github.com/greeddj/imapsync-go/internal/search"
+)
+
+// Filter narrows a source scan to the messages matching every set bound. It
+// is evaluated by the server as a UID SEARCH, so excluded messages are never
+// fetched. Zero fields are unset.
+//
+// Since and Before compare against INTERNALDATE at day granularity (SINCE and
+// BEFORE in RFC 3501); MinSize and MaxSize are inclusive RFC822.SIZE bounds.
+// Search is a user-written expression (see package search) sent as is.
+type Filter struct {
+	Since   time.Time
+	Before  time.Time
+	Search  *imap.SearchCriteria
+	MinSize int64
+	MaxSize int64
+}
+
+// IsZero reports whether f selects every message.
+func (f Filter) IsZero() bool {
+	return f.Since.IsZero() && f.Before.IsZero() && f.Search == nil && f.MinSize == 0 && f.MaxSize == 0
+}
+
+// FilterStats counts the messages each bound of a Filter excluded. A message
+// outside several bounds is counted against each of them.
+type FilterStats struct {
+	Since   int
+	Before  int
+	MinSize int
+	MaxSize int
+	Search  int
+}
+
+// Add accumulates o into s.
+func (s *FilterStats) Add(o FilterStats) {
+	s.Since += o.Since
+	s.Before += o.Before
+	s.MinSize += o.MinSize
+	s.MaxSize += o.MaxSize
+	s.Search += o.Search
+}
+
+// Total is the sum of all counters.
+func (s FilterStats) Total() int {
+	return s.Since + s.Before + s.MinSize + s.MaxSize + s.Search
+}
+
+// bound is one active Filter field as a standalone search key, with the
+// counter its exclusions go to.
+type bound struct {
+	criteria *imap.SearchCriteria
+	counter  *int
+}
+
+// bounds returns one search key per set field of f.
+func (f Filter) bounds(stats *FilterStats) []bound {
+	var out []bound
+	if !f.Since.IsZero() {
+		out = append(out, bound{&imap.SearchCriteria{Since: f.Since}, &stats.Since})
+	}
+	if !f.Before.IsZero() {
+		out = append(out, bound{&imap.SearchCriteria{Before: f.Before}, &stats.Before})
+	}
+	if f.MinSize > 1 {
+		// LARGER n matches sizes strictly above n.
+		out = append(out, bound{&imap.SearchCriteria{Larger: clampUint32(f.MinSize - 1)}, &stats.MinSize})
+	}
+	if f.MaxSize > 0 {
+		// SMALLER n matches sizes strictly below n.
+		out = append(out, bound{&imap.SearchCriteria{Smaller: clampUint32(f.MaxSize + 1)}, &stats.MaxSize})
+	}
+	if f.Search != nil {
+		out = append(out, bound{f.Search, &stats.Search})
+	}
+	return out
+}
+
+// clampUint32 fits n into the 32-bit number IMAP uses for sizes.
+func clampUint32(n int64) uint32 {
+	return uint32(min(max(n, 0), math.MaxUint32))
+}
+
+// searchFilter runs f against the selected folder. It returns the matching
+// UIDs and, with one extra SEARCH per bound, how many messages each bound
+// excluded. The extra searches return UIDs only, so they stay cheap next to
+// the Message-Id fetch they save.
+func searchFilter(cli *imapclient.Client, f Filter) ([]uint32, FilterStats, error) {
+	var stats FilterStats
+	bounds := f.bounds(&stats)
+	all := imap.NewSearchCriteria()
+	for _, b := range bounds {
+		all = search.And(all, b.criteria)
+	}
+	uids, err := cli.UidSearch(all)
+	if err != nil {
+		return nil, FilterStats{}, err
+	}
+	for _, b := range bounds {
+		excluded, err := cli.UidSearch(&imap.SearchCriteria{Not: []*imap.SearchCriteria{b.criteria}})
+		if err != nil {
+			return nil, FilterStats{}, err
+		}
+		*b.counter = len(excluded)
+	}
+	return uids, stats, nil
+}
@@ -0,0 +1,198 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// filterSearchHandler serves a two-message INBOX where only UID 1 passes the
+// filter: the combined UID SEARCH returns 1, every negated per-bound search
+// returns 2. Search arguments are appended to searches.
+func filterSearchHandler(srv *fakeServer, mu *sync.Mutex, searches *[]string) func(net.Conn) {
+	return func(conn net.Conn) {
+		defer func() { _ = conn.Close() }()
+		_, _ = fmt.Fprintf(conn, "* OK [CAPABILITY IMAP4rev1] fake ready\r\n")
+		sc := bufio.NewScanner(conn)
+		for sc.Scan() {
+			parts := strings.SplitN(sc.Text(), " ", 3)
+			if len(parts) < 2 {
+				continue
+			}
+			tag, verb := parts[0], strings.ToUpper(parts[1])
+			arg := ""
+			if len(parts) == 3 {
+				arg = parts[2]
+			}
+			srv.mu.Lock()
+			srv.counts[verb]++
+			srv.mu.Unlock()
+			switch {
+			case verb == "SELECT" || verb == "EXAMINE":
+				_, _ = fmt.Fprintf(conn, "* 2 EXISTS\r\n* 0 RECENT\r\n")
+				_, _ = fmt.Fprintf(conn, "%s OK [READ-ONLY] %s completed\r\n", tag, verb)
+			case verb == "UID" && strings.HasPrefix(arg, "SEARCH "):
+				mu.Lock()
+				*searches = append(*searches, strings.TrimPrefix(arg, "SEARCH "))
+				mu.Unlock()
+				if strings.Contains(arg, " NOT ") {
+					_, _ = fmt.Fprintf(conn, "* SEARCH 2\r\n")
+				} else {
+					_, _ = fmt.Fprintf(conn, "* SEARCH 1\r\n")
+				}
+				_, _ = fmt.Fprintf(conn, "%s OK SEARCH completed\r\n", tag)
+			case verb == "UID" && strings.HasPrefix(arg, "FETCH "):
+				srv.mu.Lock()
+				srv.counts["UID FETCH"]++
+				srv.mu.Unlock()
+				hdr := "Message-Id: <ok@host>\r\n\r\n"
+				_, _ = fmt.Fprintf(conn,
+					"* 1 FETCH (UID 1 RFC822.SIZE 1024 BODY[HEADER.FIELDS (\"MESSAGE-ID\")] {%d}\r\n%s)\r\n",
+					len(hdr), hdr)
+				_, _ = fmt.Fprintf(conn, "%s OK FETCH completed\r\n", tag)
+			case verb == "LOGOUT":
+				_, _ = fmt.Fprintf(conn, "* BYE Logging out\r\n%s OK LOGOUT completed\r\n", tag)
+				return
+			default:
+				_, _ = fmt.Fprintf(conn, "%s OK %s completed\r\n", tag, verb)
+			}
+		}
+	}
+}
+
+// Test_FetchFilteredMessageMap_searchesBeforeFetch asserts that a filter is
+// sent as one combined UID SEARCH plus one negated search per bound, that
+// only matching UIDs are fetched, and that exclusions land on the right
+// counters.
+func Test_FetchFilteredMessageMap_searchesBeforeFetch(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var searches []string
+	srv := newFakeServer(t)
+	srv.addConnHandler(filterSearchHandler(srv, &mu, &searches))
+
+	c := newClientWithFake(t, srv)
+	c.mailboxCache = mailboxCache{folders: map[string]struct{}{"INBOX": {}}, delimiter: "/", loaded: true}
+
+	f := Filter{Since: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC), MaxSize: 2048}
+	ids, size, stats, err := c.FetchFilteredMessageMap(context.Background(), "INBOX", f)
+	if err != nil {
+		t.Fatalf("FetchFilteredMessageMap: %v", err)
+	}
+	if len(ids) != 1 || ids["ok@host"] != 1 || size != 1024 {
+		t.Errorf("ids=%v size=%d, want {ok@host:1} 1024", ids, size)
+	}
+	if stats != (FilterStats{Since: 1, MaxSize: 1}) {
+		t.Errorf("stats=%+v, want Since=1 MaxSize=1", stats)
+	}
+	if got := srv.callCount("FETCH"); got != 0 {
+		t.Errorf("sequence FETCH count=%d, want 0", got)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{
+		`CHARSET UTF-8 SINCE "2-Jan-2020" SMALLER 2049`,
+		`CHARSET UTF-8 NOT (SINCE "2-Jan-2020")`,
+		`CHARSET UTF-8 NOT (SMALLER 2049)`,
+	}
+	if strings.Join(searches, "|") != strings.Join(want, "|") {
+		t.Errorf("searches=%q, want %q", searches, want)
+	}
+}
+
+// Test_Filter_bounds_sizeEdges asserts that size bounds are inclusive: a
+// minimum of N asks for LARGER N-1 and a maximum of N for SMALLER N+1.
+func Test_Filter_bounds_sizeEdges(t *testing.T) {
+	t.Parallel()
+
+	var stats FilterStats
+	b := Filter{MinSize: 100, MaxSize: 200}.bounds(&stats)
+	if len(b) != 2 {
+		t.Fatalf("bounds=%d, want 2", len(b))
+	}
+	if b[0].criteria.Larger != 99 || b[1].criteria.Smaller != 201 {
+		t.Errorf("Larger=%d Smaller=%d, want 99 and 201", b[0].criteria.Larger, b[1].criteria.Smaller)
+	}
+	if !(Filter{}).IsZero() || (Filter{MinSize: 1}).IsZero() {
+		t.Error("IsZero mismatch")
+	}
+}
+
+// sparseSearchHandler serves an INBOX whose UID SEARCH matches the odd UIDs
+// up to 2n-1 and whose UID FETCH returns nothing. The number of UIDs each
+// FETCH asked for is appended to fetched.
+func sparseSearchHandler(n int, mu *sync.Mutex, fetched *[]int) func(net.Conn) {
+	return func(conn net.Conn) {
+		defer func() { _ = conn.Close() }()
+		_, _ = fmt.Fprintf(conn, "* OK [CAPABILITY IMAP4rev1] fake ready\r\n")
+		sc := bufio.NewScanner(conn)
+		sc.Buffer(nil, 1<<20)
+		for sc.Scan() {
+			parts := strings.SplitN(sc.Text(), " ", 3)
+			if len(parts) < 2 {
+				continue
+			}
+			tag, verb := parts[0], strings.ToUpper(parts[1])
+			arg := ""
+			if len(parts) == 3 {
+				arg = parts[2]
+			}
+			switch {
+			case verb == "SELECT" || verb == "EXAMINE":
+				_, _ = fmt.Fprintf(conn, "* %d EXISTS\r\n%s OK [READ-ONLY] %s completed\r\n", 2*n, tag, verb)
+			case verb == "UID" && strings.HasPrefix(arg, "SEARCH "):
+				var uids strings.Builder
+				if !strings.Contains(arg, " NOT ") {
+					for i := range n {
+						fmt.Fprintf(&uids, " %d", 2*i+1)
+					}
+				}
+				_, _ = fmt.Fprintf(conn, "* SEARCH%s\r\n%s OK SEARCH completed\r\n", uids.String(), tag)
+			case verb == "UID" && strings.HasPrefix(arg, "FETCH "):
+				set := strings.Fields(strings.TrimPrefix(arg, "FETCH "))[0]
+				mu.Lock()
+				*fetched = append(*fetched, len(strings.Split(set, ",")))
+				mu.Unlock()
+				_, _ = fmt.Fprintf(conn, "%s OK FETCH completed\r\n", tag)
+			case verb == "LOGOUT":
+				_, _ = fmt.Fprintf(conn, "* BYE Logging out\r\n%s OK LOGOUT completed\r\n", tag)
+				return
+			default:
+				_, _ = fmt.Fprintf(conn, "%s OK %s completed\r\n", tag, verb)
+			}
+		}
+	}
+}
+
+// Test_FetchFilteredMessageMap_batchesSparseMatches asserts that the UIDs a
+// filter matched are fetched uidFetchBatchSize at a time.
+func Test_FetchFilteredMessageMap_batchesSparseMatches(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var fetched []int
+	srv := newFakeServer(t)
+	srv.addConnHandler(sparseSearchHandler(2*uidFetchBatchSize+1, &mu, &fetched))
+
+	c := newClientWithFake(t, srv)
+	c.mailboxCache = mailboxCache{folders: map[string]struct{}{"INBOX": {}}, delimiter: "/", loaded: true}
+
+	f := Filter{Since: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)}
+	if _, _, _, err := c.FetchFilteredMessageMap(context.Background(), "INBOX", f); err != nil {
+		t.Fatalf("FetchFilteredMessageMap: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []int{uidFetchBatchSize, uidFetchBatchSize, 1}; !slices.Equal(fetched, want) {
+		t.Errorf("UIDs per FETCH = %v, want %v", fetched, want)
+	}
+}
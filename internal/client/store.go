@@ -0,0 +1,115 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/emersion/go-imap"
+	imapclient "github.com/emersion/go-imap/client"
+)
+
+// StoreFlags adds (op = imap.AddFlags) or removes (op = imap.RemoveFlags)
+// flags on the messages with the given UIDs in folder. The folder is
+// selected read-write for the call.
+func (c *Client) StoreFlags(ctx context.Context, folder string, uids []uint32, op imap.FlagsOp, flags []string) error {
+	stop := c.withCancel(ctx)
+	defer stop()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(uids) == 0 || len(flags) == 0 {
+		return nil
+	}
+	err := c.safeCall(func(cli *imapclient.Client) error {
+		if err := c.selectWritable(cli, folder); err != nil {
+			return err
+		}
+		return storeFlags(cli, uids, op, flags)
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("[%s] store flags in %s: %w", c.prefix, folder, err)
+	}
+	return nil
+}
+
+// DeleteMessages marks the messages with the given UIDs in folder \Deleted
+// and, when the server supports UIDPLUS, expunges exactly those messages.
+// Without UIDPLUS the messages stay flagged: a plain EXPUNGE would also
+// remove every other message the user had marked for deletion.
+//
+// expunged reports whether the messages were removed for good.
+func (c *Client) DeleteMessages(ctx context.Context, folder string, uids []uint32) (expunged bool, err error) {
+	stop := c.withCancel(ctx)
+	defer stop()
+
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	if len(uids) == 0 {
+		return false, nil
+	}
+	uidPlus := c.HasCapability("UIDPLUS")
+	err = c.safeCall(func(cli *imapclient.Client) error {
+		if err := c.selectWritable(cli, folder); err != nil {
+			return err
+		}
+		if err := storeFlags(cli, uids, imap.AddFlags, []string{imap.DeletedFlag}); err != nil {
+			return err
+		}
+		if !uidPlus {
+			return nil
+		}
+		seqset := new(imap.SeqSet)
+		seqset.AddNum(uids...)
+		status, err := cli.Execute(&uidExpungeCmd{uids: seqset}, nil)
+		if err != nil {
+			return err
+		}
+		return status.Err()
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+		return false, fmt.Errorf("[%s] delete messages in %s: %w", c.prefix, folder, err)
+	}
+	return uidPlus, nil
+}
+
+// selectWritable selects folder read-write. Unlike selectIfNeeded it always
+// issues the SELECT, since the cached selection may be read-only; the
+// read-write selection then serves later reads as well.
+func (c *Client) selectWritable(cli *imapclient.Client, folder string) error {
+	c.selectedFolder.Store(nil)
+	if _, err := cli.Select(folder, false); err != nil {
+		return fmt.Errorf("select folder %s: %w", folder, err)
+	}
+	f := folder
+	c.selectedFolder.Store(&f)
+	return nil
+}
+
+// storeFlags runs a silent UID STORE on the selected folder.
+func storeFlags(cli *imapclient.Client, uids []uint32, op imap.FlagsOp, flags []string) error {
+	seqset := new(imap.SeqSet)
+	seqset.AddNum(uids...)
+	values := make([]any, len(flags))
+	for i, f := range flags {
+		values[i] = f
+	}
+	return cli.UidStore(seqset, imap.FormatFlagsOp(op, true), values, nil)
+}
+
+// uidExpungeCmd is UID EXPUNGE (RFC 4315), which go-imap's core client lacks.
+type uidExpungeCmd struct {
+	uids *imap.SeqSet
+}
+
+// Command implements imap.Commander.
+func (cmd *uidExpungeCmd) Command() *imap.Command {
+	return &imap.Command{Name: "UID", Arguments: []any{imap.RawString("EXPUNGE"), cmd.uids}}
+}
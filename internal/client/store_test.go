@@ -0,0 +1,43 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/emersion/go-imap"
+)
+
+func TestUIDExpungeCmd_rendersUIDSet(t *testing.T) {
+	t.Parallel()
+
+	seqset := new(imap.SeqSet)
+	seqset.AddNum(3, 4, 5, 9)
+	got := renderCommand(t, &uidExpungeCmd{uids: seqset})
+	if want := "A1 UID EXPUNGE 3:5,9\r\n"; got != want {
+		t.Errorf("rendered %q, want %q", got, want)
+	}
+}
+
+// Test_DeleteMessages_withoutUIDPlusOnlyFlags asserts that a server without
+// UIDPLUS gets the \Deleted flag but no expunge, and that the folder is
+// selected read-write.
+func Test_DeleteMessages_withoutUIDPlusOnlyFlags(t *testing.T) {
+	t.Parallel()
+
+	srv := newFakeServer(t)
+	c := newClientWithFake(t, srv)
+
+	expunged, err := c.DeleteMessages(context.Background(), "INBOX", []uint32{7, 8})
+	if err != nil {
+		t.Fatalf("DeleteMessages: %v", err)
+	}
+	if expunged {
+		t.Error("expunged = true on a server without UIDPLUS")
+	}
+	if got := srv.callCount("SELECT"); got != 1 {
+		t.Errorf("SELECT count = %d, want 1", got)
+	}
+	if got := srv.callCount("UID"); got != 1 {
+		t.Errorf("UID command count = %d, want 1 (STORE only)", got)
+	}
+}
@@ -0,0 +1,227 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultTraceLiteralLimit is how many bytes of each literal (a message body,
+// usually) a trace keeps when the caller does not choose.
+const DefaultTraceLiteralLimit = 1024
+
+// redacted replaces credentials in a trace.
+const redacted = "<redacted>"
+
+// Tracer records the raw IMAP command and response stream of every
+// connection it is given, one protocol line per trace line, labeled with the
+// client's prefix and a connection number. LOGIN and AUTHENTICATE payloads
+// are redacted and literals are cut after a limit, so a trace can go into a
+// bug report.
+//
+// A Tracer is shared by every Client of a run; it is safe for concurrent
+// use. Write errors are ignored: tracing never fails a sync.
+type Tracer struct {
+	w            io.Writer
+	conns        atomic.Uint64
+	literalLimit int
+	mu           sync.Mutex
+}
+
+// NewTracer returns a Tracer writing to w that keeps at most literalLimit
+// bytes of each literal (0 keeps none).
+func NewTracer(w io.Writer, literalLimit int) *Tracer {
+	return &Tracer{w: w, literalLimit: max(literalLimit, 0)}
+}
+
+// wrap returns conn with both directions traced. label is read for every
+// line, so a SetPrefix after the connection is made still applies.
+func (t *Tracer) wrap(conn net.Conn, label func() string) net.Conn {
+	tc := &traceConn{Conn: conn, tracer: t, label: label, id: t.conns.Add(1)}
+	tc.out = traceStream{conn: tc, dir: "C:"}
+	tc.in = traceStream{conn: tc, dir: "S:"}
+	return tc
+}
+
+// write records one line.
+func (t *Tracer) write(label string, id uint64, dir, line string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, _ = fmt.Fprintf(t.w, "%s [%s#%d] %s %s\n", time.Now().UTC().Format("2006-01-02T15:04:05.000Z"), label, id, dir, line)
+}
+
+// traceConn tees both directions of a connection into its Tracer.
+type traceConn struct {
+	net.Conn
+	tracer  *Tracer
+	label   func() string
+	out, in traceStream
+	authTag string // tag of an AUTHENTICATE still in progress
+	id      uint64
+	authMu  sync.Mutex
+}
+
+// Read implements net.Conn.
+func (c *traceConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.in.feed(p[:n])
+	return n, err
+}
+
+// Write implements net.Conn.
+func (c *traceConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.out.feed(p[:n])
+	return n, err
+}
+
+// authenticating returns the tag of the AUTHENTICATE in progress, if any.
+func (c *traceConn) authenticating() string {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+	return c.authTag
+}
+
+// setAuthTag records the start ("A1") or end ("") of an AUTHENTICATE.
+func (c *traceConn) setAuthTag(tag string) {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+	c.authTag = tag
+}
+
+// traceStream splits one direction of a connection into lines. A line ending
+// in a literal marker ({N} or {N+}) is followed by N raw bytes; of those only
+// the Tracer's literal limit is kept, broken into lines as they come.
+type traceStream struct {
+	conn      *traceConn
+	dir       string
+	line      []byte
+	literal   int64 // bytes of the current literal still to come
+	kept      int   // bytes of the current literal traced so far
+	omitted   int64 // bytes of the current literal dropped
+	redactCmd bool  // inside a LOGIN that continues after a literal
+	mu        sync.Mutex
+}
+
+// feed consumes bytes that crossed the connection.
+func (s *traceStream) feed(p []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for len(p) > 0 {
+		if s.literal > 0 {
+			n := int(min(int64(len(p)), s.literal))
+			keep := min(n, max(s.conn.tracer.literalLimit-s.kept, 0))
+			if s.redactCmd {
+				keep = 0
+			} else {
+				s.line = append(s.line, p[:keep]...)
+				s.omitted += int64(n - keep)
+			}
+			s.kept += keep
+			s.literal -= int64(n)
+			p = p[n:]
+			for {
+				i := bytes.IndexByte(s.line, '\n')
+				if i < 0 {
+					break
+				}
+				s.write(strings.TrimRight(string(s.line[:i+1]), "\r\n"))
+				s.line = s.line[i+1:]
+			}
+			if s.literal == 0 {
+				if len(s.line) > 0 {
+					s.write(string(s.line))
+					s.line = s.line[:0]
+				}
+				if s.omitted > 0 {
+					s.write(fmt.Sprintf("[%d more literal bytes omitted]", s.omitted))
+				}
+				s.kept, s.omitted = 0, 0
+			}
+			continue
+		}
+
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			s.line = append(s.line, p...)
+			return
+		}
+		s.line = append(s.line, p[:i+1]...)
+		p = p[i+1:]
+		line := strings.TrimRight(string(s.line), "\r\n")
+		s.line = s.line[:0]
+		s.literal = literalSize(line)
+		s.emit(line)
+	}
+}
+
+// emit records one complete protocol line, redacting credentials.
+func (s *traceStream) emit(line string) {
+	if s.dir == "S:" {
+		if tag := s.conn.authenticating(); tag != "" && strings.HasPrefix(line, tag+" ") {
+			s.conn.setAuthTag("")
+		}
+		s.write(line)
+		return
+	}
+
+	switch {
+	case s.redactCmd:
+		// The rest of a LOGIN sent as literals: nothing to show.
+		s.redactCmd = s.literal > 0
+		return
+	case s.conn.authenticating() != "":
+		// A SASL response to a server challenge.
+		s.write(redacted)
+		return
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		s.write(line)
+		return
+	}
+	switch tag, verb := fields[0], strings.ToUpper(fields[1]); verb {
+	case "LOGIN":
+		s.write(tag + " " + fields[1] + " " + redacted)
+		s.redactCmd = s.literal > 0
+	case "AUTHENTICATE":
+		out := strings.Join(fields[:min(len(fields), 3)], " ")
+		if len(fields) > 3 {
+			// SASL-IR: the initial response rides on the command.
+			out += " " + redacted
+		}
+		s.write(out)
+		s.conn.setAuthTag(tag)
+	default:
+		s.write(line)
+	}
+}
+
+// write hands a line to the Tracer under the connection's label.
+func (s *traceStream) write(line string) {
+	s.conn.tracer.write(s.conn.label(), s.conn.id, s.dir, line)
+}
+
+// literalSize returns N when line ends in a literal marker {N} or {N+}.
+func literalSize(line string) int64 {
+	if !strings.HasSuffix(line, "}") {
+		return 0
+	}
+	i := strings.LastIndexByte(line, '{')
+	if i < 0 {
+		return 0
+	}
+	n, err := strconv.ParseInt(strings.TrimSuffix(line[i+1:len(line)-1], "+"), 10, 64)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
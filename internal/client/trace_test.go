@@ -0,0 +1,115 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+// newTraceConn returns a traceConn with nothing behind it, for feeding its
+// streams directly.
+func newTraceConn(buf *bytes.Buffer, literalLimit int) *traceConn {
+	tc, _ := NewTracer(buf, literalLimit).wrap(nil, func() string { return "src" }).(*traceConn)
+	return tc
+}
+
+// Test_traceStream_redactsLogin asserts that LOGIN credentials never reach
+// the trace, whether sent quoted or as literals.
+func Test_traceStream_redactsLogin(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	tc := newTraceConn(&buf, 1024)
+	tc.out.feed([]byte("A1 LOGIN \"user\" \"s3cret\"\r\n"))
+	tc.out.feed([]byte("A2 LOGIN {4}\r\nuser {6}\r\ns3cret\r\nA3 NOOP\r\n"))
+
+	got := buf.String()
+	if strings.Contains(got, "s3cret") {
+		t.Errorf("password in trace:\n%s", got)
+	}
+	for _, want := range []string{"C: A1 LOGIN <redacted>", "C: A2 LOGIN <redacted>", "[src#1] C: A3 NOOP"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("trace lacks %q:\n%s", want, got)
+		}
+	}
+	if n := strings.Count(got, "\n"); n != 3 {
+		t.Errorf("trace has %d lines, want 3:\n%s", n, got)
+	}
+}
+
+// Test_traceStream_redactsAuthenticate asserts that SASL responses are
+// redacted until the server completes AUTHENTICATE, and not after.
+func Test_traceStream_redactsAuthenticate(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	tc := newTraceConn(&buf, 1024)
+	tc.out.feed([]byte("A1 AUTHENTICATE CRAM-MD5\r\n"))
+	tc.in.feed([]byte("+ PDE4OTYuNjk3MTcwOTUyQHBvc3RvZmZpY2U+\r\n"))
+	tc.out.feed([]byte("dGltIGI5MTNhNjAyYzdlZGE3YTQ5NWI0ZTZlNzMzNGQzODkw\r\n"))
+	tc.in.feed([]byte("A1 OK authenticated\r\n"))
+	tc.out.feed([]byte("A2 AUTHENTICATE PLAIN AHVzZXIAczNjcmV0\r\n"))
+	tc.in.feed([]byte("A2 OK authenticated\r\n"))
+	tc.out.feed([]byte("A3 SELECT INBOX\r\n"))
+
+	got := buf.String()
+	for _, secret := range []string{"dGltIGI5MTNh", "AHVzZXIAczNjcmV0"} {
+		if strings.Contains(got, secret) {
+			t.Errorf("SASL response %q in trace:\n%s", secret, got)
+		}
+	}
+	for _, want := range []string{"C: A1 AUTHENTICATE CRAM-MD5\n", "C: <redacted>", "C: A2 AUTHENTICATE PLAIN <redacted>", "C: A3 SELECT INBOX"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("trace lacks %q:\n%s", want, got)
+		}
+	}
+}
+
+// Test_traceStream_truncatesLiterals asserts that a literal is cut after the
+// limit, split across reads or not, and that tracing resumes after it.
+func Test_traceStream_truncatesLiterals(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	tc := newTraceConn(&buf, 8)
+	tc.in.feed([]byte("* 1 FETCH (UID 7 BODY[] {20}\r\nSubject"))
+	tc.in.feed([]byte(": hi\r\n\r\nbody text)\r\nA1 OK done\r\n"))
+
+	got := buf.String()
+	for _, want := range []string{"S: * 1 FETCH (UID 7 BODY[] {20}", "S: Subject:", "S: [12 more literal bytes omitted]", "S: text)", "S: A1 OK done"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("trace lacks %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "body") {
+		t.Errorf("literal past the limit in trace:\n%s", got)
+	}
+}
+
+// Test_New_traceLabelsWithPrefix asserts that a traced Client records its
+// login exchange and labels lines with the prefix once it is set.
+func Test_New_traceLabelsWithPrefix(t *testing.T) {
+	t.Parallel()
+
+	srv := newFakeServer(t)
+	var buf bytes.Buffer
+	tracer := NewTracer(&buf, DefaultTraceLiteralLimit)
+	c, err := New(context.Background(), srv.ln.Addr().String(), "user", "s3cret", Options{Trace: tracer, Label: "src"})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	_ = c.Logout()
+
+	tracer.mu.Lock()
+	got := buf.String()
+	tracer.mu.Unlock()
+	if strings.Contains(got, "s3cret") {
+		t.Errorf("password in trace:\n%s", got)
+	}
+	for _, want := range []string{"[src#1] S: * OK", "[src#1] C: ", "LOGIN <redacted>", "LIST"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("trace lacks %q:\n%s", want, got)
+		}
+	}
+}
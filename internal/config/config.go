@@ -2,13 +2,19 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/greeddj/imapsync-go/internal/provider"
 	"github.com/urfave/cli/v3"
 	"gopkg.in/yaml.v3"
 )
@@ -22,6 +28,15 @@ var (
 	ErrDstServerRequired = errors.New("destination server is required")
 	ErrDstUserRequired   = errors.New("destination user is required")
 	ErrDstPassRequired   = errors.New("destination password is required")
+	ErrInvalidServer     = errors.New("server must be host:port")
+	ErrInvalidAuth       = errors.New("auth must be login or cram-md5")
+)
+
+// Authentication mechanisms accepted in Credentials.Auth, case-insensitive.
+// An empty Auth means AuthLogin.
+const (
+	AuthLogin   = "login"
+	AuthCramMD5 = "cram-md5"
 )
 
 const (
@@ -36,12 +51,33 @@ const (
 )
 
 // Config holds the entire configuration for the application.
+//
+// The embedded Limits apply to every mapping; a map entry can override them.
+//
+// The "src" key holds either one account or a list of them (Sources). Src is
+// the first of those accounts, kept for code that deals with a single source.
+//
+// Providers adds provider profiles to the built-in ones or overrides them;
+// ProviderTable returns the result.
 type Config struct {
-	Src       Credentials        `json:"src"        yaml:"src"`
-	Dst       Credentials        `json:"dst"        yaml:"dst"`
-	Map       []DirectoryMapping `json:"map"        yaml:"map"`
-	RateLimit RateLimit          `json:"rate_limit" yaml:"rate_limit"`
-	Workers   int                `json:"-"          yaml:"-"`
+	Src       Credentials         `json:"-"                   yaml:"-"`
+	Sources   Sources             `json:"src"                 yaml:"src"`
+	Dst       Credentials         `json:"dst"                 yaml:"dst"`
+	Map       []DirectoryMapping  `json:"map"                 yaml:"map"`
+	RateLimit RateLimit           `json:"rate_limit"          yaml:"rate_limit"`
+	Providers []provider.Provider `json:"providers,omitempty" yaml:"providers,omitempty"`
+	providers provider.Table
+	Limits    `yaml:",inline"`
+	Workers   int `json:"-" yaml:"-"`
+}
+
+// ProviderTable returns the built-in provider profiles with the config's
+// applied, as Load merged them.
+func (c *Config) ProviderTable() provider.Table {
+	if c.providers == nil {
+		return provider.Builtin()
+	}
+	return c.providers
 }
 
 // RateLimit caps client-side throughput. Zero values mean "unlimited" and the
@@ -56,27 +92,155 @@ type RateLimit struct {
 }
 
 // Credentials holds IMAP connection data.
+//
+// The password is Pass or comes from one of the Pass* sources instead; see
+// resolvePass. Once New returns, Pass holds it whatever its source.
 type Credentials struct {
-	Label  string `json:"label"  yaml:"label"`  // Human-readable label for the server
-	Server string `json:"server" yaml:"server"` // Server address (host:port)
-	User   string `json:"user"   yaml:"user"`   // Username
-	Pass   string `json:"pass"   yaml:"pass"`   // Password
-	Auth   string `json:"auth"   yaml:"auth"`   // [ "", "login", "cram-md5" ] Default is login ("").
+	Label       string `json:"label"                  yaml:"label"`                  // Human-readable label for the server
+	Server      string `json:"server"                 yaml:"server"`                 // Server address (host:port)
+	User        string `json:"user"                   yaml:"user"`                   // Username
+	Pass        string `json:"pass,omitempty"         yaml:"pass,omitempty"`         // Password
+	PassEnv     string `json:"pass_env,omitempty"     yaml:"pass_env,omitempty"`     // Environment variable holding the password
+	PassFile    string `json:"pass_file,omitempty"    yaml:"pass_file,omitempty"`    // File whose first line is the password
+	PassCommand string `json:"pass_command,omitempty" yaml:"pass_command,omitempty"` // Shell command printing the password
+	PassKeyring string `json:"pass_keyring,omitempty" yaml:"pass_keyring,omitempty"` // OS keyring service holding the password
+	Auth        string `json:"auth,omitempty"         yaml:"auth,omitempty"`         // [ "", "login", "cram-md5" ] Default is login ("").
+}
+
+// Source is one source account. Map, when set, replaces the top-level map
+// for this account; Prefix, when set, is prepended to every destination
+// folder of the account, so that several merged accounts can land side by
+// side (e.g. "Legacy/SupportA").
+type Source struct {
+	Credentials `yaml:",inline"`
+	Map         []DirectoryMapping `json:"map,omitempty"    yaml:"map,omitempty"`
+	Prefix      string             `json:"prefix,omitempty" yaml:"prefix,omitempty"`
 }
 
-// DirectoryMapping holds source and destination folder names.
+// Sources decodes the "src" key, which may be a single object or a list.
+type Sources []Source
+
+// UnmarshalJSON accepts either one source object or an array of them.
+func (s *Sources) UnmarshalJSON(data []byte) error {
+	switch trimmed := bytes.TrimSpace(data); {
+	case bytes.Equal(trimmed, []byte("null")):
+		*s = nil
+		return nil
+	case len(trimmed) > 0 && trimmed[0] == '[':
+		var list []Source
+		if err := json.Unmarshal(trimmed, &list); err != nil {
+			return err
+		}
+		*s = list
+		return nil
+	}
+	var one Source
+	if err := json.Unmarshal(data, &one); err != nil {
+		return err
+	}
+	*s = Sources{one}
+	return nil
+}
+
+// UnmarshalYAML accepts either one source mapping or a sequence of them.
+func (s *Sources) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.SequenceNode {
+		var list []Source
+		if err := node.Decode(&list); err != nil {
+			return err
+		}
+		*s = list
+		return nil
+	}
+	var one Source
+	if err := node.Decode(&one); err != nil {
+		return err
+	}
+	*s = Sources{one}
+	return nil
+}
+
+// SourceList returns the source accounts to sync, in config order. A source
+// without its own map gets the top-level one. A Config built in code with
+// only Src set yields that single account.
+func (c *Config) SourceList() []Source {
+	if len(c.Sources) == 0 {
+		return []Source{{Credentials: c.Src, Map: c.Map}}
+	}
+	out := slices.Clone(c.Sources)
+	for i := range out {
+		if len(out[i].Map) == 0 {
+			out[i].Map = c.Map
+		}
+	}
+	return out
+}
+
+// DirectoryMapping holds source and destination folder names, plus optional
+// date and size limits that override the top-level ones for this mapping.
 type DirectoryMapping struct {
 	Source      string `json:"src" yaml:"src"` // Source folder name
 	Destination string `json:"dst" yaml:"dst"` // Destination folder name
+	Limits      `yaml:",inline"`
 }
 
-// New loads configuration from the file specified in CLI context.
-// It automatically detects the format (JSON or YAML) based on file extension.
-// Supported extensions: .json, .yaml, .yml
+// New loads configuration from the file specified in CLI context, applies
+// the CLI overrides, resolves the passwords and validates the result.
 // It returns an error if the file cannot be read or contains invalid data.
 func New(c *cli.Command) (*Config, error) {
-	configPath := c.String("config")
-	filePath, err := filepath.Abs(configPath)
+	cfg, err := Load(c.String("config"))
+	if err != nil {
+		return nil, err
+	}
+
+	// Clamp worker count to [minWorkers, maxWorkers]. The user-facing default
+	// (4) lives on the CLI flag — config only enforces the safe range, so an
+	// out-of-range value is corrected without silently falling back to 1.
+	cfg.Workers = clampWorkers(c.Int("workers"))
+
+	// CLI flags take precedence when the user explicitly sets them; config
+	// values fill in only the unset fields. We use 0 as "unset" — that also
+	// happens to be the "unlimited" sentinel, which is fine: a config-set
+	// value of 0 means the same thing.
+	if v := c.Int("bps-down"); v != 0 {
+		cfg.RateLimit.DownBPS = v
+	}
+	if v := c.Int("bps-up"); v != 0 {
+		cfg.RateLimit.UpBPS = v
+	}
+	if v := c.Int("max-connections"); v != 0 {
+		cfg.RateLimit.MaxConnections = v
+	}
+	cfg.Limits = cfg.Limits.Merge(Limits{
+		Since:   c.String("since"),
+		Before:  c.String("before"),
+		MaxAge:  c.String("max-age"),
+		MinSize: c.String("min-size"),
+		MaxSize: c.String("max-size"),
+	})
+
+	if err := cfg.resolveSecrets(); err != nil {
+		return nil, err
+	}
+	if len(cfg.Sources) > 0 {
+		cfg.Src = cfg.Sources[0].Credentials
+	}
+
+	// Validate required fields.
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// Load reads the config file at path, expands ${VAR} references, fills in
+// default labels and merges the file's provider profiles into the built-in
+// ones. The format (JSON or YAML) follows the file extension: .json, .yaml or
+// .yml. Passwords are not resolved and nothing else is validated; New does
+// both.
+func Load(path string) (*Config, error) {
+	filePath, err := filepath.Abs(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get absolute path for config file %q: %w", filePath, err)
 	}
@@ -94,50 +258,38 @@ func New(c *cli.Command) (*Config, error) {
 
 	switch ext {
 	case ".json":
-		if err := json.Unmarshal(data, &cfg); err != nil {
+		if err := decodeJSON(data, &cfg); err != nil {
 			return nil, fmt.Errorf("invalid JSON in config file %q: %w", filePath, err)
 		}
 	case ".yaml", ".yml":
-		if err := yaml.Unmarshal(data, &cfg); err != nil {
+		if err := decodeYAML(data, &cfg); err != nil {
 			return nil, fmt.Errorf("invalid YAML in config file %q: %w", filePath, err)
 		}
 	default:
 		return nil, fmt.Errorf("unsupported config file format %q; supported: .json, .yaml, .yml", ext)
 	}
 
-	// Set default labels if not provided in config.
-	if cfg.Src.Label == "" {
-		cfg.Src.Label = defaultSourceLabel
-	}
-
-	if cfg.Dst.Label == "" {
-		cfg.Dst.Label = defaultDestLabel
+	if cfg.providers, err = provider.Merge(provider.Builtin(), cfg.Providers); err != nil {
+		return nil, fmt.Errorf("config file %q: %w", filePath, err)
 	}
 
-	// Clamp worker count to [minWorkers, maxWorkers]. The user-facing default
-	// (4) lives on the CLI flag — config only enforces the safe range, so an
-	// out-of-range value is corrected without silently falling back to 1.
-	cfg.Workers = clampWorkers(c.Int("workers"))
-
-	// CLI flags take precedence when the user explicitly sets them; config
-	// values fill in only the unset fields. We use 0 as "unset" — that also
-	// happens to be the "unlimited" sentinel, which is fine: a config-set
-	// value of 0 means the same thing.
-	if v := c.Int("bps-down"); v != 0 {
-		cfg.RateLimit.DownBPS = v
+	// Set default labels if not provided in config: "src" for the first
+	// source, "src2", "src3", … for the rest.
+	for i := range cfg.Sources {
+		if cfg.Sources[i].Label != "" {
+			continue
+		}
+		cfg.Sources[i].Label = defaultSourceLabel
+		if i > 0 {
+			cfg.Sources[i].Label = fmt.Sprintf("%s%d", defaultSourceLabel, i+1)
+		}
 	}
-	if v := c.Int("bps-up"); v != 0 {
-		cfg.RateLimit.UpBPS = v
+	if len(cfg.Sources) > 0 {
+		cfg.Src = cfg.Sources[0].Credentials
 	}
-	if v := c.Int("max-connections"); v != 0 {
-		cfg.RateLimit.MaxConnections = v
-	}
-
-	// Validate required fields.
-	if err := cfg.validate(); err != nil {
-		return nil, err
+	if cfg.Dst.Label == "" {
+		cfg.Dst.Label = defaultDestLabel
 	}
-
 	return &cfg, nil
 }
 
@@ -155,14 +307,19 @@ func clampWorkers(n int) int {
 
 // validate checks that all required configuration fields are present.
 func (c *Config) validate() error {
-	if c.Src.Server == "" {
-		return ErrSrcServerRequired
-	}
-	if c.Src.User == "" {
-		return ErrSrcUserRequired
-	}
-	if c.Src.Pass == "" {
-		return ErrSrcPassRequired
+	sources := c.SourceList()
+	labels := make(map[string]int, len(sources))
+	for i, s := range sources {
+		if err := s.validate(); err != nil {
+			if len(sources) == 1 {
+				return err
+			}
+			return fmt.Errorf("source %d (%s): %w", i+1, s.Label, err)
+		}
+		if j, dup := labels[s.Label]; dup && len(sources) > 1 {
+			return fmt.Errorf("source %d: label %q is already used by source %d", i+1, s.Label, j+1)
+		}
+		labels[s.Label] = i
 	}
 	if c.Dst.Server == "" {
 		return ErrDstServerRequired
@@ -173,5 +330,67 @@ func (c *Config) validate() error {
 	if c.Dst.Pass == "" {
 		return ErrDstPassRequired
 	}
+	if err := c.Dst.validateConnection(); err != nil {
+		return fmt.Errorf("destination: %w", err)
+	}
+	now := time.Now()
+	if _, err := c.Limits.Resolve(now); err != nil {
+		return fmt.Errorf("invalid limits: %w", err)
+	}
+	for _, s := range sources {
+		for i, m := range s.Map {
+			if _, err := c.Limits.Merge(m.Limits).Resolve(now); err != nil {
+				if len(sources) > 1 {
+					return fmt.Errorf("invalid limits for map entry %d (%s) of source %s: %w", i+1, m.Source, s.Label, err)
+				}
+				return fmt.Errorf("invalid limits for map entry %d (%s): %w", i+1, m.Source, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validate checks that the source account has its connection details.
+func (s Source) validate() error {
+	switch {
+	case s.Server == "":
+		return ErrSrcServerRequired
+	case s.User == "":
+		return ErrSrcUserRequired
+	case s.Pass == "":
+		return ErrSrcPassRequired
+	}
+	return s.validateConnection()
+}
+
+// validateConnection checks the syntax of Server and Auth, which would
+// otherwise only fail once dialled, or not at all: an unknown Auth used to
+// fall back to LOGIN.
+func (cr Credentials) validateConnection() error {
+	if err := ValidateServer(cr.Server); err != nil {
+		return err
+	}
+	return ValidateAuth(cr.Auth)
+}
+
+// ValidateServer checks that addr is host:port with a valid port.
+func ValidateServer(addr string) error {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || host == "" {
+		return fmt.Errorf("%w, got %q", ErrInvalidServer, addr)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("%w, got %q: bad port", ErrInvalidServer, addr)
+	}
 	return nil
 }
+
+// ValidateAuth checks auth against the supported mechanisms; empty means
+// login.
+func ValidateAuth(auth string) error {
+	switch strings.ToLower(auth) {
+	case "", AuthLogin, AuthCramMD5:
+		return nil
+	}
+	return fmt.Errorf("%w, got %q", ErrInvalidAuth, auth)
+}
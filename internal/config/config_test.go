@@ -9,6 +9,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/greeddj/imapsync-go/internal/provider"
 	"github.com/urfave/cli/v3"
 	"gopkg.in/yaml.v3"
 )
@@ -35,6 +36,11 @@ func runNewWithArgs(t *testing.T, ext, content string, extraArgs ...string) (*Co
 			&cli.IntFlag{Name: "bps-down"},
 			&cli.IntFlag{Name: "bps-up"},
 			&cli.IntFlag{Name: "max-connections"},
+			&cli.StringFlag{Name: "since"},
+			&cli.StringFlag{Name: "before"},
+			&cli.StringFlag{Name: "max-age"},
+			&cli.StringFlag{Name: "min-size"},
+			&cli.StringFlag{Name: "max-size"},
 		},
 		Action: func(_ context.Context, c *cli.Command) error {
 			gotCfg, gotErr = New(c)
@@ -120,6 +126,11 @@ func TestNew_missingFileReturnsError(t *testing.T) {
 			&cli.IntFlag{Name: "bps-down"},
 			&cli.IntFlag{Name: "bps-up"},
 			&cli.IntFlag{Name: "max-connections"},
+			&cli.StringFlag{Name: "since"},
+			&cli.StringFlag{Name: "before"},
+			&cli.StringFlag{Name: "max-age"},
+			&cli.StringFlag{Name: "min-size"},
+			&cli.StringFlag{Name: "max-size"},
 		},
 		Action: func(_ context.Context, c *cli.Command) error {
 			_, gotErr = New(c)
@@ -224,6 +235,18 @@ func TestValidate(t *testing.T) {
 			ErrDstPassRequired, "missing destination password",
 			Config{Src: valid, Dst: Credentials{Server: valid.Server, User: valid.User}},
 		},
+		{
+			ErrInvalidServer, "source server without port",
+			Config{Src: Credentials{Server: "imap.example.com", User: valid.User, Pass: valid.Pass}, Dst: valid},
+		},
+		{
+			ErrInvalidServer, "destination port out of range",
+			Config{Src: valid, Dst: Credentials{Server: "imap.example.com:99999", User: valid.User, Pass: valid.Pass}},
+		},
+		{
+			ErrInvalidAuth, "unknown auth",
+			Config{Src: Credentials{Server: valid.Server, User: valid.User, Pass: valid.Pass, Auth: "plain"}, Dst: valid},
+		},
 	}
 
 	for _, tt := range tests {
@@ -323,3 +346,146 @@ func TestRateLimitOmittedJSON(t *testing.T) {
 		t.Errorf("RateLimit = %+v, want zero value", cfg.RateLimit)
 	}
 }
+
+const multiSourceYAMLConfig = `src:
+  - server: a:993
+    user: u
+    pass: p
+    prefix: Legacy/SupportA
+    map:
+      - {src: INBOX, dst: INBOX}
+  - label: team-b
+    server: b:993
+    user: u
+    pass: p
+  - server: c:993
+    user: u
+    pass: p
+dst: {server: dst:993, user: u, pass: p}
+map:
+  - {src: Archive, dst: Archive}
+`
+
+// TestNew_sourceList covers a list-valued src: default labels, per-source
+// maps with the top-level map as fallback, and Src mirroring the first entry.
+func TestNew_sourceList(t *testing.T) {
+	t.Parallel()
+	cfg, err := runNewWithArgs(t, ".yaml", multiSourceYAMLConfig)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	sources := cfg.SourceList()
+	if len(sources) != 3 {
+		t.Fatalf("len(SourceList) = %d, want 3", len(sources))
+	}
+	for i, want := range []string{"src", "team-b", "src3"} {
+		if sources[i].Label != want {
+			t.Errorf("source %d label = %q, want %q", i+1, sources[i].Label, want)
+		}
+	}
+	if sources[0].Prefix != "Legacy/SupportA" || len(sources[0].Map) != 1 || sources[0].Map[0].Source != "INBOX" {
+		t.Errorf("source 1 = %+v, want its own map and prefix", sources[0])
+	}
+	if len(sources[1].Map) != 1 || sources[1].Map[0].Source != "Archive" {
+		t.Errorf("source 2 map = %+v, want the top-level map", sources[1].Map)
+	}
+	if cfg.Src.Server != "a:993" || cfg.Src.Label != "src" {
+		t.Errorf("Src = %+v, want the first source", cfg.Src)
+	}
+}
+
+// TestNew_providers checks that each config's provider profiles are merged
+// into its own table, so that a second config loaded in the same process
+// sees only the built-in profiles and its own.
+func TestNew_providers(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := runNewWithArgs(t, ".yaml", `
+src: {server: "imap.corp.example:993", user: u, pass: p}
+dst: {server: "imap.gmail.com:993", user: u, pass: p}
+providers:
+  - name: Corp
+    hosts: ["*.corp.example"]
+    max_connections: 4
+    folders: {sent: Outbox}
+  - name: Gmail
+    max_connections: 10
+`)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	other, err := runNewWithArgs(t, ".yaml", `
+src: {server: "s:993", user: u, pass: p}
+dst: {server: "d:993", user: u, pass: p}
+`)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	providers := cfg.ProviderTable()
+	if p, ok := providers.Detect("imap.corp.example:993"); !ok || p.MaxConnections != 4 || p.Folders.Sent != "Outbox" {
+		t.Errorf("Detect(corp) = %+v, %v; want the custom profile", p, ok)
+	}
+	if p, _ := providers.Detect("imap.gmail.com:993"); p.MaxConnections != 10 || p.DownBPS != 300_000 {
+		t.Errorf("Detect(gmail) = %+v; want the override on top of the built-in", p)
+	}
+	if _, ok := other.ProviderTable().Detect("imap.corp.example:993"); ok {
+		t.Error("another config sees the first one's custom profile")
+	}
+	if p, _ := other.ProviderTable().Detect("imap.gmail.com:993"); p.MaxConnections != 15 {
+		t.Errorf("another config's Gmail = %+v; want the built-in", p)
+	}
+
+	_, err = runNewWithArgs(t, ".yaml", `
+src: {server: "s:993", user: u, pass: p}
+dst: {server: "d:993", user: u, pass: p}
+providers: [{name: New}]
+`)
+	if !errors.Is(err, provider.ErrInvalid) {
+		t.Errorf("New with a host-less provider: err = %v, want ErrInvalid", err)
+	}
+}
+
+// TestSourcesJSON checks that "src" decodes from both an object and an array.
+func TestSourcesJSON(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		data string
+		want int
+	}{
+		{`{"src":{"server":"a"}}`, 1},
+		{`{"src":[{"server":"a"},{"server":"b","prefix":"B"}]}`, 2},
+		{`{"src":null}`, 0},
+	} {
+		var cfg Config
+		if err := json.Unmarshal([]byte(tt.data), &cfg); err != nil {
+			t.Fatalf("Unmarshal(%s): %v", tt.data, err)
+		}
+		if len(cfg.Sources) != tt.want {
+			t.Errorf("Unmarshal(%s): %d sources, want %d", tt.data, len(cfg.Sources), tt.want)
+		}
+	}
+}
+
+// TestValidate_sourceList checks that a bad source is named in the error and
+// that two sources cannot share a label.
+func TestValidate_sourceList(t *testing.T) {
+	t.Parallel()
+	creds := Credentials{Server: "s:993", User: "u", Pass: "p"}
+	named := func(label string) Source {
+		c := creds
+		c.Label = label
+		return Source{Credentials: c}
+	}
+
+	cfg := Config{Dst: creds, Sources: Sources{named("a"), {Credentials: Credentials{Label: "b", Server: "s:993", User: "u"}}}}
+	err := cfg.validate()
+	if !errors.Is(err, ErrSrcPassRequired) || !strings.Contains(err.Error(), "source 2 (b)") {
+		t.Errorf("validate() = %v, want ErrSrcPassRequired naming source 2 (b)", err)
+	}
+
+	cfg = Config{Dst: creds, Sources: Sources{named("a"), named("a")}}
+	if err := cfg.validate(); err == nil || !strings.Contains(err.Error(), "already used") {
+		t.Errorf("validate() = %v, want duplicate label error", err)
+	}
+}
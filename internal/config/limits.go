@@ -0,0 +1,170 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-imap"
+	"github.com/greeddj/imapsync-go/internal/search"
+)
+
+// dateLayout is the format of Limits.Since and Limits.Before.
+const dateLayout = "2006-01-02"
+
+// Limits selects source messages by date, size and an IMAP SEARCH
+// expression. Empty fields are unset. The same fields appear at the top
+// level of the config (applying to every mapping) and on each map entry,
+// where set fields override the top level. Filter is the exception: a map
+// entry's expression is ANDed with the top-level one, so a global rule such
+// as "skip spam" cannot be dropped by accident.
+//
+// Dates compare against the server's INTERNALDATE, the time the message was
+// stored, which for migrated mail is usually the original delivery time.
+type Limits struct {
+	Since   string `json:"since,omitempty"    yaml:"since,omitempty"`    // YYYY-MM-DD, inclusive
+	Before  string `json:"before,omitempty"   yaml:"before,omitempty"`   // YYYY-MM-DD, exclusive
+	MaxAge  string `json:"max_age,omitempty"  yaml:"max_age,omitempty"`  // e.g. 90d, 12w, 6m, 2y
+	MinSize string `json:"min_size,omitempty" yaml:"min_size,omitempty"` // bytes, or with K/M/G suffix
+	MaxSize string `json:"max_size,omitempty" yaml:"max_size,omitempty"` // bytes, or with K/M/G suffix
+	Filter  string `json:"filter,omitempty"   yaml:"filter,omitempty"`   // IMAP SEARCH expression, see package search
+}
+
+// Bounds is Limits resolved against a point in time. Zero fields are unset.
+// When both Since and MaxAge are configured, Since holds the later of the two.
+type Bounds struct {
+	Since   time.Time
+	Before  time.Time
+	Search  *imap.SearchCriteria
+	MinSize int64
+	MaxSize int64
+}
+
+// IsZero reports whether no bound is set.
+func (b Bounds) IsZero() bool {
+	return b.Since.IsZero() && b.Before.IsZero() && b.Search == nil && b.MinSize == 0 && b.MaxSize == 0
+}
+
+// Merge returns l with every non-empty field of over applied on top.
+func (l Limits) Merge(over Limits) Limits {
+	pick := func(base, o string) string {
+		if o != "" {
+			return o
+		}
+		return base
+	}
+	filter := pick(l.Filter, over.Filter)
+	if l.Filter != "" && over.Filter != "" {
+		filter = "(" + l.Filter + ") (" + over.Filter + ")"
+	}
+	return Limits{
+		Filter:  filter,
+		Since:   pick(l.Since, over.Since),
+		Before:  pick(l.Before, over.Before),
+		MaxAge:  pick(l.MaxAge, over.MaxAge),
+		MinSize: pick(l.MinSize, over.MinSize),
+		MaxSize: pick(l.MaxSize, over.MaxSize),
+	}
+}
+
+// Resolve parses l into Bounds, evaluating MaxAge relative to now.
+func (l Limits) Resolve(now time.Time) (Bounds, error) {
+	var b Bounds
+	var err error
+	if l.Since != "" {
+		if b.Since, err = time.Parse(dateLayout, l.Since); err != nil {
+			return Bounds{}, fmt.Errorf("since %q: want YYYY-MM-DD", l.Since)
+		}
+	}
+	if l.Before != "" {
+		if b.Before, err = time.Parse(dateLayout, l.Before); err != nil {
+			return Bounds{}, fmt.Errorf("before %q: want YYYY-MM-DD", l.Before)
+		}
+	}
+	if l.MaxAge != "" {
+		cutoff, err := parseAge(l.MaxAge, now)
+		if err != nil {
+			return Bounds{}, fmt.Errorf("max_age %q: %w", l.MaxAge, err)
+		}
+		if cutoff.After(b.Since) {
+			b.Since = cutoff
+		}
+	}
+	if l.MinSize != "" {
+		if b.MinSize, err = ParseSize(l.MinSize); err != nil {
+			return Bounds{}, fmt.Errorf("min_size %q: %w", l.MinSize, err)
+		}
+	}
+	if l.MaxSize != "" {
+		if b.MaxSize, err = ParseSize(l.MaxSize); err != nil {
+			return Bounds{}, fmt.Errorf("max_size %q: %w", l.MaxSize, err)
+		}
+	}
+	if l.Filter != "" {
+		if b.Search, err = search.Parse(l.Filter); err != nil {
+			return Bounds{}, fmt.Errorf("filter %q: %w", l.Filter, err)
+		}
+	}
+	if !b.Since.IsZero() && !b.Before.IsZero() && !b.Since.Before(b.Before) {
+		return Bounds{}, errors.New("since/max_age must be earlier than before")
+	}
+	if b.MaxSize > 0 && b.MinSize > b.MaxSize {
+		return Bounds{}, errors.New("min_size must not exceed max_size")
+	}
+	return b, nil
+}
+
+// parseAge turns "<n><unit>" (d, w, m or y) into the cutoff date that many
+// days, weeks, months or years before now, truncated to the day since IMAP
+// SEARCH dates carry no time.
+func parseAge(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(strings.ToLower(s))
+	if len(s) < 2 {
+		return time.Time{}, errors.New("want a number followed by d, w, m or y")
+	}
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n <= 0 {
+		return time.Time{}, errors.New("want a positive number followed by d, w, m or y")
+	}
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	switch s[len(s)-1] {
+	case 'd':
+		return day.AddDate(0, 0, -n), nil
+	case 'w':
+		return day.AddDate(0, 0, -7*n), nil
+	case 'm':
+		return day.AddDate(0, -n, 0), nil
+	case 'y':
+		return day.AddDate(-n, 0, 0), nil
+	default:
+		return time.Time{}, errors.New("unit must be d, w, m or y")
+	}
+}
+
+// ParseSize parses a byte count such as "2500", "512K", "25MB" or "1GiB".
+// Suffixes are case-insensitive and binary (K = 1024).
+func ParseSize(s string) (int64, error) {
+	s = strings.TrimSpace(strings.ToUpper(s))
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "B"), "I")
+	mult := int64(1)
+	if s != "" {
+		switch s[len(s)-1] {
+		case 'K':
+			mult = 1 << 10
+		case 'M':
+			mult = 1 << 20
+		case 'G':
+			mult = 1 << 30
+		}
+		if mult > 1 {
+			s = s[:len(s)-1]
+		}
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil || n < 0 {
+		return 0, errors.New("want a byte count, optionally with a K, M or G suffix")
+	}
+	return n * mult, nil
+}
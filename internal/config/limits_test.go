@@ -0,0 +1,139 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSize(t *testing.T) {
+	t.Parallel()
+	for in, want := range map[string]int64{
+		"0":      0,
+		"2500":   2500,
+		"512K":   512 << 10,
+		"512kb":  512 << 10,
+		"25M":    25 << 20,
+		"25MiB":  25 << 20,
+		"1G":     1 << 30,
+		" 2 mb ": 2 << 20,
+	} {
+		got, err := ParseSize(in)
+		if err != nil || got != want {
+			t.Errorf("ParseSize(%q) = (%d, %v), want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "M", "-1", "10X", "ten"} {
+		if _, err := ParseSize(in); err == nil {
+			t.Errorf("ParseSize(%q): expected error", in)
+		}
+	}
+}
+
+func TestLimitsResolve_maxAgeTighterThanSince(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2026, 3, 15, 13, 0, 0, 0, time.UTC)
+
+	b, err := Limits{Since: "2020-01-01", MaxAge: "1y"}.Resolve(now)
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if want := time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC); !b.Since.Equal(want) {
+		t.Errorf("Since=%v, want %v", b.Since, want)
+	}
+
+	b, err = Limits{Since: "2026-01-01", MaxAge: "1y"}.Resolve(now)
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if want := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC); !b.Since.Equal(want) {
+		t.Errorf("Since=%v, want %v", b.Since, want)
+	}
+}
+
+func TestLimitsResolve_errors(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	for _, tc := range []struct {
+		l    Limits
+		want string
+	}{
+		{Limits{Since: "01/02/2020"}, "since"},
+		{Limits{Before: "yesterday"}, "before"},
+		{Limits{MaxAge: "3h"}, "max_age"},
+		{Limits{MinSize: "big"}, "min_size"},
+		{Limits{Since: "2024-01-01", Before: "2023-01-01"}, "earlier than before"},
+		{Limits{MinSize: "2M", MaxSize: "1M"}, "must not exceed"},
+	} {
+		_, err := tc.l.Resolve(now)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("Resolve(%+v) = %v, want error mentioning %q", tc.l, err, tc.want)
+		}
+	}
+}
+
+func TestLimitsMerge_overrideWinsPerField(t *testing.T) {
+	t.Parallel()
+	got := Limits{Since: "2020-01-01", MaxSize: "25M"}.Merge(Limits{MaxSize: "10M", MinSize: "1K"})
+	want := Limits{Since: "2020-01-01", MaxSize: "10M", MinSize: "1K"}
+	if got != want {
+		t.Errorf("Merge = %+v, want %+v", got, want)
+	}
+}
+
+func TestNew_limitsFromYAMLAndCLI(t *testing.T) {
+	t.Parallel()
+	content := validYAMLConfig + `since: "2020-01-01"
+max_size: 25M
+map:
+  - src: INBOX
+    dst: INBOX
+    max_size: 10M
+`
+	cfg, err := runNewWithArgs(t, ".yaml", content, "--since", "2022-06-01")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if cfg.Since != "2022-06-01" || cfg.MaxSize != "25M" {
+		t.Errorf("top-level limits = %+v", cfg.Limits)
+	}
+	if len(cfg.Map) != 1 || cfg.Map[0].MaxSize != "10M" {
+		t.Errorf("map limits = %+v", cfg.Map)
+	}
+}
+
+func TestNew_invalidMappingLimitsRejected(t *testing.T) {
+	t.Parallel()
+	content := validYAMLConfig + `map:
+  - src: INBOX
+    dst: INBOX
+    max_age: forever
+`
+	_, err := runNewWithArgs(t, ".yaml", content)
+	if err == nil || !strings.Contains(err.Error(), "map entry 1 (INBOX)") {
+		t.Errorf("err = %v, want map entry error", err)
+	}
+}
+
+func TestLimitsMerge_filtersAreANDed(t *testing.T) {
+	t.Parallel()
+	got := Limits{Filter: "NOT HEADER X-Spam-Flag YES"}.Merge(Limits{Filter: "FROM a OR FROM b"})
+	if want := "(NOT HEADER X-Spam-Flag YES) (FROM a OR FROM b)"; got.Filter != want {
+		t.Errorf("Filter = %q, want %q", got.Filter, want)
+	}
+	b, err := got.Resolve(time.Now())
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if b.Search == nil || len(b.Search.Not) != 1 || len(b.Search.Or) != 1 {
+		t.Errorf("Search = %+v, want NOT and OR parts", b.Search)
+	}
+}
+
+func TestNew_invalidFilterRejected(t *testing.T) {
+	t.Parallel()
+	_, err := runNewWithArgs(t, ".yaml", validYAMLConfig+"filter: FROM\n")
+	if err == nil || !strings.Contains(err.Error(), "filter") {
+		t.Errorf("err = %v, want filter error", err)
+	}
+}
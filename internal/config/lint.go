@@ -0,0 +1,326 @@
+package config
+
+import (
+	"cmp"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/greeddj/imapsync-go/internal/provider"
+	"gopkg.in/yaml.v3"
+)
+
+// Schema is the JSON Schema of the config file, for editors: it lists every
+// key with a description, so typos and bad enum values show while typing.
+//
+//go:embed schema.json
+var Schema []byte
+
+// minSaneBPS is the rate limit below which Lint warns: a few KB/s cannot
+// move even one typical message in reasonable time.
+const minSaneBPS = 4 * 1024
+
+// Problem is one finding of Lint. Path points into the config file, as in
+// "src[2].map[1].dst"; it is empty for findings about the whole file. A
+// warning describes a config that runs, but probably not as intended.
+type Problem struct {
+	Path    string
+	Message string
+	Warning bool
+}
+
+// String renders the problem as "path: message".
+func (p Problem) String() string {
+	if p.Path == "" {
+		return p.Message
+	}
+	return p.Path + ": " + p.Message
+}
+
+// Lint checks the config file at path more strictly than New: unknown keys,
+// auth and server syntax, password sources, duplicate and overlapping map
+// entries and rate-limit sanity. It reports every finding rather than the
+// first. Passwords are not resolved, so linting never prompts or runs
+// pass_command.
+//
+// The error is set only when the file cannot be read or decoded at all; the
+// Config is then nil.
+func Lint(path string) (*Config, []Problem, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot read config file %q: %w", path, err)
+	}
+	var raw any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &raw)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid config file %q: %w", path, err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var problems []Problem
+	for _, p := range unknownFields(raw, reflect.TypeFor[Config](), "") {
+		problems = append(problems, Problem{Path: p, Message: "unknown field"})
+	}
+	problems = append(problems, cfg.lint()...)
+	return cfg, problems, nil
+}
+
+// unknownFields returns the path of every key in raw, a decoded JSON or YAML
+// document, that has no field in t. Keys are matched against the json tags,
+// which the yaml tags mirror; embedded structs contribute their fields.
+func unknownFields(raw any, t reflect.Type, path string) []string {
+	if t == reflect.TypeFor[Sources]() {
+		if _, ok := raw.([]any); !ok {
+			// A single source object.
+			return unknownFields(raw, reflect.TypeFor[Source](), path)
+		}
+	}
+	switch t.Kind() {
+	case reflect.Slice:
+		list, _ := raw.([]any)
+		var out []string
+		for i, e := range list {
+			out = append(out, unknownFields(e, t.Elem(), fmt.Sprintf("%s[%d]", path, i+1))...)
+		}
+		return out
+	case reflect.Struct:
+		obj, ok := raw.(map[string]any)
+		if !ok {
+			return nil
+		}
+		fields := jsonFields(t)
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		var out []string
+		for _, k := range keys {
+			sub := k
+			if path != "" {
+				sub = path + "." + k
+			}
+			ft, ok := fields[k]
+			if !ok {
+				out = append(out, sub)
+				continue
+			}
+			out = append(out, unknownFields(obj[k], ft, sub)...)
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
+// jsonFields maps the JSON names of t's fields, promoted ones included, to
+// their types.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	out := make(map[string]reflect.Type)
+	for f := range t.Fields() {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		switch {
+		case name == "-":
+		case f.Anonymous && name == "":
+			for k, v := range jsonFields(f.Type) {
+				out[k] = v
+			}
+		case name != "":
+			out[name] = f.Type
+		}
+	}
+	return out
+}
+
+// lint runs the checks of Lint on a loaded config.
+func (c *Config) lint() []Problem {
+	var problems []Problem
+	add := func(path, format string, args ...any) {
+		problems = append(problems, Problem{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+	warn := func(path, format string, args ...any) {
+		problems = append(problems, Problem{Path: path, Message: fmt.Sprintf(format, args...), Warning: true})
+	}
+
+	providers := c.ProviderTable()
+	sources := c.SourceList()
+	labels := make(map[string]string, len(sources))
+	for i, s := range sources {
+		path := "src"
+		if len(c.Sources) > 1 {
+			path = fmt.Sprintf("src[%d]", i+1)
+		}
+		problems = append(problems, s.lintAccount(path, providers)...)
+		if prev, dup := labels[s.Label]; dup && len(sources) > 1 {
+			add(path+".label", "%q is already used by %s", s.Label, prev)
+		}
+		labels[s.Label] = path
+	}
+	problems = append(problems, c.Dst.lintAccount("dst", providers)...)
+
+	now := time.Now()
+	if _, err := c.Limits.Resolve(now); err != nil {
+		add("", "invalid limits: %v", err)
+	}
+	if len(c.Map) > 0 {
+		problems = append(problems, c.lintMap("map", c.Map, now)...)
+	}
+	for i, s := range c.Sources {
+		if len(s.Map) > 0 {
+			path := "src"
+			if len(c.Sources) > 1 {
+				path = fmt.Sprintf("src[%d]", i+1)
+			}
+			problems = append(problems, c.lintMap(path+".map", s.Map, now)...)
+		}
+	}
+
+	rl := c.RateLimit
+	for _, f := range []struct {
+		name string
+		v    int
+	}{{"down_bps", rl.DownBPS}, {"up_bps", rl.UpBPS}, {"max_connections", rl.MaxConnections}} {
+		if f.v < 0 {
+			add("rate_limit."+f.name, "must not be negative, got %d", f.v)
+		}
+	}
+	for _, f := range []struct {
+		name string
+		v    int
+	}{{"down_bps", rl.DownBPS}, {"up_bps", rl.UpBPS}} {
+		if f.v > 0 && f.v < minSaneBPS {
+			warn("rate_limit."+f.name, "%d bytes/sec is very slow; the value is in bytes, not kilobytes", f.v)
+		}
+	}
+	if rl.MaxConnections == 1 {
+		warn("rate_limit.max_connections", "1 leaves no connection for workers besides the planning one; sync needs at least 2")
+	}
+	return problems
+}
+
+// lintAccount checks one account's connection details and password source,
+// and the auth against what its profile in providers accepts.
+func (cr Credentials) lintAccount(path string, providers provider.Table) []Problem {
+	var problems []Problem
+	add := func(field string, err error) {
+		problems = append(problems, Problem{Path: path + "." + field, Message: err.Error()})
+	}
+	if cr.Server == "" {
+		add("server", errors.New("required"))
+	} else if err := ValidateServer(cr.Server); err != nil {
+		add("server", err)
+	}
+	if cr.User == "" {
+		add("user", errors.New("required"))
+	}
+	if err := ValidateAuth(cr.Auth); err != nil {
+		add("auth", err)
+	} else if p, ok := providers.Detect(cr.Server); ok && len(p.Auth) > 0 {
+		auth := cmp.Or(strings.ToLower(cr.Auth), AuthLogin)
+		if !slices.Contains(p.Auth, auth) {
+			problems = append(problems, Problem{
+				Path:    path + ".auth",
+				Message: fmt.Sprintf("%s does not accept %s; use %s", p.Name, auth, strings.Join(p.Auth, " or ")),
+				Warning: true,
+			})
+		}
+	}
+
+	switch set := cr.passSources(); {
+	case len(set) > 1:
+		problems = append(problems, Problem{Path: path, Message: "set only one of " + strings.Join(set, ", ")})
+	case len(set) == 0:
+		problems = append(problems, Problem{Path: path, Message: "no password configured; it will be asked for on a terminal", Warning: true})
+	case cr.PassEnv != "":
+		if _, ok := os.LookupEnv(cr.PassEnv); !ok {
+			add("pass_env", fmt.Errorf("%w: %s", ErrUnsetVariable, cr.PassEnv))
+		}
+	case cr.PassFile != "":
+		if _, err := os.Stat(cr.PassFile); err != nil {
+			add("pass_file", err)
+		}
+	}
+	return problems
+}
+
+// lintMap checks the entries of one map. An entry repeating an earlier
+// source is dropped at sync time, the way dedupeMappings does. An entry
+// inside another entry's folder collides with that entry's subfolder
+// expansion: listed after its parent it is dropped, before it, it overrides
+// the parent's mapping for that subfolder.
+func (c *Config) lintMap(path string, entries []DirectoryMapping, now time.Time) []Problem {
+	var problems []Problem
+	add := func(i int, warning bool, format string, args ...any) {
+		problems = append(problems, Problem{
+			Path:    fmt.Sprintf("%s[%d]", path, i+1),
+			Message: fmt.Sprintf(format, args...),
+			Warning: warning,
+		})
+	}
+
+	firstSrc := make(map[string]int, len(entries))
+	firstDst := make(map[string]int, len(entries))
+	for i, m := range entries {
+		if m.Source == "" {
+			add(i, false, "src is required")
+		}
+		if m.Destination == "" {
+			add(i, false, "dst is required")
+		}
+		if _, err := c.Limits.Merge(m.Limits).Resolve(now); err != nil {
+			add(i, false, "invalid limits: %v", err)
+		}
+		if m.Source == "" {
+			continue
+		}
+		if j, dup := firstSrc[m.Source]; dup {
+			add(i, false, "duplicate of %s[%d] (src %q); this entry would be ignored", path, j+1, m.Source)
+			continue
+		}
+		firstSrc[m.Source] = i
+		if j, dup := firstDst[m.Destination]; dup && m.Destination != "" {
+			add(i, true, "dst %q is also the target of %s[%d]; both folders are merged into it", m.Destination, path, j+1)
+		} else {
+			firstDst[m.Destination] = i
+		}
+	}
+
+	for i, m := range entries {
+		for j, parent := range entries {
+			if i == j || !isSubfolder(m.Source, parent.Source) {
+				continue
+			}
+			if j < i {
+				add(i, true, "%q is a subfolder of %s[%d] (%q), which already syncs it; this entry would be ignored", m.Source, path, j+1, parent.Source)
+			} else {
+				add(i, true, "%q is a subfolder of %s[%d] (%q); this entry overrides where that entry puts it", m.Source, path, j+1, parent.Source)
+			}
+			break
+		}
+	}
+	return problems
+}
+
+// isSubfolder reports whether folder lies under parent. The server's
+// hierarchy delimiter is not known offline, so both common ones count.
+func isSubfolder(folder, parent string) bool {
+	if parent == "" {
+		return false
+	}
+	return strings.HasPrefix(folder, parent+"/") || strings.HasPrefix(folder, parent+".")
+}
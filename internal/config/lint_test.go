@@ -0,0 +1,176 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/greeddj/imapsync-go/internal/provider"
+)
+
+// lintFile writes content to a temp file with the given extension and
+// lints it.
+func lintFile(t *testing.T, ext, content string) []Problem {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config"+ext)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write temp config: %v", err)
+	}
+	_, problems, err := Lint(path)
+	if err != nil {
+		t.Fatalf("Lint: %v", err)
+	}
+	return problems
+}
+
+// problemPaths returns "path" for errors and "path (warning)" for warnings.
+func problemPaths(problems []Problem) []string {
+	out := make([]string, 0, len(problems))
+	for _, p := range problems {
+		if p.Warning {
+			out = append(out, p.Path+" (warning)")
+		} else {
+			out = append(out, p.Path)
+		}
+	}
+	slices.Sort(out)
+	return out
+}
+
+func TestLint_validConfig(t *testing.T) {
+	t.Parallel()
+
+	problems := lintFile(t, ".yaml", `
+src: {server: "src:993", user: u, pass: p, auth: CRAM-MD5}
+dst: {server: "[::1]:993", user: u, pass_command: "echo p"}
+map:
+  - {src: INBOX, dst: INBOX, max_size: 25M}
+rate_limit: {down_bps: 300000, max_connections: 10}
+`)
+	if len(problems) != 0 {
+		t.Errorf("Lint() = %v; want no problems", problems)
+	}
+}
+
+func TestLint_unknownFields(t *testing.T) {
+	t.Parallel()
+
+	problems := lintFile(t, ".json", `{
+  "src": [{"server":"a:993","user":"u","pass":"p","passwd":"x"},
+          {"server":"b:993","user":"u","pass":"p","map":[{"src":"A","dst":"A","maxsize":"1M"}]}],
+  "dst": {"server":"d:993","user":"u","pass":"p"},
+  "rate_limits": {}
+}`)
+	want := []string{"rate_limits", "src[1].passwd", "src[2].map[1].maxsize"}
+	if got := problemPaths(problems); !slices.Equal(got, want) {
+		t.Errorf("Lint() paths = %q; want %q", got, want)
+	}
+}
+
+func TestLint_accountsAndRateLimit(t *testing.T) {
+	t.Parallel()
+
+	problems := lintFile(t, ".yaml", `
+src: {server: imap.example.com, user: u, auth: plain}
+dst: {server: "d:993", pass: p, pass_env: HOME}
+rate_limit: {down_bps: 300, up_bps: -1, max_connections: 1}
+`)
+	want := []string{
+		"dst", "dst.user",
+		"rate_limit.down_bps (warning)", "rate_limit.max_connections (warning)", "rate_limit.up_bps",
+		"src (warning)", "src.auth", "src.server",
+	}
+	if got := problemPaths(problems); !slices.Equal(got, want) {
+		t.Errorf("Lint() paths = %q; want %q", got, want)
+	}
+}
+
+func TestLint_mapEntries(t *testing.T) {
+	t.Parallel()
+
+	problems := lintFile(t, ".yaml", `
+src: {server: "s:993", user: u, pass: p}
+dst: {server: "d:993", user: u, pass: p}
+map:
+  - {src: Archive.2020, dst: Old/2020}
+  - {src: Archive, dst: Old}
+  - {src: Projects, dst: Old}
+  - {src: Archive/Deep, dst: Deep}
+  - {src: Archive, dst: Elsewhere}
+  - {src: Bad, dst: ""}
+`)
+	want := []string{
+		"map[1] (warning)", // overrides map[2]'s expansion
+		"map[3] (warning)", // same dst as map[2]
+		"map[4] (warning)", // already under map[2]
+		"map[5]",           // duplicate src
+		"map[6]",           // no dst
+	}
+	if got := problemPaths(problems); !slices.Equal(got, want) {
+		t.Errorf("Lint() paths = %q; want %q", got, want)
+	}
+}
+
+func TestLint_providerAuth(t *testing.T) {
+	t.Parallel()
+
+	problems := lintFile(t, ".yaml", `
+src: {server: "imap.gmail.com:993", user: u, pass: p, auth: cram-md5}
+dst: {server: "outlook.office365.com:993", user: u, pass: p, auth: LOGIN}
+`)
+	want := []string{"src.auth (warning)"}
+	if got := problemPaths(problems); !slices.Equal(got, want) {
+		t.Errorf("Lint() paths = %q; want %q", got, want)
+	}
+}
+
+// TestSchema_coversConfig asserts that the JSON Schema describes every key
+// the decoder accepts, so the two cannot drift apart.
+func TestSchema_coversConfig(t *testing.T) {
+	t.Parallel()
+
+	var schema struct {
+		Properties  map[string]json.RawMessage `json:"properties"`
+		Definitions map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"definitions"`
+	}
+	if err := json.Unmarshal(Schema, &schema); err != nil {
+		t.Fatalf("Schema is not JSON: %v", err)
+	}
+	check := func(name string, props map[string]json.RawMessage, typ reflect.Type) {
+		for key := range jsonFields(typ) {
+			if _, ok := props[key]; !ok {
+				t.Errorf("schema %s lacks %q", name, key)
+			}
+		}
+		for key := range props {
+			if _, ok := jsonFields(typ)[key]; !ok {
+				t.Errorf("schema %s has %q, which the config does not", name, key)
+			}
+		}
+	}
+	check("root", schema.Properties, reflect.TypeFor[Config]())
+	check("account", schema.Definitions["account"].Properties, reflect.TypeFor[Credentials]())
+	check("source", schema.Definitions["source"].Properties, reflect.TypeFor[Source]())
+	check("mapping", schema.Definitions["mapping"].Properties, reflect.TypeFor[DirectoryMapping]())
+	var rateLimit struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+	}
+	if err := json.Unmarshal(schema.Properties["rate_limit"], &rateLimit); err != nil {
+		t.Fatalf("rate_limit schema: %v", err)
+	}
+	check("rate_limit", rateLimit.Properties, reflect.TypeFor[RateLimit]())
+	profile := schema.Definitions["provider"].Properties
+	check("provider", profile, reflect.TypeFor[provider.Provider]())
+	var folders struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+	}
+	if err := json.Unmarshal(profile["folders"], &folders); err != nil {
+		t.Fatalf("folders schema: %v", err)
+	}
+	check("folders", folders.Properties, reflect.TypeFor[provider.Folders]())
+}
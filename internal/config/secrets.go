@@ -0,0 +1,294 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/greeddj/imapsync-go/internal/secret"
+	"golang.org/x/term"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrUnsetVariable is returned when the config references an environment
+// variable that is not set.
+var ErrUnsetVariable = errors.New("config references an unset environment variable")
+
+// variableRef matches ${VAR} and the $${ escape that stands for a literal ${.
+var variableRef = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// promptPassword asks for a password on the terminal without echoing it. It
+// returns ok=false when stdin is not a terminal. Tests replace it.
+var promptPassword = func(prompt string) (pass string, ok bool, err error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", false, nil
+	}
+	_, _ = fmt.Fprint(os.Stderr, prompt)
+	b, err := term.ReadPassword(fd)
+	_, _ = fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", true, fmt.Errorf("read password: %w", err)
+	}
+	return string(b), true, nil
+}
+
+// expandVars replaces each ${VAR} in s with the value of the environment
+// variable VAR. Only the braced form is expanded, so a "$" in a password
+// needs no escaping; $${ gives a literal ${.
+func expandVars(s string) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+	var missing []string
+	out := variableRef.ReplaceAllStringFunc(s, func(ref string) string {
+		if ref == "$${" {
+			return "${"
+		}
+		name := ref[2 : len(ref)-1]
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return v
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("%w: %s", ErrUnsetVariable, strings.Join(missing, ", "))
+	}
+	return out, nil
+}
+
+// decodeJSON decodes a JSON config into cfg, expanding ${VAR} in every
+// string value first. Values are expanded after parsing, so a variable
+// holding quotes or backslashes cannot break the document.
+func decodeJSON(data []byte, cfg *Config) error {
+	if !bytes.Contains(data, []byte("${")) {
+		return json.Unmarshal(data, cfg)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var raw any
+	if err := dec.Decode(&raw); err != nil {
+		return err
+	}
+	raw, err := expandJSON(raw)
+	if err != nil {
+		return err
+	}
+	expanded, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(expanded, cfg)
+}
+
+// expandJSON expands the strings of a decoded JSON value, keys included.
+func expandJSON(v any) (any, error) {
+	switch v := v.(type) {
+	case string:
+		return expandVars(v)
+	case []any:
+		for i := range v {
+			e, err := expandJSON(v[i])
+			if err != nil {
+				return nil, err
+			}
+			v[i] = e
+		}
+		return v, nil
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, e := range v {
+			k, err := expandVars(k)
+			if err != nil {
+				return nil, err
+			}
+			if out[k], err = expandJSON(e); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
+
+// decodeYAML decodes a YAML config into cfg, expanding ${VAR} in every
+// scalar first. Comments are not expanded.
+func decodeYAML(data []byte, cfg *Config) error {
+	if !bytes.Contains(data, []byte("${")) {
+		return yaml.Unmarshal(data, cfg)
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return err
+	}
+	if err := expandNode(&root); err != nil {
+		return err
+	}
+	return root.Decode(cfg)
+}
+
+// expandNode expands the scalars under n. A plain scalar that changed has
+// its tag cleared, so "port: ${PORT}" still decodes into an int.
+func expandNode(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		v, err := expandVars(n.Value)
+		if err != nil {
+			return err
+		}
+		if v != n.Value {
+			n.Value = v
+			if n.Style == 0 {
+				n.Tag = ""
+			}
+		}
+		return nil
+	}
+	for _, c := range n.Content {
+		if err := expandNode(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LogValue implements slog.LogValuer. It leaves out the password and where
+// it comes from.
+func (cr Credentials) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("label", cr.Label),
+		slog.String("server", cr.Server),
+		slog.String("user", cr.User),
+		slog.String("auth", cr.Auth),
+	)
+}
+
+// resolveSecrets fills in the password of every account from its source
+// and registers it for redaction.
+func (c *Config) resolveSecrets() error {
+	for i := range c.Sources {
+		if err := c.Sources[i].resolvePass(); err != nil {
+			if len(c.Sources) == 1 {
+				return fmt.Errorf("source password: %w", err)
+			}
+			return fmt.Errorf("source %d (%s) password: %w", i+1, c.Sources[i].Label, err)
+		}
+	}
+	if err := c.Dst.resolvePass(); err != nil {
+		return fmt.Errorf("destination password: %w", err)
+	}
+	return nil
+}
+
+// resolvePass sets Pass from the one password source configured: pass,
+// pass_env, pass_file, pass_command or pass_keyring. With none of them and
+// a terminal on stdin it asks for the password; otherwise Pass stays empty
+// and validate reports it.
+func (cr *Credentials) resolvePass() error {
+	if set := cr.passSources(); len(set) > 1 {
+		return fmt.Errorf("set only one of %s", strings.Join(set, ", "))
+	}
+
+	var err error
+	switch {
+	case cr.Pass != "":
+	case cr.PassEnv != "":
+		v, ok := os.LookupEnv(cr.PassEnv)
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrUnsetVariable, cr.PassEnv)
+		}
+		cr.Pass = v
+	case cr.PassFile != "":
+		cr.Pass, err = passFromFile(cr.PassFile)
+	case cr.PassCommand != "":
+		cr.Pass, err = passFromCommand(cr.PassCommand)
+	case cr.PassKeyring != "":
+		cr.Pass, err = passFromKeyring(cr.PassKeyring, cr.User)
+	case cr.User != "":
+		cr.Pass, _, err = promptPassword(fmt.Sprintf("🔑 Password for %s (%s on %s): ", cr.Label, cr.User, cr.Server))
+	}
+	if err != nil {
+		return err
+	}
+	secret.Register(cr.Pass)
+	return nil
+}
+
+// passSources returns the names of the password fields that are set.
+func (cr Credentials) passSources() []string {
+	var set []string
+	for _, f := range []struct{ name, value string }{
+		{"pass", cr.Pass}, {"pass_env", cr.PassEnv}, {"pass_file", cr.PassFile},
+		{"pass_command", cr.PassCommand}, {"pass_keyring", cr.PassKeyring},
+	} {
+		if f.value != "" {
+			set = append(set, f.name)
+		}
+	}
+	return set
+}
+
+// passFromFile returns the first line of path.
+func passFromFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read pass_file: %w", err)
+	}
+	return firstLine(data), nil
+}
+
+// passFromCommand runs command through the shell and returns the first line
+// it prints, the convention of pass(1) and most password managers. Its
+// stderr stays on the terminal, so a GPG pinentry prompt still works.
+func passFromCommand(command string) (string, error) {
+	cmd := shellCommand(command)
+	cmd.Stdin, cmd.Stderr = os.Stdin, os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("pass_command %q: %w", command, err)
+	}
+	return firstLine(out), nil
+}
+
+// passFromKeyring reads the password stored for user under service in the
+// OS keyring: the login keychain on macOS, the Secret Service (through
+// secret-tool) elsewhere.
+func passFromKeyring(service, user string) (string, error) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", service, "-a", user, "-w")
+	case "windows":
+		return "", errors.New("pass_keyring is not supported on Windows; use pass_command")
+	default:
+		cmd = exec.Command("secret-tool", "lookup", "service", service, "username", user)
+	}
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("pass_keyring %q: %w", service, err)
+	}
+	return firstLine(out), nil
+}
+
+// shellCommand runs command with the platform shell.
+func shellCommand(command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", command)
+	}
+	return exec.Command("sh", "-c", command)
+}
+
+// firstLine returns data up to its first line break.
+func firstLine(data []byte) string {
+	line, _, _ := strings.Cut(string(data), "\n")
+	return strings.TrimSuffix(line, "\r")
+}
@@ -0,0 +1,180 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/greeddj/imapsync-go/internal/secret"
+)
+
+func TestExpandVars(t *testing.T) {
+	t.Setenv("IMAPSYNC_TEST_HOST", "imap.example.com")
+	t.Setenv("IMAPSYNC_TEST_EMPTY", "")
+
+	tests := []struct {
+		in, want string
+		wantErr  bool
+	}{
+		{in: "${IMAPSYNC_TEST_HOST}:993", want: "imap.example.com:993"},
+		{in: "p$ss$word", want: "p$ss$word"},
+		{in: "$IMAPSYNC_TEST_HOST", want: "$IMAPSYNC_TEST_HOST"},
+		{in: "$${IMAPSYNC_TEST_HOST}", want: "${IMAPSYNC_TEST_HOST}"},
+		{in: "x${IMAPSYNC_TEST_EMPTY}y", want: "xy"},
+		{in: "${IMAPSYNC_TEST_UNSET_VAR}", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := expandVars(tt.in)
+		if tt.wantErr {
+			if !errors.Is(err, ErrUnsetVariable) {
+				t.Errorf("expandVars(%q) error = %v; want ErrUnsetVariable", tt.in, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("expandVars(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestNew_interpolatesJSON(t *testing.T) {
+	t.Setenv("IMAPSYNC_TEST_PASS", `q"uo\te`)
+	t.Setenv("IMAPSYNC_TEST_SERVER", "src.example.com:993")
+
+	cfg, err := runNewWithArgs(t, ".json", `{
+  "src": {"server":"${IMAPSYNC_TEST_SERVER}","user":"u","pass":"${IMAPSYNC_TEST_PASS}"},
+  "dst": {"server":"dst:993","user":"u","pass":"p"},
+  "rate_limit": {"down_bps": 1000}
+}`)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if cfg.Src.Server != "src.example.com:993" || cfg.Src.Pass != `q"uo\te` {
+		t.Errorf("Src = %+v; want the expanded server and password", cfg.Src)
+	}
+	if cfg.RateLimit.DownBPS != 1000 {
+		t.Errorf("DownBPS = %d; want 1000", cfg.RateLimit.DownBPS)
+	}
+}
+
+func TestNew_interpolatesYAML(t *testing.T) {
+	t.Setenv("IMAPSYNC_TEST_PASS", "s3cret: #not a comment")
+	t.Setenv("IMAPSYNC_TEST_BPS", "2000")
+
+	cfg, err := runNewWithArgs(t, ".yaml", `
+src: {server: "src:993", user: u, pass: "${IMAPSYNC_TEST_PASS}"}
+dst: {server: "dst:993", user: u, pass: p}
+rate_limit:
+  down_bps: ${IMAPSYNC_TEST_BPS} # ${IMAPSYNC_TEST_UNSET_VAR} in a comment is left alone
+`)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if cfg.Src.Pass != "s3cret: #not a comment" {
+		t.Errorf("Src.Pass = %q", cfg.Src.Pass)
+	}
+	if cfg.RateLimit.DownBPS != 2000 {
+		t.Errorf("DownBPS = %d; want 2000", cfg.RateLimit.DownBPS)
+	}
+}
+
+func TestNew_unsetVariable(t *testing.T) {
+	_, err := runNewWithArgs(t, ".yaml", `
+src: {server: "src:993", user: u, pass: "${IMAPSYNC_TEST_UNSET_VAR}"}
+dst: {server: "dst:993", user: u, pass: p}
+`)
+	if !errors.Is(err, ErrUnsetVariable) || !strings.Contains(err.Error(), "IMAPSYNC_TEST_UNSET_VAR") {
+		t.Errorf("New() error = %v; want ErrUnsetVariable naming the variable", err)
+	}
+}
+
+func TestNew_passSources(t *testing.T) {
+	dir := t.TempDir()
+	passFile := filepath.Join(dir, "pass")
+	if err := os.WriteFile(passFile, []byte("from-file\nsecond line\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("IMAPSYNC_TEST_PASS", "from-env")
+
+	cfg, err := runNewWithArgs(t, ".yaml", `
+src:
+  - {label: a, server: "a:993", user: u, pass_env: IMAPSYNC_TEST_PASS}
+  - {label: b, server: "b:993", user: u, pass_file: "`+passFile+`"}
+dst: {server: "dst:993", user: u, pass_command: "printf 'from-command\\nrest'"}
+`)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if got := cfg.Sources[0].Pass; got != "from-env" {
+		t.Errorf("pass_env gave %q", got)
+	}
+	if got := cfg.Sources[1].Pass; got != "from-file" {
+		t.Errorf("pass_file gave %q", got)
+	}
+	if got := cfg.Dst.Pass; got != "from-command" {
+		t.Errorf("pass_command gave %q", got)
+	}
+	if cfg.Src.Pass != "from-env" {
+		t.Errorf("Src.Pass = %q; want the first source's password", cfg.Src.Pass)
+	}
+	if got := secret.Redact("x from-command y"); got != "x "+secret.Redacted+" y" {
+		t.Errorf("resolved password not registered for redaction: %q", got)
+	}
+}
+
+func TestNew_passSourceConflict(t *testing.T) {
+	_, err := runNewWithArgs(t, ".json", `{
+  "src": {"server":"src:993","user":"u","pass":"p","pass_env":"HOME"},
+  "dst": {"server":"dst:993","user":"u","pass":"p"}
+}`)
+	if err == nil || !strings.Contains(err.Error(), "set only one of pass, pass_env") {
+		t.Errorf("New() error = %v; want a conflict between pass and pass_env", err)
+	}
+}
+
+func TestNew_passCommandFails(t *testing.T) {
+	_, err := runNewWithArgs(t, ".json", `{
+  "src": {"server":"src:993","user":"u","pass":"p"},
+  "dst": {"server":"dst:993","user":"u","pass_command":"exit 3"}
+}`)
+	if err == nil || !strings.Contains(err.Error(), "destination password") {
+		t.Errorf("New() error = %v; want a destination password error", err)
+	}
+}
+
+// TestNew_promptsOnTerminal swaps promptPassword, so it does not run in
+// parallel.
+func TestNew_promptsOnTerminal(t *testing.T) {
+	orig := promptPassword
+	t.Cleanup(func() { promptPassword = orig })
+
+	var prompts []string
+	promptPassword = func(prompt string) (string, bool, error) {
+		prompts = append(prompts, prompt)
+		return "typed-in", true, nil
+	}
+	cfg, err := runNewWithArgs(t, ".json", `{
+  "src": {"server":"src:993","user":"me","pass":"p"},
+  "dst": {"server":"dst:993","user":"u"}
+}`)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if cfg.Dst.Pass != "typed-in" {
+		t.Errorf("Dst.Pass = %q; want the prompted password", cfg.Dst.Pass)
+	}
+	if len(prompts) != 1 || !strings.Contains(prompts[0], "dst (u on dst:993)") {
+		t.Errorf("prompts = %q; want one for the destination", prompts)
+	}
+
+	promptPassword = func(string) (string, bool, error) { return "", false, nil }
+	_, err = runNewWithArgs(t, ".json", `{
+  "src": {"server":"src:993","user":"me","pass":"p"},
+  "dst": {"server":"dst:993","user":"u"}
+}`)
+	if !errors.Is(err, ErrDstPassRequired) {
+		t.Errorf("New() without a terminal error = %v; want ErrDstPassRequired", err)
+	}
+}
@@ -0,0 +1,88 @@
+// Package logging builds the structured log of a run: level, text or JSON
+// format, and an optional log file rotated by size.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/greeddj/imapsync-go/internal/secret"
+)
+
+// Log formats.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Stderr as a log file name sends the log to standard error.
+const Stderr = "-"
+
+// Options configures New. MaxSize is in bytes; 0 never rotates. MaxBackups
+// is how many rotated files are kept beside File.
+type Options struct {
+	File       string
+	Level      string
+	Format     string
+	MaxSize    int64
+	MaxBackups int
+}
+
+// New returns the logger described by opts and a func that closes its file.
+// Without File nothing is logged: the terminal belongs to the progress UI,
+// and the emoji output already tells an interactive user what happens.
+func New(opts Options) (*slog.Logger, func() error, error) {
+	level, err := ParseLevel(opts.Level)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		w       io.Writer
+		closeFn = func() error { return nil }
+	)
+	switch opts.File {
+	case "":
+		return slog.New(slog.DiscardHandler), closeFn, nil
+	case Stderr:
+		w = os.Stderr
+	default:
+		rf, err := OpenRotating(opts.File, opts.MaxSize, opts.MaxBackups)
+		if err != nil {
+			return nil, nil, err
+		}
+		w, closeFn = rf, rf.Close
+	}
+
+	// Passwords never reach the log, whatever a record carries.
+	w = secret.NewWriter(w)
+	ho := &slog.HandlerOptions{Level: level}
+	switch strings.ToLower(opts.Format) {
+	case "", FormatText:
+		return slog.New(slog.NewTextHandler(w, ho)), closeFn, nil
+	case FormatJSON:
+		return slog.New(slog.NewJSONHandler(w, ho)), closeFn, nil
+	default:
+		_ = closeFn()
+		return nil, nil, fmt.Errorf("log format must be %s or %s, got %q", FormatText, FormatJSON, opts.Format)
+	}
+}
+
+// ParseLevel reads debug, info, warn or error; "" is info.
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("log level must be debug, info, warn or error, got %q", s)
+	}
+}
@@ -0,0 +1,95 @@
+package logging
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/greeddj/imapsync-go/internal/secret"
+)
+
+func TestParseLevel(t *testing.T) {
+	t.Parallel()
+
+	for in, want := range map[string]slog.Level{
+		"":      slog.LevelInfo,
+		"debug": slog.LevelDebug,
+		"INFO":  slog.LevelInfo,
+		"warn":  slog.LevelWarn,
+		"error": slog.LevelError,
+	} {
+		got, err := ParseLevel(in)
+		if err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Error("ParseLevel(\"loud\") = nil error")
+	}
+}
+
+// Test_New_jsonFileHonoursLevel asserts that a JSON log file gets one
+// object per record at or above the level, and nothing below it.
+func Test_New_jsonFileHonoursLevel(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "run.log")
+	logger, closeFn, err := New(Options{File: path, Level: "info", Format: FormatJSON})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	logger.Debug("hidden")
+	logger.Warn("reconnecting", "side", "source", "attempt", 2)
+	if err := closeFn(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	var rec map[string]any
+	if err := json.Unmarshal(data, &rec); err != nil {
+		t.Fatalf("log is not one JSON record: %v\n%s", err, data)
+	}
+	if rec["msg"] != "reconnecting" || rec["level"] != "WARN" || rec["side"] != "source" {
+		t.Errorf("record = %v", rec)
+	}
+}
+
+// Test_New_rejectsUnknownFormat asserts that a bad --log-format is an error.
+func Test_New_rejectsUnknownFormat(t *testing.T) {
+	t.Parallel()
+
+	if _, _, err := New(Options{File: Stderr, Format: "xml"}); err == nil {
+		t.Error("New with format xml = nil error")
+	}
+}
+
+// Test_New_redactsSecrets asserts that a registered password never reaches
+// the log file, even inside an error message.
+func Test_New_redactsSecrets(t *testing.T) {
+	t.Parallel()
+
+	secret.Register("log-s3cret")
+	path := filepath.Join(t.TempDir(), "run.log")
+	logger, closeFn, err := New(Options{File: path})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	logger.Error("login failed", "error", "bad password log-s3cret")
+	if err := closeFn(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if strings.Contains(string(data), "log-s3cret") || !strings.Contains(string(data), secret.Redacted) {
+		t.Errorf("password not redacted:\n%s", data)
+	}
+}
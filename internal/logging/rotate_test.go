@@ -0,0 +1,63 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Test_RotatingFile_keepsBackups asserts that the file rotates before a
+// write would pass the limit, that only maxBackups old files survive, and
+// that the newest backup holds the most recent rotated records.
+func Test_RotatingFile_keepsBackups(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "run.log")
+	r, err := OpenRotating(path, 10, 2)
+	if err != nil {
+		t.Fatalf("OpenRotating: %v", err)
+	}
+	for _, rec := range []string{"aaaaaa\n", "bbbbbb\n", "cccccc\n", "dddddd\n"} {
+		if _, err := r.Write([]byte(rec)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	for name, want := range map[string]string{
+		path:        "dddddd\n",
+		path + ".1": "cccccc\n",
+		path + ".2": "bbbbbb\n",
+	} {
+		got, err := os.ReadFile(name)
+		if err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v; want %q", filepath.Base(name), got, err, want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); err == nil {
+		t.Error("a third backup exists, want at most 2")
+	}
+}
+
+// Test_RotatingFile_appendsAcrossOpens asserts that reopening continues the
+// file and counts its existing size toward the limit.
+func Test_RotatingFile_appendsAcrossOpens(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "run.log")
+	for range 2 {
+		r, err := OpenRotating(path, 100, 1)
+		if err != nil {
+			t.Fatalf("OpenRotating: %v", err)
+		}
+		_, _ = r.Write([]byte("line\n"))
+		_ = r.Close()
+	}
+	got, _ := os.ReadFile(path)
+	if strings.Count(string(got), "line") != 2 {
+		t.Errorf("log = %q, want both runs", got)
+	}
+}
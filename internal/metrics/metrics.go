@@ -0,0 +1,201 @@
+// Package metrics keeps the run's counters and gauges and serves them in
+// the Prometheus text exposition format, so long migrations can be watched
+// from a dashboard. Metrics are package-level and always collected; they
+// are only exposed when Serve is called.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Metric types, as named in the exposition format.
+const (
+	typeCounter = "counter"
+	typeGauge   = "gauge"
+)
+
+// The metrics of a run. Sides are "source" and "destination", the account
+// roles; a reverse two-way copy fetches from the destination side.
+var (
+	Messages = NewCounter("imapsync_messages_total",
+		"Messages fetched from the source side or appended on the destination side, by folder.", "side", "folder")
+	Bytes = NewCounter("imapsync_bytes_total",
+		"Message bytes fetched from the source side or appended on the destination side, by folder.", "side", "folder")
+	AppendFailures = NewCounter("imapsync_append_failures_total",
+		"Messages that could not be appended, by error class.", "class")
+	Reconnects = NewCounter("imapsync_reconnect_attempts_total",
+		"Reconnect attempts, by client.", "client")
+	ThrottleBackoffs = NewCounter("imapsync_throttle_backoffs_total",
+		"Backoffs after the server signalled throttling, by client.", "client")
+	RateLimitWait = NewCounter("imapsync_ratelimit_wait_seconds_total",
+		"Time spent waiting for rate-limiter tokens, by direction.", "direction")
+	RateLimitTokens = NewGauge("imapsync_ratelimit_tokens",
+		"Bytes the rate limiter would allow right now, by direction.", "direction")
+	ActiveWorkers = NewGauge("imapsync_workers_active",
+		"Workers copying a chunk right now.")
+	OpenConnections = NewGauge("imapsync_connections_open",
+		"Open IMAP connections.")
+	QueueDepth = NewGauge("imapsync_append_queue_depth",
+		"Fetched messages waiting for their APPEND.")
+)
+
+// registry holds every metric in creation order.
+var registry struct {
+	all []*Vec
+	mu  sync.Mutex
+}
+
+// Vec is a counter or gauge with zero or more labels; each set of label
+// values is its own series. It is safe for concurrent use.
+type Vec struct {
+	series map[string]*series
+	name   string
+	help   string
+	typ    string
+	labels []string
+	mu     sync.Mutex
+}
+
+// series is one labelled value, stored as float64 bits.
+type series struct {
+	values []string
+	bits   atomic.Uint64
+}
+
+// NewCounter registers a counter. Counters only go up.
+func NewCounter(name, help string, labels ...string) *Vec {
+	return register(name, help, typeCounter, labels)
+}
+
+// NewGauge registers a gauge.
+func NewGauge(name, help string, labels ...string) *Vec {
+	return register(name, help, typeGauge, labels)
+}
+
+// register adds a new metric to the registry.
+func register(name, help, typ string, labels []string) *Vec {
+	v := &Vec{series: make(map[string]*series), name: name, help: help, typ: typ, labels: labels}
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.all = append(registry.all, v)
+	return v
+}
+
+// Inc adds 1 to the series of values.
+func (v *Vec) Inc(values ...string) { v.Add(1, values...) }
+
+// Dec subtracts 1 from the series of values.
+func (v *Vec) Dec(values ...string) { v.Add(-1, values...) }
+
+// Add adds delta to the series of values. values must match the labels the
+// Vec was created with, in order.
+func (v *Vec) Add(delta float64, values ...string) {
+	s := v.get(values)
+	for {
+		old := s.bits.Load()
+		if s.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+delta)) {
+			return
+		}
+	}
+}
+
+// Set replaces the value of the series of values.
+func (v *Vec) Set(val float64, values ...string) {
+	v.get(values).bits.Store(math.Float64bits(val))
+}
+
+// Value returns the current value of the series of values; 0 when it was
+// never touched.
+func (v *Vec) Value(values ...string) float64 {
+	v.mu.Lock()
+	s := v.series[seriesKey(values)]
+	v.mu.Unlock()
+	if s == nil {
+		return 0
+	}
+	return math.Float64frombits(s.bits.Load())
+}
+
+// get returns the series of values, creating it on first use.
+func (v *Vec) get(values []string) *series {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", v.name, len(v.labels), len(values)))
+	}
+	key := seriesKey(values)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	s := v.series[key]
+	if s == nil {
+		s = &series{values: slices.Clone(values)}
+		v.series[key] = s
+	}
+	return s
+}
+
+// seriesKey joins label values with a byte that never occurs in UTF-8.
+func seriesKey(values []string) string {
+	return strings.Join(values, "\xff")
+}
+
+// WriteText writes every metric to w in the Prometheus text format. An
+// unlabelled metric is always written, at 0 if untouched, so dashboards see
+// it from the first scrape.
+func WriteText(w io.Writer) error {
+	registry.mu.Lock()
+	all := slices.Clone(registry.all)
+	registry.mu.Unlock()
+
+	var b strings.Builder
+	for _, v := range all {
+		v.writeText(&b)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeText renders one metric family, series sorted by label values.
+func (v *Vec) writeText(b *strings.Builder) {
+	v.mu.Lock()
+	all := make([]*series, 0, len(v.series))
+	for _, s := range v.series {
+		all = append(all, s)
+	}
+	v.mu.Unlock()
+	if len(all) == 0 && len(v.labels) > 0 {
+		return
+	}
+	slices.SortFunc(all, func(a, b *series) int { return slices.Compare(a.values, b.values) })
+
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", v.name, v.help, v.name, v.typ)
+	if len(all) == 0 {
+		fmt.Fprintf(b, "%s 0\n", v.name)
+		return
+	}
+	for _, s := range all {
+		b.WriteString(v.name)
+		if len(v.labels) > 0 {
+			b.WriteByte('{')
+			for i, l := range v.labels {
+				if i > 0 {
+					b.WriteByte(',')
+				}
+				fmt.Fprintf(b, "%s=\"%s\"", l, labelEscaper.Replace(s.values[i]))
+			}
+			b.WriteByte('}')
+		}
+		b.WriteByte(' ')
+		b.WriteString(strconv.FormatFloat(math.Float64frombits(s.bits.Load()), 'g', -1, 64))
+		b.WriteByte('\n')
+	}
+}
+
+// labelEscaper escapes a label value the way the text format wants it:
+// backslash, double quote and newline only.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
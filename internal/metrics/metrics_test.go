@@ -0,0 +1,119 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Test_WriteText_rendersSeries asserts the exposition of a labelled counter:
+// HELP and TYPE once, series sorted, label values escaped.
+func Test_WriteText_rendersSeries(t *testing.T) {
+	t.Parallel()
+
+	v := NewCounter("test_render_total", "Rendered things.", "side", "folder")
+	v.Add(2, "source", "INBOX")
+	v.Inc("destination", `Say "hi"\now`)
+	v.Add(1.5, "source", "INBOX")
+
+	var b strings.Builder
+	if err := WriteText(&b); err != nil {
+		t.Fatalf("WriteText: %v", err)
+	}
+	want := "# HELP test_render_total Rendered things.\n" +
+		"# TYPE test_render_total counter\n" +
+		`test_render_total{side="destination",folder="Say \"hi\"\\now"} 1` + "\n" +
+		`test_render_total{side="source",folder="INBOX"} 3.5` + "\n"
+	if got := b.String(); !strings.Contains(got, want) {
+		t.Errorf("exposition lacks\n%s\ngot:\n%s", want, got)
+	}
+}
+
+// Test_WriteText_unlabelledAtZero asserts that an untouched gauge is
+// exposed at 0 and an untouched labelled one not at all.
+func Test_WriteText_unlabelledAtZero(t *testing.T) {
+	t.Parallel()
+
+	NewGauge("test_idle_gauge", "Never set.")
+	NewCounter("test_idle_total", "Never set.", "class")
+
+	var b strings.Builder
+	if err := WriteText(&b); err != nil {
+		t.Fatalf("WriteText: %v", err)
+	}
+	got := b.String()
+	if !strings.Contains(got, "# TYPE test_idle_gauge gauge\ntest_idle_gauge 0\n") {
+		t.Errorf("idle gauge missing:\n%s", got)
+	}
+	if strings.Contains(got, "test_idle_total") {
+		t.Errorf("idle labelled counter exposed:\n%s", got)
+	}
+}
+
+// Test_Vec_concurrentAdd asserts that concurrent updates are not lost.
+func Test_Vec_concurrentAdd(t *testing.T) {
+	t.Parallel()
+
+	v := NewGauge("test_concurrent", "Concurrent updates.")
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			for range 1000 {
+				v.Inc()
+			}
+			for range 500 {
+				v.Dec()
+			}
+		})
+	}
+	wg.Wait()
+	if got := v.Value(); got != 4000 {
+		t.Errorf("Value() = %v, want 4000", got)
+	}
+}
+
+// Test_Serve_exposesMetrics asserts that Serve answers a scrape in the text
+// format and stops cleanly.
+func Test_Serve_exposesMetrics(t *testing.T) {
+	t.Parallel()
+
+	NewCounter("test_served_total", "Served.").Inc()
+	addr, stop, err := Serve("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+	defer stop()
+
+	resp, err := http.Get("http://" + addr + Path)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if !strings.Contains(string(body), "test_served_total 1\n") {
+		t.Errorf("body lacks the counter:\n%s", body)
+	}
+}
+
+// Test_Serve_reportsBusyPort asserts that a listen failure is returned
+// up front.
+func Test_Serve_reportsBusyPort(t *testing.T) {
+	t.Parallel()
+
+	addr, stop, err := Serve("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+	defer stop()
+	if _, _, err := Serve(addr); err == nil {
+		t.Error("second Serve on the same port succeeded")
+	}
+}
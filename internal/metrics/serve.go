@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Path is where Serve exposes the metrics.
+const Path = "/metrics"
+
+// contentType is the Prometheus text exposition format.
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// shutdownTimeout bounds how long the server waits for a scrape in progress
+// when the run ends.
+const shutdownTimeout = 2 * time.Second
+
+// Handler serves the metrics in the text format.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		_ = WriteText(w)
+	})
+}
+
+// Serve listens on addr (":9090", "127.0.0.1:9090") and serves the metrics
+// at Path until stop is called. The listener is opened before Serve
+// returns, so a port in use is reported up front; the returned address is
+// the one actually bound, which matters for port 0.
+func Serve(addr string) (bound string, stop func(), err error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return "", nil, fmt.Errorf("metrics listener: %w", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle(Path, Handler())
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// Serve only fails once the listener is gone; metrics never fail
+		// a sync, so there is nobody to tell.
+		_ = srv.Serve(ln)
+	}()
+	return ln.Addr().String(), func() {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		_ = srv.Shutdown(ctx)
+		<-done
+	}, nil
+}
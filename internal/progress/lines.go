@@ -0,0 +1,281 @@
+package progress
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/greeddj/imapsync-go/internal/utils"
+	"github.com/jedib0t/go-pretty/v6/text"
+)
+
+// JSON event kinds.
+const (
+	eventProgress = "progress"
+	eventDone     = "done"
+	eventError    = "error"
+	eventLog      = "log"
+)
+
+// trackerIDs numbers trackers across every Writer of the run, so that JSON
+// consumers can tell them apart even when their messages change.
+var trackerIDs atomic.Int64
+
+// lineWriter renders trackers as plain status lines or JSON events, written
+// once per interval for every tracker that moved since the last one. Nothing
+// is ever redrawn, so the output suits logs, CI and pipes.
+type lineWriter struct {
+	out      io.Writer
+	quit     chan struct{}
+	done     chan struct{}
+	mode     string
+	trackers []*lineTracker
+	interval time.Duration
+	started  bool
+	stopOnce sync.Once
+	mu       sync.Mutex
+}
+
+// lineTracker is a tracker with what was last reported about it.
+type lineTracker struct {
+	since     time.Time // when it was first seen started
+	t         *Tracker
+	id        int64
+	base      int64 // value when first seen started
+	baseMoved int64 // bytes moved, for the rate, when first seen started
+	lastValue int64
+	lastBytes int64
+	finished  bool // its done or error line is out
+}
+
+// trackerEvent is a JSON progress, done or error event. Rates are per
+// second, BytesRate of the wire traffic when the tracker counts it;
+// BytesTotal and ETASeconds are omitted while unknown.
+type trackerEvent struct {
+	Time           time.Time `json:"time"`
+	Event          string    `json:"event"`
+	Message        string    `json:"message"`
+	ID             int64     `json:"id"`
+	Done           int64     `json:"done"`
+	Total          int64     `json:"total"`
+	Percent        float64   `json:"percent"`
+	BytesDone      int64     `json:"bytes_done,omitempty"`
+	BytesTotal     int64     `json:"bytes_total,omitempty"`
+	Rate           float64   `json:"rate"`
+	BytesRate      float64   `json:"bytes_rate,omitempty"`
+	ETASeconds     float64   `json:"eta_seconds,omitempty"`
+	ElapsedSeconds float64   `json:"elapsed_seconds"`
+}
+
+// logEvent is a JSON log event, for Writer.Log.
+type logEvent struct {
+	Time    time.Time `json:"time"`
+	Event   string    `json:"event"`
+	Message string    `json:"message"`
+}
+
+// newLineWriter returns a lineWriter in mode ModePlain or ModeJSON.
+func newLineWriter(mode string, out io.Writer, interval time.Duration) *lineWriter {
+	return &lineWriter{
+		out:      out,
+		mode:     mode,
+		interval: interval,
+		quit:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// setOutput redirects the lines to out.
+func (l *lineWriter) setOutput(out io.Writer) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.out = out
+}
+
+// append adds a tracker.
+func (l *lineWriter) append(t *Tracker) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.trackers = append(l.trackers, &lineTracker{t: t, id: trackerIDs.Add(1)})
+}
+
+// start begins reporting every interval.
+func (l *lineWriter) start() {
+	l.mu.Lock()
+	l.started = true
+	l.mu.Unlock()
+	go func() {
+		defer close(l.done)
+		ticker := time.NewTicker(l.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-l.quit:
+				return
+			case <-ticker.C:
+				l.report(time.Now())
+			}
+		}
+	}()
+}
+
+// stop reports once more, so that no finished tracker goes unreported, and
+// ends the reporting. It may be called more than once.
+func (l *lineWriter) stop() {
+	l.stopOnce.Do(func() {
+		close(l.quit)
+		l.mu.Lock()
+		started := l.started
+		l.mu.Unlock()
+		if started {
+			<-l.done
+		}
+		l.report(time.Now())
+	})
+}
+
+// log writes a message right away.
+func (l *lineWriter) log(msg string) {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.mode == ModeJSON {
+		l.writeJSON(logEvent{Time: now, Event: eventLog, Message: text.StripEscape(msg)})
+		return
+	}
+	_, _ = fmt.Fprintf(l.out, "%s %s\n", now.Format(time.TimeOnly), text.StripEscape(msg))
+}
+
+// report writes a line for every tracker that moved or finished since the
+// last report. Trackers that have not started yet are skipped, so a long
+// queue of waiting folders does not flood the output.
+func (l *lineWriter) report(now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, lt := range l.trackers {
+		if lt.finished {
+			continue
+		}
+		t := lt.t
+		t.sum()
+		finished := t.IsDone() || t.IsErrored()
+		if !t.IsStarted() && !finished {
+			continue
+		}
+		value := t.Value()
+		bytesDone, _ := t.Bytes()
+		if lt.since.IsZero() {
+			lt.since, lt.base, lt.baseMoved = now, value, t.moved()
+			lt.lastValue, lt.lastBytes = -1, -1
+		}
+		if !finished && value == lt.lastValue && bytesDone == lt.lastBytes {
+			continue
+		}
+		lt.lastValue, lt.lastBytes, lt.finished = value, bytesDone, finished
+
+		ev := lt.event(now)
+		if l.mode == ModeJSON {
+			l.writeJSON(ev)
+		} else {
+			_, _ = fmt.Fprintln(l.out, plainLine(ev))
+		}
+	}
+}
+
+// event describes the tracker at now.
+func (lt *lineTracker) event(now time.Time) trackerEvent {
+	t := lt.t
+	ev := trackerEvent{
+		Time:    now,
+		Event:   eventProgress,
+		ID:      lt.id,
+		Message: strings.TrimSpace(text.StripEscape(t.Text())),
+		Done:    t.Value(),
+		Total:   t.TotalValue(),
+	}
+	switch {
+	case t.IsErrored():
+		ev.Event = eventError
+	case t.IsDone():
+		ev.Event = eventDone
+	}
+	ev.BytesDone, ev.BytesTotal = t.Bytes()
+	if ev.Total > 0 {
+		ev.Percent = math.Round(float64(ev.Done)/float64(ev.Total)*1000) / 10
+	}
+
+	elapsed := now.Sub(lt.since).Seconds()
+	ev.ElapsedSeconds = math.Round(elapsed*10) / 10
+	if elapsed <= 0 {
+		return ev
+	}
+	ev.Rate = math.Round(float64(ev.Done-lt.base)/elapsed*10) / 10
+	ev.BytesRate = math.Round(float64(t.moved()-lt.baseMoved) / elapsed)
+	if ev.Event != eventProgress {
+		return ev
+	}
+	// Bytes predict the remaining time better than a message count: one
+	// large message can take as long as a thousand small ones.
+	switch {
+	case ev.BytesTotal > 0 && ev.BytesRate > 0:
+		ev.ETASeconds = math.Round(float64(ev.BytesTotal-ev.BytesDone) / ev.BytesRate)
+	case ev.Total > 0 && ev.Rate > 0:
+		ev.ETASeconds = math.Round(float64(ev.Total-ev.Done) / ev.Rate)
+	}
+	return ev
+}
+
+// plainLine renders ev as one status line, such as
+// "12:00:01 INBOX → INBOX: 120/500 (24.0%), 1.2 MB of 5.0 MB, 3.4/s, 410 KB/s, ETA 1m52s".
+func plainLine(ev trackerEvent) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s: ", ev.Time.Format(time.TimeOnly), ev.Message)
+	switch ev.Event {
+	case eventDone:
+		b.WriteString("done, ")
+	case eventError:
+		b.WriteString("error, ")
+	}
+	if ev.Total > 0 {
+		fmt.Fprintf(&b, "%d/%d (%.1f%%)", ev.Done, ev.Total, ev.Percent)
+	} else {
+		fmt.Fprintf(&b, "%d", ev.Done)
+	}
+	if ev.BytesTotal > 0 {
+		fmt.Fprintf(&b, ", %s of %s", utils.FormatSize(uint64(ev.BytesDone)), utils.FormatSize(uint64(ev.BytesTotal)))
+	}
+	if ev.Event != eventProgress {
+		fmt.Fprintf(&b, " in %s", formatDuration(ev.ElapsedSeconds))
+		return b.String()
+	}
+	if ev.ElapsedSeconds == 0 {
+		return b.String()
+	}
+	fmt.Fprintf(&b, ", %.1f/s", ev.Rate)
+	if ev.BytesRate > 0 {
+		fmt.Fprintf(&b, ", %s/s", utils.FormatSize(uint64(ev.BytesRate)))
+	}
+	if ev.ETASeconds > 0 {
+		fmt.Fprintf(&b, ", ETA %s", formatDuration(ev.ETASeconds))
+	}
+	return b.String()
+}
+
+// writeJSON writes v as one line. Callers hold l.mu.
+func (l *lineWriter) writeJSON(v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	_, _ = l.out.Write(append(data, '\n'))
+}
+
+// formatDuration renders seconds rounded to the second.
+func formatDuration(seconds float64) string {
+	return (time.Duration(seconds) * time.Second).Round(time.Second).String()
+}
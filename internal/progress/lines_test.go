@@ -0,0 +1,113 @@
+package progress
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test_lineWriter_plain asserts that plain output reports a started tracker
+// with counts, bytes, rate and ETA, stays silent while nothing moves,
+// skips trackers not started yet, and reports completion exactly once.
+func Test_lineWriter_plain(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	l := newLineWriter(ModePlain, &buf, time.Hour)
+	tr := NewTracker("\x1b[36mINBOX → INBOX\x1b[0m", 10)
+	tr.SetBytesTotal(10 << 20)
+	l.append(tr)
+	l.append(NewTracker("Sent → Sent", 5))
+
+	start := time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)
+	tr.Start()
+	l.report(start)
+	tr.Increment(4)
+	tr.AddBytes(4 << 20)
+	l.report(start.Add(2 * time.Second))
+	l.report(start.Add(3 * time.Second))
+	tr.Increment(6)
+	tr.MarkAsDone()
+	l.report(start.Add(4 * time.Second))
+	l.report(start.Add(5 * time.Second))
+
+	want := []string{
+		"12:00:00 INBOX → INBOX: 0/10 (0.0%), 0 B of 10.00 MB",
+		"12:00:02 INBOX → INBOX: 4/10 (40.0%), 4.00 MB of 10.00 MB, 2.0/s, 2.00 MB/s, ETA 3s",
+		"12:00:04 INBOX → INBOX: done, 10/10 (100.0%), 4.00 MB of 10.00 MB in 4s",
+	}
+	got := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("output:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+// Test_lineWriter_json asserts that JSON output is one event per line, with
+// the tracker's id on every event and log messages stripped of colour.
+func Test_lineWriter_json(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	l := newLineWriter(ModeJSON, &buf, 10*time.Millisecond)
+	tr := NewTracker("INBOX → INBOX", 4)
+	l.append(tr)
+	l.start()
+	tr.Start()
+	tr.Increment(1)
+	l.log("\x1b[31mretrying\x1b[0m")
+	tr.Increment(1)
+	tr.MarkAsErrored()
+	l.stop()
+	l.stop()
+
+	var events []map[string]any
+	for line := range strings.SplitSeq(strings.TrimSpace(buf.String()), "\n") {
+		var ev map[string]any
+		if err := json.Unmarshal([]byte(line), &ev); err != nil {
+			t.Fatalf("line %q: %v", line, err)
+		}
+		events = append(events, ev)
+	}
+	if len(events) < 2 {
+		t.Fatalf("events = %v; want a log and an error event at least", events)
+	}
+	logs, errs := 0, 0
+	for _, ev := range events {
+		switch ev["event"] {
+		case eventLog:
+			logs++
+			if ev["message"] != "retrying" {
+				t.Errorf("log message = %q; want retrying", ev["message"])
+			}
+		case eventError:
+			errs++
+			if ev["done"] != 2.0 || ev["total"] != 4.0 || ev["percent"] != 50.0 {
+				t.Errorf("error event = %v; want 2 of 4 done", ev)
+			}
+		}
+		if ev["event"] != eventLog && ev["id"] == nil {
+			t.Errorf("event %v has no id", ev)
+		}
+	}
+	if logs != 1 || errs != 1 {
+		t.Errorf("logs = %d, errors = %d; want 1 of each\n%s", logs, errs, buf.String())
+	}
+	if last := events[len(events)-1]; last["event"] != eventError {
+		t.Errorf("last event = %v; want the error", last)
+	}
+}
+
+// TestNewLogWriter asserts that a log writer prints Log messages as plain
+// lines.
+func TestNewLogWriter(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	w := NewLogWriter(&buf)
+	w.Log("stream error for folder %s", "INBOX")
+	if got := buf.String(); !strings.HasSuffix(got, " stream error for folder INBOX\n") {
+		t.Errorf("output = %q; want the log line", got)
+	}
+}
@@ -0,0 +1,106 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// Output modes for Configure.
+const (
+	// ModeAuto picks ModeTTY when stdout is a terminal and ModePlain
+	// otherwise.
+	ModeAuto = "auto"
+	// ModeTTY redraws progress bars in place.
+	ModeTTY = "tty"
+	// ModePlain prints a status line per changed tracker every interval.
+	ModePlain = "plain"
+	// ModeJSON writes one JSON event per line: tracker updates every
+	// interval, tracker completion and log messages.
+	ModeJSON = "json"
+)
+
+// Default intervals between plain and JSON updates. Plain output is read by
+// people scrolling a CI log; JSON output by programs that redraw.
+const (
+	defaultPlainInterval = 10 * time.Second
+	defaultJSONInterval  = time.Second
+)
+
+// Options selects how every Writer created afterwards renders.
+//
+// FD is the file descriptor that plain and JSON output go to; 0 and 1 mean
+// stdout. Bars always go to stdout. Interval 0 picks the mode's default.
+type Options struct {
+	Mode     string
+	FD       int
+	Interval time.Duration
+}
+
+// settings is the configuration NewWriter reads.
+var settings = struct {
+	out      io.Writer
+	mode     string
+	interval time.Duration
+	explicit bool
+	mu       sync.Mutex
+}{mode: ModeTTY}
+
+// Configure sets the output mode for the Writers created afterwards. A mode
+// other than auto is explicit: it is kept even for quiet Writers, since a
+// script asking for JSON wants the events whatever else is printed.
+func Configure(opts Options) error {
+	mode := opts.Mode
+	explicit := true
+	switch mode {
+	case "", ModeAuto:
+		explicit = false
+		mode = ModePlain
+		if term.IsTerminal(int(os.Stdout.Fd())) {
+			mode = ModeTTY
+		}
+	case ModeTTY, ModePlain, ModeJSON:
+	default:
+		return fmt.Errorf("--progress must be %s, %s, %s or %s, got %q", ModeAuto, ModeTTY, ModePlain, ModeJSON, opts.Mode)
+	}
+
+	var out io.Writer = os.Stdout
+	switch opts.FD {
+	case 0, 1:
+	case 2:
+		out = os.Stderr
+	default:
+		f := os.NewFile(uintptr(opts.FD), fmt.Sprintf("fd%d", opts.FD))
+		if f == nil {
+			return fmt.Errorf("--progress-fd %d is not a valid file descriptor", opts.FD)
+		}
+		if _, err := f.Stat(); err != nil {
+			return fmt.Errorf("--progress-fd %d is not open: %w", opts.FD, err)
+		}
+		out = f
+	}
+
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = defaultPlainInterval
+		if mode == ModeJSON {
+			interval = defaultJSONInterval
+		}
+	}
+
+	settings.mu.Lock()
+	defer settings.mu.Unlock()
+	settings.mode, settings.out, settings.interval, settings.explicit = mode, out, interval, explicit
+	return nil
+}
+
+// current returns the configuration set by Configure.
+func current() (mode string, out io.Writer, interval time.Duration, explicit bool) {
+	settings.mu.Lock()
+	defer settings.mu.Unlock()
+	return settings.mode, settings.out, settings.interval, settings.explicit
+}
@@ -0,0 +1,26 @@
+package progress
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestConfigure_invalid asserts that unknown modes and closed file
+// descriptors are rejected with the flag's name.
+func TestConfigure_invalid(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		want string
+		opts Options
+	}{
+		{want: "--progress must be", opts: Options{Mode: "fancy"}},
+		{want: "--progress-fd 987", opts: Options{Mode: ModeJSON, FD: 987}},
+	}
+	for _, tt := range tests {
+		err := Configure(tt.opts)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Configure(%+v) error = %v; want %q", tt.opts, err, tt.want)
+		}
+	}
+}
@@ -1,4 +1,8 @@
 // Package progress provides pre-configured progress bar utilities.
+//
+// A Writer renders its trackers in one of three modes, chosen once per run
+// with Configure: live bars redrawn in place on a terminal, periodic plain
+// status lines for logs and CI, or NDJSON events for scripts.
 package progress
 
 import (
@@ -6,6 +10,8 @@ import (
 	"io"
 	"os"
 	"runtime"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/jedib0t/go-pretty/v6/progress"
@@ -14,9 +20,22 @@ import (
 )
 
 // Writer is a wrapper around progress.Writer with pre-configured settings.
+// In plain and JSON mode the go-pretty writer is replaced by lines.
+//
+// On a terminal, ShowBytes adds byte counts, a rate and an ETA to the lines
+// of trackers that count bytes; the trackers are then kept to be redrawn
+// every statsInterval until Stop.
 type Writer struct {
-	pw          progress.Writer
-	numTrackers int
+	pw            progress.Writer
+	lines         *lineWriter
+	quit          chan struct{}
+	done          chan struct{}
+	trackers      []*Tracker
+	numTrackers   int
+	messageLength int
+	stopOnce      sync.Once
+	mu            sync.Mutex
+	bytes         bool
 }
 
 // getTerminalWidth returns the current terminal width, defaulting to 120 if detection fails.
@@ -27,8 +46,17 @@ func getTerminalWidth() int {
 	return 120 // default width
 }
 
-// NewWriter creates a new progress writer with pre-configured settings and colors.
+// NewWriter creates a new progress writer in the configured mode. quiet
+// silences it, unless plain or JSON output was asked for explicitly.
 func NewWriter(numTrackers int, quiet bool) *Writer {
+	mode, out, interval, explicit := current()
+	if mode != ModeTTY {
+		if quiet && !explicit {
+			out = io.Discard
+		}
+		return &Writer{lines: newLineWriter(mode, out, interval), numTrackers: numTrackers}
+	}
+
 	pw := progress.NewWriter()
 	pw.SetAutoStop(false)
 	if quiet {
@@ -109,36 +137,130 @@ func NewWriter(numTrackers int, quiet bool) *Writer {
 	pw.Style().Options.TimeInProgressPrecision = time.Millisecond
 	pw.Style().Options.TimeDonePrecision = time.Millisecond
 
-	return &Writer{pw: pw, numTrackers: numTrackers}
+	return &Writer{pw: pw, numTrackers: numTrackers, messageLength: messageLength}
+}
+
+// NewLogWriter returns a Writer that draws no trackers and writes every Log
+// message as a time-stamped line to out, whatever the configured mode. It is
+// for callers that render progress themselves.
+func NewLogWriter(out io.Writer) *Writer {
+	return &Writer{lines: newLineWriter(ModePlain, out, time.Hour)}
+}
+
+// ShowBytes shows the bytes done and in all, the current rate and an ETA
+// by bytes on the line of every tracker with a byte total, in place of the
+// ETA by count: one large message can take as long as a thousand small
+// ones. Plain and JSON output always report bytes. Call it before Start.
+func (w *Writer) ShowBytes() {
+	if w.lines != nil {
+		return
+	}
+	w.bytes = true
+	w.pw.Style().Visibility.ETA = false
 }
 
 // SetOutputWriter redirects rendered output to out.
-func (w *Writer) SetOutputWriter(out io.Writer) { w.pw.SetOutputWriter(out) }
+func (w *Writer) SetOutputWriter(out io.Writer) {
+	if w.lines != nil {
+		w.lines.setOutput(out)
+		return
+	}
+	w.pw.SetOutputWriter(out)
+}
 
 // WaitForRenderDone spins until the render goroutine finishes its final pass.
 func (w *Writer) WaitForRenderDone() {
+	if w.lines != nil {
+		return
+	}
 	for w.pw.IsRenderInProgress() {
 		runtime.Gosched()
 	}
 }
 
 // AppendTracker adds a tracker to the progress writer.
-func (w *Writer) AppendTracker(tracker *progress.Tracker) {
-	w.pw.AppendTracker(tracker)
+func (w *Writer) AppendTracker(tracker *Tracker) {
+	if w.lines != nil {
+		w.lines.append(tracker)
+		return
+	}
+	if w.bytes {
+		tracker.width.Store(int64(w.messageLength))
+		w.mu.Lock()
+		w.trackers = append(w.trackers, tracker)
+		w.mu.Unlock()
+	}
+	w.pw.AppendTracker(tracker.Tracker)
 }
 
 // Log prints a message above the progress bars.
 func (w *Writer) Log(msg string, args ...any) {
+	if w.lines != nil {
+		w.lines.log(fmt.Sprintf(msg, args...))
+		return
+	}
 	w.pw.Log(msg, args...)
 }
 
 // Start begins rendering the progress bars in a goroutine.
 func (w *Writer) Start() {
+	if w.lines != nil {
+		w.lines.start()
+		return
+	}
+	if w.bytes {
+		w.quit, w.done = make(chan struct{}), make(chan struct{})
+		go w.refresh()
+	}
 	go w.pw.Render()
 }
 
+// refresh redraws the stats of the trackers every statsInterval until
+// stopRefresh.
+func (w *Writer) refresh() {
+	defer close(w.done)
+	ticker := time.NewTicker(statsInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.quit:
+			return
+		case now := <-ticker.C:
+			w.updateStats(now)
+		}
+	}
+}
+
+// updateStats sums the totals and redraws the stats of every tracker.
+func (w *Writer) updateStats(now time.Time) {
+	w.mu.Lock()
+	trackers := w.trackers
+	w.mu.Unlock()
+	for _, t := range trackers {
+		t.sum()
+		t.updateStats(now)
+	}
+}
+
+// stopRefresh ends refresh, if it runs, and draws the final stats.
+func (w *Writer) stopRefresh() {
+	w.stopOnce.Do(func() {
+		if w.quit == nil {
+			return
+		}
+		close(w.quit)
+		<-w.done
+		w.updateStats(time.Now())
+	})
+}
+
 // Stop stops the progress writer without clearing.
 func (w *Writer) Stop() {
+	if w.lines != nil {
+		w.lines.stop()
+		return
+	}
+	w.stopRefresh()
 	w.pw.Stop()
 }
 
@@ -146,6 +268,14 @@ func (w *Writer) Stop() {
 // from the terminal. Line count is taken from the NewWriter argument so
 // callers never have to keep that number in sync by hand.
 func (w *Writer) StopAndClear() {
+	if w.lines != nil {
+		// Lines are never redrawn, so there is nothing to clear.
+		w.lines.stop()
+		return
+	}
+
+	w.stopRefresh()
+
 	// Wait for final rendering
 	time.Sleep(300 * time.Millisecond)
 
@@ -163,13 +293,74 @@ func (w *Writer) StopAndClear() {
 }
 
 // NewTracker creates a new tracker with the given message and total.
-func NewTracker(message string, total int64) *progress.Tracker {
-	return &progress.Tracker{
+func NewTracker(message string, total int64) *Tracker {
+	t := &Tracker{Tracker: &progress.Tracker{
 		Message: message,
 		Total:   total,
 		Units:   progress.UnitsDefault,
+	}}
+	t.message.Store(&message)
+	t.total.Store(total)
+	return t
+}
+
+// NewTotalTracker creates a tracker that sums parts: its value and bytes
+// done are theirs added up, and it finishes when they all have. The caller
+// sets the byte total. A Writer in plain or JSON mode, or one showing bytes,
+// keeps it up to date.
+func NewTotalTracker(message string, total int64, parts []*Tracker) *Tracker {
+	t := NewTracker(message, total)
+	t.AutoStopDisabled = true
+	t.parts = parts
+	return t
+}
+
+// Tracker is one progress line: the go-pretty tracker, plus what plain and
+// JSON output need to read back safely, optional byte counts for trackers
+// that count messages, and the wire traffic their rate is taken from.
+type Tracker struct {
+	*progress.Tracker
+	message    atomic.Pointer[string]
+	stats      atomic.Pointer[string]
+	parts      []*Tracker
+	meter      Meter
+	wire       wireCount
+	total      atomic.Int64
+	bytesDone  atomic.Int64
+	bytesTotal atomic.Int64
+	width      atomic.Int64
+}
+
+// UpdateMessage updates the message string.
+func (t *Tracker) UpdateMessage(msg string) {
+	t.message.Store(&msg)
+	t.Tracker.UpdateMessage(t.display())
+}
+
+// UpdateTotal updates the total value.
+func (t *Tracker) UpdateTotal(total int64) {
+	t.total.Store(total)
+	t.Tracker.UpdateTotal(total)
+}
+
+// Text returns the current message.
+func (t *Tracker) Text() string {
+	if p := t.message.Load(); p != nil {
+		return *p
 	}
+	return ""
 }
 
-// Tracker is an alias for the underlying progress.Tracker type.
-type Tracker = progress.Tracker
+// TotalValue returns the current total.
+func (t *Tracker) TotalValue() int64 { return t.total.Load() }
+
+// SetBytesTotal sets how many bytes the tracked task moves in all.
+func (t *Tracker) SetBytesTotal(n int64) { t.bytesTotal.Store(n) }
+
+// AddBytes records n more bytes moved.
+func (t *Tracker) AddBytes(n int64) { t.bytesDone.Add(n) }
+
+// Bytes returns the bytes moved so far and in all; total is 0 when unknown.
+func (t *Tracker) Bytes() (done, total int64) {
+	return t.bytesDone.Load(), t.bytesTotal.Load()
+}
@@ -0,0 +1,178 @@
+package progress
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/greeddj/imapsync-go/internal/utils"
+	"github.com/jedib0t/go-pretty/v6/text"
+)
+
+// statsInterval is how often a terminal Writer that shows bytes redraws the
+// byte counts, rate and ETA of its trackers.
+const statsInterval = 500 * time.Millisecond
+
+// Meter turns samples of a growing count, such as bytes moved, into a rate
+// per second smoothed over the last few samples. The zero value is ready to
+// use; a Meter is not safe for concurrent use.
+type Meter struct {
+	at   time.Time
+	last int64
+	rate float64
+	seen bool
+}
+
+// Update takes the count at now and returns the rate. The first rate is
+// taken as is; later ones are eased in, so that one bursty sample does not
+// swing the ETA. A count that goes back reads as no progress.
+func (m *Meter) Update(total int64, now time.Time) float64 {
+	if dt := now.Sub(m.at).Seconds(); !m.at.IsZero() && dt > 0 {
+		r := float64(max(0, total-m.last)) / dt
+		if m.seen {
+			r = 0.7*m.rate + 0.3*r
+		}
+		m.rate, m.seen = r, true
+	}
+	m.at, m.last = now, total
+	return m.rate
+}
+
+// Rate returns the rate as of the last Update.
+func (m *Meter) Rate() float64 { return m.rate }
+
+// wireCount is the traffic counted for a tracker: the bytes of sources that
+// were stopped, and the sources still counting.
+type wireCount struct {
+	live    []*wireSource
+	done    int64
+	mu      sync.Mutex
+	counted bool
+}
+
+// wireSource is one running byte count and its value when counting began.
+type wireSource struct {
+	count func() int64
+	base  int64
+}
+
+// CountWire adds what count grows by from now on to the tracker's wire
+// traffic, until stop is called. count returns a running total, such as
+// ratelimit.Counter.Read; one source may count for one tracker at a time.
+func (t *Tracker) CountWire(count func() int64) (stop func()) {
+	src := &wireSource{count: count, base: count()}
+	t.wire.mu.Lock()
+	t.wire.live = append(t.wire.live, src)
+	t.wire.counted = true
+	t.wire.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			t.wire.mu.Lock()
+			defer t.wire.mu.Unlock()
+			for i, s := range t.wire.live {
+				if s == src {
+					t.wire.live = append(t.wire.live[:i], t.wire.live[i+1:]...)
+					break
+				}
+			}
+			t.wire.done += src.count() - src.base
+		})
+	}
+}
+
+// WireBytes returns the wire traffic counted for the tracker so far, or for
+// all its parts when it is a total; ok is false when none ever was.
+func (t *Tracker) WireBytes() (n int64, ok bool) {
+	for _, p := range t.parts {
+		pn, pok := p.WireBytes()
+		n, ok = n+pn, ok || pok
+	}
+	if t.parts != nil {
+		return n, ok
+	}
+	t.wire.mu.Lock()
+	defer t.wire.mu.Unlock()
+	n = t.wire.done
+	for _, s := range t.wire.live {
+		n += s.count() - s.base
+	}
+	return n, t.wire.counted
+}
+
+// moved returns the bytes the rate is taken from: wire traffic when it is
+// counted, as it shows what the connection really does, otherwise the
+// bytes recorded with AddBytes.
+func (t *Tracker) moved() int64 {
+	if n, ok := t.WireBytes(); ok {
+		return n
+	}
+	done, _ := t.Bytes()
+	return done
+}
+
+// sum sets a total's value and bytes from its parts; it starts when the
+// first part does and finishes when the last one does, errored if any part
+// is. It does nothing for other trackers.
+func (t *Tracker) sum() {
+	if t.parts == nil || t.IsDone() || t.IsErrored() {
+		return
+	}
+	var value, bytes int64
+	started, finished, errored := false, true, false
+	for _, p := range t.parts {
+		value += p.Value()
+		done, _ := p.Bytes()
+		bytes += done
+		ended := p.IsDone() || p.IsErrored()
+		started = started || p.IsStarted() || ended
+		finished = finished && ended
+		errored = errored || p.IsErrored()
+	}
+	t.bytesDone.Store(bytes)
+	if !started {
+		return
+	}
+	if !t.IsStarted() || value != t.Value() {
+		t.SetValue(value)
+	}
+	switch {
+	case finished && errored:
+		t.MarkAsErrored()
+	case finished:
+		t.MarkAsDone()
+	}
+}
+
+// updateStats redraws the byte counts, rate and ETA at the end of the
+// tracker's line. Trackers that count no bytes are left as they are.
+func (t *Tracker) updateStats(now time.Time) {
+	done, total := t.Bytes()
+	if total <= 0 {
+		return
+	}
+	stats := fmt.Sprintf("%s of %s", utils.FormatSize(uint64(done)), utils.FormatSize(uint64(total)))
+	if t.IsStarted() && !t.IsDone() && !t.IsErrored() {
+		rate := t.meter.Update(t.moved(), now)
+		stats += fmt.Sprintf(", %s/s", utils.FormatSize(uint64(rate)))
+		if rate > 0 && total > done {
+			stats += ", ETA " + formatDuration(float64(total-done)/rate)
+		}
+	}
+	t.stats.Store(&stats)
+	t.Tracker.UpdateMessage(t.display())
+}
+
+// display returns the message go-pretty shows: the message, cut to leave
+// room for the stats, which end the line in a column of their own.
+func (t *Tracker) display() string {
+	msg := t.Text()
+	stats := t.stats.Load()
+	width := int(t.width.Load())
+	if stats == nil || width == 0 {
+		return msg
+	}
+	room := max(0, width-text.StringWidthWithoutEscSequences(*stats)-1)
+	return text.Pad(text.Snip(msg, room, "..."), room, ' ') + " " + *stats
+}
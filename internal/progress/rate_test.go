@@ -0,0 +1,128 @@
+package progress
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMeter asserts that the first rate is taken as is, later ones are eased
+// in, and a count that goes back reads as no progress.
+func TestMeter(t *testing.T) {
+	t.Parallel()
+
+	var m Meter
+	at := time.Unix(0, 0)
+	if got := m.Update(0, at); got != 0 {
+		t.Fatalf("first sample = %v, want 0", got)
+	}
+	if got := m.Update(1000, at.Add(time.Second)); got != 1000 {
+		t.Errorf("after 1000 B in 1s = %v, want 1000", got)
+	}
+	if got := m.Update(500, at.Add(2*time.Second)); got != 700 {
+		t.Errorf("after a step back = %v, want 700", got)
+	}
+	if m.Rate() != 700 {
+		t.Errorf("Rate = %v, want 700", m.Rate())
+	}
+}
+
+// TestTracker_CountWire asserts that wire traffic counts only while a source
+// is attached, from when it was, and that stopping twice counts once.
+func TestTracker_CountWire(t *testing.T) {
+	t.Parallel()
+
+	var a, b int64 = 100, 0
+	tr := NewTracker("INBOX", 10)
+	if _, ok := tr.WireBytes(); ok {
+		t.Fatal("WireBytes ok before any source was attached")
+	}
+	stopA := tr.CountWire(func() int64 { return a })
+	stopB := tr.CountWire(func() int64 { return b })
+	a, b = 150, 20
+	if n, ok := tr.WireBytes(); n != 70 || !ok {
+		t.Fatalf("WireBytes = %d, %v; want 70, true", n, ok)
+	}
+	stopA()
+	stopA()
+	a = 1000
+	if n, _ := tr.WireBytes(); n != 70 {
+		t.Errorf("after stopping a: WireBytes = %d, want 70", n)
+	}
+	stopB()
+	if n, _ := tr.WireBytes(); n != 70 {
+		t.Errorf("after stopping both: WireBytes = %d, want 70", n)
+	}
+}
+
+// TestTotalTracker_sum asserts that a total adds up its parts' messages,
+// bytes and wire traffic, starts with the first part and finishes, errored,
+// with the last.
+func TestTotalTracker_sum(t *testing.T) {
+	t.Parallel()
+
+	parts := []*Tracker{NewTracker("INBOX", 3), NewTracker("Sent", 2)}
+	total := NewTotalTracker("Total", 5, parts)
+	total.sum()
+	if total.IsStarted() {
+		t.Fatal("total started before its parts")
+	}
+
+	parts[0].Increment(2)
+	parts[0].AddBytes(2048)
+	parts[0].CountWire(func() int64 { return 4096 })
+	total.sum()
+	if !total.IsStarted() || total.Value() != 2 {
+		t.Fatalf("total started %v, value %d; want started, 2", total.IsStarted(), total.Value())
+	}
+	if done, _ := total.Bytes(); done != 2048 {
+		t.Errorf("bytes done = %d, want 2048", done)
+	}
+	if n, ok := total.WireBytes(); n != 0 || !ok {
+		t.Errorf("WireBytes = %d, %v; want 0, true", n, ok)
+	}
+
+	parts[0].Increment(1)
+	parts[1].MarkAsErrored()
+	total.sum()
+	if !total.IsErrored() || total.Value() != 3 {
+		t.Errorf("total errored %v, value %d; want errored, 3", total.IsErrored(), total.Value())
+	}
+}
+
+// TestTracker_updateStats asserts that a started tracker with a byte total
+// ends its line with bytes, rate and an ETA by bytes, in a column of its own,
+// and that a tracker without bytes keeps its message.
+func TestTracker_updateStats(t *testing.T) {
+	t.Parallel()
+
+	tr := NewTracker("INBOX → INBOX", 10)
+	tr.width.Store(60)
+	tr.SetBytesTotal(4 << 20)
+	tr.Increment(1)
+	var wire int64
+	tr.CountWire(func() int64 { return wire })
+	at := time.Unix(0, 0)
+	tr.updateStats(at)
+	tr.AddBytes(1 << 20)
+	wire = 1 << 20
+	tr.updateStats(at.Add(time.Second))
+
+	got := tr.Tracker.Message
+	if !strings.HasPrefix(got, "INBOX → INBOX ") || !strings.HasSuffix(got, "1.00 MB of 4.00 MB, 1.00 MB/s, ETA 3s") {
+		t.Errorf("message = %q", got)
+	}
+	if w := len([]rune(got)); w != 60 {
+		t.Errorf("message is %d columns, want 60", w)
+	}
+	if tr.Text() != "INBOX → INBOX" {
+		t.Errorf("Text = %q, want the message without stats", tr.Text())
+	}
+
+	plain := NewTracker("Scanning", 10)
+	plain.width.Store(60)
+	plain.updateStats(at)
+	if plain.Tracker.Message != "Scanning" {
+		t.Errorf("tracker without bytes = %q", plain.Tracker.Message)
+	}
+}
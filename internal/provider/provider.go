@@ -0,0 +1,381 @@
+// Package provider holds the profiles of known IMAP services: their limits,
+// special folders and accepted logins, and the lookup of a server's profile.
+package provider
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// Provider describes a known IMAP service: the practical limits clients
+// should respect to avoid hitting server-side throttling, the names of its
+// special folders, and the login mechanisms it accepts.
+//
+// Numbers come from imapsync's empirical recommendations (FAQ.Gmail.txt and
+// friends) and from the providers' own documentation. They are guidance, not
+// hard constants — a server may tighten or relax them at any time. Zero
+// means the provider documents no limit.
+//
+// LabelFolders marks a provider whose folders are labels: one stored message
+// is listed in every folder it is labelled with, so the same message in two
+// folders is not two copies.
+type Provider struct {
+	Folders        Folders  `json:"folders,omitzero"          yaml:"folders,omitempty"`
+	Name           string   `json:"name"                      yaml:"name"`
+	Notes          string   `json:"notes,omitempty"           yaml:"notes,omitempty"`
+	Hosts          []string `json:"hosts,omitempty"           yaml:"hosts,omitempty"`    // exact host names or "*.suffix" wildcards
+	Auth           []string `json:"auth,omitempty"            yaml:"auth,omitempty"`     // accepted mechanisms, preferred first
+	DownBPS        int      `json:"down_bps,omitempty"        yaml:"down_bps,omitempty"` // recommended ceiling, bytes/sec
+	UpBPS          int      `json:"up_bps,omitempty"          yaml:"up_bps,omitempty"`
+	DailyDownMB    int      `json:"daily_down_mb,omitempty"   yaml:"daily_down_mb,omitempty"`
+	DailyUpMB      int      `json:"daily_up_mb,omitempty"     yaml:"daily_up_mb,omitempty"`
+	MaxConnections int      `json:"max_connections,omitempty" yaml:"max_connections,omitempty"`
+	LabelFolders   bool     `json:"label_folders,omitempty"   yaml:"label_folders,omitempty"`
+}
+
+// Folders names a provider's special folders, by the role RFC 6154 gives
+// them. Empty means the provider has no such folder or uses no fixed name.
+type Folders struct {
+	Sent    string `json:"sent,omitempty"    yaml:"sent,omitempty"`
+	Drafts  string `json:"drafts,omitempty"  yaml:"drafts,omitempty"`
+	Trash   string `json:"trash,omitempty"   yaml:"trash,omitempty"`
+	Junk    string `json:"junk,omitempty"    yaml:"junk,omitempty"`
+	Archive string `json:"archive,omitempty" yaml:"archive,omitempty"`
+	All     string `json:"all,omitempty"     yaml:"all,omitempty"`
+}
+
+// Folder roles, as used by Folders.Role and Folders.Folder.
+const (
+	RoleSent    = "sent"
+	RoleDrafts  = "drafts"
+	RoleTrash   = "trash"
+	RoleJunk    = "junk"
+	RoleArchive = "archive"
+	RoleAll     = "all"
+)
+
+// folderRole is one role of Folders and its field.
+type folderRole struct {
+	folder *string
+	name   string
+}
+
+// roles returns every role with its field, in a fixed order.
+func (f *Folders) roles() []folderRole {
+	return []folderRole{
+		{&f.Sent, RoleSent}, {&f.Drafts, RoleDrafts}, {&f.Trash, RoleTrash},
+		{&f.Junk, RoleJunk}, {&f.Archive, RoleArchive}, {&f.All, RoleAll},
+	}
+}
+
+// Role returns the role of folder, or "" when it is not a special folder.
+func (f Folders) Role(folder string) string {
+	for _, r := range f.roles() {
+		if *r.folder != "" && *r.folder == folder {
+			return r.name
+		}
+	}
+	return ""
+}
+
+// Folder returns the folder with role, or "" when there is none.
+func (f Folders) Folder(role string) string {
+	for _, r := range f.roles() {
+		if r.name == role {
+			return *r.folder
+		}
+	}
+	return ""
+}
+
+// Translate returns the name that folder, one of f's special folders, has
+// under to's conventions. Other folders, and roles to has no name for, keep
+// their name.
+func (f Folders) Translate(folder string, to Folders) string {
+	if role := f.Role(folder); role != "" {
+		return cmp.Or(to.Folder(role), folder)
+	}
+	return folder
+}
+
+// builtinProviders are the profiles shipped with imapsync-go. Add new
+// entries here when their limits become well-documented; users extend and
+// override them in the config, through Merge.
+var builtinProviders = []Provider{
+	{
+		Name:           "Gmail",
+		Notes:          "Workspace IMAP bandwidth limits & 15 simultaneous connections per account; log in with an app password",
+		Hosts:          []string{"imap.gmail.com", "imap.googlemail.com"},
+		Auth:           []string{"login"},
+		DownBPS:        300_000,
+		UpBPS:          300_000,
+		DailyDownMB:    2500,
+		DailyUpMB:      500,
+		MaxConnections: 15,
+		LabelFolders:   true,
+		Folders: Folders{
+			Sent: "[Gmail]/Sent Mail", Drafts: "[Gmail]/Drafts", Trash: "[Gmail]/Trash",
+			Junk: "[Gmail]/Spam", All: "[Gmail]/All Mail",
+		},
+	},
+	{
+		Name:           "Microsoft 365",
+		Notes:          "Exchange Online allows 20 IMAP connections per mailbox and answers throttled commands with NO; messages above 35 MB are rejected by default",
+		Hosts:          []string{"outlook.office365.com", "outlook.office.com", "imap-mail.outlook.com", "*.mail.protection.outlook.com"},
+		Auth:           []string{"login"},
+		MaxConnections: 20,
+		Folders: Folders{
+			Sent: "Sent Items", Drafts: "Drafts", Trash: "Deleted Items",
+			Junk: "Junk Email", Archive: "Archive",
+		},
+	},
+	{
+		Name:           "Yahoo",
+		Notes:          "Needs an app password; drops clients opening more than 5 connections",
+		Hosts:          []string{"imap.mail.yahoo.com", "export.imap.mail.yahoo.com", "imap.aol.com"},
+		Auth:           []string{"login"},
+		MaxConnections: 5,
+		Folders: Folders{
+			Sent: "Sent", Drafts: "Draft", Trash: "Trash", Junk: "Bulk", Archive: "Archive",
+		},
+	},
+	{
+		Name:  "iCloud",
+		Notes: "Needs an app-specific password; the user is the address without @icloud.com",
+		Hosts: []string{"imap.mail.me.com"},
+		Auth:  []string{"login"},
+		Folders: Folders{
+			Sent: "Sent Messages", Drafts: "Drafts", Trash: "Deleted Messages",
+			Junk: "Junk", Archive: "Archive",
+		},
+	},
+	{
+		Name:  "Fastmail",
+		Notes: "Needs an app password",
+		Hosts: []string{"imap.fastmail.com"},
+		Auth:  []string{"login"},
+		Folders: Folders{
+			Sent: "Sent", Drafts: "Drafts", Trash: "Trash", Junk: "Spam", Archive: "Archive",
+		},
+	},
+	{
+		Name:  "Zoho",
+		Notes: "IMAP access must be enabled in the mail settings; organization accounts use imappro",
+		Hosts: []string{
+			"imap.zoho.com", "imap.zoho.eu", "imap.zoho.in", "imap.zoho.com.au",
+			"imappro.zoho.com", "imappro.zoho.eu", "imappro.zoho.in", "imappro.zoho.com.au",
+		},
+		Auth: []string{"login"},
+		Folders: Folders{
+			Sent: "Sent", Drafts: "Drafts", Trash: "Trash", Junk: "Spam",
+		},
+	},
+	{
+		Name:  "GMX",
+		Notes: "IMAP access must be enabled in the mail settings",
+		Hosts: []string{"imap.gmx.net", "imap.gmx.de", "imap.gmx.at", "imap.gmx.ch"},
+		Auth:  []string{"login"},
+		Folders: Folders{
+			Sent: "Gesendet", Drafts: "Entwürfe", Trash: "Gelöscht", Junk: "Spamverdacht",
+		},
+	},
+	{
+		Name:  "GMX.com",
+		Notes: "IMAP access must be enabled in the mail settings",
+		Hosts: []string{"imap.gmx.com"},
+		Auth:  []string{"login"},
+		Folders: Folders{
+			Sent: "Sent", Drafts: "Drafts", Trash: "Trash", Junk: "Spam",
+		},
+	},
+	{
+		Name:  "Yandex",
+		Notes: "Needs an app password and IMAP enabled in the mail settings",
+		Hosts: []string{"imap.yandex.com", "imap.yandex.ru"},
+		Auth:  []string{"login"},
+		Folders: Folders{
+			Sent: "Sent", Drafts: "Drafts", Trash: "Trash", Junk: "Spam",
+		},
+	},
+	{
+		Name:  "Mail.ru",
+		Notes: "Needs an app password",
+		Hosts: []string{"imap.mail.ru"},
+		Auth:  []string{"login"},
+		Folders: Folders{
+			Sent: "Отправленные", Drafts: "Черновики", Trash: "Корзина", Junk: "Спам",
+		},
+	},
+}
+
+// Table is a list of provider profiles to look servers up in.
+type Table []Provider
+
+// ErrInvalid is wrapped by the errors of Merge.
+var ErrInvalid = errors.New("invalid provider profile")
+
+// Builtin returns the profiles shipped with imapsync-go.
+func Builtin() Table {
+	return normalize(builtinProviders)
+}
+
+// Merge returns base with the custom profiles applied. A profile named like
+// one of base (case-insensitively) overrides that profile's non-zero
+// fields; any other profile is added and needs hosts. Added profiles win
+// over those of base matching the same host equally well.
+func Merge(base Table, custom []Provider) (Table, error) {
+	merged := normalize(base)
+	var added Table
+	for i, p := range normalize(custom) {
+		if err := p.validate(); err != nil {
+			return nil, fmt.Errorf("%w %d (%s): %w", ErrInvalid, i+1, p.Name, err)
+		}
+		j := slices.IndexFunc(merged, func(b Provider) bool { return strings.EqualFold(b.Name, p.Name) })
+		if j < 0 {
+			if len(p.Hosts) == 0 {
+				return nil, fmt.Errorf("%w %d (%s): hosts are required for a new provider", ErrInvalid, i+1, p.Name)
+			}
+			added = append(added, p)
+			continue
+		}
+		merged[j] = merged[j].override(p)
+	}
+	return append(added, merged...), nil
+}
+
+// normalize returns a copy of list with host patterns and auth mechanisms
+// trimmed and lower-cased.
+func normalize(list []Provider) Table {
+	out := slices.Clone(list)
+	for i := range out {
+		hosts := make([]string, len(out[i].Hosts))
+		for j, h := range out[i].Hosts {
+			hosts[j] = strings.ToLower(strings.TrimSpace(h))
+		}
+		out[i].Hosts = hosts
+		auth := make([]string, len(out[i].Auth))
+		for j, a := range out[i].Auth {
+			auth[j] = strings.ToLower(strings.TrimSpace(a))
+		}
+		out[i].Auth = auth
+	}
+	return out
+}
+
+// validate checks a custom profile on its own.
+func (p Provider) validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return errors.New("name is required")
+	}
+	for _, h := range p.Hosts {
+		suffix, wild := strings.CutPrefix(h, "*.")
+		if suffix == "" || strings.ContainsAny(suffix, "*: /") || (!wild && strings.HasPrefix(h, ".")) {
+			return fmt.Errorf("host %q must be a host name or *.suffix", h)
+		}
+	}
+	for _, a := range p.Auth {
+		if a != "login" && a != "cram-md5" {
+			return fmt.Errorf("auth %q must be login or cram-md5", a)
+		}
+	}
+	if p.DownBPS < 0 || p.UpBPS < 0 || p.DailyDownMB < 0 || p.DailyUpMB < 0 || p.MaxConnections < 0 {
+		return errors.New("limits must not be negative")
+	}
+	return nil
+}
+
+// override returns p with the non-zero fields of o.
+func (p Provider) override(o Provider) Provider {
+	if o.Notes != "" {
+		p.Notes = o.Notes
+	}
+	if len(o.Hosts) > 0 {
+		p.Hosts = o.Hosts
+	}
+	if len(o.Auth) > 0 {
+		p.Auth = o.Auth
+	}
+	for _, f := range []struct {
+		dst *int
+		src int
+	}{
+		{&p.DownBPS, o.DownBPS}, {&p.UpBPS, o.UpBPS},
+		{&p.DailyDownMB, o.DailyDownMB}, {&p.DailyUpMB, o.DailyUpMB},
+		{&p.MaxConnections, o.MaxConnections},
+	} {
+		if f.src != 0 {
+			*f.dst = f.src
+		}
+	}
+	if o.LabelFolders {
+		p.LabelFolders = true
+	}
+	ours, theirs := p.Folders.roles(), o.Folders.roles()
+	for i := range ours {
+		if *theirs[i].folder != "" {
+			*ours[i].folder = *theirs[i].folder
+		}
+	}
+	return p
+}
+
+// Detect returns the profile of serverAddr if its host is known.
+// serverAddr may include a port (host:port). An exact host name beats a
+// wildcard, and a longer wildcard suffix beats a shorter one.
+func (t Table) Detect(serverAddr string) (Provider, bool) {
+	host := serverAddr
+	if i := strings.LastIndex(host, ":"); i >= 0 {
+		// Trim port only if what follows looks numeric — guards against IPv6
+		// literals being mangled, though we don't expect them here.
+		port := host[i+1:]
+		if port != "" && allDigits(port) {
+			host = host[:i]
+		}
+	}
+	host = strings.ToLower(strings.TrimSpace(host))
+	if host == "" {
+		return Provider{}, false
+	}
+
+	best, bestScore := -1, -1
+	for i, p := range t {
+		for _, pattern := range p.Hosts {
+			if score := matchHost(pattern, host); score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+	}
+	if best < 0 {
+		return Provider{}, false
+	}
+	return t[best], true
+}
+
+// matchHost scores how well pattern matches host: -1 for no match, the
+// suffix length for a "*.suffix" wildcard, and more than any wildcard for an
+// exact name. The wildcard needs at least one label before the suffix.
+func matchHost(pattern, host string) int {
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		if strings.HasSuffix(host, "."+suffix) {
+			return len(suffix)
+		}
+		return -1
+	}
+	if pattern == host {
+		return math.MaxInt
+	}
+	return -1
+}
+
+func allDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
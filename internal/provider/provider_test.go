@@ -0,0 +1,116 @@
+package provider
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTable_Detect(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		addr    string
+		want    string
+		matched bool
+	}{
+		{"imap.gmail.com:993", "Gmail", true},
+		{"imap.gmail.com", "Gmail", true},
+		{"IMAP.gmail.com:993", "Gmail", true},
+		{"  imap.gmail.com  :993", "Gmail", true},
+		{"outlook.office365.com:993", "Microsoft 365", true},
+		{"contoso-com.mail.protection.outlook.com:993", "Microsoft 365", true},
+		{"mail.protection.outlook.com:993", "", false},
+		{"imap.mail.me.com:993", "iCloud", true},
+		{"imap.gmx.net:993", "GMX", true},
+		{"imap.gmx.com:993", "GMX.com", true},
+		{"imap.mail.ru:993", "Mail.ru", true},
+		{"imap.example.org:993", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			t.Parallel()
+			got, ok := Builtin().Detect(tt.addr)
+			if ok != tt.matched {
+				t.Fatalf("matched = %v, want %v", ok, tt.matched)
+			}
+			if ok && got.Name != tt.want {
+				t.Errorf("Name = %q, want %q", got.Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	t.Parallel()
+
+	base := Table{
+		{Name: "Gmail", Hosts: []string{"imap.gmail.com"}, DownBPS: 300_000, MaxConnections: 15, LabelFolders: true, Folders: Folders{Sent: "[Gmail]/Sent Mail"}},
+		{Name: "Corp", Hosts: []string{"*.corp.example"}},
+	}
+	got, err := Merge(base, []Provider{
+		{Name: "gmail", MaxConnections: 5, Folders: Folders{Trash: "[Gmail]/Bin"}},
+		{Name: "Mine", Hosts: []string{"IMAP.Mine.example"}, Auth: []string{"CRAM-MD5"}},
+		{Name: "Nearer", Hosts: []string{"*.eu.corp.example"}},
+		{Name: "Workspace", Hosts: []string{"imap.workspace.example"}, LabelFolders: true},
+	})
+	if err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+
+	gmail, _ := got.Detect("imap.gmail.com:993")
+	if gmail.MaxConnections != 5 || gmail.DownBPS != 300_000 {
+		t.Errorf("Gmail limits = %d conns, %d B/s; want the override and the kept built-in", gmail.MaxConnections, gmail.DownBPS)
+	}
+	if !gmail.LabelFolders {
+		t.Error("Gmail lost label_folders to an override that does not set it")
+	}
+	if p, _ := got.Detect("imap.workspace.example"); !p.LabelFolders {
+		t.Errorf("added provider = %+v; want label_folders kept", p)
+	}
+	if gmail.Folders.Sent != "[Gmail]/Sent Mail" || gmail.Folders.Trash != "[Gmail]/Bin" {
+		t.Errorf("Gmail folders = %+v; want Sent kept and Trash overridden", gmail.Folders)
+	}
+	if p, ok := got.Detect("imap.mine.example:143"); !ok || p.Name != "Mine" || p.Auth[0] != "cram-md5" {
+		t.Errorf("detect added provider = %+v, %v", p, ok)
+	}
+	if p, _ := got.Detect("imap.eu.corp.example:993"); p.Name != "Nearer" {
+		t.Errorf("detect = %q; want the longer wildcard to win", p.Name)
+	}
+	if p, _ := got.Detect("imap.us.corp.example:993"); p.Name != "Corp" {
+		t.Errorf("detect = %q; want Corp", p.Name)
+	}
+
+	for _, bad := range []Provider{
+		{Hosts: []string{"x.example"}},
+		{Name: "NoHosts"},
+		{Name: "BadHost", Hosts: []string{"*.*.example"}},
+		{Name: "BadAuth", Hosts: []string{"x.example"}, Auth: []string{"xoauth2"}},
+		{Name: "Gmail", UpBPS: -1},
+	} {
+		if _, err := Merge(base, []Provider{bad}); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Merge(%+v) error = %v; want ErrInvalid", bad, err)
+		}
+	}
+}
+
+func TestFolders_Translate(t *testing.T) {
+	t.Parallel()
+
+	gmail, _ := Builtin().Detect("imap.gmail.com")
+	outlook, _ := Builtin().Detect("outlook.office365.com")
+	tests := []struct {
+		folder string
+		want   string
+	}{
+		{"[Gmail]/Sent Mail", "Sent Items"},
+		{"[Gmail]/Trash", "Deleted Items"},
+		{"[Gmail]/All Mail", "[Gmail]/All Mail"}, // no All Mail on Outlook
+		{"Work", "Work"},
+	}
+	for _, tt := range tests {
+		if got := gmail.Folders.Translate(tt.folder, outlook.Folders); got != tt.want {
+			t.Errorf("Translate(%q) = %q; want %q", tt.folder, got, tt.want)
+		}
+	}
+}
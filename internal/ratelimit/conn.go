@@ -1,12 +1,16 @@
 // Package ratelimit provides a net.Conn wrapper that throttles read and write
-// throughput using a token bucket. It is meant to be applied at the dial level
-// so the underlying protocol library (go-imap) is unaware of throttling.
+// throughput using a token bucket and counts the bytes that pass. It is meant
+// to be applied at the dial level so the underlying protocol library
+// (go-imap) is unaware of throttling.
 package ratelimit
 
 import (
 	"context"
 	"net"
+	"sync/atomic"
+	"time"
 
+	"github.com/greeddj/imapsync-go/internal/metrics"
 	"golang.org/x/time/rate"
 )
 
@@ -16,19 +20,36 @@ import (
 const minBurst = 64 * 1024
 
 // Conn wraps a net.Conn and applies token-bucket rate limiting to Read and Write.
-// Either limiter may be nil — that direction is then unlimited.
+// Either limiter may be nil — that direction is then unlimited. Bytes that
+// pass are added to the Conn's Counter, if it has one.
 //
 // Conn is safe for concurrent use by multiple goroutines because *rate.Limiter
 // is itself concurrency-safe and net.Conn implementations are required to be
 // safe for concurrent reads and writes.
 type Conn struct {
 	net.Conn
-	read   *rate.Limiter
-	write  *rate.Limiter
-	ctx    context.Context
-	cancel context.CancelFunc
+	read    *rate.Limiter
+	write   *rate.Limiter
+	counter *Counter
+	ctx     context.Context
+	cancel  context.CancelFunc
 }
 
+// Counter totals the bytes read and written through every Conn that shares
+// it. Sharing one Counter across reconnects keeps a running total for a
+// client however often its connection is replaced. The zero value is ready
+// to use.
+type Counter struct {
+	read    atomic.Int64
+	written atomic.Int64
+}
+
+// Read returns the bytes read so far.
+func (c *Counter) Read() int64 { return c.read.Load() }
+
+// Written returns the bytes written so far.
+func (c *Counter) Written() int64 { return c.written.Load() }
+
 // NewLimiter constructs a *rate.Limiter for the given bytes-per-second budget.
 // Returns nil when bps <= 0, signaling "unlimited" to callers.
 func NewLimiter(bps int) *rate.Limiter {
@@ -39,10 +60,10 @@ func NewLimiter(bps int) *rate.Limiter {
 	return rate.NewLimiter(rate.Limit(bps), burst)
 }
 
-// New wraps c with the provided limiters. Either limiter may be nil.
-func New(c net.Conn, read, write *rate.Limiter) *Conn {
+// New wraps c with the provided limiters and counter. Any of them may be nil.
+func New(c net.Conn, read, write *rate.Limiter, counter *Counter) *Conn {
 	ctx, cancel := context.WithCancel(context.Background())
-	return &Conn{Conn: c, read: read, write: write, ctx: ctx, cancel: cancel}
+	return &Conn{Conn: c, read: read, write: write, counter: counter, ctx: ctx, cancel: cancel}
 }
 
 // Close cancels any in-flight WaitN call and closes the underlying connection.
@@ -56,8 +77,11 @@ func (c *Conn) Close() error {
 // bytes are already in flight: we just delay the next read.
 func (c *Conn) Read(p []byte) (int, error) {
 	n, err := c.Conn.Read(p)
+	if n > 0 && c.counter != nil {
+		c.counter.read.Add(int64(n))
+	}
 	if n > 0 && c.read != nil {
-		if werr := waitN(c.ctx, c.read, n); werr != nil && err == nil {
+		if werr := waitN(c.ctx, c.read, n, "read"); werr != nil && err == nil {
 			err = werr
 		}
 	}
@@ -68,17 +92,27 @@ func (c *Conn) Read(p []byte) (int, error) {
 // burst that could trigger server-side rate limits.
 func (c *Conn) Write(p []byte) (int, error) {
 	if c.write != nil {
-		if err := waitN(c.ctx, c.write, len(p)); err != nil {
+		if err := waitN(c.ctx, c.write, len(p), "write"); err != nil {
 			return 0, err
 		}
 	}
-	return c.Conn.Write(p)
+	n, err := c.Conn.Write(p)
+	if n > 0 && c.counter != nil {
+		c.counter.written.Add(int64(n))
+	}
+	return n, err
 }
 
 // waitN blocks until n tokens are available, splitting requests larger than
 // the limiter's burst capacity. lim.WaitN returns an error when n > burst, so
-// large IMAP literals must be chunked.
-func waitN(ctx context.Context, lim *rate.Limiter, n int) error {
+// large IMAP literals must be chunked. The time waited and the tokens left
+// are reported to the metrics under direction.
+func waitN(ctx context.Context, lim *rate.Limiter, n int, direction string) error {
+	start := time.Now()
+	defer func() {
+		metrics.RateLimitWait.Add(time.Since(start).Seconds(), direction)
+		metrics.RateLimitTokens.Set(lim.Tokens(), direction)
+	}()
 	burst := lim.Burst()
 	for n > 0 {
 		chunk := min(n, burst)
@@ -70,7 +70,7 @@ func TestConnWriteThrottles(t *testing.T) {
 	// 1 KB/s throttle, burst = minBurst (64 KB).
 	lim := NewLimiter(1024)
 	fake := &fakeConn{}
-	c := New(fake, nil, lim)
+	c := New(fake, nil, lim, nil)
 
 	// Drain the bucket first so subsequent writes must wait.
 	if err := lim.WaitN(context.Background(), lim.Burst()); err != nil {
@@ -95,7 +95,7 @@ func TestConnCloseCancelsWait(t *testing.T) {
 	// Tight bucket: 1 byte/s, large request will block.
 	lim := NewLimiter(1)
 	fake := &fakeConn{}
-	c := New(fake, nil, lim)
+	c := New(fake, nil, lim, nil)
 
 	// Drain so next Write blocks.
 	_ = lim.WaitN(context.Background(), lim.Burst())
@@ -125,7 +125,7 @@ func TestConnReadPassthroughWhenUnlimited(t *testing.T) {
 	t.Parallel()
 
 	fake := &fakeConn{readBuf: []byte("hello")}
-	c := New(fake, nil, nil)
+	c := New(fake, nil, nil, nil)
 
 	buf := make([]byte, 5)
 	n, err := c.Read(buf)
@@ -137,12 +137,31 @@ func TestConnReadPassthroughWhenUnlimited(t *testing.T) {
 	}
 }
 
+// TestConnCounts asserts that reads and writes add to the Counter, and that
+// a Counter shared by two Conns keeps one total.
+func TestConnCounts(t *testing.T) {
+	t.Parallel()
+
+	var ctr Counter
+	a := New(&fakeConn{readBuf: []byte("hello")}, nil, nil, &ctr)
+	b := New(&fakeConn{readBuf: []byte("hi")}, nil, NewLimiter(1<<20), &ctr)
+
+	buf := make([]byte, 16)
+	_, _ = a.Read(buf)
+	_, _ = b.Read(buf)
+	_, _ = a.Write([]byte("abc"))
+	_, _ = b.Write([]byte("de"))
+	if ctr.Read() != 7 || ctr.Written() != 5 {
+		t.Errorf("counter = %d read, %d written; want 7 and 5", ctr.Read(), ctr.Written())
+	}
+}
+
 // Sanity: WaitN with chunk > burst must succeed via splitting.
 func TestWaitNChunksLargerThanBurst(t *testing.T) {
 	t.Parallel()
 
 	lim := rate.NewLimiter(1024*1024, 64*1024) // 1 MB/s, 64 KB burst
-	if err := waitN(context.Background(), lim, 256*1024); err != nil {
+	if err := waitN(context.Background(), lim, 256*1024, "read"); err != nil {
 		t.Fatalf("waitN: %v", err)
 	}
 }
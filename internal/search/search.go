@@ -0,0 +1,395 @@
+// Package search parses the IMAP SEARCH expressions users write in config
+// files into go-imap search criteria.
+//
+// The syntax is RFC 3501 §6.4.4 search keys with a few conveniences: keys
+// are case-insensitive, juxtaposed keys (or an explicit AND) must all match,
+// OR may be written infix ("FROM a OR FROM b") as well as in the IMAP prefix
+// form ("OR FROM a FROM b"), and parentheses group. NOT binds tightest, then
+// AND, then OR. Dates are YYYY-MM-DD or the IMAP form 2-Jan-2006.
+package search
+
+import (
+	"errors"
+	"fmt"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-imap"
+)
+
+// flagKeys maps the flag search keys to the system flag they test. The UN-
+// prefixed form of each key tests for the flag's absence.
+var flagKeys = map[string]string{
+	"ANSWERED": imap.AnsweredFlag,
+	"DELETED":  imap.DeletedFlag,
+	"DRAFT":    imap.DraftFlag,
+	"FLAGGED":  imap.FlaggedFlag,
+	"SEEN":     imap.SeenFlag,
+}
+
+// headerKeys are the search keys that match one header field by substring.
+var headerKeys = map[string]string{
+	"FROM":    "From",
+	"TO":      "To",
+	"CC":      "Cc",
+	"BCC":     "Bcc",
+	"SUBJECT": "Subject",
+}
+
+// Parse turns expr into search criteria. An empty expression is an error:
+// callers treat "no filter" as the absence of an expression, not as ALL.
+func Parse(expr string) (*imap.SearchCriteria, error) {
+	toks, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	if len(toks) == 0 {
+		return nil, errors.New("empty search expression")
+	}
+	p := &parser{toks: toks}
+	c, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t, ok := p.peek(); ok {
+		return nil, fmt.Errorf("unexpected %q at position %d", t.text, t.pos)
+	}
+	return c, nil
+}
+
+// And returns criteria matching messages that match both a and b. Neither
+// argument is modified.
+func And(a, b *imap.SearchCriteria) *imap.SearchCriteria {
+	out := clone(a)
+	out.Since = later(out.Since, b.Since)
+	out.SentSince = later(out.SentSince, b.SentSince)
+	out.Before = earlier(out.Before, b.Before)
+	out.SentBefore = earlier(out.SentBefore, b.SentBefore)
+	for k, vs := range b.Header {
+		for _, v := range vs {
+			out.Header.Add(k, v)
+		}
+	}
+	out.Body = append(out.Body, b.Body...)
+	out.Text = append(out.Text, b.Text...)
+	out.WithFlags = append(out.WithFlags, b.WithFlags...)
+	out.WithoutFlags = append(out.WithoutFlags, b.WithoutFlags...)
+	out.Larger = max(out.Larger, b.Larger)
+	if b.Smaller > 0 && (out.Smaller == 0 || b.Smaller < out.Smaller) {
+		out.Smaller = b.Smaller
+	}
+	out.Not = append(out.Not, b.Not...)
+	out.Or = append(out.Or, b.Or...)
+	if b.SeqNum != nil || b.Uid != nil {
+		// Sequence sets do not intersect field-wise; NOT NOT keeps b intact.
+		out.Not = append(out.Not, &imap.SearchCriteria{Not: []*imap.SearchCriteria{b}})
+	}
+	return out
+}
+
+// clone copies c deeply enough that And can append to the result.
+func clone(c *imap.SearchCriteria) *imap.SearchCriteria {
+	out := *c
+	out.Header = make(textproto.MIMEHeader, len(c.Header))
+	for k, vs := range c.Header {
+		out.Header[k] = append([]string(nil), vs...)
+	}
+	out.Body = append([]string(nil), c.Body...)
+	out.Text = append([]string(nil), c.Text...)
+	out.WithFlags = append([]string(nil), c.WithFlags...)
+	out.WithoutFlags = append([]string(nil), c.WithoutFlags...)
+	out.Not = append([]*imap.SearchCriteria(nil), c.Not...)
+	out.Or = append([][2]*imap.SearchCriteria(nil), c.Or...)
+	return &out
+}
+
+func later(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+func earlier(a, b time.Time) time.Time {
+	if a.IsZero() || (!b.IsZero() && b.Before(a)) {
+		return b
+	}
+	return a
+}
+
+// token is one lexical item of an expression. pos is its 1-based byte offset,
+// for error messages.
+type token struct {
+	text   string
+	pos    int
+	quoted bool
+}
+
+// keyword reports whether t is the unquoted keyword kw (case-insensitive).
+func (t token) keyword(kw string) bool {
+	return !t.quoted && strings.EqualFold(t.text, kw)
+}
+
+// tokenize splits expr into parentheses, quoted strings and atoms.
+func tokenize(expr string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(expr); {
+		switch ch := expr[i]; {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			i++
+		case ch == '(' || ch == ')':
+			toks = append(toks, token{text: string(ch), pos: i + 1})
+			i++
+		case ch == '"':
+			start := i
+			var sb strings.Builder
+			i++
+			for ; i < len(expr) && expr[i] != '"'; i++ {
+				if expr[i] == '\\' && i+1 < len(expr) {
+					i++
+				}
+				sb.WriteByte(expr[i])
+			}
+			if i >= len(expr) {
+				return nil, fmt.Errorf("unterminated quoted string at position %d", start+1)
+			}
+			i++
+			toks = append(toks, token{text: sb.String(), pos: start + 1, quoted: true})
+		default:
+			start := i
+			for i < len(expr) && !strings.ContainsRune(" \t\r\n()\"", rune(expr[i])) {
+				i++
+			}
+			toks = append(toks, token{text: expr[start:i], pos: start + 1})
+		}
+	}
+	return toks, nil
+}
+
+// parser is a recursive-descent parser over a token slice.
+type parser struct {
+	toks []token
+	i    int
+}
+
+func (p *parser) peek() (token, bool) {
+	if p.i >= len(p.toks) {
+		return token{}, false
+	}
+	return p.toks[p.i], true
+}
+
+func (p *parser) next(what string) (token, error) {
+	t, ok := p.peek()
+	if !ok {
+		return token{}, fmt.Errorf("expected %s, got end of expression", what)
+	}
+	p.i++
+	return t, nil
+}
+
+// parseOr parses and-expressions joined by infix OR.
+func (p *parser) parseOr() (*imap.SearchCriteria, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t, ok := p.peek()
+		if !ok || !t.keyword("OR") {
+			return left, nil
+		}
+		p.i++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		c := imap.NewSearchCriteria()
+		c.Or = [][2]*imap.SearchCriteria{{left, right}}
+		left = c
+	}
+}
+
+// parseAnd parses a run of unary expressions, optionally separated by AND.
+// It stops before infix OR and before a closing parenthesis.
+func (p *parser) parseAnd() (*imap.SearchCriteria, error) {
+	out, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t, ok := p.peek()
+		if !ok || t.keyword("OR") || (!t.quoted && t.text == ")") {
+			return out, nil
+		}
+		if t.keyword("AND") {
+			p.i++
+		}
+		c, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		out = And(out, c)
+	}
+}
+
+// parseUnary parses NOT, prefix OR, a parenthesized group or one search key.
+func (p *parser) parseUnary() (*imap.SearchCriteria, error) {
+	t, err := p.next("a search key")
+	if err != nil {
+		return nil, err
+	}
+	if !t.quoted && t.text == "(" {
+		c, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if cl, err := p.next(`")"`); err != nil {
+			return nil, err
+		} else if cl.quoted || cl.text != ")" {
+			return nil, fmt.Errorf(`expected ")" at position %d, got %q`, cl.pos, cl.text)
+		}
+		return c, nil
+	}
+	if t.quoted || t.text == ")" {
+		return nil, fmt.Errorf("expected a search key at position %d, got %q", t.pos, t.text)
+	}
+
+	key := strings.ToUpper(t.text)
+	c := imap.NewSearchCriteria()
+	switch key {
+	case "ALL":
+		return c, nil
+	case "NOT":
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		c.Not = []*imap.SearchCriteria{inner}
+		return c, nil
+	case "OR":
+		a, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		b, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		c.Or = [][2]*imap.SearchCriteria{{a, b}}
+		return c, nil
+	case "HEADER":
+		name, err := p.value("a header name after HEADER")
+		if err != nil {
+			return nil, err
+		}
+		v, err := p.value("a value after HEADER " + name)
+		if err != nil {
+			return nil, err
+		}
+		c.Header.Add(name, v)
+		return c, nil
+	case "BODY", "TEXT":
+		v, err := p.value("a string after " + key)
+		if err != nil {
+			return nil, err
+		}
+		if key == "BODY" {
+			c.Body = []string{v}
+		} else {
+			c.Text = []string{v}
+		}
+		return c, nil
+	case "KEYWORD", "UNKEYWORD":
+		v, err := p.value("a flag after " + key)
+		if err != nil {
+			return nil, err
+		}
+		if key == "KEYWORD" {
+			c.WithFlags = []string{v}
+		} else {
+			c.WithoutFlags = []string{v}
+		}
+		return c, nil
+	case "LARGER", "SMALLER":
+		v, err := p.value("a size after " + key)
+		if err != nil {
+			return nil, err
+		}
+		n, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid size %q", key, v)
+		}
+		if key == "LARGER" {
+			c.Larger = uint32(n)
+		} else {
+			c.Smaller = uint32(n)
+		}
+		return c, nil
+	case "SINCE", "BEFORE", "ON", "SENTSINCE", "SENTBEFORE", "SENTON":
+		v, err := p.value("a date after " + key)
+		if err != nil {
+			return nil, err
+		}
+		d, err := parseDate(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		switch key {
+		case "SINCE":
+			c.Since = d
+		case "BEFORE":
+			c.Before = d
+		case "ON":
+			c.Since, c.Before = d, d.AddDate(0, 0, 1)
+		case "SENTSINCE":
+			c.SentSince = d
+		case "SENTBEFORE":
+			c.SentBefore = d
+		case "SENTON":
+			c.SentSince, c.SentBefore = d, d.AddDate(0, 0, 1)
+		}
+		return c, nil
+	}
+	if name, ok := headerKeys[key]; ok {
+		v, err := p.value("a string after " + key)
+		if err != nil {
+			return nil, err
+		}
+		c.Header.Add(name, v)
+		return c, nil
+	}
+	if flag, ok := flagKeys[key]; ok {
+		c.WithFlags = []string{flag}
+		return c, nil
+	}
+	if flag, ok := flagKeys[strings.TrimPrefix(key, "UN")]; ok && strings.HasPrefix(key, "UN") {
+		c.WithoutFlags = []string{flag}
+		return c, nil
+	}
+	return nil, fmt.Errorf("unknown search key %q at position %d", t.text, t.pos)
+}
+
+// value reads the argument of a search key: a quoted string or an atom.
+func (p *parser) value(what string) (string, error) {
+	t, err := p.next(what)
+	if err != nil {
+		return "", err
+	}
+	if !t.quoted && (t.text == "(" || t.text == ")") {
+		return "", fmt.Errorf("expected %s at position %d, got %q", what, t.pos, t.text)
+	}
+	return t.text, nil
+}
+
+// parseDate accepts YYYY-MM-DD and the IMAP date form 2-Jan-2006.
+func parseDate(s string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02", "2-Jan-2006"} {
+		if d, err := time.Parse(layout, s); err == nil {
+			return d, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q, want YYYY-MM-DD", s)
+}
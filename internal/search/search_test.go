@@ -0,0 +1,125 @@
+package search
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-imap"
+)
+
+func TestParse_headerAndFlagKeys(t *testing.T) {
+	t.Parallel()
+
+	c, err := Parse(`from "@example.com" UNSEEN header X-Spam-Flag YES keyword $Work`)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if got := c.Header.Get("From"); got != "@example.com" {
+		t.Errorf("From = %q", got)
+	}
+	if got := c.Header.Get("X-Spam-Flag"); got != "YES" {
+		t.Errorf("X-Spam-Flag = %q", got)
+	}
+	if len(c.WithoutFlags) != 1 || c.WithoutFlags[0] != imap.SeenFlag {
+		t.Errorf("WithoutFlags = %v", c.WithoutFlags)
+	}
+	if len(c.WithFlags) != 1 || c.WithFlags[0] != "$Work" {
+		t.Errorf("WithFlags = %v", c.WithFlags)
+	}
+}
+
+func TestParse_precedence(t *testing.T) {
+	t.Parallel()
+
+	// NOT binds tighter than AND, AND tighter than OR.
+	c, err := Parse(`FLAGGED AND NOT DELETED OR TO boss`)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(c.Or) != 1 {
+		t.Fatalf("top level is not OR: %+v", c)
+	}
+	left, right := c.Or[0][0], c.Or[0][1]
+	if len(left.WithFlags) != 1 || len(left.Not) != 1 || left.Not[0].WithFlags[0] != imap.DeletedFlag {
+		t.Errorf("left = %+v", left)
+	}
+	if right.Header.Get("To") != "boss" {
+		t.Errorf("right = %+v", right)
+	}
+}
+
+func TestParse_prefixOrAndGrouping(t *testing.T) {
+	t.Parallel()
+
+	prefix, err := Parse(`OR FROM a FROM b`)
+	if err != nil {
+		t.Fatalf("Parse prefix: %v", err)
+	}
+	grouped, err := Parse(`(FROM a OR FROM b)`)
+	if err != nil {
+		t.Fatalf("Parse grouped: %v", err)
+	}
+	for _, c := range []*imap.SearchCriteria{prefix, grouped} {
+		if len(c.Or) != 1 || c.Or[0][0].Header.Get("From") != "a" || c.Or[0][1].Header.Get("From") != "b" {
+			t.Errorf("criteria = %+v", c)
+		}
+	}
+}
+
+func TestParse_dates(t *testing.T) {
+	t.Parallel()
+
+	c, err := Parse(`ON 2024-02-29 SENTSINCE 1-Jan-2020`)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	day := time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)
+	if !c.Since.Equal(day) || !c.Before.Equal(day.AddDate(0, 0, 1)) {
+		t.Errorf("ON = [%v, %v)", c.Since, c.Before)
+	}
+	if !c.SentSince.Equal(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("SentSince = %v", c.SentSince)
+	}
+}
+
+func TestParse_errors(t *testing.T) {
+	t.Parallel()
+
+	for expr, want := range map[string]string{
+		"":                   "empty",
+		"FROM":               "expected a string after FROM",
+		"BOGUS x":            `unknown search key "BOGUS"`,
+		"(SEEN":              `expected ")"`,
+		"SEEN )":             `unexpected ")"`,
+		`SUBJECT "open`:      "unterminated",
+		"LARGER lots":        "invalid size",
+		"SINCE yesterday":    "invalid date",
+		`"SEEN"`:             "expected a search key",
+		"NOT":                "expected a search key",
+		"HEADER X-Spam-Flag": "a value after HEADER",
+	} {
+		_, err := Parse(expr)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Parse(%q) = %v, want error containing %q", expr, err, want)
+		}
+	}
+}
+
+func TestAnd_mergesWithoutMutating(t *testing.T) {
+	t.Parallel()
+
+	a, _ := Parse(`FROM a SINCE 2020-01-01 SMALLER 100`)
+	b, _ := Parse(`FROM b SINCE 2021-01-01 SMALLER 50 NOT SEEN`)
+	c := And(a, b)
+
+	if got := c.Header.Values("From"); len(got) != 2 {
+		t.Errorf("From = %v, want both", got)
+	}
+	if c.Since.Year() != 2021 || c.Smaller != 50 || len(c.Not) != 1 {
+		t.Errorf("merged = %+v", c)
+	}
+	if len(a.Header.Values("From")) != 1 || a.Since.Year() != 2020 || len(a.Not) != 0 {
+		t.Errorf("And modified its first argument: %+v", a)
+	}
+}
@@ -0,0 +1,87 @@
+// Package secret keeps the passwords of a run and scrubs them from anything
+// written for people to read: logs, traces, reports and error messages.
+package secret
+
+import (
+	"encoding/json"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces a secret in output.
+const Redacted = "<redacted>"
+
+// minLen is the shortest secret Register accepts. Scrubbing every "a" or
+// "12" from a log would make it unreadable and protect nothing.
+const minLen = 3
+
+// registry holds every registered secret and the replacer built from them.
+var registry struct {
+	replacer *strings.Replacer
+	secrets  []string
+	mu       sync.RWMutex
+}
+
+// Register adds s to the secrets scrubbed by Redact and Writer, along with
+// its JSON-escaped form, so a password with a quote or backslash is caught
+// in JSON logs too.
+func Register(s string) {
+	if len(s) < minLen {
+		return
+	}
+	forms := []string{s}
+	if b, err := json.Marshal(s); err == nil {
+		if esc := string(b[1 : len(b)-1]); esc != s {
+			forms = append(forms, esc)
+		}
+	}
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	for _, f := range forms {
+		if !slices.Contains(registry.secrets, f) {
+			registry.secrets = append(registry.secrets, f)
+		}
+	}
+	// Longest first, so a secret that contains another is replaced whole.
+	slices.SortFunc(registry.secrets, func(a, b string) int { return len(b) - len(a) })
+	pairs := make([]string, 0, 2*len(registry.secrets))
+	for _, s := range registry.secrets {
+		pairs = append(pairs, s, Redacted)
+	}
+	registry.replacer = strings.NewReplacer(pairs...)
+}
+
+// Redact returns s with every registered secret replaced by Redacted.
+func Redact(s string) string {
+	registry.mu.RLock()
+	r := registry.replacer
+	registry.mu.RUnlock()
+	if r == nil {
+		return s
+	}
+	return r.Replace(s)
+}
+
+// Writer scrubs registered secrets from everything written to it. Each
+// Write is redacted on its own, which fits writers fed one record or line
+// per call: log handlers, JSON encoders, the IMAP trace.
+type Writer struct {
+	w io.Writer
+}
+
+// NewWriter returns a Writer that redacts into w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write implements io.Writer. It reports len(p) on success even when the
+// redacted record differs in length.
+func (w *Writer) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.w, Redact(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,45 @@
+package secret
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// Test_Redact_registeredSecrets asserts that registered secrets are
+// scrubbed, including their JSON-escaped form, and short ones are ignored.
+func Test_Redact_registeredSecrets(t *testing.T) {
+	t.Parallel()
+
+	Register(`pa"ss\word-redact`)
+	Register("ab")
+
+	quoted, _ := json.Marshal(map[string]string{"error": `bad pa"ss\word-redact`})
+	for _, in := range []string{`login pa"ss\word-redact failed`, string(quoted)} {
+		if got := Redact(in); strings.Contains(got, "word-redact") || !strings.Contains(got, Redacted) {
+			t.Errorf("Redact(%q) = %q", in, got)
+		}
+	}
+	if got := Redact("abc ab"); got != "abc ab" {
+		t.Errorf("short secret redacted: %q", got)
+	}
+}
+
+// Test_Writer_redacts asserts that Writer scrubs each write and reports the
+// caller's length.
+func Test_Writer_redacts(t *testing.T) {
+	t.Parallel()
+
+	Register("hunter2-writer")
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	in := []byte("user=a pass=hunter2-writer\n")
+	n, err := w.Write(in)
+	if err != nil || n != len(in) {
+		t.Fatalf("Write = %d, %v; want %d, nil", n, err, len(in))
+	}
+	if got, want := buf.String(), "user=a pass="+Redacted+"\n"; got != want {
+		t.Errorf("wrote %q; want %q", got, want)
+	}
+}
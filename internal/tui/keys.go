@@ -0,0 +1,103 @@
+package tui
+
+import "unicode/utf8"
+
+// KeyCode identifies a key press that is not a printable rune.
+type KeyCode int
+
+// Keys that ParseKeys recognizes. KeyRune is a printable character, space
+// included, held in Key.Rune.
+const (
+	KeyRune KeyCode = iota
+	KeyUp
+	KeyDown
+	KeyLeft
+	KeyRight
+	KeyHome
+	KeyEnd
+	KeyPageUp
+	KeyPageDown
+	KeyEnter
+	KeyTab
+	KeyBackspace
+	KeyEscape
+	KeyCtrlC
+	KeyCtrlU
+)
+
+// Key is one key press.
+type Key struct {
+	Rune rune
+	Code KeyCode
+}
+
+// csiKeys maps the final part of "ESC [" and "ESC O" sequences to keys.
+var csiKeys = map[string]KeyCode{
+	"A":  KeyUp,
+	"B":  KeyDown,
+	"C":  KeyRight,
+	"D":  KeyLeft,
+	"H":  KeyHome,
+	"F":  KeyEnd,
+	"1~": KeyHome,
+	"4~": KeyEnd,
+	"7~": KeyHome,
+	"8~": KeyEnd,
+	"5~": KeyPageUp,
+	"6~": KeyPageDown,
+}
+
+// ParseKeys decodes the bytes of one read from a raw-mode terminal. A read
+// holds whole escape sequences in practice, so an ESC that starts no known
+// sequence is taken as the Escape key, and unknown sequences are dropped.
+func ParseKeys(b []byte) []Key {
+	var keys []Key
+	for len(b) > 0 {
+		switch c := b[0]; {
+		case c == 0x1b:
+			n, code, ok := parseEscape(b)
+			b = b[n:]
+			if ok {
+				keys = append(keys, Key{Code: code})
+			}
+			continue
+		case c == '\r' || c == '\n':
+			keys = append(keys, Key{Code: KeyEnter})
+		case c == '\t':
+			keys = append(keys, Key{Code: KeyTab})
+		case c == 0x7f || c == 0x08:
+			keys = append(keys, Key{Code: KeyBackspace})
+		case c == 0x03:
+			keys = append(keys, Key{Code: KeyCtrlC})
+		case c == 0x15:
+			keys = append(keys, Key{Code: KeyCtrlU})
+		case c < 0x20:
+			// Other control characters have no use here.
+		default:
+			r, size := utf8.DecodeRune(b)
+			b = b[size:]
+			if r != utf8.RuneError {
+				keys = append(keys, Key{Code: KeyRune, Rune: r})
+			}
+			continue
+		}
+		b = b[1:]
+	}
+	return keys
+}
+
+// parseEscape decodes the escape sequence at the start of b and returns the
+// bytes it spans.
+func parseEscape(b []byte) (n int, code KeyCode, ok bool) {
+	if len(b) < 2 || (b[1] != '[' && b[1] != 'O') {
+		return 1, KeyEscape, true
+	}
+	// Parameters and intermediates run up to a final byte in 0x40-0x7e.
+	for i := 2; i < len(b); i++ {
+		if b[i] >= 0x40 && b[i] <= 0x7e {
+			code, ok = csiKeys[string(b[2:i+1])]
+			return i + 1, code, ok
+		}
+	}
+	return len(b), 0, false
+}
@@ -0,0 +1,32 @@
+package tui
+
+import (
+	"slices"
+	"testing"
+)
+
+// TestParseKeys covers runes, control keys and escape sequences, including
+// several keys in one read and sequences it does not know.
+func TestParseKeys(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		in   string
+		want []Key
+	}{
+		{name: "runes", in: "aé ", want: []Key{{Rune: 'a'}, {Rune: 'é'}, {Rune: ' '}}},
+		{name: "controls", in: "\r\t\x7f\x03\x15", want: []Key{{Code: KeyEnter}, {Code: KeyTab}, {Code: KeyBackspace}, {Code: KeyCtrlC}, {Code: KeyCtrlU}}},
+		{name: "arrows", in: "\x1b[A\x1b[B\x1bOC\x1b[D", want: []Key{{Code: KeyUp}, {Code: KeyDown}, {Code: KeyRight}, {Code: KeyLeft}}},
+		{name: "paging", in: "\x1b[5~\x1b[6~\x1b[H\x1b[4~", want: []Key{{Code: KeyPageUp}, {Code: KeyPageDown}, {Code: KeyHome}, {Code: KeyEnd}}},
+		{name: "escape", in: "\x1b", want: []Key{{Code: KeyEscape}}},
+		{name: "escape then rune", in: "\x1bq", want: []Key{{Code: KeyEscape}, {Rune: 'q'}}},
+		{name: "unknown sequence", in: "\x1b[1;5Ax", want: []Key{{Rune: 'x'}}},
+		{name: "cut sequence", in: "\x1b[1", want: nil},
+	}
+	for _, tt := range tests {
+		if got := ParseKeys([]byte(tt.in)); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: ParseKeys(%q) = %v; want %v", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
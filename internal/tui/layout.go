@@ -0,0 +1,81 @@
+package tui
+
+import (
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/text"
+)
+
+// snipIndicator ends lines cut to fit.
+const snipIndicator = "…"
+
+// Fit cuts s to width columns, or pads it with spaces to width. Escape
+// sequences take no columns.
+func Fit(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	return text.Pad(text.Snip(s, width, snipIndicator), width, ' ')
+}
+
+// Box frames body in a width by height box with title in its top border.
+// Body lines past the box are dropped; missing ones are left blank.
+func Box(title string, body []string, width, height int) []string {
+	if width < 4 || height < 2 {
+		return nil
+	}
+	inner := width - 2
+	top := "┌"
+	if title != "" {
+		top += text.Snip("─ "+title+" ", inner, snipIndicator)
+	}
+	top += strings.Repeat("─", max(0, inner-text.StringWidthWithoutEscSequences(top)+1)) + "┐"
+
+	lines := make([]string, 0, height)
+	lines = append(lines, top)
+	for i := range height - 2 {
+		var line string
+		if i < len(body) {
+			line = body[i]
+		}
+		lines = append(lines, "│"+Fit(line, inner)+"\x1b[0m│")
+	}
+	return append(lines, "└"+strings.Repeat("─", inner)+"┘")
+}
+
+// SideBySide joins blocks of lines into one block, each padded to its
+// width. Blocks shorter than the tallest are padded with blank lines.
+func SideBySide(blocks [][]string, widths []int) []string {
+	height := 0
+	for _, b := range blocks {
+		height = max(height, len(b))
+	}
+	lines := make([]string, height)
+	for i := range lines {
+		var sb strings.Builder
+		for j, b := range blocks {
+			var line string
+			if i < len(b) {
+				line = b[i]
+			}
+			sb.WriteString(Fit(line, widths[j]))
+		}
+		lines[i] = sb.String()
+	}
+	return lines
+}
+
+// Window returns the range [start, end) of n rows to show in height rows so
+// that row cursor is visible, scrolling no more than needed from the
+// previous start.
+func Window(n, cursor, start, height int) (int, int) {
+	if height <= 0 || n == 0 {
+		return 0, 0
+	}
+	start = min(start, cursor, max(0, n-height))
+	if cursor >= start+height {
+		start = cursor - height + 1
+	}
+	start = max(0, start)
+	return start, min(n, start+height)
+}
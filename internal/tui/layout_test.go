@@ -0,0 +1,88 @@
+package tui
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/jedib0t/go-pretty/v6/text"
+)
+
+// TestFit asserts that lines are cut or padded to the width, ignoring
+// escape sequences.
+func TestFit(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		in    string
+		want  string
+		width int
+	}{
+		{in: "abc", want: "abc  ", width: 5},
+		{in: "abcdef", want: "abc…", width: 4},
+		{in: "\x1b[32mok\x1b[0m", want: "\x1b[32mok\x1b[0m ", width: 3},
+		{in: "abc", want: "", width: 0},
+	} {
+		if got := Fit(tt.in, tt.width); got != tt.want {
+			t.Errorf("Fit(%q, %d) = %q; want %q", tt.in, tt.width, got, tt.want)
+		}
+	}
+}
+
+// TestBox asserts the frame's size and title, and that every line spans
+// the full width.
+func TestBox(t *testing.T) {
+	t.Parallel()
+
+	got := Box("log", []string{"one", "two", "three"}, 12, 4)
+	want := []string{
+		"┌─ log ────┐",
+		"│one       \x1b[0m│",
+		"│two       \x1b[0m│",
+		"└──────────┘",
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("Box() = %q; want %q", got, want)
+	}
+	for _, line := range got {
+		if w := text.StringWidthWithoutEscSequences(line); w != 12 {
+			t.Errorf("line %q is %d wide; want 12", line, w)
+		}
+	}
+	if Box("x", nil, 3, 3) != nil {
+		t.Error("Box() of a too narrow box is not nil")
+	}
+}
+
+// TestSideBySide asserts that blocks are padded to their widths and to the
+// tallest block.
+func TestSideBySide(t *testing.T) {
+	t.Parallel()
+
+	got := SideBySide([][]string{{"a", "b"}, {"c"}}, []int{3, 2})
+	if want := []string{"a  c ", "b    "}; !slices.Equal(got, want) {
+		t.Errorf("SideBySide() = %q; want %q", got, want)
+	}
+}
+
+// TestWindow covers scrolling down to the cursor, back up to it, and a list
+// that fits.
+func TestWindow(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		n, cursor, start, height int
+		wantStart, wantEnd       int
+	}{
+		{n: 3, cursor: 2, start: 0, height: 5, wantStart: 0, wantEnd: 3},
+		{n: 10, cursor: 7, start: 0, height: 5, wantStart: 3, wantEnd: 8},
+		{n: 10, cursor: 2, start: 3, height: 5, wantStart: 2, wantEnd: 7},
+		{n: 10, cursor: 9, start: 8, height: 5, wantStart: 5, wantEnd: 10},
+		{n: 0, cursor: 0, start: 0, height: 5, wantStart: 0, wantEnd: 0},
+	} {
+		start, end := Window(tt.n, tt.cursor, tt.start, tt.height)
+		if start != tt.wantStart || end != tt.wantEnd {
+			t.Errorf("Window(%d, %d, %d, %d) = %d, %d; want %d, %d",
+				tt.n, tt.cursor, tt.start, tt.height, start, end, tt.wantStart, tt.wantEnd)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+// Package tui draws full-screen terminal interfaces without a widget library:
+// it switches the terminal to raw mode and the alternate screen, decodes key
+// presses and provides a few layout helpers for rendering frames as lines.
+package tui
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"sync"
+
+	"golang.org/x/term"
+)
+
+// ErrNoTerminal is returned by Open when stdin or stdout is not a terminal.
+var ErrNoTerminal = errors.New("the tui needs an interactive terminal")
+
+// Escape sequences for the alternate screen, cursor and clearing.
+const (
+	enterAltScreen = "\x1b[?1049h\x1b[?25l"
+	leaveAltScreen = "\x1b[?25h\x1b[?1049l"
+	cursorHome     = "\x1b[H"
+	clearLineEnd   = "\x1b[K"
+	clearBelow     = "\x1b[J"
+)
+
+// Screen is a terminal in raw mode showing the alternate screen. Frames are
+// drawn whole with Draw; key presses arrive on Keys.
+type Screen struct {
+	in    *os.File
+	out   io.Writer
+	state *term.State
+	keys  chan Key
+	buf   bytes.Buffer
+	once  sync.Once
+}
+
+// Open takes over the terminal. Close must be called to give it back, also
+// on error paths, or the shell is left in raw mode.
+func Open() (*Screen, error) {
+	in, out := os.Stdin, os.Stdout
+	if !term.IsTerminal(int(in.Fd())) || !term.IsTerminal(int(out.Fd())) {
+		return nil, ErrNoTerminal
+	}
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return nil, err
+	}
+	s := &Screen{in: in, out: out, state: state, keys: make(chan Key, 64)}
+	_, _ = io.WriteString(out, enterAltScreen)
+	go s.readKeys()
+	return s, nil
+}
+
+// Close restores the terminal to the state Open found it in. Calls after
+// the first do nothing.
+func (s *Screen) Close() {
+	s.once.Do(func() {
+		_, _ = io.WriteString(s.out, leaveAltScreen)
+		_ = term.Restore(int(s.in.Fd()), s.state)
+	})
+}
+
+// Keys delivers key presses. The channel is closed when stdin ends.
+func (s *Screen) Keys() <-chan Key { return s.keys }
+
+// Size returns the terminal's width and height, or 80x24 when unknown.
+func (s *Screen) Size() (width, height int) {
+	w, h, err := term.GetSize(int(s.in.Fd()))
+	if err != nil || w <= 0 || h <= 0 {
+		return 80, 24
+	}
+	return w, h
+}
+
+// Draw replaces the screen with lines, fitted to the terminal width. Lines
+// past the bottom of the terminal are dropped.
+func (s *Screen) Draw(lines []string) {
+	width, height := s.Size()
+	s.buf.Reset()
+	s.buf.WriteString(cursorHome)
+	for i, line := range lines {
+		if i == height {
+			break
+		}
+		if i > 0 {
+			s.buf.WriteString("\r\n")
+		}
+		s.buf.WriteString(Fit(line, width))
+		s.buf.WriteString(clearLineEnd)
+	}
+	s.buf.WriteString(clearBelow)
+	_, _ = s.out.Write(s.buf.Bytes())
+}
+
+// readKeys decodes stdin into Keys until it fails. The goroutine outlives
+// Close, blocked in Read; the process is about to exit by then.
+func (s *Screen) readKeys() {
+	defer close(s.keys)
+	buf := make([]byte, 256)
+	for {
+		n, err := s.in.Read(buf)
+		for _, k := range ParseKeys(buf[:n]) {
+			s.keys <- k
+		}
+		if err != nil {
+			return
+		}
+	}
+}
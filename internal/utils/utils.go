@@ -5,8 +5,12 @@ import (
 	"bufio"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"strings"
+	"sync"
+
+	"golang.org/x/term"
 )
 
 const (
@@ -23,20 +27,57 @@ const (
 //
 // ctx must be non-nil; passing nil is a programmer error.
 func AskConfirm(ctx context.Context, prompt string) (bool, error) {
-	reader := bufio.NewReader(os.Stdin)
-
 	message := strings.TrimSpace(prompt)
 	if message == "" {
 		message = "Proceed?"
 	}
 
 	fmt.Println()
+	return Stdio().Confirm(ctx, message, false)
+}
+
+// Prompter asks questions on a pair of streams, one answer per line. Every
+// read gives up when its context is canceled.
+type Prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+	// password reads a line without echo; nil reads it like any other.
+	password func() (string, error)
+}
+
+// NewPrompter returns a Prompter reading answers from in and writing
+// questions to out.
+func NewPrompter(in io.Reader, out io.Writer) *Prompter {
+	return &Prompter{in: bufio.NewReader(in), out: out}
+}
+
+// Stdio returns the Prompter on the process's stdin and stdout. It is
+// shared, so answers piped in several lines at once are not lost between
+// questions. When stdin is a terminal, passwords are read without echo.
+var Stdio = sync.OnceValue(func() *Prompter {
+	p := NewPrompter(os.Stdin, os.Stdout)
+	if fd := int(os.Stdin.Fd()); term.IsTerminal(fd) {
+		p.password = func() (string, error) {
+			b, err := term.ReadPassword(fd)
+			return string(b), err
+		}
+	}
+	return p
+})
+
+// Confirm asks a yes/no question; an empty answer picks def. It allows up
+// to maxConfirmAttempts tries before returning false.
+func (p *Prompter) Confirm(ctx context.Context, prompt string, def bool) (bool, error) {
+	choices := "[y/N]"
+	if def {
+		choices = "[Y/n]"
+	}
 	for i := range maxConfirmAttempts {
 		if err := ctx.Err(); err != nil {
 			return false, err
 		}
-		fmt.Printf("%s [y/N]: ", message)
-		response, err := readLine(ctx, reader)
+		_, _ = fmt.Fprintf(p.out, "%s %s: ", prompt, choices)
+		response, err := readLine(ctx, p.in)
 		if err != nil {
 			return false, fmt.Errorf("error reading user input: %w", err)
 		}
@@ -45,11 +86,13 @@ func AskConfirm(ctx context.Context, prompt string) (bool, error) {
 		switch response {
 		case "yes", "y":
 			return true, nil
-		case "no", "n", "":
+		case "no", "n":
 			return false, nil
+		case "":
+			return def, nil
 		default:
 			if i < maxConfirmAttempts-1 {
-				fmt.Println("Please answer with 'yes'/'no' or 'y'/'n'.")
+				_, _ = fmt.Fprintln(p.out, "Please answer with 'yes'/'no' or 'y'/'n'.")
 			}
 		}
 	}
@@ -57,6 +100,63 @@ func AskConfirm(ctx context.Context, prompt string) (bool, error) {
 	return false, nil
 }
 
+// Ask asks for a line of text; an empty answer picks def, which is shown in
+// brackets when set.
+func (p *Prompter) Ask(ctx context.Context, prompt, def string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if def != "" {
+		_, _ = fmt.Fprintf(p.out, "%s [%s]: ", prompt, def)
+	} else {
+		_, _ = fmt.Fprintf(p.out, "%s: ", prompt)
+	}
+	response, err := readLine(ctx, p.in)
+	if err != nil {
+		return "", fmt.Errorf("error reading user input: %w", err)
+	}
+	if response = strings.TrimSpace(response); response == "" {
+		return def, nil
+	}
+	return response, nil
+}
+
+// AskPassword asks for a password, without echo on a terminal. The answer
+// is kept as typed, spaces included.
+func (p *Prompter) AskPassword(ctx context.Context, prompt string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	_, _ = fmt.Fprintf(p.out, "%s: ", prompt)
+	if p.password == nil {
+		line, err := readLine(ctx, p.in)
+		if err != nil {
+			return "", fmt.Errorf("error reading user input: %w", err)
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	type result struct {
+		err  error
+		pass string
+	}
+	resultCh := make(chan result, 1)
+	go func() {
+		pass, err := p.password()
+		resultCh <- result{pass: pass, err: err}
+	}()
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case res := <-resultCh:
+		_, _ = fmt.Fprintln(p.out)
+		if res.err != nil {
+			return "", fmt.Errorf("error reading user input: %w", res.err)
+		}
+		return res.pass, nil
+	}
+}
+
 func readLine(ctx context.Context, reader *bufio.Reader) (string, error) {
 	type result struct {
 		err  error
@@ -66,6 +166,10 @@ func readLine(ctx context.Context, reader *bufio.Reader) (string, error) {
 	resultCh := make(chan result, 1)
 	go func() {
 		line, err := reader.ReadString('\n')
+		if err == io.EOF && line != "" {
+			// A last answer without a newline still counts.
+			err = nil
+		}
 		resultCh <- result{line: line, err: err}
 	}()
 
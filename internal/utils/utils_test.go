@@ -1,6 +1,10 @@
 package utils
 
 import (
+	"context"
+	"errors"
+	"io"
+	"strings"
 	"testing"
 )
 
@@ -71,3 +75,62 @@ func TestFormatSize(t *testing.T) {
 		})
 	}
 }
+
+func TestPrompter_Confirm(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		def   bool
+		want  bool
+	}{
+		{name: "yes", input: "y\n", want: true},
+		{name: "no", input: "no\n", def: true, want: false},
+		{name: "default yes", input: "\n", def: true, want: true},
+		{name: "default no", input: "\n", want: false},
+		{name: "retry", input: "maybe\nyes\n", want: true},
+		{name: "gives up", input: "a\nb\nc\ny\n", def: true, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPrompter(strings.NewReader(tt.input), io.Discard)
+			got, err := p.Confirm(context.Background(), "Go?", tt.def)
+			if err != nil {
+				t.Fatalf("Confirm() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Confirm() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrompter_Ask(t *testing.T) {
+	var out strings.Builder
+	p := NewPrompter(strings.NewReader("\n  value  \n pass word \nlast"), &out)
+	ctx := context.Background()
+
+	if got, _ := p.Ask(ctx, "Name", "def"); got != "def" {
+		t.Errorf("Ask() empty = %q, want the default", got)
+	}
+	if got, _ := p.Ask(ctx, "Name", ""); got != "value" {
+		t.Errorf("Ask() = %q, want %q", got, "value")
+	}
+	if got, _ := p.AskPassword(ctx, "Password"); got != " pass word " {
+		t.Errorf("AskPassword() = %q, want the spaces kept", got)
+	}
+	if got, err := p.Ask(ctx, "Name", ""); err != nil || got != "last" {
+		t.Errorf("Ask() at EOF = %q, %v; want %q", got, err, "last")
+	}
+	if !strings.Contains(out.String(), "Name [def]: ") {
+		t.Errorf("output %q does not show the default", out.String())
+	}
+}
+
+func TestPrompter_canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p := NewPrompter(strings.NewReader("y\n"), io.Discard)
+	if _, err := p.Ask(ctx, "Name", ""); !errors.Is(err, context.Canceled) {
+		t.Errorf("Ask() error = %v, want context.Canceled", err)
+	}
+}